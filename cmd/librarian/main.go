@@ -18,12 +18,22 @@ import (
 	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/googleapis/librarian/internal/librarian"
 )
 
 func main() {
-	ctx := context.Background()
+	// On interrupt, the context is cancelled so that running containers are
+	// killed and in-flight git operations are aborted. A second interrupt
+	// terminates the process immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		log.Println("Interrupted; cleaning up. Interrupt again to exit immediately.")
+		stop()
+	}()
 
 	log.Println("Arguments passed to librarian:")
 	for i, arg := range os.Args {
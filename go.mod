@@ -7,6 +7,7 @@ require github.com/go-git/go-git/v5 v5.13.2
 require (
 	github.com/google/go-github/v69 v69.0.0
 	google.golang.org/protobuf v1.36.4
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
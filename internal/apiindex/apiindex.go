@@ -0,0 +1,77 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apiindex reads the API index (api-index-v1.json) published at the
+// root of googleapis, which describes every API in the repository.
+package apiindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the name of the API index file at the root of googleapis.
+const FileName = "api-index-v1.json"
+
+// Index is the content of the API index.
+type Index struct {
+	APIs []*API `json:"apis"`
+}
+
+// API describes a single API (a single version of a service) in the index.
+type API struct {
+	// ID is the proto package of the API, e.g. "google.cloud.functions.v2".
+	ID string `json:"id"`
+	// Directory is the API path, e.g. "google/cloud/functions/v2".
+	Directory string `json:"directory"`
+	// Version is the API version, e.g. "v2".
+	Version string `json:"version"`
+	// HostName is the default host name, e.g. "cloudfunctions.googleapis.com".
+	HostName string `json:"hostName"`
+	// Title is the human-readable title, e.g. "Cloud Functions API".
+	Title string `json:"title"`
+	// ConfigFile is the name of the service config file in Directory.
+	ConfigFile string `json:"configFile"`
+}
+
+// Load reads the API index from the googleapis tree at apiRoot. It returns
+// nil if there's no index.
+func Load(apiRoot string) (*Index, error) {
+	path := filepath.Join(apiRoot, FileName)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{}
+	if err := json.Unmarshal(content, index); err != nil {
+		return nil, fmt.Errorf("invalid API index %s: %w", path, err)
+	}
+	return index, nil
+}
+
+// Find returns the API with the given API path, or nil if it isn't in the index.
+func (index *Index) Find(apiPath string) *API {
+	for _, api := range index.APIs {
+		if api.Directory == apiPath {
+			return api
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package artifactregistry finds images in Artifact Registry docker
+// repositories, using the Artifact Registry REST API.
+package artifactregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Channels which images can be resolved from.
+const (
+	// StableChannel is the image with the highest stable version tag (e.g.
+	// v1.2.3).
+	StableChannel = "stable"
+	// LatestChannel is the most recently updated tagged image.
+	LatestChannel = "latest"
+)
+
+// Repository is an Artifact Registry docker repository.
+type Repository struct {
+	// Host is the registry host, e.g. us-central1-docker.pkg.dev.
+	Host     string
+	Location string
+	Project  string
+	Name     string
+}
+
+// ParseRepository parses a repository of the form
+// LOCATION-docker.pkg.dev/PROJECT/REPOSITORY.
+func ParseRepository(s string) (*Repository, error) {
+	parts := strings.Split(strings.TrimSuffix(s, "/"), "/")
+	location, ok := strings.CutSuffix(parts[0], "-docker.pkg.dev")
+	if !ok || len(parts) != 3 || location == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("%q is not an Artifact Registry repository (LOCATION-docker.pkg.dev/PROJECT/REPOSITORY)", s)
+	}
+	return &Repository{Host: parts[0], Location: location, Project: parts[1], Name: parts[2]}, nil
+}
+
+// Image is a tagged image in a repository.
+type Image struct {
+	// Ref is the image reference, by digest, e.g.
+	// us-central1-docker.pkg.dev/project/repo/image@sha256:...
+	Ref string
+	// Tag is the tag through which the image was resolved.
+	Tag    string
+	Digest string
+}
+
+// version is the subset of an Artifact Registry package version which is read.
+type version struct {
+	Name        string    `json:"name"`
+	UpdateTime  time.Time `json:"updateTime"`
+	RelatedTags []struct {
+		Name string `json:"name"`
+	} `json:"relatedTags"`
+}
+
+// digest returns the digest of the version, which is the last component of its
+// name.
+func (v *version) digest() string {
+	return path.Base(v.Name)
+}
+
+// tags returns the tag names of the version.
+func (v *version) tags() []string {
+	var tags []string
+	for _, tag := range v.RelatedTags {
+		tags = append(tags, path.Base(tag.Name))
+	}
+	return tags
+}
+
+var stableTag = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)$`)
+
+// Resolve returns the image of the named package (e.g.
+// google-cloud-go-generator) in the repository for the given channel, using
+// token to authenticate.
+func (r *Repository) Resolve(ctx context.Context, token, pkg, channel string) (*Image, error) {
+	versions, err := r.listVersions(ctx, token, pkg)
+	if err != nil {
+		return nil, err
+	}
+	var best *Image
+	switch channel {
+	case LatestChannel:
+		slices.SortStableFunc(versions, func(a, b *version) int {
+			return b.UpdateTime.Compare(a.UpdateTime)
+		})
+		for _, v := range versions {
+			if tags := v.tags(); len(tags) > 0 {
+				best = &Image{Tag: tags[0], Digest: v.digest()}
+				break
+			}
+		}
+	case StableChannel:
+		var bestVersion []int
+		for _, v := range versions {
+			for _, tag := range v.tags() {
+				parsed := parseStableTag(tag)
+				if parsed != nil && (bestVersion == nil || slices.Compare(parsed, bestVersion) > 0) {
+					best, bestVersion = &Image{Tag: tag, Digest: v.digest()}, parsed
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid image channel %q; must be %s or %s", channel, StableChannel, LatestChannel)
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no %s image of %s found in %s/%s/%s", channel, pkg, r.Host, r.Project, r.Name)
+	}
+	best.Ref = fmt.Sprintf("%s/%s/%s/%s@%s", r.Host, r.Project, r.Name, pkg, best.Digest)
+	return best, nil
+}
+
+// parseStableTag returns the major, minor and patch versions of a stable
+// version tag, or nil if tag isn't a stable version.
+func parseStableTag(tag string) []int {
+	match := stableTag.FindStringSubmatch(tag)
+	if match == nil {
+		return nil
+	}
+	var parsed []int
+	for _, part := range match[1:] {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		parsed = append(parsed, n)
+	}
+	return parsed
+}
+
+// listVersions lists all the versions of the named package, with their tags.
+func (r *Repository) listVersions(ctx context.Context, token, pkg string) ([]*version, error) {
+	baseURL := fmt.Sprintf("https://artifactregistry.googleapis.com/v1/projects/%s/locations/%s/repositories/%s/packages/%s/versions",
+		url.PathEscape(r.Project), url.PathEscape(r.Location), url.PathEscape(r.Name), url.PathEscape(pkg))
+	var versions []*version
+	pageToken := ""
+	for {
+		query := url.Values{"view": {"FULL"}, "pageSize": {"1000"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Versions      []*version `json:"versions"`
+			NextPageToken string     `json:"nextPageToken"`
+		}
+		err = decodeResponse(resp, &page)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list versions of %s in %s/%s/%s: %w", pkg, r.Host, r.Project, r.Name, err)
+		}
+		versions = append(versions, page.Versions...)
+		if page.NextPageToken == "" {
+			return versions, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func decodeResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bazel extracts GAPIC generation options from the BUILD.bazel files
+// in googleapis.
+package bazel
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GapicLibrary describes a *_gapic_library rule in a BUILD.bazel file.
+type GapicLibrary struct {
+	// Rule is the kind of the rule, e.g. "csharp_gapic_library".
+	Rule string
+	Name string
+	// GRPCServiceConfig is the path of the gRPC service config file, relative
+	// to the root of googleapis.
+	GRPCServiceConfig string
+	// ServiceYAML is the path of the service config file, relative to the
+	// root of googleapis.
+	ServiceYAML string
+	// Transport is the transport(s) to generate, e.g. "grpc+rest".
+	Transport string
+	// RestNumericEnums indicates whether enums are sent as numbers over REST.
+	RestNumericEnums bool
+}
+
+// languageRules maps each language to the prefix of its *_gapic_library rule.
+var languageRules = map[string]string{
+	"cpp":    "cc",
+	"dotnet": "csharp",
+	"go":     "go",
+	"java":   "java",
+	"node":   "nodejs",
+	"php":    "php",
+	"python": "py",
+	"ruby":   "ruby",
+}
+
+// FindGapicLibrary returns the *_gapic_library rule for the given language in
+// the BUILD.bazel file for the API at apiPath under apiRoot. If there's no rule
+// specifically for the language, the first *_gapic_library rule is returned,
+// as most options are common to all languages. It returns nil if there's no
+// BUILD.bazel file or no *_gapic_library rule.
+func FindGapicLibrary(apiRoot, apiPath, language string) (*GapicLibrary, error) {
+	content, err := os.ReadFile(filepath.Join(apiRoot, filepath.FromSlash(apiPath), "BUILD.bazel"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	libraries := ParseGapicLibraries(string(content), apiPath)
+	if len(libraries) == 0 {
+		return nil, nil
+	}
+	if prefix, ok := languageRules[language]; ok {
+		for _, library := range libraries {
+			if library.Rule == prefix+"_gapic_library" {
+				return library, nil
+			}
+		}
+	}
+	return libraries[0], nil
+}
+
+// gapicRuleStart matches the start of a *_gapic_library rule.
+var gapicRuleStart = regexp.MustCompile(`(?m)^\s*(\w+_gapic_library)\s*\(`)
+
+// ParseGapicLibraries returns the *_gapic_library rules in the content of a
+// BUILD.bazel file in the given package (the API path). Only the attributes
+// with literal values are parsed; others are ignored.
+func ParseGapicLibraries(content, pkg string) []*GapicLibrary {
+	content = stripComments(content)
+	var libraries []*GapicLibrary
+	for _, match := range gapicRuleStart.FindAllStringSubmatchIndex(content, -1) {
+		library := &GapicLibrary{Rule: content[match[2]:match[3]]}
+		for name, value := range parseArguments(content[match[1]:]) {
+			switch name {
+			case "name":
+				library.Name = unquote(value)
+			case "grpc_service_config":
+				library.GRPCServiceConfig = resolveLabel(unquote(value), pkg)
+			case "service_yaml":
+				library.ServiceYAML = resolveLabel(unquote(value), pkg)
+			case "transport":
+				library.Transport = unquote(value)
+			case "rest_numeric_enums":
+				library.RestNumericEnums = value == "True"
+			}
+		}
+		libraries = append(libraries, library)
+	}
+	return libraries
+}
+
+// parseArguments parses the keyword arguments of a rule, starting just after
+// the opening parenthesis, up to the matching closing parenthesis. Each value
+// is returned as written, e.g. with quotes for a string.
+func parseArguments(content string) map[string]string {
+	arguments := map[string]string{}
+	depth := 0
+	start := 0
+	addArgument := func(end int) {
+		name, value, found := strings.Cut(content[start:end], "=")
+		if found {
+			arguments[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		start = end + 1
+	}
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '"', '\'':
+			for i++; i < len(content) && content[i] != c; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth == 0 {
+				addArgument(i)
+				return arguments
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				addArgument(i)
+			}
+		}
+	}
+	return arguments
+}
+
+// stripComments removes comments (from "#" to the end of the line, outside
+// string literals).
+func stripComments(content string) string {
+	var builder strings.Builder
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '"', '\'':
+			start := i
+			for i++; i < len(content) && content[i] != c; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+			builder.WriteString(content[start:min(i+1, len(content))])
+		case '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			if i < len(content) {
+				builder.WriteByte('\n')
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// unquote returns the value of a string literal, or an empty string if value
+// isn't a string literal.
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value = `"` + strings.ReplaceAll(value[1:len(value)-1], `"`, `\"`) + `"`
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return ""
+	}
+	return unquoted
+}
+
+// resolveLabel converts a Bazel label for a file (e.g. "file.json",
+// ":file.json" or "//google/example/v1:file.json") to a path relative to the
+// root of googleapis.
+func resolveLabel(label, pkg string) string {
+	if label == "" {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(label, "//"); ok {
+		labelPkg, name, found := strings.Cut(rest, ":")
+		if !found {
+			// "//google/example/v1" refers to the target named v1.
+			return path.Join(labelPkg, path.Base(labelPkg))
+		}
+		return path.Join(labelPkg, name)
+	}
+	return path.Join(pkg, strings.TrimPrefix(label, ":"))
+}
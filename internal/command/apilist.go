@@ -0,0 +1,88 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// apiListEntry is an API listed in an -api-list file.
+type apiListEntry struct {
+	Path string
+	// Channel is the release channel to configure the API with, or empty to
+	// derive it from the API version.
+	Channel string
+}
+
+// readAPIList reads an -api-list file. Each non-blank line which isn't a
+// comment (starting with "#") specifies an API path, optionally followed by
+// space-separated options of the form name=value. The only option at the
+// moment is channel, which overrides the release channel derived from the
+// API version. For example:
+//
+//	# Onboarded 2025-06
+//	google/cloud/functions/v2
+//	google/cloud/memorystore/v1beta channel=alpha
+func readAPIList(path string) ([]apiListEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []apiListEntry
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		apiPath, err := normalizeAPIPath(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		if seen[apiPath] {
+			return nil, fmt.Errorf("%s:%d: %s is listed more than once", path, lineNumber, apiPath)
+		}
+		seen[apiPath] = true
+		entry := apiListEntry{Path: apiPath}
+		for _, option := range fields[1:] {
+			name, value, _ := strings.Cut(option, "=")
+			switch name {
+			case "channel":
+				switch value {
+				case "stable", "beta", "alpha":
+					entry.Channel = value
+				default:
+					return nil, fmt.Errorf("%s:%d: invalid channel %q; must be stable, beta or alpha", path, lineNumber, value)
+				}
+			default:
+				return nil, fmt.Errorf("%s:%d: unknown option %q", path, lineNumber, option)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s doesn't list any APIs", path)
+	}
+	return entries, nil
+}
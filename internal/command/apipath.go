@@ -0,0 +1,138 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/apiindex"
+	"github.com/googleapis/librarian/internal/serviceconfig"
+)
+
+var apiPathSegment = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// normalizeAPIPath converts an API path as specified by a user into the
+// canonical form used for state and container arguments: a relative,
+// slash-separated path with no leading or trailing slashes. An error is
+// returned for paths which can never identify an API, so that they're not
+// passed to containers (where they'd be used to construct mount paths).
+func normalizeAPIPath(apiPath string) (string, error) {
+	original := apiPath
+	apiPath = strings.TrimSpace(apiPath)
+	// Accept Windows-style separators.
+	apiPath = strings.ReplaceAll(apiPath, `\`, "/")
+	apiPath = strings.Trim(apiPath, "/")
+	if apiPath == "" {
+		return "", fmt.Errorf("invalid api-path %q: path is empty", original)
+	}
+	for _, segment := range strings.Split(apiPath, "/") {
+		switch {
+		case segment == "":
+			// Repeated slashes are harmless; path.Clean removes them.
+		case segment == "." || segment == "..":
+			return "", fmt.Errorf("invalid api-path %q: relative segments are not allowed", original)
+		case strings.ToLower(segment) != segment:
+			return "", fmt.Errorf("invalid api-path %q: API paths are lowercase (did you mean %q?)", original, strings.ToLower(apiPath))
+		case !apiPathSegment.MatchString(segment):
+			return "", fmt.Errorf("invalid api-path %q: segment %q contains invalid characters", original, segment)
+		}
+	}
+	return path.Clean(apiPath), nil
+}
+
+// validateAPIPath checks that the API at apiPath exists in the API tree at
+// apiRoot (normally googleapis), contains protos and has a service config
+// (unless it's in a local proto tree, which needn't have one), so that
+// problems are reported clearly before any container is run. If the API
+// doesn't exist, the error suggests the most similar API path, if any is close
+// enough.
+func validateAPIPath(apiRoot, apiPath string) error {
+	dir := filepath.Join(apiRoot, filepath.FromSlash(apiPath))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		if suggestion := suggestAPIPath(apiRoot, apiPath); suggestion != "" {
+			return fmt.Errorf("api-path %s not found in the API root (did you mean %s?)", apiPath, suggestion)
+		}
+		return fmt.Errorf("api-path %s not found in the API root", apiPath)
+	}
+	protos, err := filepath.Glob(filepath.Join(dir, "*.proto"))
+	if err != nil {
+		return err
+	}
+	if len(protos) == 0 {
+		return fmt.Errorf("api-path %s in the API root contains no .proto files", apiPath)
+	}
+	if isLocalProtoTree(apiRoot) {
+		return nil
+	}
+	if config := generationConfig(apiRoot, apiPath); config != nil && config.ServiceYAML != "" {
+		return nil
+	}
+	if index, _ := apiindex.Load(apiRoot); index != nil {
+		if api := index.Find(apiPath); api != nil && api.ConfigFile != "" {
+			return nil
+		}
+	}
+	configs, err := serviceconfig.List(apiRoot, apiPath)
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return fmt.Errorf("api-path %s in the API root has no service config", apiPath)
+	}
+	return nil
+}
+
+// suggestAPIPath returns the API path in the googleapis tree at apiRoot which
+// is most similar to apiPath, or an empty string if none is similar enough.
+func suggestAPIPath(apiRoot, apiPath string) string {
+	paths, err := listAPIPaths(apiRoot)
+	if err != nil {
+		return ""
+	}
+	// Allow roughly one edit for every five characters.
+	best, bestDistance := "", len(apiPath)/5+1
+	for _, candidate := range paths {
+		if distance := editDistance(apiPath, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzNormalizeAPIPath(f *testing.F) {
+	for _, seed := range []string{
+		"google/cloud/functions/v2",
+		" /google//cloud/functions/v2/ ",
+		`google\cloud\functions\v2`,
+		"google/../etc",
+		"./google/cloud",
+		"Google/Cloud",
+		"google/cloud/functions/v2.1",
+		"",
+		"/",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, apiPath string) {
+		got, err := normalizeAPIPath(apiPath)
+		if err != nil {
+			return
+		}
+		again, err := normalizeAPIPath(got)
+		if err != nil {
+			t.Fatalf("normalizeAPIPath(%q) = %q, which doesn't normalize again: %v", apiPath, got, err)
+		}
+		if again != got {
+			t.Errorf("normalizeAPIPath(%q) = %q, which normalizes to %q", apiPath, got, again)
+		}
+		if strings.HasPrefix(got, "/") {
+			t.Errorf("normalizeAPIPath(%q) = %q, which has a leading slash", apiPath, got)
+		}
+		for _, segment := range strings.Split(got, "/") {
+			if segment == ".." {
+				t.Errorf("normalizeAPIPath(%q) = %q, which has a .. segment", apiPath, got)
+			}
+		}
+	})
+}
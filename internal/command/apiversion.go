@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/apiindex"
+)
+
+// stableAPIVersion matches a GA API version, e.g. "v2", capturing the number.
+var stableAPIVersion = regexp.MustCompile(`^v(\d+)$`)
+
+// resolveAPIVersion returns the versioned API path to configure for apiPath.
+// If apiPath already ends with a version, it's returned unchanged. Otherwise
+// the versions of the API are found in the googleapis tree at apiRoot (from the
+// API index if there is one), and the latest stable version is chosen. If there
+// is no stable version, the user is prompted to choose a version when running
+// interactively, and otherwise an error listing the versions is returned. If
+// no versions are found, apiPath is returned unchanged.
+func resolveAPIVersion(apiRoot, apiPath string) (string, error) {
+	if apiVersionDir.MatchString(path.Base(apiPath)) {
+		return apiPath, nil
+	}
+	versions, err := apiVersions(apiRoot, apiPath)
+	if err != nil || len(versions) == 0 {
+		return apiPath, err
+	}
+	if version := latestStableVersion(versions); version != "" {
+		slog.Info(fmt.Sprintf("Using the latest stable version of %s: %s", apiPath, version))
+		return apiPath + "/" + version, nil
+	}
+	if flagInteractive {
+		version, err := promptAPIVersion(os.Stdin, os.Stdout, apiPath, versions)
+		if err != nil {
+			return "", err
+		}
+		return apiPath + "/" + version, nil
+	}
+	return "", fmt.Errorf("%s has no stable version; specify one of the available versions in -api-path: %s", apiPath, strings.Join(versions, ", "))
+}
+
+// apiVersions returns the versions of the API at apiPath (which doesn't
+// include a version) in the googleapis tree at apiRoot, sorted.
+func apiVersions(apiRoot, apiPath string) ([]string, error) {
+	var versions []string
+	index, err := apiindex.Load(apiRoot)
+	if err != nil {
+		return nil, err
+	}
+	if index != nil {
+		for _, api := range index.APIs {
+			if path.Dir(api.Directory) == apiPath {
+				versions = append(versions, path.Base(api.Directory))
+			}
+		}
+	} else {
+		dir := filepath.Join(apiRoot, filepath.FromSlash(apiPath))
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || !apiVersionDir.MatchString(entry.Name()) {
+				continue
+			}
+			protos, err := filepath.Glob(filepath.Join(dir, entry.Name(), "*.proto"))
+			if err != nil {
+				return nil, err
+			}
+			if len(protos) > 0 {
+				versions = append(versions, entry.Name())
+			}
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// latestStableVersion returns the stable version with the highest major
+// version among versions, or an empty string if none are stable.
+func latestStableVersion(versions []string) string {
+	latest, latestMajor := "", -1
+	for _, version := range versions {
+		match := stableAPIVersion.FindStringSubmatch(version)
+		if match == nil {
+			continue
+		}
+		if major, err := strconv.Atoi(match[1]); err == nil && major > latestMajor {
+			latest, latestMajor = version, major
+		}
+	}
+	return latest
+}
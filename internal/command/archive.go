@@ -0,0 +1,239 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveManifestName is the name of the manifest within an archive written by
+// writeArchive.
+const archiveManifestName = "librarian-manifest.json"
+
+// archiveManifest lists the files in an archive.
+type archiveManifest struct {
+	Files []archiveManifestEntry `json:"files"`
+}
+
+type archiveManifestEntry struct {
+	// Path is the slash-separated path of the file within the archive.
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// writeArchive writes the regular files under dir (other than any .git
+// directory) to a gzipped tarball at archivePath, preceded by a manifest
+// (librarian-manifest.json) listing the size and SHA-256 hash of each file.
+// The archive is written to a temporary file which is renamed into place, so an
+// incomplete archive is never left at archivePath.
+func writeArchive(dir, archivePath string) error {
+	if rel, err := filepath.Rel(dir, archivePath); err == nil && filepath.IsLocal(rel) {
+		return fmt.Errorf("archive %s cannot be within the directory being archived", archivePath)
+	}
+	manifest, err := buildArchiveManifest(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(archivePath), ".librarian-archive-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeArchiveContent(tmp, dir, manifest); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Wrote archive of %d files to %s", len(manifest.Files), archivePath))
+	return nil
+}
+
+// buildArchiveManifest hashes each regular file under dir.
+func buildArchiveManifest(dir string) (*archiveManifest, error) {
+	manifest := &archiveManifest{Files: []archiveManifestEntry{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		hash := sha256.New()
+		size, err := io.Copy(hash, f)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, archiveManifestEntry{
+			Path:   filepath.ToSlash(rel),
+			Size:   size,
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeArchiveContent(w io.Writer, dir string, manifest *archiveManifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    archiveManifestName,
+		Mode:    0644,
+		Size:    int64(len(manifestJSON)),
+		ModTime: now,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestJSON); err != nil {
+		return err
+	}
+
+	for _, entry := range manifest.Files {
+		if err := addFileToArchive(tw, filepath.Join(dir, filepath.FromSlash(entry.Path)), entry); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFileToArchive(tw *tar.Writer, path string, entry archiveManifestEntry) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != entry.Size {
+		return fmt.Errorf("%s changed while being archived", path)
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = entry.Path
+	header.Uname, header.Gname = "", ""
+	header.Uid, header.Gid = 0, 0
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, entry.Size)
+	return err
+}
+
+// extractArchive extracts an archive written by writeArchive into dir,
+// checking each file against the archive's manifest.
+func extractArchive(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	header, err := tr.Next()
+	if err != nil {
+		return err
+	}
+	if header.Name != archiveManifestName {
+		return fmt.Errorf("archive doesn't start with %s", archiveManifestName)
+	}
+	var manifest archiveManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return fmt.Errorf("invalid %s: %w", archiveManifestName, err)
+	}
+	for _, entry := range manifest.Files {
+		header, err := tr.Next()
+		if err != nil {
+			return fmt.Errorf("archive is missing %s: %w", entry.Path, err)
+		}
+		if header.Name != entry.Path || !filepath.IsLocal(filepath.FromSlash(header.Name)) {
+			return fmt.Errorf("unexpected file %q in archive; expected %q", header.Name, entry.Path)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		hash := sha256.New()
+		if err := writeFile(target, io.TeeReader(tr, hash), os.FileMode(header.Mode).Perm()); err != nil {
+			return err
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != entry.SHA256 {
+			return fmt.Errorf("%s doesn't match its hash in %s", entry.Path, archiveManifestName)
+		}
+	}
+	return nil
+}
+
+// languageArchivePath returns the path of the archive for a language when
+// -archive is used with multiple languages: the language is inserted before
+// the extension (e.g. output.tar.gz becomes output-python.tar.gz).
+func languageArchivePath(archivePath, language string) string {
+	base, ext := archivePath, ""
+	for _, suffix := range []string{".tar.gz", ".tgz"} {
+		if trimmed, ok := strings.CutSuffix(archivePath, suffix); ok {
+			base, ext = trimmed, suffix
+			break
+		}
+	}
+	return fmt.Sprintf("%s-%s%s", base, language, ext)
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gcs"
+	"github.com/googleapis/librarian/internal/googleauth"
+)
+
+// artifacts records the directories produced by a command which are uploaded
+// as artifacts when -artifact-bucket is specified, keyed by the (slash-separated)
+// name under which they're uploaded.
+var artifacts struct {
+	mu   sync.Mutex
+	dirs map[string]string
+	// url is the Cloud Console URL of the current run's artifacts, if they're
+	// being uploaded.
+	url string
+}
+
+// artifactsURL returns the Cloud Console URL of the artifacts of the current
+// run, or an empty string if -artifact-bucket isn't specified.
+func artifactsURL() string {
+	artifacts.mu.Lock()
+	defer artifacts.mu.Unlock()
+	return artifacts.url
+}
+
+// recordArtifactDir records dir to be uploaded as an artifact with the given
+// name, prefixed by the current language.
+func recordArtifactDir(name, dir string) {
+	artifacts.mu.Lock()
+	defer artifacts.mu.Unlock()
+	if artifacts.dirs == nil {
+		artifacts.dirs = map[string]string{}
+	}
+	artifacts.dirs[path.Join(flagLanguage, name)] = dir
+}
+
+// uploadArtifacts wraps the Run function of a command so that, when
+// -artifact-bucket is specified, the artifacts of each run are uploaded to
+// <prefix>/<command>-<timestamp>/ in the bucket once the run has completed
+// (whether or not it succeeded): the recorded artifact directories, the log of
+// the run (librarian.log) and the recent output of containers
+// (container-output.log). Nothing is uploaded if the run is interrupted.
+func uploadArtifacts(c *Command, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if flagArtifactBucket == "" {
+			return run(ctx)
+		}
+		location, err := gcs.ParseLocation(flagArtifactBucket)
+		if err != nil {
+			return err
+		}
+		runPrefix := location.Object(fmt.Sprintf("%s-%s", c.Name, time.Now().UTC().Format("20060102T150405Z")))
+		artifacts.mu.Lock()
+		artifacts.url = fmt.Sprintf("https://console.cloud.google.com/storage/browser/%s/%s", location.Bucket, runPrefix)
+		artifacts.mu.Unlock()
+
+		logFile, err := os.CreateTemp("", "librarian-log-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(logFile.Name())
+		defer logFile.Close()
+		logWriter := log.Writer()
+		log.SetOutput(io.MultiWriter(logWriter, logFile))
+		defer log.SetOutput(logWriter)
+
+		runErr := run(ctx)
+		if ctx.Err() != nil {
+			return runErr
+		}
+
+		slog.Info(fmt.Sprintf("Uploading artifacts to gs://%s/%s", location.Bucket, runPrefix))
+		if err := uploadRunArtifacts(ctx, location.Bucket, runPrefix, logFile.Name()); err != nil {
+			slog.Error(fmt.Sprintf("Unable to upload artifacts: %s", err))
+			if runErr == nil {
+				return err
+			}
+		}
+		return runErr
+	}
+}
+
+// uploadRunArtifacts uploads the artifacts recorded during a run, and its logs,
+// under prefix in bucket. The recorded artifacts are then cleared, so that a
+// subsequent run (in the same process) starts afresh.
+func uploadRunArtifacts(ctx context.Context, bucket, prefix, logFile string) error {
+	artifacts.mu.Lock()
+	dirs := artifacts.dirs
+	artifacts.dirs = nil
+	artifacts.mu.Unlock()
+
+	token, err := googleauth.AccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	for name, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		count, err := gcs.UploadDir(ctx, token, bucket, path.Join(prefix, name), dir)
+		if err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Uploaded %d files from %s", count, dir))
+	}
+	if err := gcs.UploadFile(ctx, token, bucket, path.Join(prefix, "librarian.log"), logFile); err != nil {
+		return err
+	}
+	if output := container.RecentOutput(math.MaxInt); strings.TrimSpace(output) != "" {
+		if err := gcs.Upload(ctx, token, bucket, path.Join(prefix, "container-output.log"), strings.NewReader(output+"\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// resolveGitHubToken populates flagGitHubToken when it hasn't been specified
+// directly. The token is obtained from the first of these sources to succeed:
+//
+//   - an installation token for the GitHub App specified with -github-app-id
+//   - the GitHub CLI ("gh auth token")
+//   - the configured git credential helper for github.com (or the host
+//     specified by -github-host)
+//
+// If no token can be found, flagGitHubToken is left empty.
+func resolveGitHubToken(ctx context.Context) error {
+	if flagGitHubToken != "" {
+		return nil
+	}
+	if flagGitHubAppID != 0 {
+		return resolveGitHubAppToken(ctx)
+	}
+	if token, err := runCredentialCommand(ctx, "", "gh", "auth", "token", "--hostname", gitrepo.GitHubHost()); err == nil && token != "" {
+		slog.Info("Using GitHub token from the GitHub CLI")
+		flagGitHubToken = token
+		return nil
+	}
+	output, err := runCredentialCommand(ctx, fmt.Sprintf("protocol=https\nhost=%s\n\n", gitrepo.GitHubHost()), "git", "credential", "fill")
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if password, ok := strings.CutPrefix(line, "password="); ok && password != "" {
+			slog.Info("Using GitHub token from the git credential helper")
+			flagGitHubToken = password
+			return nil
+		}
+	}
+	return nil
+}
+
+// runCredentialCommand runs a command which prints credentials, returning its
+// trimmed standard output. Interactive prompts are disabled.
+func runCredentialCommand(ctx context.Context, stdin, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GH_PROMPT_DISABLED=1")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// resolveGitHubAppToken mints an installation token for the GitHub App
+// specified with -github-app-id and -github-app-private-key.
+func resolveGitHubAppToken(ctx context.Context) error {
+	if flagGitHubAppPrivateKey == "" {
+		return fmt.Errorf("-github-app-private-key must be provided if -github-app-id is set")
+	}
+	privateKey, err := os.ReadFile(flagGitHubAppPrivateKey)
+	if err != nil {
+		return err
+	}
+	gitHubRepo, err := gitrepo.ParseGitHubURL(languageRepoURL(flagLanguage))
+	if err != nil {
+		return err
+	}
+	token, err := gitrepo.CreateInstallationToken(ctx, flagGitHubAppID, privateKey, flagGitHubAppInstallationID, gitHubRepo)
+	if err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Authenticated as GitHub App %d", flagGitHubAppID))
+	flagGitHubToken = token
+	return nil
+}
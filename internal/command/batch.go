@@ -0,0 +1,389 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// batchReportFileName is the name of the consolidated report of a batch,
+// written to the output root.
+const batchReportFileName = "batch-report.json"
+
+// logTimestampFormat is the format of the timestamp which the log package
+// prefixes each line with.
+const logTimestampFormat = "2006/01/02 15:04:05"
+
+// batchControlledFlags are the generate flags which the batch command sets for
+// each entry, so can't be specified as entry options.
+var batchControlledFlags = []string{"api-path", "api-root", "api-tarball", "ci", "descriptor-set", "language", "output", "work-root"}
+
+// CmdBatch runs generate for each entry of a run manifest (specified by
+// -manifest), such as:
+//
+//	options:
+//	  build: true
+//	entries:
+//	- language: dotnet,java
+//	  api-path: google/cloud/functions/v2
+//	- language: python
+//	  api-path: google/cloud/workflows/v1
+//	  options:
+//	    build: false
+//	    image-channel: candidate
+//
+// Each entry's language may list several languages (or "all"), in which case
+// the entry is run for each of them. Options are generate flags (without the
+// leading "-"); the top-level options apply to every entry, and an entry's own
+// options override them. googleapis is fetched once (unless -api-root is
+// specified) and shared by every entry. Up to -parallelism entries are run at
+// once, each as a separate generate process with its own working directory
+// and log file, so that a failure in one entry doesn't affect the others.
+// Each entry writes to <output>/<language>/<api-path>, and its log to
+// <output>/logs. The result of every entry is logged at the end and written to
+// batch-report.json in the output root, and the command fails if any entry
+// failed.
+var CmdBatch = &Command{
+	Name:  "batch",
+	Short: "Generate client library code for each entry of a run manifest",
+	Run: func(ctx context.Context) error {
+		if flagManifest == "" {
+			return fmt.Errorf("-manifest is not provided")
+		}
+		if flagParallelism < 1 {
+			return fmt.Errorf("invalid -parallelism: %d; must be at least 1", flagParallelism)
+		}
+		if flagAPIRoot == "" && flagAPITarball == "" {
+			return fmt.Errorf("-api-root or -api-tarball must be provided")
+		}
+		tasks, err := loadBatchManifest(flagManifest)
+		if err != nil {
+			return err
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("unable to find the librarian executable: %w", err)
+		}
+		// The batch itself runs no containers: each generate process runs
+		// its own preflight checks.
+		startOfRun := time.Now()
+		tmpRoot, err := createTmpWorkingRoot(startOfRun)
+		if err != nil {
+			return err
+		}
+		var apiRoot string
+		if flagAPIRoot == "" {
+			apiRoot, err = downloadGoogleapisTarball(ctx, tmpRoot, flagAPITarball)
+		} else if apiRoot, err = filepath.Abs(flagAPIRoot); err == nil {
+			apiRoot, err = resolveLocalAPIRoot(ctx, tmpRoot, apiRoot)
+		}
+		if err != nil {
+			return err
+		}
+
+		outputRoot := filepath.Join(tmpRoot, "output")
+		if flagOutput != "" {
+			if outputRoot, err = filepath.Abs(flagOutput); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(outputRoot, 0755); err != nil {
+			return err
+		}
+		recordArtifactDir("output", outputRoot)
+		tmpRootHoldsOutput = flagOutput == ""
+
+		runner := &batchRunner{
+			executable: executable,
+			apiRoot:    apiRoot,
+			outputRoot: outputRoot,
+			entriesDir: filepath.Join(tmpRoot, "entries"),
+			logsDir:    filepath.Join(outputRoot, "logs"),
+		}
+		results := runner.run(ctx, tasks)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return reportBatch(results, startOfRun, outputRoot)
+	},
+}
+
+// batchManifest is the content of a run manifest.
+type batchManifest struct {
+	// Options are the generate flags applied to every entry.
+	Options map[string]string    `yaml:"options"`
+	Entries []batchManifestEntry `yaml:"entries"`
+}
+
+// batchManifestEntry is a single entry in a run manifest.
+type batchManifestEntry struct {
+	// Language is a comma-separated list of languages, or "all".
+	Language string `yaml:"language"`
+	APIPath  string `yaml:"api-path"`
+	// Options are the generate flags for this entry, overriding the
+	// manifest's top-level options.
+	Options map[string]string `yaml:"options"`
+}
+
+// batchTask is a single generate run in a batch: a language and API path from
+// a manifest entry, with the generate flags for its options.
+type batchTask struct {
+	language string
+	apiPath  string
+	args     []string
+}
+
+func (t batchTask) String() string {
+	return t.language + ":" + t.apiPath
+}
+
+// loadBatchManifest reads and validates the run manifest at path, returning
+// the tasks it describes. (YAML is a superset of JSON, so a manifest can be
+// written in either.)
+func loadBatchManifest(path string) ([]batchTask, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest batchManifest
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid run manifest %s: %w", path, err)
+	}
+	if len(manifest.Entries) == 0 {
+		return nil, fmt.Errorf("run manifest %s has no entries", path)
+	}
+
+	var tasks []batchTask
+	seen := map[string]bool{}
+	for i, entry := range manifest.Entries {
+		if entry.Language == "" || entry.APIPath == "" {
+			return nil, fmt.Errorf("entry %d in run manifest %s must specify language and api-path", i+1, path)
+		}
+		languages, err := parseLanguages(entry.Language)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d in run manifest %s: %w", i+1, path, err)
+		}
+		apiPath, err := normalizeAPIPath(entry.APIPath)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d in run manifest %s: %w", i+1, path, err)
+		}
+		options := maps.Clone(manifest.Options)
+		if options == nil {
+			options = map[string]string{}
+		}
+		maps.Copy(options, entry.Options)
+		args, err := batchOptionArgs(options)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d in run manifest %s: %w", i+1, path, err)
+		}
+		for _, language := range languages {
+			task := batchTask{language: language, apiPath: apiPath, args: args}
+			if seen[task.String()] {
+				return nil, fmt.Errorf("run manifest %s lists %s more than once", path, task)
+			}
+			seen[task.String()] = true
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// batchOptionArgs returns the generate flags for the given entry options,
+// checking that each is a generate flag which isn't set by the batch command.
+func batchOptionArgs(options map[string]string) ([]string, error) {
+	var args []string
+	for name, value := range options {
+		if slices.Contains(batchControlledFlags, name) {
+			return nil, fmt.Errorf("option %s is set by the batch command, so can't be specified", name)
+		}
+		if CmdGenerate.flags.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown option %s: not a flag of the generate command", name)
+		}
+		args = append(args, fmt.Sprintf("-%s=%s", name, value))
+	}
+	slices.Sort(args)
+	return args, nil
+}
+
+// batchRunner runs the tasks of a batch, each as a generate process.
+type batchRunner struct {
+	executable string
+	apiRoot    string
+	outputRoot string
+	// entriesDir holds the working directory of each task.
+	entriesDir string
+	// logsDir holds the log file of each task. It's in the output root, so
+	// that the logs referenced by batch-report.json are kept with it.
+	logsDir string
+}
+
+// batchResult is the result of a task, as written to batch-report.json.
+type batchResult struct {
+	Language        string  `json:"language"`
+	APIPath         string  `json:"apiPath"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	// Result is "success" or "failure".
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	Output string `json:"output"`
+	Log    string `json:"log"`
+}
+
+// run runs the tasks, up to -parallelism at once, returning their results in
+// the same order.
+func (r *batchRunner) run(ctx context.Context, tasks []batchTask) []*batchResult {
+	results := make([]*batchResult, len(tasks))
+	semaphore := make(chan struct{}, flagParallelism)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = r.runTask(ctx, i, task)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runTask runs generate for a single task, in its own working directory, with
+// its output written to a log file.
+func (r *batchRunner) runTask(ctx context.Context, index int, task batchTask) *batchResult {
+	result := &batchResult{
+		Language: task.language,
+		APIPath:  task.apiPath,
+		Output:   filepath.Join(r.outputRoot, task.language, filepath.FromSlash(task.apiPath)),
+		Log:      filepath.Join(r.logsDir, fmt.Sprintf("%d.log", index+1)),
+	}
+	start := time.Now()
+	err := r.runGenerate(ctx, index, task, result)
+	result.DurationSeconds = time.Since(start).Seconds()
+	if err != nil {
+		result.Result = "failure"
+		result.Error = err.Error()
+		slog.Error(fmt.Sprintf("%s: failed: %s (see %s)", task, err, result.Log))
+	} else {
+		result.Result = "success"
+		slog.Info(fmt.Sprintf("%s: succeeded", task))
+	}
+	return result
+}
+
+func (r *batchRunner) runGenerate(ctx context.Context, index int, task batchTask, result *batchResult) error {
+	workRoot := filepath.Join(r.entriesDir, fmt.Sprint(index+1))
+	for _, dir := range []string{workRoot, result.Output, r.logsDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	logFile, err := os.Create(result.Log)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	args := append([]string{
+		CmdGenerate.Name,
+		"-language=" + task.language,
+		"-api-path=" + task.apiPath,
+		"-api-root=" + r.apiRoot,
+		"-output=" + result.Output,
+		"-work-root=" + workRoot,
+	}, task.args...)
+	slog.Info(fmt.Sprintf("%s: running generate", task))
+	cmd := exec.CommandContext(ctx, r.executable, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Run(); err != nil {
+		if line := lastLogLine(result.Log); line != "" {
+			return fmt.Errorf("generate failed (%w): %s", err, line)
+		}
+		return err
+	}
+	return nil
+}
+
+// lastLogLine returns the last non-empty line of the log file at path, without
+// its timestamp. For a failed run, that's normally the error.
+func lastLogLine(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	var last string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			last = line
+		}
+	}
+	if len(last) > len(logTimestampFormat) {
+		if _, err := time.Parse(logTimestampFormat, last[:len(logTimestampFormat)]); err == nil {
+			last = strings.TrimSpace(last[len(logTimestampFormat):])
+		}
+	}
+	return last
+}
+
+// reportBatch logs the result of each task and writes them to
+// batch-report.json in the output root, returning an error if any task failed.
+func reportBatch(results []*batchResult, startOfRun time.Time, outputRoot string) error {
+	var failed []string
+	for _, result := range results {
+		if result.Result == "success" {
+			slog.Info(fmt.Sprintf("%s:%s: succeeded in %.0fs", result.Language, result.APIPath, result.DurationSeconds))
+		} else {
+			slog.Error(fmt.Sprintf("%s:%s: failed: %s", result.Language, result.APIPath, result.Error))
+			failed = append(failed, result.Language+":"+result.APIPath)
+		}
+	}
+
+	report := struct {
+		StartTime       time.Time      `json:"startTime"`
+		DurationSeconds float64        `json:"durationSeconds"`
+		Entries         []*batchResult `json:"entries"`
+	}{startOfRun, time.Since(startOfRun).Seconds(), results}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	reportPath := filepath.Join(outputRoot, batchReportFileName)
+	if err := os.WriteFile(reportPath, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Batch report written to %s", reportPath))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed for %d of %d entries: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
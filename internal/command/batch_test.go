@@ -0,0 +1,49 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBatchRunnerWritesLogsUnderOutput(t *testing.T) {
+	executable, err := exec.LookPath("false")
+	if err != nil {
+		t.Skip("false is not available")
+	}
+	tmpRoot, outputRoot := t.TempDir(), t.TempDir()
+	runner := &batchRunner{
+		executable: executable,
+		apiRoot:    t.TempDir(),
+		outputRoot: outputRoot,
+		entriesDir: filepath.Join(tmpRoot, "entries"),
+		logsDir:    filepath.Join(outputRoot, "logs"),
+	}
+	result := runner.runTask(context.Background(), 0, batchTask{language: "testlang", apiPath: selfTestAPIPath})
+	if result.Result != "failure" {
+		t.Errorf("result = %q; want failure", result.Result)
+	}
+	if !strings.HasPrefix(result.Log, outputRoot+string(filepath.Separator)) {
+		t.Errorf("log = %s; want it under the output root %s", result.Log, outputRoot)
+	}
+	if _, err := os.Stat(result.Log); err != nil {
+		t.Error(err)
+	}
+}
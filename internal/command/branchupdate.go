@@ -0,0 +1,195 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// prepareBranchUpdate rewrites the commits made by this run as specified by
+// -update-commits, before they're force-pushed to the branch of an existing
+// pull request:
+//
+//   - replace leaves them as they are, so they replace the commits on the branch.
+//   - squash combines them into a single commit on top of the base commit.
+//   - amend replaces the last commit on the branch with a single commit
+//     containing the run's changes, keeping any earlier commits (e.g. made by
+//     reviewers). If the last commit wasn't made by librarian, it's kept too,
+//     and the new commit is added on top of it. This is only possible when
+//     the branch already contains the base commit, and the run's changes
+//     apply cleanly on top of it; otherwise the commits are squashed instead.
+func prepareBranchUpdate(ctx context.Context, result *generationResult, pr *gitrepo.PullRequest) error {
+	if flagUpdateCommits == "replace" {
+		return nil
+	}
+	commits, err := gitrepo.CommitsSince(ctx, result.repo, result.baseHash)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+	parent := result.baseHash
+	if flagUpdateCommits == "amend" {
+		amendParent, err := amendableParent(ctx, result, pr)
+		if err != nil {
+			return err
+		}
+		switch {
+		case amendParent == "":
+			slog.Info(fmt.Sprintf("Branch %s can't be amended, as it doesn't contain the base commit %s; squashing instead", pr.HeadBranch, result.baseHash))
+		case amendParent == result.baseHash:
+			// Amending is the same as squashing.
+		default:
+			if err := gitrepo.StageChangesOnto(ctx, result.repo, result.baseHash, amendParent); err != nil {
+				slog.Info(fmt.Sprintf("Unable to amend branch %s, so squashing instead: %s", pr.HeadBranch, err))
+			} else {
+				parent = amendParent
+			}
+		}
+	}
+	if parent == result.baseHash {
+		if len(commits) == 1 {
+			return nil
+		}
+		if err := gitrepo.SoftReset(ctx, result.repo, parent); err != nil {
+			return err
+		}
+	}
+	var hashes []string
+	for _, commit := range commits {
+		hashes = append(hashes, commit.Hash.String())
+	}
+	forgetCommits(hashes)
+	slog.Info(fmt.Sprintf("Combining %d commits into one on top of %s", len(commits), parent))
+	return commitAll(ctx, result.repo, combinedCommitMessage(result, commits))
+}
+
+// amendableParent returns the commit on the branch of the existing pull
+// request on top of which the changes of this run are committed when amending:
+// the parent of the last commit if it was made by librarian, or otherwise the
+// last commit itself, so that commits made by anyone else are never replaced.
+// An empty string is returned if the branch can't be amended.
+func amendableParent(ctx context.Context, result *generationResult, pr *gitrepo.PullRequest) (string, error) {
+	tip, err := gitrepo.FetchBranch(ctx, result.repo, pr.HeadCloneURL, pr.HeadBranch, forgeToken())
+	if err != nil {
+		return "", err
+	}
+	tipCommit, err := gitrepo.GetCommit(ctx, result.repo, tip)
+	if err != nil {
+		return "", err
+	}
+	parent := tip
+	if gitrepo.IsGeneratedCommit(tipCommit) {
+		if tipCommit.NumParents() != 1 {
+			return "", nil
+		}
+		parent = tipCommit.ParentHashes[0].String()
+	}
+	contained, err := gitrepo.IsAncestor(ctx, result.repo, result.baseHash, parent)
+	if err != nil || !contained {
+		return "", err
+	}
+	return parent, nil
+}
+
+// combinedCommitMessage returns the message for a single commit combining
+// commits (most recent first): the message of the only commit, or a summary
+// line followed by the message of each commit in chronological order.
+func combinedCommitMessage(result *generationResult, commits []*object.Commit) string {
+	if len(commits) == 1 {
+		return commits[0].Message
+	}
+	title := result.title
+	if title == "" {
+		title = fmt.Sprintf("Regenerated %s", apiScope())
+		if apiScope() == "all" {
+			title = "Regenerated all changed APIs"
+		}
+	}
+	var msg strings.Builder
+	msg.WriteString(title)
+	msg.WriteString("\n")
+	for _, commit := range slices.Backward(commits) {
+		fmt.Fprintf(&msg, "\n%s\n", strings.TrimSpace(commit.Message))
+	}
+	return msg.String()
+}
+
+// pushToExistingBranch force-pushes HEAD to the branch of an existing pull
+// request, with a lease on the commit the branch was last seen at, so that
+// changes pushed in the meantime are never overwritten silently. The branch is
+// only overwritten if every commit on it which HEAD doesn't include was made by
+// librarian, so that changes pushed by a person cause a clear failure. If the
+// branch changes while pushing, which happens when another run updates the
+// same pull request at the same time, the push is retried up to -push-retries
+// times.
+func pushToExistingBranch(ctx context.Context, repo *gitrepo.Repo, pr *gitrepo.PullRequest) error {
+	head, err := gitrepo.HeadHash(ctx, repo)
+	if err != nil {
+		return err
+	}
+	delay := flagPushRetryDelay
+	for attempt := 0; ; attempt++ {
+		tip, err := gitrepo.FetchBranch(ctx, repo, pr.HeadCloneURL, pr.HeadBranch, forgeToken())
+		if err != nil {
+			return err
+		}
+		if err := checkBranchOverwritable(ctx, repo, pr, tip, head); err != nil {
+			return err
+		}
+		err = gitrepo.PushBranchWithLease(ctx, repo, pr.HeadCloneURL, pr.HeadBranch, forgeToken(), tip)
+		if !errors.Is(err, gitrepo.ErrStaleLease) {
+			return err
+		}
+		if attempt >= flagPushRetries {
+			return fmt.Errorf("branch %s kept changing while being pushed to, after %d retries: %w", pr.HeadBranch, flagPushRetries, err)
+		}
+		slog.Info(fmt.Sprintf("Branch %s changed while being pushed to; retrying in %s", pr.HeadBranch, delay))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// checkBranchOverwritable returns an error if overwriting the branch of pr
+// (currently at tip) with head would discard commits which weren't made by
+// librarian, i.e. if any commit reachable from tip but not from head wasn't
+// made by librarian.
+func checkBranchOverwritable(ctx context.Context, repo *gitrepo.Repo, pr *gitrepo.PullRequest, tip, head string) error {
+	discarded, err := gitrepo.CommitsNotIn(ctx, repo, tip, head)
+	if err != nil {
+		return err
+	}
+	for _, commit := range discarded {
+		if !gitrepo.IsGeneratedCommit(commit) {
+			return fmt.Errorf("branch %s of pull request %s has commit %s by %s <%s>, which would be overwritten; not updating the pull request",
+				pr.HeadBranch, pr.HTMLURL, commit.Hash.String()[:7], commit.Committer.Name, commit.Committer.Email)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// pullRequestBranch is a branch of an existing pull request in a scratch
+// origin repo, with a clone of the origin.
+type pullRequestBranch struct {
+	repo     *gitrepo.Repo
+	pr       *gitrepo.PullRequest
+	baseHash string
+}
+
+// newPullRequestBranch clones a scratch language repo, and pushes a commit
+// made by librarian to a pull request branch in it.
+func newPullRequestBranch(t *testing.T) *pullRequestBranch {
+	t.Helper()
+	ctx := context.Background()
+	_, origin := setUpScratchRepos(t)
+	repo, err := gitrepo.Clone(ctx, filepath.Join(t.TempDir(), "clone"), origin, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseHash, err := gitrepo.HeadHash(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &pullRequestBranch{
+		repo:     repo,
+		pr:       &gitrepo.PullRequest{HeadBranch: "librarian-test", HeadCloneURL: origin},
+		baseHash: baseHash,
+	}
+	b.commit(t, "generated.txt", "")
+	return b
+}
+
+// commit commits a new file and pushes it to the pull request branch, made by
+// librarian if author is empty, or otherwise by git as author.
+func (b *pullRequestBranch) commit(t *testing.T, name, author string) string {
+	t.Helper()
+	ctx := context.Background()
+	if err := os.WriteFile(filepath.Join(b.repo.Dir, name), []byte(name), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if author == "" {
+		if err := commitAll(ctx, b.repo, "chore: "+name); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git not found")
+		}
+		if output, err := exec.Command("git", "-C", b.repo.Dir, "add", name).CombinedOutput(); err != nil {
+			t.Fatalf("git add failed: %v: %s", err, output)
+		}
+		cmd := exec.Command("git", "-C", b.repo.Dir, "-c", "user.name="+author, "-c", "user.email="+author+"@example.com", "commit", "-qm", "chore: "+name)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit failed: %v: %s", err, output)
+		}
+	}
+	if err := gitrepo.PushBranch(ctx, b.repo, "", b.pr.HeadBranch, "", true); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := gitrepo.HeadHash(ctx, b.repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestAmendableParent(t *testing.T) {
+	ctx := context.Background()
+	b := newPullRequestBranch(t)
+	result := &generationResult{repo: b.repo, baseHash: b.baseHash}
+
+	got, err := amendableParent(ctx, result, b.pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != b.baseHash {
+		t.Errorf("amendableParent with a generated tip = %q; want its parent %q", got, b.baseHash)
+	}
+
+	reviewed := b.commit(t, "review.txt", "reviewer")
+	got, err = amendableParent(ctx, result, b.pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != reviewed {
+		t.Errorf("amendableParent with a reviewer's tip = %q; want the tip %q", got, reviewed)
+	}
+}
+
+func TestCheckBranchOverwritable(t *testing.T) {
+	ctx := context.Background()
+	b := newPullRequestBranch(t)
+	generated, err := gitrepo.HeadHash(ctx, b.repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBranchOverwritable(ctx, b.repo, b.pr, generated, b.baseHash); err != nil {
+		t.Errorf("overwriting a commit made by librarian: %v", err)
+	}
+
+	b.commit(t, "review.txt", "reviewer")
+	tip := b.commit(t, "regenerated.txt", "")
+	if err := checkBranchOverwritable(ctx, b.repo, b.pr, tip, b.baseHash); err == nil {
+		t.Error("overwriting a reviewer's commit under a commit made by librarian succeeded; want an error")
+	}
+	if err := checkBranchOverwritable(ctx, b.repo, b.pr, tip, tip); err != nil {
+		t.Errorf("overwriting with a commit including the tip: %v", err)
+	}
+}
+
+func TestPushBranchWithLeaseWithoutTrackingRef(t *testing.T) {
+	ctx := context.Background()
+	b := newPullRequestBranch(t)
+	tip, err := gitrepo.HeadHash(ctx, b.repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output, err := exec.Command("git", "-C", b.repo.Dir, "update-ref", "-d", "refs/remotes/origin/main").CombinedOutput(); err != nil {
+		t.Skipf("unable to delete the tracking ref: %v: %s", err, output)
+	}
+	if err := os.WriteFile(filepath.Join(b.repo.Dir, "amended.txt"), []byte("amended"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitAll(ctx, b.repo, "chore: amended"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitrepo.PushBranchWithLease(ctx, b.repo, b.pr.HeadCloneURL, b.pr.HeadBranch, "", tip); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(b.repo.Dir, "amended.txt"), []byte("amended again"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitAll(ctx, b.repo, "chore: amended again"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitrepo.PushBranchWithLease(ctx, b.repo, b.pr.HeadCloneURL, b.pr.HeadBranch, "", tip); !errors.Is(err, gitrepo.ErrStaleLease) {
+		t.Errorf("PushBranchWithLease with a stale lease = %v; want ErrStaleLease", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// CmdBuild runs only the container build step, against either an existing
+// language repo (-repo-root) or the output of the generate command (-output).
+// This allows a failed build to be rerun without regenerating code. If
+// -api-path isn't specified, the container builds everything.
+var CmdBuild = &Command{
+	Name:  "build",
+	Short: "Build existing generated code in a language repo or output directory",
+	Run: func(ctx context.Context) error {
+		if !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		if (flagRepoRoot == "") == (flagOutput == "") {
+			return fmt.Errorf("exactly one of -repo-root or -output must be provided")
+		}
+		if flagAPIPath != "" {
+			apiPath, err := normalizeAPIPath(flagAPIPath)
+			if err != nil {
+				return err
+			}
+			flagAPIPath = apiPath
+		}
+
+		if flagOutput != "" {
+			outputDir, err := filepath.Abs(flagOutput)
+			if err != nil {
+				return err
+			}
+			return container.Build(ctx, containerOptions(nil), "generator-output", outputDir, flagAPIPath)
+		}
+
+		repoRoot, err := filepath.Abs(flagRepoRoot)
+		if err != nil {
+			return err
+		}
+		languageRepo, err := gitrepo.Open(ctx, repoRoot)
+		if err != nil {
+			return err
+		}
+		state, err := loadState(languageRepo)
+		if err != nil {
+			return err
+		}
+		if flagAPIPath != "" && findApiState(state, flagAPIPath) == nil {
+			return fmt.Errorf("API %s is not configured in the language repo", flagAPIPath)
+		}
+		return container.Build(ctx, containerOptions(state), "repo-root", languageRepo.Dir, flagAPIPath)
+	},
+}
+
+// findApiState returns the state of the API with the given path, or nil if the
+// API isn't configured.
+func findApiState(state *statepb.PipelineState, apiPath string) *statepb.ApiGenerationState {
+	for _, apiState := range state.ApiGenerationStates {
+		if apiState.Id == apiPath {
+			return apiState
+		}
+	}
+	return nil
+}
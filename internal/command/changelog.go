@@ -0,0 +1,111 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// changelogEntry describes a single googleapis commit included when
+// regenerating an API.
+type changelogEntry struct {
+	APIPath     string
+	Description string
+	Commit      string
+	Breaking    bool
+}
+
+// changelogSection groups changelog entries by conventional commit type.
+type changelogSection struct {
+	Title   string
+	Entries []changelogEntry
+}
+
+// conventionalCommitSubject matches the subject line of a conventional commit,
+// e.g. "feat(pubsub)!: Add a new method". The groups are the type, the
+// optional breaking change marker and the description.
+var conventionalCommitSubject = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?:\s*(.*)$`)
+
+// changelogSectionTitles maps conventional commit types to section titles, in
+// the order in which the sections are listed. Other types are listed under
+// "Other changes".
+var changelogSectionTitles = []struct {
+	commitType string
+	title      string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug fixes"},
+	{"docs", "Documentation"},
+}
+
+// newChangelogEntries returns the changelog entries for googleapis commits
+// included when regenerating the given API.
+func newChangelogEntries(apiPath string, commits []object.Commit) []changelogEntry {
+	var entries []changelogEntry
+	for _, commit := range commits {
+		subject, body, _ := strings.Cut(commit.Message, "\n")
+		entry := changelogEntry{
+			APIPath:     apiPath,
+			Description: strings.TrimSpace(subject),
+			Commit:      commit.Hash.String(),
+			Breaking:    strings.Contains(body, "BREAKING CHANGE"),
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// buildChangelog groups changelog entries into sections by conventional commit
+// type. Breaking changes are listed first, in their own section, and empty
+// sections are omitted.
+func buildChangelog(entries []changelogEntry) []changelogSection {
+	breaking := changelogSection{Title: "Breaking changes"}
+	sections := make([]changelogSection, len(changelogSectionTitles))
+	for i, section := range changelogSectionTitles {
+		sections[i].Title = section.title
+	}
+	other := changelogSection{Title: "Other changes"}
+
+	for _, entry := range entries {
+		commitType := ""
+		if match := conventionalCommitSubject.FindStringSubmatch(entry.Description); match != nil {
+			commitType = strings.ToLower(match[1])
+			entry.Breaking = entry.Breaking || match[2] == "!"
+			entry.Description = match[3]
+		}
+		if entry.Breaking {
+			breaking.Entries = append(breaking.Entries, entry)
+			continue
+		}
+		section := &other
+		for i, candidate := range changelogSectionTitles {
+			if candidate.commitType == commitType {
+				section = &sections[i]
+			}
+		}
+		section.Entries = append(section.Entries, entry)
+	}
+
+	var changelog []changelogSection
+	for _, section := range append(append([]changelogSection{breaking}, sections...), other) {
+		if len(section.Entries) > 0 {
+			changelog = append(changelog, section)
+		}
+	}
+	return changelog
+}
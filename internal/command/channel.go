@@ -0,0 +1,51 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"path"
+	"regexp"
+
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+var (
+	alphaVersion = regexp.MustCompile(`^v\d+(p\d+)?alpha\d*$`)
+	betaVersion  = regexp.MustCompile(`^v\d+(p\d+)?beta\d*$`)
+)
+
+// releaseChannel returns the name of the release channel for an API, as
+// passed to language containers. An explicit channel in the API state takes
+// precedence; otherwise the channel is derived from the final (version)
+// segment of the API path.
+func releaseChannel(apiPath string, apiState *statepb.ApiGenerationState) string {
+	switch apiState.GetReleaseChannel() {
+	case statepb.ReleaseChannel_RELEASE_CHANNEL_STABLE:
+		return "stable"
+	case statepb.ReleaseChannel_RELEASE_CHANNEL_BETA:
+		return "beta"
+	case statepb.ReleaseChannel_RELEASE_CHANNEL_ALPHA:
+		return "alpha"
+	}
+	version := path.Base(apiPath)
+	switch {
+	case alphaVersion.MatchString(version):
+		return "alpha"
+	case betaVersion.MatchString(version):
+		return "beta"
+	default:
+		return "stable"
+	}
+}
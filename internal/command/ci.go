@@ -0,0 +1,334 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// ciResults records the results of a command which are reported to the CI
+// system and in the generation report: the commits made, the pull requests
+// created, and the libraries changed. Each is only ever appended to during a
+// run, so that a report can cover just the results recorded during its run;
+// they're discarded between runs by resetRunResults.
+var ciResults struct {
+	mu               sync.Mutex
+	commits          []reportCommit
+	pullRequests     []string
+	changedLibraries []string
+	skippedAPIs      []reportSkippedAPI
+	skippedLibraries []reportSkippedLibrary
+}
+
+// resetRunResults discards the results recorded by previous runs in this
+// process, in ciResults and by the container package, so that a long-running
+// process (i.e. serve) doesn't report them again, or retain them forever.
+func resetRunResults() {
+	ciResults.mu.Lock()
+	ciResults.commits = nil
+	ciResults.pullRequests = nil
+	ciResults.changedLibraries = nil
+	ciResults.skippedAPIs = nil
+	ciResults.skippedLibraries = nil
+	ciResults.mu.Unlock()
+	container.ResetResults()
+}
+
+// recordCommit records a commit made by the command, and the files it changed.
+func recordCommit(hash, message string, files []string) {
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	subject, _, _ := strings.Cut(message, "\n")
+	ciResults.commits = append(ciResults.commits, reportCommit{Hash: hash, Subject: subject, Files: files})
+}
+
+// forgetCommits removes recorded commits which no longer exist, e.g. because
+// they've been squashed.
+func forgetCommits(hashes []string) {
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	ciResults.commits = slices.DeleteFunc(ciResults.commits, func(commit reportCommit) bool {
+		return slices.Contains(hashes, commit.Hash)
+	})
+}
+
+// recordPullRequest records the URL of a pull request created by the command.
+func recordPullRequest(url string) {
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	ciResults.pullRequests = append(ciResults.pullRequests, url)
+}
+
+// recordSkippedAPI records an API which the command deliberately didn't
+// generate, and why.
+func recordSkippedAPI(apiPath, reason string) {
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	ciResults.skippedAPIs = append(ciResults.skippedAPIs, reportSkippedAPI{APIPath: apiPath, Reason: reason})
+}
+
+// recordSkippedLibrary records a library which a scheduled regeneration didn't
+// regenerate, and why.
+func recordSkippedLibrary(id, reason string) {
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	ciResults.skippedLibraries = append(ciResults.skippedLibraries, reportSkippedLibrary{ID: id, Reason: reason})
+}
+
+// recordChangedLibraries records the libraries changed by the command. An API
+// which isn't part of any library is recorded by its API path.
+func recordChangedLibraries(state *statepb.PipelineState, apiPath string) {
+	var libraries []string
+	for _, library := range state.GetLibraryReleaseStates() {
+		if slices.Contains(library.ApiIds, apiPath) {
+			libraries = append(libraries, library.Id)
+		}
+	}
+	if len(libraries) == 0 {
+		libraries = []string{apiPath}
+	}
+	recordChangedLibrary(libraries...)
+}
+
+// recordChangedLibrary records libraries (by ID) changed by the command.
+func recordChangedLibrary(ids ...string) {
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	ciResults.changedLibraries = append(ciResults.changedLibraries, ids...)
+}
+
+// uniqueLibraries returns the distinct libraries in libraries, in the order in
+// which they were first recorded.
+func uniqueLibraries(libraries []string) []string {
+	var unique []string
+	for _, library := range libraries {
+		if !slices.Contains(unique, library) {
+			unique = append(unique, library)
+		}
+	}
+	return unique
+}
+
+// reportToCI wraps the Run function of a command to integrate with the CI
+// system specified by -ci. For GitHub Actions ("github"):
+//   - unspecified flags are taken from the inputs of a workflow_dispatch event,
+//     or the client payload of a repository_dispatch event;
+//   - a failure is reported as an error annotation;
+//   - the result, pull request URL and changed libraries are written as step
+//     outputs (result, pull-request-url and changed-libraries);
+//   - a summary is appended to the job summary.
+func reportToCI(c *Command, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		switch flagCI {
+		case "":
+			return run(ctx)
+		case "github":
+		default:
+			return fmt.Errorf("invalid -ci: %q; must be github", flagCI)
+		}
+		if err := applyGitHubEventInputs(c.flags); err != nil {
+			return err
+		}
+		err := run(ctx)
+		if err != nil {
+			fmt.Printf("::error title=%s::%s\n", githubEscapeProperty(githubErrorTitle(c.Name, err)), githubEscapeData(err.Error()))
+		}
+		if reportErr := writeGitHubOutputs(err); reportErr != nil {
+			slog.Warn(fmt.Sprintf("Unable to write GitHub Actions outputs: %s", reportErr))
+		}
+		if reportErr := writeGitHubSummary(c.Name, err); reportErr != nil {
+			slog.Warn(fmt.Sprintf("Unable to write GitHub Actions job summary: %s", reportErr))
+		}
+		return err
+	}
+}
+
+// ciRunURL returns the URL of the CI run (currently only a GitHub Actions
+// workflow run) this process is part of, or an empty string if it isn't known.
+func ciRunURL() string {
+	server, repository, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if server == "" || repository == "" || runID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/actions/runs/%s", server, repository, runID)
+}
+
+// githubEventInputFlags are the flags which may be set from the inputs of the
+// event which triggered the workflow. They only select what's generated and
+// how, as the inputs may come from anyone able to trigger the workflow; flags
+// which choose where to push, credentials, images or other executables must be
+// specified by the workflow itself.
+var githubEventInputFlags = map[string]bool{
+	"api-list":           true,
+	"api-path":           true,
+	"build":              true,
+	"commit-granularity": true,
+	"dry-run":            true,
+	"experiments":        true,
+	"force":              true,
+	"image-channel":      true,
+	"language":           true,
+	"library-id":         true,
+	"on-existing-pr":     true,
+	"regenerate-all":     true,
+	"skip-build":         true,
+	"skip-clean":         true,
+	"update-commits":     true,
+}
+
+// applyGitHubEventInputs sets the flags which still have their default values
+// from the inputs of the event which triggered the workflow, if any. Only the
+// flags in githubEventInputFlags are set; other inputs are ignored.
+func applyGitHubEventInputs(fs *flag.FlagSet) error {
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var event struct {
+		Inputs        map[string]any `json:"inputs"`
+		ClientPayload map[string]any `json:"client_payload"`
+	}
+	if err := json.Unmarshal(content, &event); err != nil {
+		return fmt.Errorf("invalid GitHub event payload %s: %w", path, err)
+	}
+	inputs := event.Inputs
+	if inputs == nil {
+		inputs = event.ClientPayload
+	}
+	for name, value := range inputs {
+		f := fs.Lookup(name)
+		if f == nil || f.Value.String() != f.DefValue {
+			continue
+		}
+		if !githubEventInputFlags[name] {
+			slog.Warn(fmt.Sprintf("Ignoring GitHub event input %q: -%s can't be set from event inputs", name, name))
+			continue
+		}
+		text := fmt.Sprint(value)
+		if text == "" {
+			continue
+		}
+		if err := fs.Set(name, text); err != nil {
+			return fmt.Errorf("invalid value %q for -%s from the GitHub event: %w", text, name, err)
+		}
+	}
+	return nil
+}
+
+// githubErrorTitle returns the title of the error annotation for a failure.
+func githubErrorTitle(command string, err error) string {
+	var reported *container.Error
+	if errors.As(err, &reported) && reported.Code != "" {
+		return fmt.Sprintf("librarian %s failed (%s)", command, reported.Code)
+	}
+	return fmt.Sprintf("librarian %s failed", command)
+}
+
+// githubEscapeData escapes the message of a workflow command.
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubEscapeProperty escapes a property value of a workflow command.
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(githubEscapeData(s))
+}
+
+// writeGitHubOutputs writes the step outputs to the GITHUB_OUTPUT file.
+func writeGitHubOutputs(runErr error) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+	result := "success"
+	if runErr != nil {
+		result = "failure"
+	}
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	var pullRequestURL string
+	if len(ciResults.pullRequests) > 0 {
+		pullRequestURL = ciResults.pullRequests[len(ciResults.pullRequests)-1]
+	}
+	return appendToFile(path, fmt.Sprintf("result=%s\npull-request-url=%s\nchanged-libraries=%s\n",
+		result, pullRequestURL, strings.Join(uniqueLibraries(ciResults.changedLibraries), ",")))
+}
+
+// writeGitHubSummary appends a Markdown summary of the command to the
+// GITHUB_STEP_SUMMARY file.
+func writeGitHubSummary(command string, runErr error) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+	ciResults.mu.Lock()
+	defer ciResults.mu.Unlock()
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "## librarian %s\n\n", command)
+	if runErr == nil {
+		summary.WriteString("**Result:** :white_check_mark: succeeded\n\n")
+	} else {
+		fmt.Fprintf(&summary, "**Result:** :x: failed\n\n```\n%s\n```\n\n", runErr)
+		if tail := container.RecentOutput(20); tail != "" {
+			fmt.Fprintf(&summary, "<details><summary>Recent container output</summary>\n\n```\n%s\n```\n\n</details>\n\n", tail)
+		}
+	}
+	if libraries := uniqueLibraries(ciResults.changedLibraries); len(libraries) > 0 {
+		summary.WriteString("| Changed library |\n| --- |\n")
+		for _, library := range libraries {
+			fmt.Fprintf(&summary, "| `%s` |\n", library)
+		}
+		summary.WriteString("\n")
+	}
+	if len(ciResults.skippedLibraries) > 0 {
+		summary.WriteString("| Skipped library | Reason |\n| --- | --- |\n")
+		for _, library := range ciResults.skippedLibraries {
+			fmt.Fprintf(&summary, "| `%s` | %s |\n", library.ID, library.Reason)
+		}
+		summary.WriteString("\n")
+	}
+	for _, url := range ciResults.pullRequests {
+		fmt.Fprintf(&summary, "Pull request: %s\n\n", url)
+	}
+	return appendToFile(path, summary.String())
+}
+
+func appendToFile(path, content string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
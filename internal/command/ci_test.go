@@ -0,0 +1,54 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyGitHubEventInputsOnlySetsAllowedFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.json")
+	event := `{"inputs": {"api-path": "google/example/v1", "push": "true", "repo-url": "https://github.com/attacker/repo"}}`
+	if err := os.WriteFile(path, []byte(event), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_EVENT_PATH", path)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	apiPath := fs.String("api-path", "", "")
+	push := fs.Bool("push", false, "")
+	repoURL := fs.String("repo-url", "", "")
+	if err := applyGitHubEventInputs(fs); err != nil {
+		t.Fatal(err)
+	}
+	if *apiPath != "google/example/v1" {
+		t.Errorf("-api-path = %q; want it set from the event inputs", *apiPath)
+	}
+	if *push || *repoURL != "" {
+		t.Errorf("-push = %v, -repo-url = %q; want them not set from the event inputs", *push, *repoURL)
+	}
+}
+
+func TestResetRunResults(t *testing.T) {
+	recordCommit("abc", "feat: Update API", []string{"a.txt"})
+	recordPullRequest("https://github.com/example/repo/pull/1")
+	recordSkippedAPI(selfTestAPIPath, "excluded")
+	resetRunResults()
+	if len(ciResults.commits) != 0 || len(ciResults.pullRequests) != 0 || len(ciResults.skippedAPIs) != 0 {
+		t.Errorf("results after reset: commits %v, pull requests %v, skipped APIs %v; want none", ciResults.commits, ciResults.pullRequests, ciResults.skippedAPIs)
+	}
+}
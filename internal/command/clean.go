@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// CmdClean runs only the container clean step for an API in a local language
+// repo, removing its generated files without regenerating it. The API doesn't
+// need to be configured, so files for APIs which have been removed from the
+// pipeline state can also be cleaned. Changes are left uncommitted.
+var CmdClean = &Command{
+	Name:  "clean",
+	Short: "Remove the generated files for an API from a language repo",
+	Run: func(ctx context.Context) error {
+		if !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		if flagRepoRoot == "" {
+			return fmt.Errorf("-repo-root is not provided")
+		}
+		if flagAPIPath == "" {
+			return fmt.Errorf("-api-path is not provided")
+		}
+		apiPath, err := normalizeAPIPath(flagAPIPath)
+		if err != nil {
+			return err
+		}
+		flagAPIPath = apiPath
+
+		release, err := acquireRepoLock()
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		repoRoot, err := filepath.Abs(flagRepoRoot)
+		if err != nil {
+			return err
+		}
+		languageRepo, err := gitrepo.Open(ctx, repoRoot)
+		if err != nil {
+			return err
+		}
+		state, err := loadState(languageRepo)
+		if err != nil {
+			return err
+		}
+		if findApiState(state, flagAPIPath) == nil {
+			slog.Warn(fmt.Sprintf("API %s is not configured in the language repo", flagAPIPath))
+		}
+		if err := container.Clean(ctx, containerOptions(state), languageRepo.Dir, flagAPIPath); err != nil {
+			return err
+		}
+		return gitrepo.PrintStatus(ctx, languageRepo)
+	},
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// setUpScratchRepos creates a googleapis repo with two commits of the selftest
+// API, and a testlang language repo in which the API was last generated at the
+// first commit, returning their directories.
+func setUpScratchRepos(t *testing.T) (apiRoot, languageRoot string) {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	apiRepo, err := gitrepo.Init(ctx, filepath.Join(dir, "googleapis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSelfTestProto(apiRepo, "Initial version"); err != nil {
+		t.Fatal(err)
+	}
+	// The common protos make the API root a googleapis tree, rather than a
+	// local proto tree.
+	marker := filepath.Join(apiRepo.Dir, filepath.FromSlash(commonProtosMarker))
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(marker, []byte("syntax = \"proto3\";\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitAll(ctx, apiRepo, "feat: Add selftest API"); err != nil {
+		t.Fatal(err)
+	}
+	initialCommit, err := gitrepo.HeadHash(ctx, apiRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSelfTestProto(apiRepo, "Updated version"); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitAll(ctx, apiRepo, "feat: Update selftest API\n\nPiperOrigin-RevId: 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	languageRepo, err := gitrepo.Init(ctx, filepath.Join(dir, "language"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(languageRepo.Dir, "generator-input"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	state := &statepb.PipelineState{
+		ImageTag: "selftest",
+		ApiGenerationStates: []*statepb.ApiGenerationState{{
+			Id:                  selfTestAPIPath,
+			AutomationLevel:     statepb.AutomationLevel_AUTOMATION_LEVEL_AUTOMATIC,
+			LastGeneratedCommit: initialCommit,
+		}},
+	}
+	if err := saveState(languageRepo, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitAll(ctx, languageRepo, "chore: Initial pipeline state"); err != nil {
+		t.Fatal(err)
+	}
+	return apiRepo.Dir, languageRepo.Dir
+}
+
+// useTestlang runs language containers in-process with testlangRunner for the
+// duration of the test.
+func useTestlang(t *testing.T) {
+	t.Helper()
+	supportedLanguages["testlang"] = true
+	containerRunner = testlangRunner{}
+	t.Cleanup(func() {
+		delete(supportedLanguages, "testlang")
+		containerRunner = nil
+	})
+}
+
+func TestUpdateApisWithoutPushKeepsWorkingDirectory(t *testing.T) {
+	useTestlang(t)
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	apiRoot, languageRoot := setUpScratchRepos(t)
+
+	if err := CmdUpdateApis.Parse([]string{
+		"-language=testlang",
+		"-image=testlang",
+		"-api-root=" + apiRoot,
+		"-repo-url=" + languageRoot,
+		"-no-lock",
+		"-auto-prune-days=0",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CmdUpdateApis.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpRoots, err := filepath.Glob(filepath.Join(tmpDir, tmpRootPrefix+"*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmpRoots) != 1 {
+		t.Fatalf("got temporary working directories %v; want exactly one to be kept", tmpRoots)
+	}
+	if _, err := os.Stat(filepath.Join(tmpRoots[0], "output", reportFileName)); err != nil {
+		t.Errorf("report not kept: %v", err)
+	}
+	clone, err := gitrepo.Open(context.Background(), filepath.Join(tmpRoots[0], "language"))
+	if err != nil {
+		t.Fatalf("language repo clone not kept: %v", err)
+	}
+	original, err := gitrepo.Open(context.Background(), languageRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := gitrepo.HeadHash(context.Background(), original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, err := gitrepo.CommitsSince(context.Background(), clone, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) == 0 {
+		t.Errorf("no unpushed commit in the kept clone")
+	}
+}
@@ -17,20 +17,265 @@ package command
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/metrics"
 )
 
 const googleapisURL = "https://github.com/googleapis/googleapis"
 
 func cloneGoogleapis(ctx context.Context, tmpRoot string) (*gitrepo.Repo, error) {
+	if flagGoogleapisMirror != "" {
+		return cloneGoogleapisMirror(ctx, tmpRoot)
+	}
+	if flagOffline {
+		return nil, fmt.Errorf("googleapis can't be cloned with -offline; specify a local checkout with -api-root")
+	}
+	if flagCloneCache {
+		return cloneOrUpdateCached(ctx, "googleapis", googleapisURL, "")
+	}
 	repoPath := filepath.Join(tmpRoot, "googleapis")
-	return gitrepo.CloneOrOpen(ctx, repoPath, googleapisURL)
+	return gitrepo.CloneOrOpen(ctx, repoPath, googleapisURL, "")
 }
 
+// googleapisCommonPaths are the paths within googleapis which are commonly
+// imported by APIs, and so are always included in a sparse clone.
+var googleapisCommonPaths = []string{
+	"google/api/",
+	"google/cloud/common_resources.proto",
+	"google/cloud/extended_operations.proto",
+	"google/cloud/location/",
+	"google/iam/v1/",
+	"google/longrunning/",
+	"google/rpc/",
+	"google/type/",
+}
+
+// cloneGoogleapisSparse performs a shallow clone of googleapis, only checking out
+// the files for the specified APIs and common dependencies. This is suitable
+// for commands which don't need the googleapis history.
+func cloneGoogleapisSparse(ctx context.Context, tmpRoot string, apiPaths ...string) (*gitrepo.Repo, error) {
+	// A cached full clone can be updated cheaply, so is preferred when enabled.
+	// Mirrors are always cloned in full, so that the checkout can be verified.
+	if flagCloneCache || flagGoogleapisMirror != "" {
+		return cloneGoogleapis(ctx, tmpRoot)
+	}
+	repoPath := filepath.Join(tmpRoot, "googleapis")
+	if _, err := os.Stat(repoPath); err == nil {
+		return gitrepo.Open(ctx, repoPath)
+	}
+	if flagOffline {
+		return nil, fmt.Errorf("googleapis can't be cloned with -offline; specify a local checkout with -api-root")
+	}
+	var paths []string
+	for _, apiPath := range apiPaths {
+		paths = append(paths, strings.TrimSuffix(apiPath, "/")+"/")
+	}
+	paths = append(paths, googleapisCommonPaths...)
+	return gitrepo.SparseClone(ctx, repoPath, googleapisURL, paths)
+}
+
+// cloneLanguageRepo clones the repository for the given language, at the
+// branch specified by -repo-branch or the configuration files (or the default
+// branch if unspecified).
 func cloneLanguageRepo(ctx context.Context, language, tmpRoot string) (*gitrepo.Repo, error) {
-	languageRepoURL := fmt.Sprintf("https://github.com/googleapis/google-cloud-%s", language)
-	repoPath := filepath.Join(tmpRoot, fmt.Sprintf("google-cloud-%s", language))
-	return gitrepo.CloneOrOpen(ctx, repoPath, languageRepoURL)
+	repoURL := languageRepoURL(language)
+	if flagOffline && !isLocalRepoURL(repoURL) {
+		return nil, fmt.Errorf("%s can't be cloned with -offline; specify a local clone with -repo-root, or a local path with -repo-url", repoURL)
+	}
+	repoName := path.Base(strings.TrimSuffix(repoURL, ".git"))
+	branch := languageRepoBranch(language)
+	if flagCloneCache {
+		cacheName := repoName
+		if repoURL != defaultLanguageRepoURL(language) {
+			// Forks and mirrors are cached separately from the usual repo.
+			cacheName = cacheNameForURL(repoURL)
+		}
+		// The cached clone is reset and committed to, so it's locked against
+		// other runs (for any APIs) until the end of this run.
+		if err := lockForRun("clone-cache|" + cacheName + "@" + branch); err != nil {
+			return nil, err
+		}
+		return cloneOrUpdateCached(ctx, cacheName, repoURL, branch)
+	}
+	repoPath := filepath.Join(tmpRoot, repoName)
+	return gitrepo.CloneOrOpen(ctx, repoPath, repoURL, branch)
+}
+
+// openLanguageRepo returns the language repo for a command which updates it:
+// the repo in -repo-root if specified, or otherwise a new clone in tmpRoot. If
+// -repo-root doesn't exist or is empty, the language repo is cloned into it.
+// Otherwise the existing clone is fetched and reset to the latest commit on its
+// branch (or, with -offline, to the commit last fetched), so that each run
+// starts from the same state as a fresh clone. An existing clone with
+// uncommitted changes, or with commits which aren't on origin (so that the
+// reset isn't a fast-forward), is only reset if -force is specified, in which
+// case the changes or commits are discarded.
+func openLanguageRepo(ctx context.Context, tmpRoot string) (*gitrepo.Repo, error) {
+	if flagRepoRoot == "" {
+		return cloneLanguageRepo(ctx, flagLanguage, tmpRoot)
+	}
+	repoRoot, err := filepath.Abs(flagRepoRoot)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(repoRoot)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		repoURL := languageRepoURL(flagLanguage)
+		if flagOffline && !isLocalRepoURL(repoURL) {
+			return nil, fmt.Errorf("%s can't be cloned into empty -repo-root %s with -offline; specify an existing clone, or a local path with -repo-url", repoURL, repoRoot)
+		}
+		return gitrepo.Clone(ctx, repoRoot, repoURL, languageRepoBranch(flagLanguage))
+	}
+	languageRepo, err := gitrepo.Open(ctx, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	clean, err := gitrepo.IsClean(ctx, languageRepo)
+	if err != nil {
+		return nil, err
+	}
+	if !clean {
+		if !flagForce {
+			return nil, fmt.Errorf("language repo %s has uncommitted changes; commit or stash them, or specify -force to discard them", repoRoot)
+		}
+		slog.Warn(fmt.Sprintf("Discarding uncommitted changes in %s", repoRoot))
+	}
+	if !gitrepo.HasRemote(ctx, languageRepo, "origin") {
+		// A local repo can't be updated, but any changes must still be discarded.
+		// Its commits are kept, as there's nowhere else they could have come from.
+		slog.Warn(fmt.Sprintf("Language repo %s has no origin remote, so is not fetched", repoRoot))
+		if err := gitrepo.ResetHard(ctx, languageRepo); err != nil {
+			return nil, err
+		}
+		return languageRepo, gitrepo.RemoveUntracked(ctx, languageRepo)
+	}
+	branch, err := gitrepo.CurrentBranch(ctx, languageRepo)
+	if err != nil {
+		return nil, err
+	}
+	if want := languageRepoBranch(flagLanguage); want != "" && branch != want {
+		return nil, fmt.Errorf("language repo %s has branch %q checked out, not %q", repoRoot, branch, want)
+	}
+	head, err := gitrepo.HeadHash(ctx, languageRepo)
+	if err != nil {
+		return nil, err
+	}
+	var latest string
+	if flagOffline {
+		// The origin branch as last fetched is the best available.
+		slog.Info(fmt.Sprintf("Language repo %s is not fetched with -offline", repoRoot))
+		latest, err = gitrepo.RemoteTrackingHash(ctx, languageRepo)
+	} else {
+		latest, err = gitrepo.FetchCurrentBranch(ctx, languageRepo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if latest == "" {
+		if err := gitrepo.ResetHard(ctx, languageRepo); err != nil {
+			return nil, err
+		}
+		return languageRepo, gitrepo.RemoveUntracked(ctx, languageRepo)
+	}
+	fastForward, err := gitrepo.IsAncestor(ctx, languageRepo, head, latest)
+	if err != nil {
+		return nil, err
+	}
+	if !fastForward {
+		if !flagForce {
+			return nil, fmt.Errorf("language repo %s has commits on %s which aren't on origin/%s; push them or move them to another branch, or specify -force to discard them", repoRoot, branch, branch)
+		}
+		slog.Warn(fmt.Sprintf("Discarding commits on %s in %s which aren't on origin/%s (HEAD was %s)", branch, repoRoot, branch, head))
+	}
+	if err := gitrepo.ResetHardTo(ctx, languageRepo, latest); err != nil {
+		return nil, err
+	}
+	slog.Info(fmt.Sprintf("Reset %s to the latest commit on %s (HEAD was %s)", repoRoot, branch, head))
+	return languageRepo, nil
+}
+
+// languageRepoURL returns the URL of the repository for the given language:
+// the URL specified by -repo-url, or in the configuration files (see
+// loadLanguageConfigs), or
+// https://github.com/googleapis/google-cloud-{language} by default (on the
+// host specified by -github-host).
+func languageRepoURL(language string) string {
+	if flagRepoURL != "" {
+		return flagRepoURL
+	}
+	if url := languageConfigs[language].URL; url != "" {
+		return url
+	}
+	return defaultLanguageRepoURL(language)
+}
+
+// defaultLanguageRepoURL returns the URL of the repository for the given
+// language, following the google-cloud-{language} naming convention.
+func defaultLanguageRepoURL(language string) string {
+	return fmt.Sprintf("https://%s/googleapis/google-cloud-%s", gitrepo.GitHubHost(), language)
+}
+
+// languageRepoBranch returns the branch of the repository for the given
+// language to clone and target with pull requests: the branch specified by
+// -repo-branch, or in the configuration files, or an empty string for the
+// repository's default branch.
+func languageRepoBranch(language string) string {
+	if flagRepoBranch != "" {
+		return flagRepoBranch
+	}
+	return languageConfigs[language].Branch
+}
+
+// cacheNameForURL returns the name of the cached clone of a repo which isn't
+// cached under its usual name (e.g. a fork or mirror), derived from its URL.
+func cacheNameForURL(repoURL string) string {
+	location := strings.TrimSuffix(repoURL, ".git")
+	if _, rest, found := strings.Cut(location, "://"); found {
+		location = rest
+	}
+	return strings.NewReplacer("/", "-", ":", "-", "@", "-").Replace(strings.Trim(location, "/"))
+}
+
+// cloneOrUpdateCached returns a clone of repoURL stored under the user cache
+// directory. If the clone already exists, it is fetched and hard reset to the
+// latest commit on its branch rather than being cloned again. Clones of
+// non-default branches are cached separately.
+func cloneOrUpdateCached(ctx context.Context, name, repoURL, branch string) (*gitrepo.Repo, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	if branch != "" {
+		name = fmt.Sprintf("%s@%s", name, strings.ReplaceAll(branch, "/", "-"))
+	}
+	repoPath := filepath.Join(cacheDir, "librarian", name)
+	if _, err := os.Stat(repoPath); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		metrics.CloneCache.Inc("miss")
+		if err := os.MkdirAll(filepath.Dir(repoPath), 0755); err != nil {
+			return nil, err
+		}
+		return gitrepo.Clone(ctx, repoPath, repoURL, branch)
+	}
+	metrics.CloneCache.Inc("hit")
+	slog.Info(fmt.Sprintf("Using cached clone of %q at %q", repoURL, repoPath))
+	repo, err := gitrepo.Open(ctx, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := gitrepo.FetchAndReset(ctx, repo); err != nil {
+		return nil, err
+	}
+	return repo, nil
 }
@@ -0,0 +1,57 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestOpenLanguageRepoKeepsUnpushedCommits(t *testing.T) {
+	ctx := context.Background()
+	_, origin := setUpScratchRepos(t)
+	repoRoot := filepath.Join(t.TempDir(), "repo")
+	repo, err := gitrepo.Clone(ctx, repoRoot, origin, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "local.txt"), []byte("unpushed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitAll(ctx, repo, "chore: Unpushed commit"); err != nil {
+		t.Fatal(err)
+	}
+	flagRepoRoot, flagForce = repoRoot, false
+	t.Cleanup(func() { flagRepoRoot, flagForce = "", false })
+
+	if _, err := openLanguageRepo(ctx, t.TempDir()); err == nil {
+		t.Fatal("openLanguageRepo succeeded with an unpushed commit; want an error")
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "local.txt")); err != nil {
+		t.Fatalf("unpushed commit was discarded: %v", err)
+	}
+
+	flagForce = true
+	if _, err := openLanguageRepo(ctx, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "local.txt")); !os.IsNotExist(err) {
+		t.Errorf("unpushed commit was kept with -force: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// humanReviewLabel is added to pull requests which touch files with specific
+// owners, to indicate that the pull request must not be merged automatically.
+const humanReviewLabel = "needs human review"
+
+// codeownersRule is a single line of a CODEOWNERS file.
+type codeownersRule struct {
+	pattern string
+	re      *regexp.Regexp
+	owners  []string
+}
+
+// loadCodeowners loads the CODEOWNERS file from any of the locations supported
+// by GitHub. If the repository has no CODEOWNERS file, no rules are returned.
+func loadCodeowners(repoDir string) ([]codeownersRule, error) {
+	for _, path := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"} {
+		content, err := os.ReadFile(filepath.Join(repoDir, path))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseCodeowners(content), nil
+	}
+	return nil, nil
+}
+
+func parseCodeowners(content []byte) []codeownersRule {
+	var rules []codeownersRule
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var owners []string
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			owners = append(owners, owner)
+		}
+		rules = append(rules, codeownersRule{pattern: fields[0], re: codeownersPattern(fields[0]), owners: owners})
+	}
+	return rules
+}
+
+// codeownersPattern converts a gitignore-style CODEOWNERS pattern to a regular expression.
+func codeownersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	directory := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("(^|/)")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if directory {
+		expr.WriteString("/")
+	} else {
+		expr.WriteString("(/|$)")
+	}
+	return regexp.MustCompile(expr.String())
+}
+
+// specificOwners returns the owners of the given paths, as determined by the
+// last matching rule for each path. Owners from a catch-all "*" rule are ignored,
+// as they typically own generated code as well as handwritten code.
+func specificOwners(rules []codeownersRule, paths []string) []string {
+	var owners []string
+	for _, path := range paths {
+		for i := len(rules) - 1; i >= 0; i-- {
+			rule := rules[i]
+			if !rule.re.MatchString(path) {
+				continue
+			}
+			if rule.pattern != "*" {
+				for _, owner := range rule.owners {
+					if !slices.Contains(owners, owner) {
+						owners = append(owners, owner)
+					}
+				}
+			}
+			break
+		}
+	}
+	return owners
+}
+
+// splitOwners splits CODEOWNERS owners into GitHub users and team slugs. Email
+// addresses are ignored, as they can't be requested as reviewers.
+func splitOwners(owners []string) (users, teams []string) {
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "@") {
+			continue
+		}
+		owner = strings.TrimPrefix(owner, "@")
+		if _, team, found := strings.Cut(owner, "/"); found {
+			teams = append(teams, team)
+		} else {
+			users = append(users, owner)
+		}
+	}
+	return users, teams
+}
@@ -18,18 +18,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/googleapis/librarian/internal/container"
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/metrics"
 	"github.com/googleapis/librarian/internal/statepb"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -42,8 +47,62 @@ type Command struct {
 	flags *flag.FlagSet
 }
 
+// Parse parses the command's flags from args. Any flag which isn't specified
+// in args falls back to the value of the corresponding environment variable,
+// if set (for example, -api-path falls back to LIBRARIAN_API_PATH), and then
+// to the value in the configuration files (see loadConfigDefaults).
 func (c *Command) Parse(args []string) error {
-	return c.flags.Parse(args)
+	if err := c.flags.Parse(args); err != nil {
+		return err
+	}
+	defaults, err := loadConfigDefaults(c.Name)
+	if err != nil {
+		return err
+	}
+	if languageConfigs, err = loadLanguageConfigs(); err != nil {
+		return err
+	}
+	registerConfiguredLanguages(languageConfigs)
+	specified := map[string]bool{}
+	c.flags.Visit(func(f *flag.Flag) { specified[f.Name] = true })
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if specified[f.Name] || err != nil {
+			return
+		}
+		source := flagEnvVar(f.Name)
+		value, ok := os.LookupEnv(source)
+		if !ok {
+			source = "configuration files"
+			value, ok = defaults[f.Name]
+		}
+		if ok {
+			if setErr := c.flags.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for -%s from %s: %w", value, f.Name, source, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := configureProxy(); err != nil {
+		return err
+	}
+	if err := configureOffline(); err != nil {
+		return err
+	}
+	if err := gitrepo.SetGitLabURL(flagGitLabURL); err != nil {
+		return err
+	}
+	if err := gitrepo.SetGerritEndpoint(flagGerritURL, flagGerritUser); err != nil {
+		return err
+	}
+	return gitrepo.SetGitHubEndpoint(flagGitHubHost, flagGitHubAPIURL, flagGitHubAPIVersion)
+}
+
+// flagEnvVar returns the name of the environment variable used as a fallback
+// for the named flag.
+func flagEnvVar(flagName string) string {
+	return "LIBRARIAN_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
 }
 
 func Lookup(name string) (*Command, error) {
@@ -61,17 +120,55 @@ func Lookup(name string) (*Command, error) {
 
 var CmdConfigure = &Command{
 	Name:  "configure",
-	Short: "Configure a new API in a given language",
+	Short: "Configure a new API (or a list of APIs) in a given language",
 	Run: func(ctx context.Context) error {
-		if flagAPIPath == "" {
+		if flagInteractive {
+			if !isInteractive() {
+				return fmt.Errorf("-interactive requires standard input to be a terminal")
+			}
+			if err := configureWizard(os.Stdin, os.Stdout); err != nil {
+				return err
+			}
+		}
+		var entries []apiListEntry
+		scopeOverride = ""
+		switch {
+		case flagAPIList != "" && flagAPIPath != "":
+			return fmt.Errorf("-api-path and -api-list cannot both be specified")
+		case flagAPIList != "":
+			var err error
+			if entries, err = readAPIList(flagAPIList); err != nil {
+				return err
+			}
+			var apiPaths []string
+			for _, entry := range entries {
+				apiPaths = append(apiPaths, entry.Path)
+			}
+			scopeOverride = strings.Join(apiPaths, ",")
+		case flagAPIPath == "":
 			return fmt.Errorf("-api-path is not provided")
+		default:
+			apiPath, err := normalizeAPIPath(flagAPIPath)
+			if err != nil {
+				return err
+			}
+			flagAPIPath = apiPath
+			entries = []apiListEntry{{Path: apiPath}}
 		}
 		if !supportedLanguages[flagLanguage] {
 			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
 		}
-		if flagPush && flagGitHubToken == "" {
-			return fmt.Errorf("-github-token must be provided if -push is set to true")
+		if err := validatePushFlags(ctx); err != nil {
+			return err
+		}
+		if err := preflight(); err != nil {
+			return err
+		}
+		release, err := acquireRepoLock()
+		if err != nil {
+			return err
 		}
+		defer release()
 
 		startOfRun := time.Now()
 		// tmpRoot is a newly-created working directory under /tmp
@@ -85,12 +182,24 @@ var CmdConfigure = &Command{
 		}
 
 		var apiRoot string
-		if flagAPIRoot == "" {
-			repo, err := cloneGoogleapis(ctx, tmpRoot)
+		// apiRepo is only set when googleapis is available as a git repository.
+		var apiRepo *gitrepo.Repo
+		if flagAPIRoot == "" && flagAPITarball != "" {
+			apiRoot, err = downloadGoogleapisTarball(ctx, tmpRoot, flagAPITarball)
+			if err != nil {
+				return err
+			}
+		} else if flagAPIRoot == "" {
+			// Configuration only needs the current state of the APIs, so a sparse clone is sufficient.
+			var apiPaths []string
+			for _, entry := range entries {
+				apiPaths = append(apiPaths, entry.Path)
+			}
+			apiRepo, err = cloneGoogleapisSparse(ctx, tmpRoot, apiPaths...)
 			if err != nil {
 				return err
 			}
-			apiRoot = repo.Dir
+			apiRoot = apiRepo.Dir
 		} else {
 			// We assume it's okay not to take a defensive copy of apiRoot in the configure command,
 			// as "vanilla" configuration/generation shouldn't need to edit any protos. (That's just an escape hatch.)
@@ -98,73 +207,148 @@ var CmdConfigure = &Command{
 			if err != nil {
 				return err
 			}
-		}
-
-		var languageRepo *gitrepo.Repo
-		if flagRepoRoot == "" {
-			languageRepo, err = cloneLanguageRepo(ctx, flagLanguage, tmpRoot)
-			if err != nil {
+			// The API root isn't required to be a git repository; it's only used for pull request details.
+			apiRepo, _ = gitrepo.Open(ctx, apiRoot)
+			if apiRoot, err = resolveLocalAPIRoot(ctx, tmpRoot, apiRoot); err != nil {
 				return err
 			}
-		} else {
-			repoRoot, err := filepath.Abs(flagRepoRoot)
-			if err != nil {
+		}
+		for i := range entries {
+			if entries[i].Path, err = resolveAPIVersion(apiRoot, entries[i].Path); err != nil {
 				return err
 			}
-			languageRepo, err = gitrepo.Open(ctx, repoRoot)
-			if err != nil {
+			if err := validateAPIPath(apiRoot, entries[i].Path); err != nil {
 				return err
 			}
 		}
+		if flagAPIPath != "" {
+			flagAPIPath = entries[0].Path
+		}
 
-		state, err := loadState(languageRepo)
+		languageRepo, err := openLanguageRepo(ctx, tmpRoot)
 		if err != nil {
 			return err
 		}
 
-		image := deriveImage(state)
-
-		generatorInput := filepath.Join(languageRepo.Dir, "generator-input")
-		if err := container.Configure(ctx, image, apiRoot, flagAPIPath, generatorInput); err != nil {
+		state, err := loadState(languageRepo)
+		if err != nil {
 			return err
 		}
+		containerOpts := containerOptions(state)
 
-		// After configuring, we run quite a lot of the same code as in CmdUpdateApis.Run.
-		outputDir := filepath.Join(tmpRoot, "output")
-		if err := os.Mkdir(outputDir, 0755); err != nil {
+		outputRoot := filepath.Join(tmpRoot, "output")
+		if err := os.Mkdir(outputRoot, 0755); err != nil {
 			return err
 		}
+		recordArtifactDir("output", outputRoot)
+		setReportDir(outputRoot)
 
-		// Take a defensive copy of the generator input directory from the language repo.
-		// Note that we didn't do this earlier, as the container.Configure step is *intended* to modify
-		// generator input in the repo. Any changes during generation aren't intended to be persisted though.
-		generatorInput = filepath.Join(tmpRoot, "generator-input")
-		if err := os.CopyFS(generatorInput, os.DirFS(filepath.Join(languageRepo.Dir, "generator-input"))); err != nil {
+		hashBefore, err := gitrepo.HeadHash(ctx, languageRepo)
+		if err != nil {
 			return err
 		}
-
-		if err := container.Generate(ctx, image, apiRoot, outputDir, generatorInput, flagAPIPath); err != nil {
-			return err
+		var apiHash string
+		if apiRepo != nil {
+			if apiHash, err = gitrepo.HeadHash(ctx, apiRepo); err != nil {
+				return err
+			}
 		}
-		// We don't need to clean the newly-configured API, but we *do* need to clean any non-API-specific files.
-		if err := container.Clean(ctx, image, languageRepo.Dir, "none"); err != nil {
-			return err
+		result := &generationResult{
+			repo:          languageRepo,
+			containerOpts: containerOpts,
+			startOfRun:    startOfRun,
+			baseHash:      hashBefore,
+			apiHash:       apiHash,
 		}
-		if err := os.CopyFS(languageRepo.Dir, os.DirFS(outputDir)); err != nil {
-			return err
+		for _, entry := range entries {
+			title, err := configureAPI(ctx, languageRepo, containerOpts, apiRoot, tmpRoot, outputRoot, entry)
+			if err != nil {
+				return fmt.Errorf("configuring %s failed: %w", entry.Path, err)
+			}
+			result.title = title
+			if apiHash != "" {
+				result.commitRanges = append(result.commitRanges, apiCommitRange{APIPath: entry.Path, To: apiHash})
+			}
 		}
-		msg := fmt.Sprintf("Configured API %s", flagAPIPath) // TODO: Improve info using googleapis commits and version info
-		if err := commitAll(ctx, languageRepo, msg); err != nil {
-			return err
+		if len(entries) > 1 {
+			result.title = fmt.Sprintf("feat: Configure %d APIs", len(entries))
 		}
-		if err := container.Build(ctx, image, "repo-root", languageRepo.Dir, flagAPIPath); err != nil {
+		// The state has been updated by configuration.
+		if result.state, err = loadState(languageRepo); err != nil {
 			return err
 		}
-
-		return push(ctx, languageRepo, startOfRun)
+		return push(ctx, result)
 	},
 }
 
+// configureAPI configures a single API in the language repo, generates it
+// into a subdirectory of outputRoot, and commits the result. The title of a
+// pull request for the change is returned.
+func configureAPI(ctx context.Context, languageRepo *gitrepo.Repo, containerOpts *container.Options, apiRoot, tmpRoot, outputRoot string, entry apiListEntry) (string, error) {
+	channel := entry.Channel
+	if channel == "" {
+		channel = releaseChannel(entry.Path, nil)
+	}
+	generatorInput := filepath.Join(languageRepo.Dir, "generator-input")
+	metadata := apiMetadata(apiRoot, entry.Path)
+	var repoRoot string
+	if container.SupportsFeature(ctx, containerOpts, container.ConfigureRepoFeature) {
+		repoRoot = languageRepo.Dir
+	}
+	if err := container.Configure(ctx, containerOpts, apiRoot, entry.Path, channel, generatorInput, repoRoot, metadata); err != nil {
+		return "", err
+	}
+
+	// After configuring, we run quite a lot of the same code as in CmdUpdateApis.Run.
+	outputDir := filepath.Join(outputRoot, entry.Path)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", err
+	}
+
+	// Take a defensive copy of the generator input directory from the language repo.
+	// Note that we didn't do this earlier, as the container.Configure step is *intended* to modify
+	// generator input in the repo. Any changes during generation aren't intended to be persisted though.
+	generatorInput = filepath.Join(tmpRoot, "generator-input")
+	if err := os.RemoveAll(generatorInput); err != nil {
+		return "", err
+	}
+	if err := os.CopyFS(generatorInput, os.DirFS(filepath.Join(languageRepo.Dir, "generator-input"))); err != nil {
+		return "", err
+	}
+
+	if err := container.Generate(ctx, containerOpts, apiRoot, outputDir, generatorInput, entry.Path, channel, generationConfig(apiRoot, entry.Path)); err != nil {
+		return "", err
+	}
+	if err := checkGenerateOutput(outputDir, flagLanguage, entry.Path); err != nil {
+		return "", err
+	}
+	// We don't need to clean the newly-configured API, but we *do* need to clean any non-API-specific files.
+	if err := container.Clean(ctx, containerOpts, languageRepo.Dir, "none"); err != nil {
+		return "", err
+	}
+	if err := os.CopyFS(languageRepo.Dir, os.DirFS(outputDir)); err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("Configured API %s", entry.Path) // TODO: Improve info using googleapis commits and version info
+	title := fmt.Sprintf("feat: Configure API %s", entry.Path)
+	if metadata != nil && metadata.Title != "" {
+		msg = fmt.Sprintf("Configured API %s (%s)", entry.Path, metadata.Title)
+		title = fmt.Sprintf("feat: Configure %s (%s)", metadata.Title, entry.Path)
+	}
+	if err := commitAll(ctx, languageRepo, msg); err != nil {
+		return "", err
+	}
+	state, err := loadState(languageRepo)
+	if err != nil {
+		return "", err
+	}
+	recordChangedLibraries(state, entry.Path)
+	if err := container.Build(ctx, containerOpts, "repo-root", languageRepo.Dir, entry.Path); err != nil {
+		return "", err
+	}
+	return title, nil
+}
+
 var CmdGenerate = &Command{
 	Name:  "generate",
 	Short: "Generate client library code for an API",
@@ -172,16 +356,20 @@ var CmdGenerate = &Command{
 		if flagAPIPath == "" {
 			return fmt.Errorf("-api-path is not provided")
 		}
+		apiPath, err := normalizeAPIPath(flagAPIPath)
+		if err != nil {
+			return err
+		}
+		flagAPIPath = apiPath
 		if !supportedLanguages[flagLanguage] {
 			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
 		}
-		if flagAPIRoot == "" {
-			return fmt.Errorf("-api-root is not provided")
-		}
-
-		apiRoot, err := filepath.Abs(flagAPIRoot)
-		if err != nil {
-			return err
+		if flagDescriptorSet != "" {
+			if flagAPIRoot != "" || flagAPITarball != "" {
+				return fmt.Errorf("-descriptor-set cannot be combined with -api-root or -api-tarball")
+			}
+		} else if flagAPIRoot == "" && flagAPITarball == "" {
+			return fmt.Errorf("-api-root, -api-tarball or -descriptor-set must be provided")
 		}
 
 		// tmpRoot is a newly-created working directory under /tmp
@@ -189,35 +377,87 @@ var CmdGenerate = &Command{
 		// actually needed in generate if the user hasn't specified an output directory
 		// - we could potentially only create it in that case, but always creating it
 		// is a more general case.
+		if err := preflight(); err != nil {
+			return err
+		}
 		tmpRoot, err := createTmpWorkingRoot(time.Now())
 		if err != nil {
 			return err
 		}
 
-		var outputDir string
-		if flagOutput == "" {
-			outputDir = filepath.Join(tmpRoot, "output")
-			if err := os.Mkdir(outputDir, 0755); err != nil {
-				return err
+		// Exactly one of apiRoot and descriptorSet is set.
+		var apiRoot, descriptorSet string
+		switch {
+		case flagDescriptorSet != "":
+			descriptorSet, err = prepareDescriptorSet(tmpRoot, flagAPIPath)
+		case flagAPIRoot == "":
+			apiRoot, err = downloadGoogleapisTarball(ctx, tmpRoot, flagAPITarball)
+		default:
+			if apiRoot, err = filepath.Abs(flagAPIRoot); err == nil {
+				apiRoot, err = resolveLocalAPIRoot(ctx, tmpRoot, apiRoot)
 			}
-			slog.Info(fmt.Sprintf("No output directory specified. Defaulting to %s", outputDir))
-		} else {
-			outputDir, err = filepath.Abs(flagOutput)
-			if err != nil {
+		}
+		if err != nil {
+			return err
+		}
+		if apiRoot != "" {
+			if err := validateAPIPath(apiRoot, flagAPIPath); err != nil {
 				return err
 			}
 		}
 
-		image := deriveImage(nil)
-		// The final empty string argument is for generator input - we don't have any
-		if err := container.Generate(ctx, image, apiRoot, outputDir, "", flagAPIPath); err != nil {
+		outputDir, err := prepareOutputDir(tmpRoot)
+		if err != nil {
+			return err
+		}
+		recordArtifactDir("output", outputDir)
+		setReportDir(outputDir)
+		// With -archive, the archive is the result and the output directory
+		// is only needed if it was specified.
+		tmpRootHoldsOutput = flagOutput == "" && flagArchive == ""
+
+		containerOpts := containerOptions(nil)
+		// The empty string argument is for generator input - we don't have any
+		channel := releaseChannel(flagAPIPath, nil)
+		if descriptorSet != "" {
+			if !container.SupportsFeature(ctx, containerOpts, container.DescriptorSetFeature) {
+				return fmt.Errorf("image %s does not support generation from a descriptor set (it doesn't declare %q in its %s label)", containerOpts.Image, container.DescriptorSetFeature, container.FeaturesLabel)
+			}
+			err = container.GenerateFromDescriptorSet(ctx, containerOpts, descriptorSet, outputDir, flagAPIPath, channel)
+		} else {
+			config := generationConfig(apiRoot, flagAPIPath)
+			var inputHash string
+			if flagOutputCache != "" {
+				if digest, digestErr := container.ImageDigest(ctx, containerOpts); digestErr != nil || digest == "" {
+					slog.Warn(fmt.Sprintf("Unable to determine the digest of image %s, so the output cache isn't used", containerOpts.Image))
+				} else if inputHash, err = generationInputHash(apiRoot, flagAPIPath, "", digest, channel, containerOpts, config); err != nil {
+					return err
+				}
+			}
+			err = generateWithOutputCache(ctx, flagAPIPath, inputHash, outputDir, func() error {
+				return container.Generate(ctx, containerOpts, apiRoot, outputDir, "", flagAPIPath, channel, config)
+			})
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkGenerateOutput(outputDir, flagLanguage, flagAPIPath); err != nil {
 			return err
 		}
 
 		if flagBuild {
-			if err := container.Build(ctx, image, "generator-output", outputDir, flagAPIPath); err != nil {
+			if err := container.Build(ctx, containerOpts, "generator-output", outputDir, flagAPIPath); err != nil {
+				return err
+			}
+		}
+		if flagArchive != "" {
+			archivePath, err := filepath.Abs(flagArchive)
+			if err != nil {
 				return err
 			}
+			if err := writeArchive(outputDir, archivePath); err != nil {
+				return fmt.Errorf("unable to write archive: %w", err)
+			}
 		}
 		return nil
 	},
@@ -231,9 +471,41 @@ var CmdUpdateApis = &Command{
 		if !supportedLanguages[flagLanguage] {
 			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
 		}
-		if flagPush && flagGitHubToken == "" {
-			return fmt.Errorf("-github-token must be provided if -push is set to true")
+		if flagAPIPath != "" {
+			apiPath, err := normalizeAPIPath(flagAPIPath)
+			if err != nil {
+				return err
+			}
+			flagAPIPath = apiPath
+		}
+		if flagSkipCommit && flagPush {
+			return fmt.Errorf("-skip-commit cannot be combined with -push")
+		}
+		switch flagCommitGranularity {
+		case "per-api", "single":
+		default:
+			return fmt.Errorf("invalid -commit-granularity: %q; must be per-api or single", flagCommitGranularity)
+		}
+		if flagParallelGenerate < 0 || flagParallelBuild < 0 {
+			return fmt.Errorf("-parallel-generate and -parallel-build must not be negative")
+		}
+		if flagParallelBuild > 0 && flagSkipCommit {
+			return fmt.Errorf("-parallel-build cannot be combined with -skip-commit")
+		}
+		if flagScheduled && flagAPIPath != "" {
+			return fmt.Errorf("-scheduled cannot be combined with -api-path")
+		}
+		if err := validatePushFlags(ctx); err != nil {
+			return err
+		}
+		if err := preflight(); err != nil {
+			return err
+		}
+		release, err := acquireRepoLock()
+		if err != nil {
+			return err
 		}
+		defer release()
 
 		startOfRun := time.Now()
 
@@ -245,14 +517,18 @@ var CmdUpdateApis = &Command{
 		}
 
 		var apiRepo *gitrepo.Repo
+		// apiRoot is the tree generated from: the API repo itself, or (for a
+		// local proto tree) its combination with googleapis.
+		var apiRoot string
 		hardResetApiRepo := true
 		if flagAPIRoot == "" {
 			apiRepo, err = cloneGoogleapis(ctx, tmpRoot)
 			if err != nil {
 				return err
 			}
+			apiRoot = apiRepo.Dir
 		} else {
-			apiRoot, err := filepath.Abs(flagAPIRoot)
+			apiRoot, err = filepath.Abs(flagAPIRoot)
 			slog.Info(fmt.Sprintf("Using apiRoot: %s", apiRoot))
 			if err != nil {
 				slog.Info(fmt.Sprintf("Error retrieving apiRoot: %s", err))
@@ -270,44 +546,21 @@ var CmdUpdateApis = &Command{
 				hardResetApiRepo = false
 				slog.Warn("API repo has modifications, so will not be reset after generation")
 			}
-		}
-
-		var outputDir string
-		if flagOutput == "" {
-			outputDir = filepath.Join(tmpRoot, "output")
-			if err := os.Mkdir(outputDir, 0755); err != nil {
-				return err
-			}
-			slog.Info(fmt.Sprintf("No output directory specified. Defaulting to %s", outputDir))
-		} else {
-			outputDir, err = filepath.Abs(flagOutput)
-			if err != nil {
+			if apiRoot, err = resolveLocalAPIRoot(ctx, tmpRoot, apiRepo.Dir); err != nil {
 				return err
 			}
 		}
 
-		var languageRepo *gitrepo.Repo
-		if flagRepoRoot == "" {
-			languageRepo, err = cloneLanguageRepo(ctx, flagLanguage, tmpRoot)
-			if err != nil {
-				return err
-			}
-		} else {
-			repoRoot, err := filepath.Abs(flagRepoRoot)
-			if err != nil {
-				return err
-			}
-			languageRepo, err = gitrepo.Open(ctx, repoRoot)
-			if err != nil {
-				return err
-			}
-			clean, err := gitrepo.IsClean(ctx, apiRepo)
-			if err != nil {
-				return err
-			}
-			if !clean {
-				return errors.New("language repo must be clean before update")
-			}
+		outputDir, err := prepareOutputDir(tmpRoot)
+		if err != nil {
+			return err
+		}
+		recordArtifactDir("output", outputDir)
+		setReportDir(outputDir)
+
+		languageRepo, err := openLanguageRepo(ctx, tmpRoot)
+		if err != nil {
+			return err
 		}
 
 		state, err := loadState(languageRepo)
@@ -315,7 +568,13 @@ var CmdUpdateApis = &Command{
 			return err
 		}
 
-		image := deriveImage(state)
+		exclusions, err := loadExclusions(filepath.Join(languageRepo.Dir, "generator-input"))
+		if err != nil {
+			return err
+		}
+
+		containerOpts := containerOptions(state)
+		defer skipUnsupportedSteps(ctx, containerOpts)()
 
 		// Take a defensive copy of the generator input directory from the language repo.
 		generatorInput := filepath.Join(tmpRoot, "generator-input")
@@ -327,11 +586,76 @@ var CmdUpdateApis = &Command{
 		if err != nil {
 			return err
 		}
+		result := &generationResult{
+			repo:          languageRepo,
+			state:         state,
+			containerOpts: containerOpts,
+			startOfRun:    startOfRun,
+			baseHash:      hashBefore,
+			apiRoot:       apiRoot,
+		}
+		if result.commitTemplate, err = loadCommitMessageTemplate(); err != nil {
+			return err
+		}
+		if result.apiHash, err = gitrepo.HeadHash(ctx, apiRepo); err != nil {
+			return err
+		}
+		if result.imageDigest, err = container.ImageDigest(ctx, containerOpts); err != nil || result.imageDigest == "" {
+			slog.Info(fmt.Sprintf("Unable to determine the digest of image %s, so APIs will be generated even if their inputs are unchanged", containerOpts.Image))
+		}
+
+		if flagParallelBuild > 0 && !flagSkipBuild {
+			result.builds = newWorktreeBuilds(languageRepo, filepath.Join(tmpRoot, "worktrees"))
+			// Builds still running after a failure are waited for, so that
+			// their worktrees are removed.
+			defer result.builds.wait()
+		}
+
+		var scheduled map[string]bool
+		if flagScheduled {
+			if scheduled, err = scheduledAPIs(ctx, apiRepo, state, exclusions); err != nil {
+				return err
+			}
+		}
 
 		// Perform "generate, clean, commit, build" on each element in ApiGenerationStates.
-		for _, apiState := range state.ApiGenerationStates {
-			err = updateApi(ctx, apiRepo, languageRepo, generatorInput, image, outputDir, state, apiState)
-			if err != nil {
+		if flagParallelGenerate > 0 {
+			var updates []*apiUpdate
+			for _, apiState := range state.ApiGenerationStates {
+				if scheduled != nil && !scheduled[apiState.Id] {
+					continue
+				}
+				update, err := planApiUpdate(ctx, apiRepo, result, generatorInput, outputDir, apiState, exclusions)
+				if err != nil {
+					return err
+				}
+				if update != nil {
+					updates = append(updates, update)
+				}
+			}
+			if err := updateApisPipelined(ctx, apiRepo, result, generatorInput, updates); err != nil {
+				return err
+			}
+		} else {
+			for i, apiState := range state.ApiGenerationStates {
+				metrics.QueueDepth.Set(float64(len(state.ApiGenerationStates) - i))
+				if scheduled != nil && !scheduled[apiState.Id] {
+					continue
+				}
+				err = updateApi(ctx, apiRepo, result, generatorInput, outputDir, apiState, exclusions)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		metrics.QueueDepth.Set(0)
+		if result.builds != nil {
+			if err := result.builds.wait(); err != nil {
+				return err
+			}
+		}
+		if flagCommitGranularity == "single" {
+			if err := squashApiCommits(ctx, result); err != nil {
 				return err
 			}
 		}
@@ -341,7 +665,12 @@ var CmdUpdateApis = &Command{
 			gitrepo.ResetHard(ctx, apiRepo)
 		}
 
+		if skipped := skippedSteps(); len(skipped) > 0 {
+			slog.Info(fmt.Sprintf("Skipped steps: %s", strings.Join(skipped, ", ")))
+		}
+
 		if !flagPush {
+			keepTmpRootIfContains(languageRepo.Dir)
 			slog.Info("Pushing not specified; update complete.")
 			return nil
 		}
@@ -355,50 +684,156 @@ var CmdUpdateApis = &Command{
 			return nil
 		}
 
-		return push(ctx, languageRepo, startOfRun)
+		return push(ctx, result)
 	},
 }
 
-func updateApi(ctx context.Context, apiRepo *gitrepo.Repo, languageRepo *gitrepo.Repo, generatorInput string, image string, outputRoot string, repoState *statepb.PipelineState, apiState *statepb.ApiGenerationState) error {
+func updateApi(ctx context.Context, apiRepo *gitrepo.Repo, result *generationResult, generatorInput string, outputRoot string, apiState *statepb.ApiGenerationState, exclusions []apiExclusion) error {
+	update, err := planApiUpdate(ctx, apiRepo, result, generatorInput, outputRoot, apiState, exclusions)
+	if err != nil || update == nil {
+		return err
+	}
+	if err := update.generate(ctx, result.containerOpts, result.apiRoot, generatorInput); err != nil {
+		return err
+	}
+	return applyApiUpdate(ctx, apiRepo, result, update)
+}
+
+// apiUpdate is the regeneration of an API in update-apis, once it's been
+// decided that the API needs regenerating.
+type apiUpdate struct {
+	apiState *statepb.ApiGenerationState
+	// commits are the new googleapis commits for the API, most recent first.
+	commits   []object.Commit
+	channel   string
+	config    *container.GenerationConfig
+	inputHash string
+	outputDir string
+}
+
+// planApiUpdate decides whether an API needs regenerating, returning nil if
+// it doesn't (because it's excluded, blocked, unchanged or not the API
+// specified by -api-path).
+func planApiUpdate(ctx context.Context, apiRepo *gitrepo.Repo, result *generationResult, generatorInput string, outputRoot string, apiState *statepb.ApiGenerationState, exclusions []apiExclusion) (*apiUpdate, error) {
 	if flagAPIPath != "" && flagAPIPath != apiState.Id {
 		// If flagAPIPath has been passed in, we only act on that API.
-		return nil
+		return nil, nil
 	}
 
+	// An excluded API is still generated if it's explicitly requested.
+	if reason := exclusionReason(exclusions, apiState.Id); reason != "" && flagAPIPath == "" {
+		slog.Info(fmt.Sprintf("Ignoring excluded API: '%s' (%s)", apiState.Id, reason))
+		recordSkippedAPI(apiState.Id, fmt.Sprintf("excluded: %s", reason))
+		return nil, nil
+	}
 	if apiState.AutomationLevel == statepb.AutomationLevel_AUTOMATION_LEVEL_BLOCKED {
 		slog.Info(fmt.Sprintf("Ignoring blocked API: '%s'", apiState.Id))
-		return nil
+		recordSkippedAPI(apiState.Id, "blocked")
+		return nil, nil
 	}
 	commits, err := gitrepo.GetApiCommits(ctx, apiRepo, apiState.Id, apiState.LastGeneratedCommit)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if len(commits) == 0 {
+	if len(commits) == 0 && !flagRegenerateAll {
 		slog.Info(fmt.Sprintf("API '%s' has no changes.", apiState.Id))
-		return nil
+		return nil, nil
 	}
-	slog.Info(fmt.Sprintf("Generating '%s' with %d new commit(s)", apiState.Id, len(commits)))
-
-	// Now that we know the API has at least one new API commit, regenerate it, update the state, commit the change and build the output.
+	channel := releaseChannel(apiState.Id, apiState)
+	config := generationConfig(result.apiRoot, apiState.Id)
+	inputHash, err := generationInputHash(result.apiRoot, apiState.Id, generatorInput, result.imageDigest, channel, result.containerOpts, config)
+	if err != nil {
+		return nil, err
+	}
+	if inputHash != "" && inputHash == apiState.LastGeneratedInputHash {
+		slog.Info(fmt.Sprintf("Inputs of '%s' are unchanged since it was last generated; skipping", apiState.Id))
+		recordSkippedAPI(apiState.Id, "inputs unchanged since last generated")
+		if len(commits) > 0 {
+			return nil, advanceLastGeneratedCommit(ctx, result, apiState, commits[0].Hash.String())
+		}
+		return nil, nil
+	}
+	return &apiUpdate{
+		apiState:  apiState,
+		commits:   commits,
+		channel:   channel,
+		config:    config,
+		inputHash: inputHash,
+		// We create an output directory separately for each API.
+		outputDir: filepath.Join(outputRoot, apiState.Id),
+	}, nil
+}
 
-	// We create an output directory separately for each API.
-	outputDir := filepath.Join(outputRoot, apiState.Id)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
+// advanceLastGeneratedCommit records that an API whose inputs weren't changed
+// by its new googleapis commits is up to date with the latest of them, and
+// commits the state (unless -skip-commit is set), so that later runs don't
+// consider the same commits again.
+func advanceLastGeneratedCommit(ctx context.Context, result *generationResult, apiState *statepb.ApiGenerationState, commit string) error {
+	apiState.LastGeneratedCommit = commit
+	if err := saveState(result.repo, result.state); err != nil {
 		return err
 	}
+	if flagSkipCommit {
+		return nil
+	}
+	msg := fmt.Sprintf("chore: Advance %s to googleapis commit %s\n\nThe inputs of the API are unchanged since it was last generated.", apiState.Id, commit[:min(len(commit), 7)])
+	return commitAll(ctx, result.repo, msg)
+}
 
-	if err := container.Generate(ctx, image, apiRepo.Dir, outputDir, generatorInput, apiState.Id); err != nil {
+// generate regenerates the API into its own output directory. It doesn't
+// modify the language repo, so the generation of several APIs can overlap.
+func (u *apiUpdate) generate(ctx context.Context, containerOpts *container.Options, apiRoot, generatorInput string) error {
+	apiId := u.apiState.Id
+	slog.Info(fmt.Sprintf("Generating '%s' with %d new commit(s)", apiId, len(u.commits)))
+	if err := os.MkdirAll(u.outputDir, 0755); err != nil {
 		return err
 	}
-	if err := container.Clean(ctx, image, languageRepo.Dir, apiState.Id); err != nil {
+	err := generateWithOutputCache(ctx, apiId, u.inputHash, u.outputDir, func() error {
+		return container.Generate(ctx, containerOpts, apiRoot, u.outputDir, generatorInput, apiId, u.channel, u.config)
+	})
+	if err != nil {
 		return err
 	}
+	return checkGenerateOutput(u.outputDir, flagLanguage, apiId)
+}
+
+// applyApiUpdate applies the generated code for an API to the language repo:
+// cleaning, copying, updating the state, committing and building.
+func applyApiUpdate(ctx context.Context, apiRepo *gitrepo.Repo, result *generationResult, update *apiUpdate) error {
+	languageRepo, containerOpts := result.repo, result.containerOpts
+	apiState, commits, outputDir := update.apiState, update.commits, update.outputDir
+	if !flagSkipClean {
+		if err := container.Clean(ctx, containerOpts, languageRepo.Dir, apiState.Id); err != nil {
+			return err
+		}
+	}
 	if err := os.CopyFS(languageRepo.Dir, os.DirFS(outputDir)); err != nil {
 		return err
 	}
 
-	apiState.LastGeneratedCommit = commits[0].Hash.String()
-	if err := saveState(languageRepo, repoState); err != nil {
+	// With -regenerate-all, there may be no new commits: the API is
+	// regenerated from the commit it was last generated from.
+	previousCommit := apiState.LastGeneratedCommit
+	if len(commits) > 0 {
+		bump, err := recommendVersionBump(ctx, apiRepo, apiState.Id, apiState.LastGeneratedCommit, commits[0].Hash.String())
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Unable to analyze proto changes for '%s': %s", apiState.Id, err))
+		} else {
+			slog.Info(fmt.Sprintf("Recommended version bump for '%s': %s", apiState.Id, versionBumpName(bump)))
+			apiState.UnreleasedVersionBump = max(apiState.UnreleasedVersionBump, bump)
+		}
+
+		result.commitRanges = append(result.commitRanges, apiCommitRange{
+			APIPath: apiState.Id,
+			From:    apiState.LastGeneratedCommit,
+			To:      commits[0].Hash.String(),
+		})
+		result.changelog = append(result.changelog, newChangelogEntries(apiState.Id, commits)...)
+		apiState.LastGeneratedCommit = commits[0].Hash.String()
+	}
+	recordChangedLibraries(result.state, apiState.Id)
+	apiState.LastGeneratedInputHash = update.inputHash
+	if err := saveState(languageRepo, result.state); err != nil {
 		return err
 	}
 
@@ -407,14 +842,46 @@ func updateApi(ctx context.Context, apiRepo *gitrepo.Repo, languageRepo *gitrepo
 	// that we really are at the latest state. We could skip the build step here if there are no changes
 	// prior to updating the state, but it's probably not worth the additional complexity (and it does
 	// no harm to check the code is still "healthy").
-	var msg = createCommitMessage(commits)
-	if err := commitAll(ctx, languageRepo, msg); err != nil {
-		return err
+	if !flagSkipCommit {
+		var defaultMsg = createCommitMessage(commits)
+		if len(commits) == 0 {
+			defaultMsg = fmt.Sprintf("Regenerated %s", apiState.Id)
+		}
+		msg, err := renderCommitMessage(result.commitTemplate, &commitMessageData{
+			ApiPath:               apiState.Id,
+			GoogleapisSHA:         apiState.LastGeneratedCommit,
+			PreviousGoogleapisSHA: previousCommit,
+			Image:                 containerOpts.Image,
+			ImageDigest:           result.imageDigest,
+			PiperOriginRevIds:     piperOriginRevIds(commits),
+			Message:               defaultMsg,
+		})
+		if err != nil {
+			return err
+		}
+		if err := commitAll(ctx, languageRepo, msg); err != nil {
+			return err
+		}
+		hash, err := gitrepo.HeadHash(ctx, languageRepo)
+		if err != nil {
+			return err
+		}
+		result.apiCommits = append(result.apiCommits, apiCommit{APIPath: apiState.Id, Hash: hash, Message: msg})
 	}
 
-	// Once we've committed, we can build - but then check that nothing has changed afterwards.
-	if err := container.Build(ctx, image, "repo-root", languageRepo.Dir, apiState.Id); err != nil {
-		return err
+	if flagSkipBuild {
+		return nil
+	}
+	if result.builds != nil {
+		return result.builds.start(ctx, containerOpts, apiState.Id, result.apiCommits[len(result.apiCommits)-1].Hash)
+	}
+	// Once we've committed, we can build - but then check that nothing has changed afterwards.
+	if err := container.Build(ctx, containerOpts, "repo-root", languageRepo.Dir, apiState.Id); err != nil {
+		return err
+	}
+	if flagSkipCommit {
+		// Without a commit, there's no baseline to detect changes made by the build.
+		return nil
 	}
 	clean, err := gitrepo.IsClean(ctx, languageRepo)
 	if err != nil {
@@ -426,6 +893,77 @@ func updateApi(ctx context.Context, apiRepo *gitrepo.Repo, languageRepo *gitrepo
 	return nil
 }
 
+// skipUnsupportedSteps skips the optional steps (clean and build) which the
+// image doesn't support, as if -skip-clean or -skip-build had been specified.
+// It returns a function to restore the flags, so that the next language in a
+// multi-language run is unaffected.
+func skipUnsupportedSteps(ctx context.Context, containerOpts *container.Options) func() {
+	skipClean, skipBuild := flagSkipClean, flagSkipBuild
+	for _, step := range []struct {
+		name string
+		skip *bool
+	}{{"clean", &flagSkipClean}, {"build", &flagSkipBuild}} {
+		if !*step.skip && !container.Supports(ctx, containerOpts, step.name) {
+			slog.Warn(fmt.Sprintf("Image %s does not support the %s command; skipping the %s step", containerOpts.Image, step.name, step.name))
+			*step.skip = true
+		}
+	}
+	return func() {
+		flagSkipClean, flagSkipBuild = skipClean, skipBuild
+	}
+}
+
+// skippedSteps returns the names of the steps skipped by -skip-clean,
+// -skip-commit and -skip-build.
+func skippedSteps() []string {
+	var skipped []string
+	for _, step := range []struct {
+		name string
+		skip bool
+	}{{"clean", flagSkipClean}, {"commit", flagSkipCommit}, {"build", flagSkipBuild}} {
+		if step.skip {
+			skipped = append(skipped, step.name)
+		}
+	}
+	return skipped
+}
+
+// squashApiCommits replaces the commits made for each regenerated API with a
+// single commit, for -commit-granularity=single. The message of the combined
+// commit includes the message of each API's commit.
+func squashApiCommits(ctx context.Context, result *generationResult) error {
+	if len(result.apiCommits) < 2 {
+		return nil
+	}
+	if err := gitrepo.SoftReset(ctx, result.repo, result.baseHash); err != nil {
+		return err
+	}
+	var hashes []string
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "Regenerated %d APIs\n", len(result.apiCommits))
+	for _, commit := range result.apiCommits {
+		hashes = append(hashes, commit.Hash)
+		fmt.Fprintf(&msg, "\n%s:\n\n%s\n", commit.APIPath, strings.TrimSpace(commit.Message))
+	}
+	forgetCommits(hashes)
+	slog.Info(fmt.Sprintf("Squashing the commits for %d APIs into one", len(result.apiCommits)))
+	return commitAll(ctx, result.repo, msg.String())
+}
+
+// piperOriginRevIds returns the PiperOrigin-RevId values in the messages of
+// commits (which are in reverse chronological order), in chronological order.
+func piperOriginRevIds(commits []object.Commit) []string {
+	var ids []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		for _, line := range strings.Split(commits[i].Message, "\n") {
+			if id, ok := strings.CutPrefix(line, "PiperOrigin-RevId: "); ok {
+				ids = append(ids, strings.TrimSpace(id))
+			}
+		}
+	}
+	return ids
+}
+
 func createCommitMessage(commits []object.Commit) string {
 	const PiperPrefix = "PiperOrigin-RevId: "
 	var builder strings.Builder
@@ -458,20 +996,110 @@ func createCommitMessage(commits []object.Commit) string {
 	return builder.String()
 }
 
+func containerOptions(state *statepb.PipelineState) *container.Options {
+	if containerRunner == nil {
+		containerRunner = newContainerRunner()
+	}
+	return &container.Options{
+		Image:       deriveImage(state),
+		Experiments: activeExperiments(state),
+		BuildChecks: languageBuildChecks[flagLanguage],
+		Commands:    languageConfigs[flagLanguage].Commands,
+		Runner:      containerRunner,
+		StepTimeout: flagStepTimeout,
+		Docker:      dockerConfig(),
+		LogTail:     container.NewLogTail(),
+	}
+}
+
+// dockerConfig returns the configuration for running containers using docker,
+// from the -container-* flags.
+func dockerConfig() container.DockerConfig {
+	config := container.DockerConfig{
+		Env:          slices.Concat(containerProxyEnv(), flagContainerEnv),
+		Network:      flagContainerNetwork,
+		CacheVolumes: flagContainerCache,
+		User:         containerUser(),
+	}
+	if flagContainerSecrets != "" {
+		// Docker requires absolute paths for mounts.
+		config.SecretsDir, _ = filepath.Abs(flagContainerSecrets)
+	}
+	return config
+}
+
+// containerUser returns the user to run containers as, as specified by
+// -container-user.
+func containerUser() string {
+	switch flagContainerUser {
+	case "auto":
+		// Getuid returns -1 on Windows, where files aren't owned by uid.
+		if uid := os.Getuid(); uid > 0 {
+			return fmt.Sprintf("%d:%d", uid, os.Getgid())
+		}
+		return ""
+	case "image", "":
+		return ""
+	default:
+		return flagContainerUser
+	}
+}
+
+// containerRunner runs all containers, or is nil to run them using docker.
+// It's created from the -record-containers and -replay-containers flags on
+// first use, and may be set directly to run commands without docker.
+var containerRunner container.Runner
+
+func newContainerRunner() container.Runner {
+	switch {
+	case flagReplayContainers != "":
+		return &container.ReplayRunner{Dir: flagReplayContainers}
+	case flagRecordContainers != "":
+		runner := container.NewDockerRunner(dockerConfig())
+		if flagBackend == "local" {
+			runner = container.LocalRunner{Env: slices.Concat(containerProxyEnv(), flagContainerEnv)}
+		}
+		return &container.RecordingRunner{Dir: flagRecordContainers, Runner: runner}
+	case flagBackend == "local":
+		return container.LocalRunner{Env: slices.Concat(containerProxyEnv(), flagContainerEnv)}
+	default:
+		return nil
+	}
+}
+
 func deriveImage(state *statepb.PipelineState) string {
+	return deriveLanguageImage(flagLanguage, state)
+}
+
+// deriveLanguageImage returns the image to use for the given language: the
+// image specified by -image, or otherwise the language's configured (or
+// default) image with the tag from the pipeline state (or "latest" if state is
+// nil). If an image was resolved from -image-channel, that's used instead of
+// the default image, but never instead of a configured image.
+func deriveLanguageImage(language string, state *statepb.PipelineState) string {
 	if flagImage != "" {
 		return flagImage
 	}
 
-	defaultRepository := os.Getenv("LIBRARIAN_REPOSITORY")
-	relativeImage := fmt.Sprintf("google-cloud-%s-generator", flagLanguage)
-
 	var tag string
 	if state == nil {
 		tag = "latest"
 	} else {
 		tag = state.ImageTag
 	}
+	if image := languageConfigs[language].Image; image != "" {
+		// A tag (or digest) follows the last path element.
+		if strings.ContainsAny(path.Base(image), ":@") {
+			return image
+		}
+		return fmt.Sprintf("%s:%s", image, tag)
+	}
+	if image, ok := channelImages[language]; ok {
+		return image
+	}
+
+	defaultRepository := os.Getenv("LIBRARIAN_REPOSITORY")
+	relativeImage := fmt.Sprintf("google-cloud-%s-generator", language)
 	if defaultRepository == "" {
 		return fmt.Sprintf("%s:%s", relativeImage, tag)
 	} else {
@@ -480,7 +1108,10 @@ func deriveImage(state *statepb.PipelineState) string {
 }
 
 func loadState(languageRepo *gitrepo.Repo) (*statepb.PipelineState, error) {
-	path := filepath.Join(languageRepo.Dir, "generator-input", "pipeline-state.json")
+	return loadStateFile(filepath.Join(languageRepo.Dir, "generator-input", "pipeline-state.json"))
+}
+
+func loadStateFile(path string) (*statepb.PipelineState, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -495,7 +1126,10 @@ func loadState(languageRepo *gitrepo.Repo) (*statepb.PipelineState, error) {
 }
 
 func saveState(languageRepo *gitrepo.Repo, state *statepb.PipelineState) error {
-	path := filepath.Join(languageRepo.Dir, "generator-input", "pipeline-state.json")
+	return saveStateFile(filepath.Join(languageRepo.Dir, "generator-input", "pipeline-state.json"), state)
+}
+
+func saveStateFile(path string, state *statepb.PipelineState) error {
 	// Marshal the protobuf message as JSON...
 	unformatted, err := protojson.Marshal(state)
 	if err != nil {
@@ -519,9 +1153,13 @@ func createTmpWorkingRoot(t time.Time) (string, error) {
 		return flagWorkRoot, nil
 	}
 
-	const yyyyMMddHHmmss = "20060102T150405" // Expected format by time library
+	if flagAutoPruneDays > 0 {
+		if err := pruneTmpWorkingRoots(time.Duration(flagAutoPruneDays)*24*time.Hour, false); err != nil {
+			slog.Warn(fmt.Sprintf("Unable to prune old temporary working directories: %s", err))
+		}
+	}
 
-	path := filepath.Join(os.TempDir(), fmt.Sprintf("librarian-%s", t.Format(yyyyMMddHHmmss)))
+	path := filepath.Join(os.TempDir(), tmpRootPrefix+t.Format(tmpRootTimestampFormat))
 
 	_, err := os.Stat(path)
 	switch {
@@ -536,9 +1174,69 @@ func createTmpWorkingRoot(t time.Time) (string, error) {
 	}
 
 	slog.Info(fmt.Sprintf("Temporary working directory: %s", path))
+	createdTmpRoot = path
 	return path, nil
 }
 
+// createdTmpRoot is the temporary working directory created by
+// createTmpWorkingRoot, if any. (A directory specified with -work-root
+// is never recorded here.)
+var createdTmpRoot string
+
+// tmpRootHoldsOutput is set by commands whose result is written to the
+// temporary working directory (because no -output was specified), so that
+// the directory isn't removed after a successful run. The result may be
+// output files, a report, or a clone of the language repo with unpushed
+// commits.
+var tmpRootHoldsOutput bool
+
+// keepTmpRootIfContains sets tmpRootHoldsOutput if path, which holds part of
+// the command's result, is within the temporary working directory.
+func keepTmpRootIfContains(path string) {
+	if createdTmpRoot == "" || path == "" {
+		return
+	}
+	if rel, err := filepath.Rel(createdTmpRoot, path); err == nil && filepath.IsLocal(rel) {
+		tmpRootHoldsOutput = true
+	}
+}
+
+// cleanupTmpRoot wraps the Run function of a command so that the temporary
+// working directory it created is removed when the command completes
+// successfully (unless -keep-temp is specified or the directory holds the
+// command's output), or when the command is interrupted, rather than being
+// left behind with incomplete output. After a failure the directory is kept
+// for debugging.
+func cleanupTmpRoot(run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		err := run(ctx)
+		tmpRoot, holdsOutput := createdTmpRoot, tmpRootHoldsOutput
+		createdTmpRoot, tmpRootHoldsOutput = "", false
+		if tmpRoot == "" {
+			return err
+		}
+		switch {
+		case ctx.Err() != nil:
+			slog.Warn(fmt.Sprintf("Interrupted; removing temporary working directory %s", tmpRoot))
+		case err != nil:
+			slog.Info(fmt.Sprintf("Keeping temporary working directory %s after failure", tmpRoot))
+			return err
+		case flagKeepTemp:
+			slog.Info(fmt.Sprintf("Keeping temporary working directory %s as -keep-temp was specified", tmpRoot))
+			return err
+		case holdsOutput:
+			slog.Info(fmt.Sprintf("Keeping temporary working directory %s as it contains the output", tmpRoot))
+			return err
+		default:
+			slog.Info(fmt.Sprintf("Removing temporary working directory %s", tmpRoot))
+		}
+		if removeErr := os.RemoveAll(tmpRoot); removeErr != nil {
+			slog.Error(fmt.Sprintf("Unable to remove temporary working directory: %s", removeErr))
+		}
+		return err
+	}
+}
+
 // No commit is made if there are no file modifications.
 func commitAll(ctx context.Context, repo *gitrepo.Repo, msg string) error {
 	status, err := gitrepo.AddAll(ctx, repo)
@@ -551,50 +1249,480 @@ func commitAll(ctx context.Context, repo *gitrepo.Repo, msg string) error {
 	}
 
 	gitrepo.PrintStatus(ctx, repo)
-	return gitrepo.Commit(ctx, repo, msg)
+	if flagForge == "gerrit" {
+		// Gerrit identifies the change for each commit by its Change-Id.
+		msg = gitrepo.AddChangeID(msg)
+	}
+	signer, err := commitSigner()
+	if err != nil {
+		return err
+	}
+	if err := gitrepo.Commit(ctx, repo, msg, signer); err != nil {
+		return err
+	}
+	hash, err := gitrepo.HeadHash(ctx, repo)
+	if err != nil {
+		return err
+	}
+	recordCommit(hash, msg, slices.Sorted(maps.Keys(status)))
+	return nil
+}
+
+// commitSigner returns the signer specified by -sign-commits, or nil
+// if commits should not be signed.
+func commitSigner() (git.Signer, error) {
+	switch flagSignCommits {
+	case "":
+		return nil, nil
+	case "gpg", "gitsign":
+		return &gitrepo.ProgramSigner{Program: flagSignCommits, Key: flagSigningKey}, nil
+	default:
+		return nil, fmt.Errorf("invalid -sign-commits flag specified: %q", flagSignCommits)
+	}
+}
+
+// generatedBranchPrefix is the prefix for all branches pushed by librarian.
+const generatedBranchPrefix = "librarian-"
+
+// forgeToken returns the access token for the forge specified by -forge.
+func forgeToken() string {
+	switch flagForge {
+	case "gitlab":
+		return flagGitLabToken
+	case "gerrit":
+		return flagGerritToken
+	}
+	return flagGitHubToken
+}
+
+// forgeUsername returns the username to push to the forge specified by
+// -forge with. Only Gerrit requires one; other forges ignore it.
+func forgeUsername() string {
+	if flagForge == "gerrit" {
+		return flagGerritUser
+	}
+	return ""
+}
+
+// forgeName returns the name of the forge specified by -forge, for messages.
+func forgeName() string {
+	switch flagForge {
+	case "gitlab":
+		return "GitLab"
+	case "gerrit":
+		return "Gerrit"
+	}
+	return "GitHub"
 }
 
-func push(ctx context.Context, repo *gitrepo.Repo, startOfRun time.Time) error {
+// validatePushFlags checks the flags used when pushing changes, so that any
+// problems are reported before generation rather than afterwards.
+func validatePushFlags(ctx context.Context) error {
 	if !flagPush {
 		return nil
 	}
-	if flagGitHubToken == "" {
-		return fmt.Errorf("no GitHub token supplied for push")
+	switch flagForge {
+	case "github":
+		if err := resolveGitHubToken(ctx); err != nil {
+			return err
+		}
+		if flagGitHubToken == "" {
+			return fmt.Errorf("-github-token must be provided if -push is set to true")
+		}
+	case "gitlab":
+		if flagGitLabToken == "" {
+			return fmt.Errorf("-gitlab-token must be provided if -push is set to true with -forge=gitlab")
+		}
+		if flagFork != "" {
+			return fmt.Errorf("-fork is not supported with -forge=gitlab")
+		}
+	case "gerrit":
+		if flagGerritURL == "" || flagGerritUser == "" || flagGerritToken == "" {
+			return fmt.Errorf("-gerrit-url, -gerrit-user and -gerrit-token must be provided if -push is set to true with -forge=gerrit")
+		}
+		if flagFork != "" {
+			return fmt.Errorf("-fork is not supported with -forge=gerrit")
+		}
+		if flagOnExistingPR == "update" {
+			// Changes are updated by uploading new patch sets with the same
+			// Change-Id, not by pushing to a branch.
+			return fmt.Errorf("-on-existing-pr=update is not supported with -forge=gerrit")
+		}
+	default:
+		return fmt.Errorf("invalid -forge flag specified: %q", flagForge)
+	}
+	switch flagOnExistingPR {
+	case "create", "skip", "update", "fail":
+	default:
+		return fmt.Errorf("invalid -on-existing-pr flag specified: %q", flagOnExistingPR)
+	}
+	switch flagUpdateCommits {
+	case "replace", "squash", "amend":
+	default:
+		return fmt.Errorf("invalid -update-commits flag specified: %q", flagUpdateCommits)
+	}
+	if flagPushRetries < 0 {
+		return fmt.Errorf("-push-retries must not be negative")
+	}
+	if _, err := parseBranchNameTemplate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// scopeOverride is the scope of the current run when it acts on specific APIs
+// which aren't specified by -api-path: the comma-separated paths of the APIs
+// configured from an -api-list file, or of the APIs of a library being
+// removed.
+var scopeOverride string
+
+// apiScope returns the APIs the current run acts on, as used to identify its
+// pull request and lock: the API specified by -api-path, the APIs in
+// scopeOverride, or "all".
+func apiScope() string {
+	switch {
+	case flagAPIPath != "":
+		return flagAPIPath
+	case scopeOverride != "":
+		return scopeOverride
+	default:
+		return "all"
+	}
+}
+
+// pullRequestMarker returns the text embedded in the body of each pull request,
+// identifying the APIs (or all APIs) it was generated for.
+func pullRequestMarker() string {
+	return fmt.Sprintf("<!-- librarian:api-path=%s -->", apiScope())
+}
+
+// push pushes the commits in result to a new branch, and creates a pull request.
+// If there's already an open pull request for the same API path, the -on-existing-pr
+// flag determines whether it's ignored, updated, or causes the push to be skipped or fail.
+func push(ctx context.Context, result *generationResult) error {
+	if !flagPush {
+		keepTmpRootIfContains(result.repo.Dir)
+		return nil
+	}
+	if forgeToken() == "" {
+		return fmt.Errorf("no %s token supplied for push", forgeName())
+	}
+	repo := result.repo
+	forge, err := gitrepo.NewForge(flagForge, repo, forgeToken())
+	if err != nil {
+		return err
+	}
+	if flagOnExistingPR != "create" {
+		existing, err := forge.FindOpenPullRequests(ctx, generatedBranchPrefix, pullRequestMarker())
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			pr := existing[0]
+			switch flagOnExistingPR {
+			case "skip":
+				slog.Info(fmt.Sprintf("Open pull request %s already exists; skipping push.", pr.HTMLURL))
+				return nil
+			case "fail":
+				return fmt.Errorf("open pull request %s already exists", pr.HTMLURL)
+			case "update":
+				slog.Info(fmt.Sprintf("Updating existing pull request %s", pr.HTMLURL))
+				if err := prepareBranchUpdate(ctx, result, pr); err != nil {
+					return err
+				}
+				if err := pushToExistingBranch(ctx, repo, pr); err != nil {
+					metrics.StepFailures.Inc("push")
+					return err
+				}
+				postDiffSummaryComment(ctx, forge, pr, result)
+				return nil
+			}
+		}
 	}
+
 	const yyyyMMddHHmmss = "20060102T150405" // Expected format by time library
-	timestamp := startOfRun.Format(yyyyMMddHHmmss)
-	branch := fmt.Sprintf("librarian-%s", timestamp)
-	err := gitrepo.PushBranch(ctx, repo, branch, flagGitHubToken)
+	timestamp := result.startOfRun.Format(yyyyMMddHHmmss)
+	branch, err := pullRequestBranchName(result)
+	if err != nil {
+		return err
+	}
+	title := result.title
+	if title == "" {
+		title = fmt.Sprintf("feat: API regeneration: %s", timestamp)
+	}
+	body, err := createPullRequestBody(ctx, result)
+	if err != nil {
+		return err
+	}
+	pr, err := createPullRequest(ctx, repo, forge, result.state, result.baseHash, branch, title, body)
 	if err != nil {
 		return err
 	}
+	postDiffSummaryComment(ctx, forge, pr, result)
+	return nil
+}
+
+// branchNameData is the data available to -branch-name-template.
+type branchNameData struct {
+	// ApiPath is the API path (or comma-separated paths) the run acts on, or "all".
+	ApiPath  string
+	Language string
+	// Date is the date of the start of the run, as YYYYMMDD.
+	Date string
+	// Timestamp is the time of the start of the run, as YYYYMMDDTHHMMSS.
+	Timestamp string
+	// GoogleapisSHA is the googleapis commit generated from, if known.
+	GoogleapisSHA string
+}
 
-	title := fmt.Sprintf("feat: API regeneration: %s", timestamp)
-	return gitrepo.CreatePullRequest(ctx, repo, branch, flagGitHubToken, title)
+// pullRequestBranchName returns the name of the branch to push for a pull
+// request, rendered from -branch-name-template (or librarian-<timestamp> by
+// default). The name is sanitized to be a valid git ref, and prefixed with
+// generatedBranchPrefix if necessary, so that the branch is still found when
+// looking for existing pull requests and pruning branches.
+func pullRequestBranchName(result *generationResult) (string, error) {
+	const yyyyMMddHHmmss = "20060102T150405" // Expected format by time library
+	data := &branchNameData{
+		ApiPath:       apiScope(),
+		Language:      flagLanguage,
+		Date:          result.startOfRun.Format("20060102"),
+		Timestamp:     result.startOfRun.Format(yyyyMMddHHmmss),
+		GoogleapisSHA: result.apiHash,
+	}
+	tmpl, err := parseBranchNameTemplate()
+	if err != nil {
+		return "", err
+	}
+	if tmpl == nil {
+		return generatedBranchPrefix + data.Timestamp, nil
+	}
+	return renderBranchName(tmpl, data)
+}
+
+// parseBranchNameTemplate parses -branch-name-template, returning nil if the
+// flag isn't set. As every run pushes a new branch, the template must render
+// a different name for each run, which is checked by rendering it for two runs
+// started a second apart on the same day: a template without .Timestamp would
+// make a later run fail to push, or push onto the branch of another pull
+// request.
+func parseBranchNameTemplate() (*template.Template, error) {
+	if flagBranchNameTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("branch").Funcs(templateFuncs).Parse(flagBranchNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid branch name template: %w", err)
+	}
+	data := &branchNameData{
+		ApiPath:       "google/example/v1",
+		Language:      "go",
+		Date:          "20250101",
+		Timestamp:     "20250101T000000",
+		GoogleapisSHA: "0123456789abcdef0123456789abcdef01234567",
+	}
+	first, err := renderBranchName(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	data.Timestamp = "20250101T000001"
+	second, err := renderBranchName(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	if first == second {
+		return nil, fmt.Errorf("branch name template %q must include .Timestamp, so that each run pushes a new branch", flagBranchNameTemplate)
+	}
+	return tmpl, nil
+}
+
+// renderBranchName renders a branch name template with the given data.
+func renderBranchName(tmpl *template.Template, data *branchNameData) (string, error) {
+	var name strings.Builder
+	if err := tmpl.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("unable to render branch name: %w", err)
+	}
+	branch := gitrepo.SanitizeBranchName(name.String())
+	if branch == "" {
+		return "", fmt.Errorf("branch name template %q rendered an empty name", flagBranchNameTemplate)
+	}
+	if !strings.HasPrefix(branch, generatedBranchPrefix) {
+		branch = generatedBranchPrefix + branch
+	}
+	return branch, nil
+}
+
+// createPullRequest pushes the HEAD of repo to a new branch (in the fork, if
+// -fork is specified), and creates a pull request from it in the upstream
+// repository. The pull request is configured as specified in the pipeline
+// state, and code owners of the files changed since baseHash are requested
+// to review it. The pull request is returned.
+func createPullRequest(ctx context.Context, repo *gitrepo.Repo, forge gitrepo.Forge, state *statepb.PipelineState, baseHash, branch, title, body string) (*gitrepo.PullRequest, error) {
+	// When using a fork, the branch is pushed to the fork but the pull request
+	// is still created in the upstream repository.
+	var pushURL string
+	if flagFork != "" {
+		var err error
+		if pushURL, err = forge.ForkURL(flagFork); err != nil {
+			return nil, err
+		}
+	}
+	baseBranch := languageRepoBranch(flagLanguage)
+	if baseBranch == "" {
+		baseBranch = "main"
+	}
+	if err := gitrepo.PushToRef(ctx, repo, pushURL, forge.UploadRef(branch, baseBranch), forgeUsername(), forgeToken()); err != nil {
+		metrics.StepFailures.Inc("push")
+		return nil, err
+	}
+
+	pr, err := forge.CreatePullRequest(ctx, flagFork, branch, baseBranch, title, body)
+	if err != nil {
+		metrics.StepFailures.Inc("pull-request")
+		return nil, err
+	}
+	recordPullRequest(pr.HTMLURL)
+	if err := configurePullRequest(ctx, forge, pr, state.GetPullRequestConfig()); err != nil {
+		return nil, err
+	}
+	if err := requestCodeownersReview(ctx, repo, forge, pr, baseHash); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// configurePullRequest adds the reviewers, labels and assignees specified in
+// the pipeline state and on the command line to a newly-created pull request.
+func configurePullRequest(ctx context.Context, forge gitrepo.Forge, pr *gitrepo.PullRequest, config *statepb.PullRequestConfig) error {
+	var users, teams []string
+	for _, reviewer := range slices.Concat(config.GetReviewers(), splitList(flagPRReviewers)) {
+		reviewer = strings.TrimPrefix(reviewer, "@")
+		if _, team, found := strings.Cut(reviewer, "/"); found {
+			teams = append(teams, team)
+		} else {
+			users = append(users, reviewer)
+		}
+	}
+	if err := forge.RequestReviewers(ctx, pr, users, teams); err != nil {
+		return err
+	}
+	labels := slices.Concat(config.GetLabels(), splitList(flagPRLabels))
+	if err := forge.AddLabels(ctx, pr, labels); err != nil {
+		return err
+	}
+	assignees := slices.Concat(config.GetAssignees(), splitList(flagPRAssignees))
+	return forge.AddAssignees(ctx, pr, assignees)
+}
+
+// requestCodeownersReview requests reviews from the specific owners of any
+// files changed since baseHash, according to the repository's CODEOWNERS file.
+// If there are any such owners, the pull request is labeled as requiring human
+// review, to protect handwritten code which lives alongside generated code.
+func requestCodeownersReview(ctx context.Context, repo *gitrepo.Repo, forge gitrepo.Forge, pr *gitrepo.PullRequest, baseHash string) error {
+	rules, err := loadCodeowners(repo.Dir)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	paths, err := gitrepo.ChangedFilesSince(ctx, repo, baseHash)
+	if err != nil {
+		return err
+	}
+	owners := specificOwners(rules, paths)
+	if len(owners) == 0 {
+		return nil
+	}
+	slog.Info(fmt.Sprintf("Changes touch files with specific owners; requesting review from %s", strings.Join(owners, ", ")))
+	users, teams := splitOwners(owners)
+	if err := forge.RequestReviewers(ctx, pr, users, teams); err != nil {
+		return err
+	}
+	return forge.AddLabels(ctx, pr, []string{humanReviewLabel})
 }
 
 var Commands = []*Command{
 	CmdConfigure,
 	CmdGenerate,
+	CmdBatch,
 	CmdUpdateApis,
+	CmdServe,
+	CmdPruneBranches,
+	CmdPrune,
+	CmdVerify,
+	CmdBuild,
+	CmdClean,
+	CmdDebugShell,
+	CmdRelease,
+	CmdRemoveLibrary,
+	CmdListApis,
+	CmdStatus,
+	CmdGolden,
+	CmdCompareImages,
+	CmdSelfTest,
+	CmdConformance,
+	CmdCompletion,
+	CmdVersion,
 }
 
 func init() {
+	CmdGolden.Run = runGolden
+	CmdCompareImages.Run = runCompareImages
+	CmdCompletion.Run = runCompletion
+	CmdRelease.Run = runRelease
+	CmdListApis.Run = runListApis
+	for _, c := range []*Command{CmdConfigure, CmdGenerate, CmdUpdateApis, CmdVerify} {
+		c.Run = instrumentLanguage(c.Name, writeReport(c.Name, c.Run))
+	}
+	CmdGenerate.Run = forEachLanguage(CmdGenerate.Run, fetchGenerateAPIRoot)
+	CmdUpdateApis.Run = forEachLanguage(CmdUpdateApis.Run, fetchUpdateApisAPIRoot)
 	for _, c := range Commands {
 		c.flags = flag.NewFlagSet(c.Name, flag.ContinueOnError)
 		c.flags.Usage = constructUsage(c.flags, c.Name)
+		c.Run = instrument(c.Name, releaseRunLocks(cleanupTmpRoot(notify(c, uploadArtifacts(c, reportToCI(c, resolveImageChannel(c.Run)))))))
+		addFlagCI(c.flags)
+		addFlagProxy(c.flags)
+		addFlagOffline(c.flags)
 	}
 
 	fs := CmdConfigure.flags
 	for _, fn := range []func(fs *flag.FlagSet){
 		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
 		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagNotify,
+		addFlagMinFreeDiskGB,
+		addFlagNoLock,
 		addFlagAPIPath,
+		addFlagAPIList,
 		addFlagAPIRoot,
+		addFlagAPITarball,
 		addFlagLanguage,
+		addFlagInteractive,
 		addFlagPush,
 		addFlagGitHubToken,
+		addFlagGitHubApp,
+		addFlagGitHubEndpoint,
+		addFlagForge,
 		addFlagRepoRoot,
+		addFlagForce,
+		addFlagRepoBranch,
+		addFlagRepoURL,
+		addFlagCloneCache,
+		addFlagGoogleapisMirror,
+		addFlagFork,
+		addFlagSignCommits,
+		addFlagPRBodyTemplate,
+		addFlagBranchNameTemplate,
+		addFlagPullRequestConfig,
+		addFlagOnExistingPR,
+		addFlagUpdateCommits,
+		addFlagPushRetries,
 	} {
 		fn(fs)
 	}
@@ -602,28 +1730,427 @@ func init() {
 	fs = CmdGenerate.flags
 	for _, fn := range []func(fs *flag.FlagSet){
 		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
 		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagNotify,
+		addFlagMinFreeDiskGB,
 		addFlagAPIPath,
 		addFlagAPIRoot,
+		addFlagAPITarball,
+		addFlagDescriptorSet,
 		addFlagLanguage,
 		addFlagOutput,
+		addFlagOutputCache,
+		addFlagForce,
+		addFlagArchive,
 		addFlagBuild,
 	} {
 		fn(fs)
 	}
 
+	fs = CmdBatch.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagNotify,
+		addFlagManifest,
+		addFlagParallelism,
+		addFlagAPIRoot,
+		addFlagAPITarball,
+		addFlagOutput,
+	} {
+		fn(fs)
+	}
+
 	fs = CmdUpdateApis.flags
 	for _, fn := range []func(fs *flag.FlagSet){
 		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
 		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagNotify,
+		addFlagMinFreeDiskGB,
+		addFlagNoLock,
 		addFlagAPIPath,
 		addFlagAPIRoot,
 		addFlagBranch,
 		addFlagGitHubToken,
+		addFlagGitHubApp,
+		addFlagGitHubEndpoint,
+		addFlagForge,
+		addFlagLanguage,
+		addFlagOutput,
+		addFlagPush,
+		addFlagRepoRoot,
+		addFlagForce,
+		addFlagRepoBranch,
+		addFlagRepoURL,
+		addFlagCloneCache,
+		addFlagGoogleapisMirror,
+		addFlagFork,
+		addFlagSignCommits,
+		addFlagPRBodyTemplate,
+		addFlagBranchNameTemplate,
+		addFlagPullRequestConfig,
+		addFlagOnExistingPR,
+		addFlagUpdateCommits,
+		addFlagPushRetries,
+		addFlagMetricsAddr,
+		addFlagSkipSteps,
+		addFlagScheduled,
+		addFlagRegenerateAll,
+		addFlagOutputCache,
+		addFlagParallelGenerate,
+		addFlagParallelBuild,
+		addFlagCommitGranularity,
+		addFlagCommitMessageTemplate,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdServe.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagNotify,
+		addFlagMinFreeDiskGB,
+		addFlagNoLock,
+		addFlagAPIRoot,
+		addFlagBranch,
+		addFlagGitHubToken,
+		addFlagGitHubApp,
+		addFlagGitHubEndpoint,
+		addFlagForge,
 		addFlagLanguage,
 		addFlagOutput,
 		addFlagPush,
 		addFlagRepoRoot,
+		addFlagForce,
+		addFlagRepoBranch,
+		addFlagRepoURL,
+		addFlagCloneCache,
+		addFlagGoogleapisMirror,
+		addFlagFork,
+		addFlagSignCommits,
+		addFlagPRBodyTemplate,
+		addFlagBranchNameTemplate,
+		addFlagPullRequestConfig,
+		addFlagOnExistingPR,
+		addFlagUpdateCommits,
+		addFlagPushRetries,
+		addFlagSkipSteps,
+		addFlagListen,
+		addFlagWebhookSecret,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdPruneBranches.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagLanguage,
+		addFlagRepoURL,
+		addFlagGitHubToken,
+		addFlagGitHubApp,
+		addFlagGitHubEndpoint,
+		addFlagMaxAgeDays,
+		addFlagDryRun,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdPrune.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagMaxAgeDays,
+		addFlagDryRun,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdVersion.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagLanguage,
+		addFlagFormat,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdVerify.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagNotify,
+		addFlagMinFreeDiskGB,
+		addFlagNoLock,
+		addFlagAPIPath,
+		addFlagAPIRoot,
+		addFlagLanguage,
+		addFlagRepoRoot,
+		addFlagRepoBranch,
+		addFlagRepoURL,
+		addFlagCloneCache,
+		addFlagGoogleapisMirror,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdBuild.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
+		addFlagAPIPath,
+		addFlagLanguage,
+		addFlagOutput,
+		addFlagRepoRoot,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdClean.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
+		addFlagNoLock,
+		addFlagAPIPath,
+		addFlagLanguage,
+		addFlagRepoRoot,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdDebugShell.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerConfig,
+		addFlagAPIPath,
+		addFlagAPIRoot,
+		addFlagLanguage,
+		addFlagOutput,
+		addFlagRepoRoot,
+		addFlagShell,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdRelease.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagNotify,
+		addFlagMinFreeDiskGB,
+		addFlagNoLock,
+		addFlagLanguage,
+		addFlagLibraryID,
+		addFlagDryRun,
+		addFlagPublishCredentials,
+		addFlagPush,
+		addFlagGitHubToken,
+		addFlagGitHubApp,
+		addFlagGitHubEndpoint,
+		addFlagForge,
+		addFlagRepoRoot,
+		addFlagForce,
+		addFlagRepoBranch,
+		addFlagRepoURL,
+		addFlagCloneCache,
+		addFlagFork,
+		addFlagSignCommits,
+		addFlagPullRequestConfig,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdRemoveLibrary.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagNotify,
+		addFlagMinFreeDiskGB,
+		addFlagNoLock,
+		addFlagLanguage,
+		addFlagLibraryID,
+		addFlagPush,
+		addFlagGitHubToken,
+		addFlagGitHubApp,
+		addFlagGitHubEndpoint,
+		addFlagForge,
+		addFlagRepoRoot,
+		addFlagForce,
+		addFlagRepoBranch,
+		addFlagRepoURL,
+		addFlagCloneCache,
+		addFlagFork,
+		addFlagSignCommits,
+		addFlagPRBodyTemplate,
+		addFlagBranchNameTemplate,
+		addFlagPullRequestConfig,
+		addFlagOnExistingPR,
+		addFlagUpdateCommits,
+		addFlagPushRetries,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdListApis.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagAPIRoot,
+		addFlagFormat,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdStatus.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagLanguage,
+		addFlagRepoRoot,
+		addFlagRepoBranch,
+		addFlagRepoURL,
+		addFlagAPIRoot,
+		addFlagCloneCache,
+		addFlagGoogleapisMirror,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagImage,
+		addFlagGitHubToken,
+		addFlagGitHubApp,
+		addFlagGitHubEndpoint,
+		addFlagForge,
+		addFlagFormat,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdGolden.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagImageChannel,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagMinFreeDiskGB,
+		addFlagAPIRoot,
+		addFlagLanguage,
+		addFlagCloneCache,
+		addFlagGoogleapisMirror,
+		addFlagGoldenDir,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdCompareImages.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagExperiments,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+		addFlagArtifactBucket,
+		addFlagMinFreeDiskGB,
+		addFlagAPIPath,
+		addFlagAPIRoot,
+		addFlagAPITarball,
+		addFlagLanguage,
+		addFlagOutput,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdSelfTest.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
+	} {
+		fn(fs)
+	}
+
+	fs = CmdConformance.flags
+	for _, fn := range []func(fs *flag.FlagSet){
+		addFlagImage,
+		addFlagLanguage,
+		addFlagAPIRoot,
+		addFlagAPIPath,
+		addFlagContainerRecording,
+		addFlagBackend,
+		addFlagContainerConfig,
+		addFlagStepTimeout,
+		addFlagWorkRoot,
+		addFlagKeepTemp,
+		addFlagAutoPruneDays,
 	} {
 		fn(fs)
 	}
@@ -631,7 +2158,9 @@ func init() {
 
 func constructUsage(fs *flag.FlagSet, name string) func() {
 	output := fmt.Sprintf("Usage:\n\n  librarian %s [arguments]\n", name)
-	output += "\nFlags:\n\n"
+	output += "\nFlags (each of which may instead be specified with an environment variable,\n"
+	output += "e.g. LIBRARIAN_API_PATH for -api-path, or in librarian.yaml or the user\n"
+	output += "configuration file librarian/config.yaml):\n\n"
 	return func() {
 		fmt.Fprint(fs.Output(), output)
 		fs.PrintDefaults()
@@ -0,0 +1,67 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPullRequestBranchName(t *testing.T) {
+	startOfRun := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	result := &generationResult{startOfRun: startOfRun, apiHash: "fedcba9876543210fedcba9876543210fedcba98"}
+	for _, test := range []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{template: "", want: "librarian-20250304T050607"},
+		{template: "{{.Language}}/{{.Timestamp}}", want: "librarian-go/20250304T050607"},
+		{template: "librarian-{{short .GoogleapisSHA}}-{{.Timestamp}}", want: "librarian-fedcba9-20250304T050607"},
+		// Templates which render the same name for two runs are rejected.
+		{template: "{{.Date}}-{{short .GoogleapisSHA}}", wantErr: true},
+		{template: "regen", wantErr: true},
+		{template: "{{.Timestamp", wantErr: true},
+		{template: "{{.Unknown}}-{{.Timestamp}}", wantErr: true},
+	} {
+		flagBranchNameTemplate = test.template
+		flagLanguage = "go"
+		got, err := pullRequestBranchName(result)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("pullRequestBranchName with template %q = %q; want an error", test.template, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("pullRequestBranchName with template %q: %v", test.template, err)
+		} else if got != test.want {
+			t.Errorf("pullRequestBranchName with template %q = %q; want %q", test.template, got, test.want)
+		}
+	}
+	flagBranchNameTemplate = ""
+	flagLanguage = ""
+}
+
+func TestBranchFlagAliasesRepoBranch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := CmdUpdateApis.Parse([]string{"-branch=develop"}); err != nil {
+		t.Fatal(err)
+	}
+	if flagRepoBranch != "develop" {
+		t.Errorf("-branch=develop set the repo branch to %q; want %q", flagRepoBranch, "develop")
+	}
+	flagRepoBranch = ""
+}
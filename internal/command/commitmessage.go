@@ -0,0 +1,98 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// commitMessageData is the data available to commit message templates.
+type commitMessageData struct {
+	// ApiPath is the path of the regenerated API, e.g. google/cloud/functions/v2.
+	ApiPath string
+	// GoogleapisSHA is the googleapis commit the API has been generated from.
+	GoogleapisSHA string
+	// PreviousGoogleapisSHA is the googleapis commit the API was previously
+	// generated from, if any.
+	PreviousGoogleapisSHA string
+	Image                 string
+	ImageDigest           string
+	// PiperOriginRevIds are the PiperOrigin-RevId values of the googleapis
+	// commits included, in chronological order.
+	PiperOriginRevIds []string
+	// Message is the default commit message.
+	Message string
+}
+
+// templateFuncs are the functions available to the templates of commit
+// messages, pull request bodies and branch names: short abbreviates a commit
+// hash to 7 characters, and codeBlock formats text as a markdown code block.
+var templateFuncs = template.FuncMap{
+	"short":     func(hash string) string { return hash[:min(len(hash), 7)] },
+	"codeBlock": codeBlock,
+}
+
+// backtickRunPattern matches a run of backticks.
+var backtickRunPattern = regexp.MustCompile("`+")
+
+// codeBlock returns text as a fenced markdown code block, with a fence longer
+// than any run of backticks in the text so that the text can't end the block.
+// HTML comments are broken up, so that the text can't contain a pull request
+// marker (see pullRequestMarker).
+func codeBlock(text string) string {
+	fence := 3
+	for _, run := range backtickRunPattern.FindAllString(text, -1) {
+		fence = max(fence, len(run)+1)
+	}
+	text = strings.ReplaceAll(text, "<!--", "<!-\u200b-")
+	return fmt.Sprintf("%s\n%s\n%s", strings.Repeat("`", fence), text, strings.Repeat("`", fence))
+}
+
+// loadCommitMessageTemplate parses the template specified by
+// -commit-message-template, returning nil if the flag isn't set.
+func loadCommitMessageTemplate() (*template.Template, error) {
+	if flagCommitMessageTemplate == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(flagCommitMessageTemplate)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("commit").Funcs(templateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderCommitMessage renders the commit message for a regenerated API, or
+// returns data.Message unchanged if tmpl is nil.
+func renderCommitMessage(tmpl *template.Template, data *commitMessageData) (string, error) {
+	if tmpl == nil {
+		return data.Message, nil
+	}
+	var msg strings.Builder
+	if err := tmpl.Execute(&msg, data); err != nil {
+		return "", fmt.Errorf("unable to render the commit message for '%s': %w", data.ApiPath, err)
+	}
+	if strings.TrimSpace(msg.String()) == "" {
+		return "", fmt.Errorf("the commit message template rendered an empty message for '%s'", data.ApiPath)
+	}
+	return msg.String(), nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeBlock(t *testing.T) {
+	text := "error:\n```\n## Injected\n<!-- librarian:api-path=google/other/v1 -->"
+	got := codeBlock(text)
+	if !strings.HasPrefix(got, "````\n") || !strings.HasSuffix(got, "\n````") {
+		t.Errorf("codeBlock(%q) = %q; want a fence of 4 backticks", text, got)
+	}
+	if pullRequestMarkerPattern.MatchString(got) {
+		t.Errorf("codeBlock(%q) = %q; want no pull request marker", text, got)
+	}
+	if got, want := codeBlock("plain"), "```\nplain\n```"; got != want {
+		t.Errorf("codeBlock(plain) = %q; want %q", got, want)
+	}
+}
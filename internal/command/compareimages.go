@@ -0,0 +1,212 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+)
+
+// compareReportFile and compareDiffFile are the names of the summary and the
+// full diff written by compare-images.
+const (
+	compareReportFile = "compare-report.md"
+	compareDiffFile   = "compare.diff"
+)
+
+// CmdCompareImages generates an API with two generator images (typically the
+// current production image and a new release) and reports the differences in
+// the generated output, so that a new image can be validated before it's
+// rolled out. The output of each image is written to the "base" and
+// "candidate" subdirectories of the output directory, along with a summary
+// (compare-report.md) and, if the diff tool is available, a unified diff
+// (compare.diff). Differences don't cause the command to fail.
+var CmdCompareImages = &Command{
+	Name:  "compare-images",
+	Short: "Compare the code generated for an API by two images: compare-images base-image candidate-image",
+	// Run is set in init, as it refers to CmdCompareImages.
+}
+
+func runCompareImages(ctx context.Context) error {
+	// The images are positional, but flags may appear before or after them.
+	args := CmdCompareImages.flags.Args()
+	var images []string
+	for len(args) > 0 {
+		images = append(images, args[0])
+		if err := CmdCompareImages.flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		args = CmdCompareImages.flags.Args()
+	}
+	if len(images) != 2 {
+		return fmt.Errorf("compare-images requires two images: the base image and the candidate image")
+	}
+	if flagAPIPath == "" {
+		return fmt.Errorf("-api-path is not provided")
+	}
+	apiPath, err := normalizeAPIPath(flagAPIPath)
+	if err != nil {
+		return err
+	}
+	flagAPIPath = apiPath
+	if !supportedLanguages[flagLanguage] {
+		return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+	}
+	if flagAPIRoot == "" && flagAPITarball == "" {
+		return fmt.Errorf("-api-root or -api-tarball must be provided")
+	}
+	if err := preflight(); err != nil {
+		return err
+	}
+	tmpRoot, err := createTmpWorkingRoot(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var apiRoot string
+	if flagAPIRoot == "" {
+		apiRoot, err = downloadGoogleapisTarball(ctx, tmpRoot, flagAPITarball)
+	} else if apiRoot, err = filepath.Abs(flagAPIRoot); err == nil {
+		apiRoot, err = resolveLocalAPIRoot(ctx, tmpRoot, apiRoot)
+	}
+	if err != nil {
+		return err
+	}
+	if err := validateAPIPath(apiRoot, flagAPIPath); err != nil {
+		return err
+	}
+
+	outputRoot := filepath.Join(tmpRoot, "compare")
+	tmpRootHoldsOutput = flagOutput == ""
+	if flagOutput != "" {
+		if outputRoot, err = filepath.Abs(flagOutput); err != nil {
+			return err
+		}
+	}
+	recordArtifactDir("compare", outputRoot)
+
+	labels := []string{"base", "candidate"}
+	channel := releaseChannel(flagAPIPath, nil)
+	for i, image := range images {
+		outputDir := filepath.Join(outputRoot, labels[i])
+		if err := os.RemoveAll(outputDir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return err
+		}
+		containerOpts := containerOptions(nil)
+		containerOpts.Image = image
+		slog.Info(fmt.Sprintf("Generating %s with %s image %s", flagAPIPath, labels[i], image))
+		if err := container.Generate(ctx, containerOpts, apiRoot, outputDir, "", flagAPIPath, channel, generationConfig(apiRoot, flagAPIPath)); err != nil {
+			return fmt.Errorf("generation with %s image %s failed: %w", labels[i], image, err)
+		}
+	}
+
+	differences, err := compareTrees(filepath.Join(outputRoot, "base"), filepath.Join(outputRoot, "candidate"))
+	if err != nil {
+		return err
+	}
+	hasDiff, err := writeCompareDiff(ctx, outputRoot)
+	if err != nil {
+		return err
+	}
+	if err := writeCompareReport(ctx, outputRoot, images, differences, hasDiff); err != nil {
+		return err
+	}
+	if len(differences) == 0 {
+		slog.Info("The images generate identical output.")
+	} else {
+		slog.Info(fmt.Sprintf("%d file(s) differ between the images:\n%s", len(differences), strings.Join(differences, "\n")))
+	}
+	slog.Info(fmt.Sprintf("Comparison report written to %s", filepath.Join(outputRoot, compareReportFile)))
+	return nil
+}
+
+// writeCompareDiff writes a unified diff of the base and candidate output to
+// compare.diff, using the diff tool. It reports whether the diff was written;
+// if the diff tool isn't available, the diff is skipped.
+func writeCompareDiff(ctx context.Context, outputRoot string) (bool, error) {
+	if _, err := exec.LookPath("diff"); err != nil {
+		slog.Warn("diff not found on PATH; the unified diff is not written")
+		return false, nil
+	}
+	cmd := exec.CommandContext(ctx, "diff", "-ruN", "base", "candidate")
+	cmd.Dir = outputRoot
+	output, err := cmd.Output()
+	// diff exits with 1 if the directories differ.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return false, fmt.Errorf("diff failed: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputRoot, compareDiffFile), output, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeCompareReport writes a Markdown summary of the comparison to
+// compare-report.md.
+func writeCompareReport(ctx context.Context, outputRoot string, images, differences []string, hasDiff bool) error {
+	var report strings.Builder
+	fmt.Fprintf(&report, "# Image comparison for %s (%s)\n\n", flagAPIPath, flagLanguage)
+	report.WriteString("| | Image | Digest |\n|---|---|---|\n")
+	for i, label := range []string{"Base", "Candidate"} {
+		digest, err := container.ImageDigest(ctx, &container.Options{Image: images[i]})
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Unable to determine digest of %s: %s", images[i], err))
+		}
+		if digest == "" {
+			digest = "unknown"
+		} else {
+			digest = "`" + digest + "`"
+		}
+		fmt.Fprintf(&report, "| %s | `%s` | %s |\n", label, images[i], digest)
+	}
+	report.WriteString("\n")
+
+	if len(differences) == 0 {
+		report.WriteString("The generated output is identical.\n")
+	} else {
+		var added, deleted, modified int
+		for _, difference := range differences {
+			switch difference[0] {
+			case 'A':
+				added++
+			case 'D':
+				deleted++
+			case 'M':
+				modified++
+			}
+		}
+		fmt.Fprintf(&report, "%d file(s) differ: %d only generated by the candidate, %d only generated by the base, %d modified.\n\n", len(differences), added, deleted, modified)
+		report.WriteString("```\n")
+		report.WriteString(strings.Join(differences, "\n"))
+		report.WriteString("\n```\n")
+		if hasDiff {
+			fmt.Fprintf(&report, "\nSee %s for the full diff.\n", compareDiffFile)
+		}
+	}
+	return os.WriteFile(filepath.Join(outputRoot, compareReportFile), []byte(report.String()), 0644)
+}
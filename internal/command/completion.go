@@ -0,0 +1,218 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/apiindex"
+)
+
+// CmdCompletion prints a shell completion script for bash, zsh or fish. The
+// scripts complete commands, flags and -language values, and complete
+// -api-path values from the cached googleapis clone (see -clone-cache) by
+// running "librarian completion api-paths".
+var CmdCompletion = &Command{
+	Name:  "completion",
+	Short: "Print a shell completion script: completion bash|zsh|fish",
+	// Run is set in init, as it refers to Commands.
+}
+
+func runCompletion(ctx context.Context) error {
+	switch shell := CmdCompletion.flags.Arg(0); shell {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		// zsh can use bash completion functions directly.
+		fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	case "api-paths":
+		paths, err := cachedAPIPaths()
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.Join(paths, "\n"))
+	case "":
+		return fmt.Errorf("completion requires a shell: bash, zsh or fish")
+	default:
+		return fmt.Errorf("unsupported shell %q; must be bash, zsh or fish", shell)
+	}
+	return nil
+}
+
+func bashCompletion() string {
+	var script strings.Builder
+	script.WriteString(`_librarian() {
+  local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+  if [[ $COMP_CWORD -eq 1 ]]; then
+`)
+	fmt.Fprintf(&script, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	script.WriteString(`    return
+  fi
+  case "$prev" in
+`)
+	fmt.Fprintf(&script, "    -language) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", strings.Join(completionLanguages(), " "))
+	script.WriteString(`    -api-path) COMPREPLY=($(compgen -W "$(librarian completion api-paths 2>/dev/null)" -- "$cur")); return ;;
+  esac
+  case "${COMP_WORDS[1]}" in
+`)
+	for _, c := range Commands {
+		fmt.Fprintf(&script, "    %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", c.Name, strings.Join(flagNames(c.flags), " "))
+	}
+	script.WriteString(`  esac
+}
+complete -F _librarian librarian
+`)
+	return script.String()
+}
+
+func fishCompletion() string {
+	var script strings.Builder
+	script.WriteString("complete -c librarian -f\n")
+	for _, c := range Commands {
+		fmt.Fprintf(&script, "complete -c librarian -n __fish_use_subcommand -a %s -d %s\n", c.Name, fishQuote(c.Short))
+	}
+	for _, c := range Commands {
+		condition := fishQuote("__fish_seen_subcommand_from " + c.Name)
+		c.flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(&script, "complete -c librarian -n %s -o %s -d %s", condition, f.Name, fishQuote(firstSentence(f.Usage)))
+			switch f.Name {
+			case "language":
+				fmt.Fprintf(&script, " -xa %s", fishQuote(strings.Join(completionLanguages(), " ")))
+			case "api-path":
+				script.WriteString(" -xa '(librarian completion api-paths 2>/dev/null)'")
+			}
+			script.WriteString("\n")
+		})
+	}
+	return script.String()
+}
+
+func commandNames() []string {
+	var names []string
+	for _, c := range Commands {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func flagNames(fs *flag.FlagSet) []string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+	return names
+}
+
+// completionLanguages returns the supported languages, sorted.
+func completionLanguages() []string {
+	var languages []string
+	for language, supported := range supportedLanguages {
+		if supported {
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// apiVersionDir matches the final segment of an API path, e.g. "v1" or "v2beta".
+var apiVersionDir = regexp.MustCompile(`^v\d+(p\d+)?(alpha|beta)?\d*$`)
+
+// cachedAPIPaths returns the API paths in the cached googleapis clone, if
+// there is one.
+func cachedAPIPaths() ([]string, error) {
+	root, err := cachedGoogleapisRoot()
+	if err != nil || root == "" {
+		return nil, err
+	}
+	return listAPIPaths(root)
+}
+
+// cachedGoogleapisRoot returns the directory of the cached googleapis clone,
+// or an empty string if there isn't one.
+func cachedGoogleapisRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Join(cacheDir, "librarian", "googleapis")
+	if _, err := os.Stat(root); err != nil {
+		return "", nil
+	}
+	return root, nil
+}
+
+// listAPIPaths returns the API paths in the googleapis tree at root, from the
+// API index if there is one, or otherwise by scanning the tree. An API path is
+// a versioned directory containing protos.
+func listAPIPaths(root string) ([]string, error) {
+	index, err := apiindex.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	if index != nil {
+		var paths []string
+		for _, api := range index.APIs {
+			paths = append(paths, api.Directory)
+		}
+		sort.Strings(paths)
+		return paths, nil
+	}
+	var paths []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !apiVersionDir.MatchString(d.Name()) {
+			return nil
+		}
+		protos, err := filepath.Glob(filepath.Join(path, "*.proto"))
+		if err != nil || len(protos) == 0 {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	return paths, err
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFiles returns the paths of the configuration files which provide
+// default flag values, in increasing order of precedence: the user's
+// configuration file, then librarian.yaml in the current directory.
+func configFiles() []string {
+	var files []string
+	if configDir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(configDir, "librarian", "config.yaml"))
+	}
+	return append(files, "librarian.yaml")
+}
+
+// loadConfigDefaults returns the default flag values for the named command
+// from the configuration files. Each file is a YAML map from flag name (without
+// the leading "-") to value. A key which is a command name maps to values which
+// only apply to that command, and take precedence over top-level values.
+// Values for flags which the command doesn't have are ignored. For example:
+//
+//	language: dotnet
+//	repo-root: /home/user/google-cloud-dotnet
+//	update-apis:
+//	  push: true
+func loadConfigDefaults(command string) (map[string]string, error) {
+	defaults := map[string]string{}
+	for _, path := range configFiles() {
+		content, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var config map[string]any
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+		commandValues, _ := config[command].(map[string]any)
+		for _, values := range []map[string]any{config, commandValues} {
+			for name, value := range values {
+				if _, isMap := value.(map[string]any); isMap {
+					continue
+				}
+				defaults[name] = fmt.Sprint(value)
+			}
+		}
+	}
+	return defaults, nil
+}
+
+// languageConfig configures a language: its language repo, for repos which
+// don't follow the github.com/googleapis/google-cloud-{language} convention or
+// use a different default branch, and the conventions of its generator image.
+// A language which isn't built in (e.g. an experimental or third-party
+// generator) is registered by configuring its image.
+type languageConfig struct {
+	URL    string `yaml:"repo-url"`
+	Branch string `yaml:"repo-branch"`
+	// Image is the generator image, instead of google-cloud-{language}-generator.
+	// If it has no tag, the tag from the pipeline state is used.
+	Image string `yaml:"image"`
+	// Commands are the container commands the image supports, overriding any
+	// the image declares in its labels.
+	Commands []string `yaml:"commands"`
+	// SourceExtensions are the file extensions of the language's source files
+	// (e.g. ".go"), at least one of which generated output must include.
+	SourceExtensions []string `yaml:"source-extensions"`
+	// ReleaseTagFormat is the format of release tags in the language repo (see
+	// releaseTagFormats).
+	ReleaseTagFormat string `yaml:"release-tag-format"`
+}
+
+// languageConfigs are the language configurations from the configuration
+// files, keyed by language. They're loaded when a command's flags are parsed.
+var languageConfigs map[string]languageConfig
+
+// languageNamePattern matches valid names for configured languages.
+var languageNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// loadLanguageConfigs returns the language configurations from the
+// "languages" section of the configuration files, keyed by language. Values in
+// later files take precedence. For example:
+//
+//	languages:
+//	  java:
+//	    repo-url: https://github.com/googleapis/java-cloud-libraries
+//	    repo-branch: develop
+//	  kotlin:
+//	    image: us-docker.pkg.dev/example/images/kotlin-generator
+//	    repo-url: https://github.com/example/google-cloud-kotlin
+//	    commands: [configure, generate, clean, build]
+//	    source-extensions: [.kt]
+//	    release-tag-format: "{id}/v{version}"
+func loadLanguageConfigs() (map[string]languageConfig, error) {
+	configs := map[string]languageConfig{}
+	for _, path := range configFiles() {
+		content, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var config struct {
+			Languages map[string]languageConfig `yaml:"languages"`
+		}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+		for language, languageConfig := range config.Languages {
+			if !languageNamePattern.MatchString(language) || language == "all" {
+				return nil, fmt.Errorf("invalid configuration file %s: invalid language name %q", path, language)
+			}
+			merged := configs[language]
+			if languageConfig.URL != "" {
+				merged.URL = languageConfig.URL
+			}
+			if languageConfig.Branch != "" {
+				merged.Branch = languageConfig.Branch
+			}
+			if languageConfig.Image != "" {
+				merged.Image = languageConfig.Image
+			}
+			if languageConfig.Commands != nil {
+				merged.Commands = languageConfig.Commands
+			}
+			if languageConfig.SourceExtensions != nil {
+				merged.SourceExtensions = languageConfig.SourceExtensions
+			}
+			if languageConfig.ReleaseTagFormat != "" {
+				merged.ReleaseTagFormat = languageConfig.ReleaseTagFormat
+			}
+			configs[language] = merged
+		}
+	}
+	return configs, nil
+}
+
+// registerConfiguredLanguages adds the languages with a configured image to
+// the supported languages.
+func registerConfiguredLanguages(configs map[string]languageConfig) {
+	for language, config := range configs {
+		if config.Image != "" {
+			supportedLanguages[language] = true
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// conformanceAPIPath is the fixture API used by the conformance command when
+// -api-root isn't specified.
+const conformanceAPIPath = "google/example/conformance/v1"
+
+// conformanceSentinel is a file in the scratch language repo which clean must
+// leave alone, as it isn't generated.
+const conformanceSentinel = "README.md"
+
+// CmdConformance checks that a language container image implements the
+// container contract, so that new language containers can be validated before
+// they're onboarded. It runs the image's configure, generate, clean and build
+// commands against a fixture API (or the API specified by -api-root and
+// -api-path) in scratch directories, checking the declared protocol version,
+// that the input mounts are left unchanged, that failures are reported with a
+// non-zero exit code, and the structure of the output. Each check is reported,
+// and the command fails if any check fails. Commands which the image declares
+// it doesn't support are skipped.
+var CmdConformance = &Command{
+	Name:  "conformance",
+	Short: "Check that a language container image implements the container contract",
+	Run: func(ctx context.Context) error {
+		if flagImage == "" {
+			return fmt.Errorf("-image is not provided")
+		}
+		if (flagAPIRoot == "") != (flagAPIPath == "") {
+			return fmt.Errorf("-api-root and -api-path must be specified together")
+		}
+		if flagLanguage != "" && !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		if err := preflight(); err != nil {
+			return err
+		}
+		tmpRoot, err := createTmpWorkingRoot(time.Now())
+		if err != nil {
+			return err
+		}
+
+		apiRoot, apiPath := filepath.Join(tmpRoot, "apis"), conformanceAPIPath
+		if flagAPIRoot != "" {
+			if apiRoot, err = filepath.Abs(flagAPIRoot); err != nil {
+				return err
+			}
+			if apiPath, err = normalizeAPIPath(flagAPIPath); err != nil {
+				return err
+			}
+		} else if err := writeConformanceFixture(apiRoot); err != nil {
+			return err
+		}
+		if err := validateAPIPath(apiRoot, apiPath); err != nil {
+			return err
+		}
+
+		checker := &conformanceChecker{
+			opts:           containerOptions(nil),
+			apiRoot:        apiRoot,
+			apiPath:        apiPath,
+			generatorInput: filepath.Join(tmpRoot, "generator-input"),
+			outputDir:      filepath.Join(tmpRoot, "output"),
+			repoRoot:       filepath.Join(tmpRoot, "repo"),
+		}
+		for _, dir := range []string{checker.generatorInput, checker.outputDir, checker.repoRoot} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		if err := saveStateFile(filepath.Join(checker.generatorInput, "pipeline-state.json"), &statepb.PipelineState{ImageTag: "conformance"}); err != nil {
+			return err
+		}
+		return checker.run(ctx)
+	},
+}
+
+// conformanceChecker runs the conformance checks against an image, in scratch
+// directories.
+type conformanceChecker struct {
+	opts           *container.Options
+	apiRoot        string
+	apiPath        string
+	generatorInput string
+	outputDir      string
+	repoRoot       string
+
+	passed, failed, skipped int
+}
+
+// check runs a single conformance check, logging the result. If the image
+// doesn't support the container command the check depends on (if any), the
+// check is skipped. It reports whether the check passed.
+func (c *conformanceChecker) check(ctx context.Context, name, command string, fn func() error) bool {
+	if command != "" && !container.Supports(ctx, c.opts, command) {
+		slog.Info(fmt.Sprintf("SKIP %s: the image doesn't support the %s command", name, command))
+		c.skipped++
+		return false
+	}
+	if err := fn(); err != nil {
+		slog.Error(fmt.Sprintf("FAIL %s: %s", name, err))
+		c.failed++
+		return false
+	}
+	slog.Info(fmt.Sprintf("PASS %s", name))
+	c.passed++
+	return true
+}
+
+func (c *conformanceChecker) run(ctx context.Context) error {
+	if c.opts.Runner == nil {
+		c.check(ctx, "protocol version", "", func() error {
+			version, err := container.ImageProtocolVersion(ctx, c.opts.Image)
+			switch {
+			case err != nil:
+				return err
+			case version != container.ProtocolVersion:
+				return fmt.Errorf("image declares protocol version %d in its %s label; expected %d", version, container.ProtocolVersionLabel, container.ProtocolVersion)
+			}
+			return nil
+		})
+	}
+
+	configured := c.check(ctx, "configure", "configure", func() error {
+		return c.unchangedAPIRoot(func() error {
+			if err := container.Configure(ctx, c.opts, c.apiRoot, c.apiPath, "", c.generatorInput, "", apiMetadata(c.apiRoot, c.apiPath)); err != nil {
+				return err
+			}
+			state, err := loadStateFile(filepath.Join(c.generatorInput, "pipeline-state.json"))
+			if err != nil {
+				return fmt.Errorf("invalid pipeline state after configure: %w", err)
+			}
+			if findApiState(state, c.apiPath) == nil {
+				return fmt.Errorf("configure did not add %s to the pipeline state", c.apiPath)
+			}
+			return nil
+		})
+	})
+	// Images which don't support configure generate without generator input.
+	generatorInput := c.generatorInput
+	if !configured {
+		generatorInput = ""
+	}
+
+	generated := c.check(ctx, "generate", "", func() error {
+		return c.unchangedAPIRoot(func() error {
+			if err := container.Generate(ctx, c.opts, c.apiRoot, c.outputDir, generatorInput, c.apiPath, releaseChannel(c.apiPath, nil), generationConfig(c.apiRoot, c.apiPath)); err != nil {
+				return err
+			}
+			return checkGenerateOutput(c.outputDir, flagLanguage, c.apiPath)
+		})
+	})
+	c.check(ctx, "generate failure", "", func() error {
+		missingOutput := c.outputDir + "-missing"
+		if err := os.MkdirAll(missingOutput, 0755); err != nil {
+			return err
+		}
+		missingAPIPath := c.apiPath + "/missing"
+		if err := container.Generate(ctx, c.opts, c.apiRoot, missingOutput, generatorInput, missingAPIPath, "", nil); err == nil {
+			return fmt.Errorf("generate exited successfully for API %s, which doesn't exist", missingAPIPath)
+		}
+		return nil
+	})
+	if !generated {
+		slog.Warn("Skipping the clean and build checks, as generation failed")
+		return c.result()
+	}
+
+	c.check(ctx, "clean", "clean", func() error {
+		if err := c.populateRepo(); err != nil {
+			return err
+		}
+		before, err := listFiles(c.repoRoot)
+		if err != nil {
+			return err
+		}
+		sentinel, err := os.ReadFile(filepath.Join(c.repoRoot, conformanceSentinel))
+		if err != nil {
+			return err
+		}
+		if err := container.Clean(ctx, c.opts, c.repoRoot, c.apiPath); err != nil {
+			return err
+		}
+		after, err := listFiles(c.repoRoot)
+		if err != nil {
+			return err
+		}
+		if content, err := os.ReadFile(filepath.Join(c.repoRoot, conformanceSentinel)); err != nil || string(content) != string(sentinel) {
+			return fmt.Errorf("clean modified or deleted %s, which wasn't generated", conformanceSentinel)
+		}
+		if len(after) >= len(before) {
+			return fmt.Errorf("clean didn't delete any generated files for %s", c.apiPath)
+		}
+		return nil
+	})
+	c.check(ctx, "build (repo root)", "build", func() error {
+		if err := c.populateRepo(); err != nil {
+			return err
+		}
+		return container.Build(ctx, c.opts, "repo-root", c.repoRoot, c.apiPath)
+	})
+	c.check(ctx, "build (generator output)", "build", func() error {
+		return container.Build(ctx, c.opts, "generator-output", c.outputDir, c.apiPath)
+	})
+	return c.result()
+}
+
+// unchangedAPIRoot runs fn, checking that it doesn't modify the API root,
+// which containers must treat as read-only.
+func (c *conformanceChecker) unchangedAPIRoot(fn func() error) error {
+	before, err := buildArchiveManifest(c.apiRoot)
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	after, err := buildArchiveManifest(c.apiRoot)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(before.Files, after.Files) {
+		return fmt.Errorf("the API root (mounted at /apis) was modified")
+	}
+	return nil
+}
+
+// populateRepo replaces the content of the scratch language repo with the
+// generated output and the sentinel file.
+func (c *conformanceChecker) populateRepo() error {
+	if err := os.RemoveAll(c.repoRoot); err != nil {
+		return err
+	}
+	if err := os.CopyFS(c.repoRoot, os.DirFS(c.outputDir)); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.repoRoot, conformanceSentinel), []byte("Not generated; clean must not delete this file.\n"), 0644)
+}
+
+// result logs a summary of the checks, returning an error if any failed.
+func (c *conformanceChecker) result() error {
+	slog.Info(fmt.Sprintf("Conformance checks for %s: %d passed, %d failed, %d skipped", c.opts.Image, c.passed, c.failed, c.skipped))
+	if c.failed > 0 {
+		return fmt.Errorf("%d conformance check(s) failed", c.failed)
+	}
+	return nil
+}
+
+// writeConformanceFixture writes the fixture API (a proto and service config)
+// under apiRoot.
+func writeConformanceFixture(apiRoot string) error {
+	dir := filepath.Join(apiRoot, conformanceAPIPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	proto := `syntax = "proto3";
+
+package google.example.conformance.v1;
+
+// A service used to check language container conformance.
+service Conformance {
+  // Echoes the request message.
+  rpc Echo(EchoRequest) returns (EchoResponse);
+}
+
+// The request for Echo.
+message EchoRequest {
+  // The message to echo.
+  string message = 1;
+}
+
+// The response for Echo.
+message EchoResponse {
+  // The echoed message.
+  string message = 1;
+}
+`
+	serviceConfig := `type: google.api.Service
+config_version: 3
+name: conformance.googleapis.com
+title: Conformance Test API
+
+apis:
+- name: google.example.conformance.v1.Conformance
+`
+	if err := os.WriteFile(filepath.Join(dir, "conformance.proto"), []byte(proto), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "conformance_v1.yaml"), []byte(serviceConfig), 0644)
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// CmdDebugShell starts an interactive shell in a language container, with the
+// same mounts the pipeline uses, to debug container failures. The image is
+// determined as for other commands (including the image tag from the pipeline
+// state, if -repo-root is specified). The container commands which the
+// pipeline would run against the mounted directories are printed first.
+var CmdDebugShell = &Command{
+	Name:  "debug-shell",
+	Short: "Open an interactive shell in a language container",
+	Run: func(ctx context.Context) error {
+		if !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		if !isInteractive() {
+			return fmt.Errorf("debug-shell requires standard input to be a terminal")
+		}
+		if flagAPIPath != "" {
+			apiPath, err := normalizeAPIPath(flagAPIPath)
+			if err != nil {
+				return err
+			}
+			flagAPIPath = apiPath
+		}
+
+		var apiRoot, repoRoot, generatorInput, output string
+		var state *statepb.PipelineState
+		var err error
+		if flagAPIRoot != "" {
+			if apiRoot, err = filepath.Abs(flagAPIRoot); err != nil {
+				return err
+			}
+		}
+		if flagRepoRoot != "" {
+			if repoRoot, err = filepath.Abs(flagRepoRoot); err != nil {
+				return err
+			}
+			languageRepo, err := gitrepo.Open(ctx, repoRoot)
+			if err != nil {
+				return err
+			}
+			if state, err = loadState(languageRepo); err != nil {
+				return err
+			}
+			generatorInput = filepath.Join(repoRoot, "generator-input")
+		}
+		if flagOutput != "" {
+			if output, err = filepath.Abs(flagOutput); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(output, 0755); err != nil {
+				return err
+			}
+		}
+
+		printDebugShellCommands(apiRoot, repoRoot, output)
+		return container.DebugShell(ctx, containerOptions(state), flagShell, apiRoot, repoRoot, generatorInput, output)
+	},
+}
+
+// printDebugShellCommands prints the container commands which the pipeline
+// would run against the directories mounted in a debug shell.
+func printDebugShellCommands(apiRoot, repoRoot, output string) {
+	apiPath := flagAPIPath
+	if apiPath == "" {
+		apiPath = "<api-path>"
+	}
+	var commands []string
+	if apiRoot != "" && repoRoot != "" {
+		commands = append(commands, fmt.Sprintf("configure --api-root=/apis --generator-input=/generator-input --api-path=%s", apiPath))
+	}
+	if apiRoot != "" && output != "" {
+		generate := fmt.Sprintf("generate --api-root=/apis --output=/output --api-path=%s", apiPath)
+		if repoRoot != "" {
+			generate += " --generator-input=/generator-input"
+		}
+		commands = append(commands, generate)
+	}
+	if repoRoot != "" {
+		commands = append(commands,
+			fmt.Sprintf("clean --repo-root=/repo --api-path=%s", apiPath),
+			fmt.Sprintf("build --repo-root=/repo --api-path=%s", apiPath))
+	}
+	if output != "" {
+		commands = append(commands, fmt.Sprintf("build --generator-output=/output --api-path=%s", apiPath))
+	}
+	if len(commands) == 0 {
+		fmt.Println("No directories are mounted; specify -api-root, -repo-root and/or -output to mount them.")
+		return
+	}
+	fmt.Println("The pipeline would run the container's entrypoint with arguments such as:")
+	fmt.Printf("  %s\n", strings.Join(commands, "\n  "))
+}
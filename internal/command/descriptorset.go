@@ -0,0 +1,60 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// prepareDescriptorSet checks that the -descriptor-set file is a
+// FileDescriptorSet containing protos in apiPath, so that problems are
+// reported clearly before any container is run, and copies it into its own
+// directory under tmpRoot to be mounted. The path of the copy is returned.
+func prepareDescriptorSet(tmpRoot, apiPath string) (string, error) {
+	content, err := os.ReadFile(flagDescriptorSet)
+	if err != nil {
+		return "", err
+	}
+	var descriptors descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(content, &descriptors); err != nil {
+		return "", fmt.Errorf("invalid descriptor set %s: %w", flagDescriptorSet, err)
+	}
+	found := false
+	for _, file := range descriptors.File {
+		if path.Dir(file.GetName()) == apiPath {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("descriptor set %s contains no protos in api-path %s", flagDescriptorSet, apiPath)
+	}
+
+	dir := filepath.Join(tmpRoot, "descriptors")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	descriptorSet := filepath.Join(dir, filepath.Base(flagDescriptorSet))
+	if err := os.WriteFile(descriptorSet, content, 0644); err != nil {
+		return "", err
+	}
+	return descriptorSet, nil
+}
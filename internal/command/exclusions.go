@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exclusionsFile is the name of the file in generator-input listing the APIs
+// which the language intentionally doesn't publish.
+const exclusionsFile = "excluded-apis.yaml"
+
+// apiExclusion is an entry in the exclusions file.
+type apiExclusion struct {
+	// Path is an API path, or a prefix of API paths (e.g. "google/ads").
+	Path   string `yaml:"path"`
+	Reason string `yaml:"reason"`
+}
+
+// loadExclusions loads the exclusions file from the generator input directory.
+// The file is a YAML list of API paths (or path prefixes) with the reason they
+// are excluded; APIs which match an entry are skipped when regenerating all
+// APIs, and when detecting the APIs affected by googleapis changes. If the
+// file doesn't exist, there are no exclusions. For example:
+//
+//	# APIs which aren't published for this language.
+//	- path: google/cloud/secretmanager/v1beta2
+//	  reason: Private preview
+//	- path: google/ads
+//	  reason: Published separately by the Ads team
+func loadExclusions(generatorInput string) ([]apiExclusion, error) {
+	path := filepath.Join(generatorInput, exclusionsFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var exclusions []apiExclusion
+	if err := yaml.Unmarshal(content, &exclusions); err != nil {
+		return nil, fmt.Errorf("invalid exclusions file %s: %w", path, err)
+	}
+	for i, exclusion := range exclusions {
+		if exclusions[i].Path, err = normalizeAPIPath(exclusion.Path); err != nil {
+			return nil, fmt.Errorf("invalid exclusions file %s: %w", path, err)
+		}
+		if exclusion.Reason == "" {
+			return nil, fmt.Errorf("invalid exclusions file %s: no reason given for excluding %s", path, exclusion.Path)
+		}
+	}
+	return exclusions, nil
+}
+
+// exclusionReason returns the reason the API is excluded, or an empty string
+// if it isn't excluded.
+func exclusionReason(exclusions []apiExclusion, apiPath string) string {
+	for _, exclusion := range exclusions {
+		if apiPath == exclusion.Path || strings.HasPrefix(apiPath, exclusion.Path+"/") {
+			return exclusion.Reason
+		}
+	}
+	return ""
+}
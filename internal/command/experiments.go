@@ -0,0 +1,37 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// activeExperiments returns the experimental features enabled for a run: those
+// listed in the pipeline state of the language repo (if any) and those specified
+// with -experiments. The result is sorted and contains no duplicates.
+func activeExperiments(state *statepb.PipelineState) []string {
+	experiments := append(slices.Clone(state.GetExperiments()), splitList(flagExperiments)...)
+	slices.Sort(experiments)
+	experiments = slices.Compact(experiments)
+	if len(experiments) > 0 {
+		slog.Info(fmt.Sprintf("Active experiments: %s", strings.Join(experiments, ", ")))
+	}
+	return experiments
+}
@@ -16,75 +16,458 @@ package command
 
 import (
 	"flag"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
-	flagAPIPath     string
-	flagAPIRoot     string
-	flagBranch      string
-	flagBuild       bool
-	flagGitHubToken string
-	flagImage       string
-	flagLanguage    string
-	flagOutput      string
-	flagPush        bool
-	flagRepoRoot    string
-	flagWorkRoot    string
+	flagAPIList                 string
+	flagAPIPath                 string
+	flagAPIRoot                 string
+	flagAPITarball              string
+	flagArchive                 string
+	flagArtifactBucket          string
+	flagAutoPruneDays           int
+	flagBackend                 string
+	flagBranchNameTemplate      string
+	flagBuild                   bool
+	flagCI                      string
+	flagCloneCache              bool
+	flagCommitGranularity       string
+	flagCommitMessageTemplate   string
+	flagContainerCache          []string
+	flagContainerEnv            []string
+	flagContainerNetwork        string
+	flagContainerSecrets        string
+	flagContainerUser           string
+	flagDescriptorSet           string
+	flagDryRun                  bool
+	flagExperiments             string
+	flagForce                   bool
+	flagForge                   string
+	flagFork                    string
+	flagFormat                  string
+	flagGerritToken             string
+	flagGerritURL               string
+	flagGerritUser              string
+	flagGitHubAPIURL            string
+	flagGitHubAPIVersion        string
+	flagGitHubAppID             int64
+	flagGitHubAppInstallationID int64
+	flagGitHubAppPrivateKey     string
+	flagGitHubHost              string
+	flagGitHubToken             string
+	flagGitLabToken             string
+	flagGitLabURL               string
+	flagGoldenDir               string
+	flagGoogleapisMirror        string
+	flagGoogleapisSHA           string
+	flagImage                   string
+	flagImageChannel            string
+	flagInteractive             bool
+	flagKeepTemp                bool
+	flagLanguage                string
+	flagLibraryID               string
+	flagListen                  string
+	flagManifest                string
+	flagMaxAgeDays              int
+	flagMetricsAddr             string
+	flagMinFreeDiskGB           int
+	flagNoLock                  bool
+	flagNotifyOn                string
+	flagNotifyWebhook           string
+	flagOffline                 bool
+	flagOnExistingPR            string
+	flagOutput                  string
+	flagOutputCache             string
+	flagParallelBuild           int
+	flagParallelGenerate        int
+	flagParallelism             int
+	flagPRAssignees             string
+	flagPRBodyTemplate          string
+	flagPRLabels                string
+	flagProxy                   string
+	flagPRReviewers             string
+	flagPublishCredentials      string
+	flagPush                    bool
+	flagPushRetries             int
+	flagPushRetryDelay          time.Duration
+	flagRecordContainers        string
+	flagRegenerateAll           bool
+	flagReplayContainers        string
+	flagRepoBranch              string
+	flagRepoRoot                string
+	flagRepoURL                 string
+	flagScheduled               bool
+	flagShell                   string
+	flagSignCommits             string
+	flagSigningKey              string
+	flagSkipBuild               bool
+	flagSkipClean               bool
+	flagSkipCommit              bool
+	flagStepTimeout             time.Duration
+	flagUpdateCommits           string
+	flagWebhookSecret           string
+	flagWorkRoot                string
 )
 
+func addFlagAPIList(fs *flag.FlagSet) {
+	fs.StringVar(&flagAPIList, "api-list", "", "file listing the APIs to configure, one per line, optionally followed by options such as channel=beta. An alternative to -api-path.")
+}
+
 func addFlagAPIPath(fs *flag.FlagSet) {
 	fs.StringVar(&flagAPIPath, "api-path", "", "(Required) path api-root to the API to be generated (e.g., google/cloud/functions/v2)")
 }
 
 func addFlagAPIRoot(fs *flag.FlagSet) {
-	fs.StringVar(&flagAPIRoot, "api-root", "", "location of googleapis repository. If undefined, googleapis will be cloned to /tmp")
+	fs.StringVar(&flagAPIRoot, "api-root", "", "location of googleapis repository. If undefined, googleapis will be cloned to /tmp. For configure, generate, update-apis, batch and compare-images, this may instead be a tree of other protos (with -api-path relative to it, and which must be a git repository for update-apis), which is combined with googleapis for the common protos it imports. Any directory without google/api/annotations.proto is treated as such a tree")
+}
+
+func addFlagAPITarball(fs *flag.FlagSet) {
+	fs.StringVar(&flagAPITarball, "api-tarball", "", "ref (branch, tag or commit) at which to download googleapis as a tarball instead of cloning it, when -api-root is not specified. When -api-root is a tree of other protos, this is the ref of googleapis used for their dependencies (default master)")
+}
+
+func addFlagArchive(fs *flag.FlagSet) {
+	fs.StringVar(&flagArchive, "archive", "", "path of a .tar.gz archive to write the generated output to, with a manifest (librarian-manifest.json) of file sizes and SHA-256 hashes. This is written in addition to the output directory, which is temporary if -output is not specified.")
+}
+
+func addFlagArtifactBucket(fs *flag.FlagSet) {
+	fs.StringVar(&flagArtifactBucket, "artifact-bucket", "", "Cloud Storage location (gs://bucket/prefix) to upload the generated output and logs of each run to, under <prefix>/<command>-<timestamp>/")
+}
+
+func addFlagAutoPruneDays(fs *flag.FlagSet) {
+	fs.IntVar(&flagAutoPruneDays, "auto-prune-days", 0, "if positive, delete temporary working directories older than this many days before creating a new one")
+}
+
+func addFlagBackend(fs *flag.FlagSet) {
+	fs.StringVar(&flagBackend, "backend", "docker", "how language generators are run: docker, or local to run the executable named after the image (e.g. google-cloud-go-generator) from PATH on the host, for languages whose toolchain can be installed locally. The -container-* options other than -container-env don't apply to local execution.")
 }
 
 func addFlagBranch(fs *flag.FlagSet) {
-	fs.StringVar(&flagBranch, "branch", "main", "repository branch")
+	fs.StringVar(&flagRepoBranch, "branch", "", "deprecated alias of -repo-branch")
+}
+
+func addFlagBranchNameTemplate(fs *flag.FlagSet) {
+	fs.StringVar(&flagBranchNameTemplate, "branch-name-template", "", "Go text/template for the name of the branch pushed for a pull request, with the fields .ApiPath, .Language, .Date, .Timestamp and .GoogleapisSHA (default librarian-{{.Timestamp}}). The template must include .Timestamp, so that each run pushes a new branch. The name is made valid for git, and always starts with librarian-")
 }
 
 func addFlagBuild(fs *flag.FlagSet) {
 	fs.BoolVar(&flagBuild, "build", false, "whether to build the generated code")
 }
 
+func addFlagCI(fs *flag.FlagSet) {
+	fs.StringVar(&flagCI, "ci", "", "CI system to integrate with: github, to take unspecified flags which select what's generated (e.g. -language and -api-path, but not -push, -repo-url, -image or credentials) from the workflow event inputs and report failures, outputs and a job summary to GitHub Actions")
+}
+
+func addFlagCloneCache(fs *flag.FlagSet) {
+	fs.BoolVar(&flagCloneCache, "clone-cache", false, "cache repository clones under the user cache directory, fetching updates instead of recloning. A cached language repo clone is worked on directly, so it's locked against other runs (for any APIs) until the end of each run which uses it")
+}
+
+func addFlagCommitGranularity(fs *flag.FlagSet) {
+	fs.StringVar(&flagCommitGranularity, "commit-granularity", "per-api", "how regenerated APIs are committed: per-api, for a commit per API, or single, to squash all the regenerated APIs into one commit")
+}
+
+func addFlagCommitMessageTemplate(fs *flag.FlagSet) {
+	fs.StringVar(&flagCommitMessageTemplate, "commit-message-template", "", "path to a Go text/template file used to render the commit message for each regenerated API, instead of the default message")
+}
+
+func addFlagContainerConfig(fs *flag.FlagSet) {
+	fs.Var(stringList{&flagContainerEnv}, "container-env", "environment variable to set in language containers, as KEY=VALUE, or KEY to pass through the value from the environment (preferred for secrets). May be repeated.")
+	fs.Var(stringList{&flagContainerCache}, "container-cache", "named docker volume to mount in the clean and build containers to persist a package cache between runs, as volume-name:container-path (e.g. librarian-nuget:/tmp/.nuget/packages). The volume must be writable by the container user (see -container-user). May be repeated.")
+	fs.StringVar(&flagContainerNetwork, "container-network", "", "docker network mode for language containers, e.g. none for no network access. \"hermetic\" runs configure, generate and clean without network access, and other steps (e.g. build) with the default network.")
+	fs.StringVar(&flagContainerSecrets, "container-secrets", "", "directory of secrets (e.g. package feed credentials) to mount read-only at /secrets in language containers. The secrets are never recorded by -record-containers.")
+	fs.StringVar(&flagContainerUser, "container-user", "auto", "user to run language containers as: auto (the current user, unless it's root, so that generated files aren't owned by root), image (the image's default user), or uid[:gid]")
+}
+
+func addFlagDescriptorSet(fs *flag.FlagSet) {
+	fs.StringVar(&flagDescriptorSet, "descriptor-set", "", "compiled FileDescriptorSet (e.g. api.binpb, built with protoc --include_imports) to generate from instead of proto sources, for images which support descriptor-based generation. Cannot be combined with -api-root or -api-tarball.")
+}
+
+func addFlagDryRun(fs *flag.FlagSet) {
+	fs.BoolVar(&flagDryRun, "dry-run", false, "log the changes which would be made, without making them")
+}
+
+func addFlagExperiments(fs *flag.FlagSet) {
+	fs.StringVar(&flagExperiments, "experiments", "", "comma-separated experimental pipeline features to enable, in addition to those in the pipeline state")
+}
+
+func addFlagForce(fs *flag.FlagSet) {
+	fs.BoolVar(&flagForce, "force", false, "discard any uncommitted changes, and any commits which aren't on origin, in -repo-root when resetting it to the latest commit, and delete the contents of a non-empty output directory")
+}
+
+func addFlagForge(fs *flag.FlagSet) {
+	fs.StringVar(&flagForge, "forge", "github", "service hosting the language repo, on which pull requests are created: github, gitlab (for merge requests) or gerrit (for changes uploaded to refs/for/{branch})")
+	fs.StringVar(&flagGerritURL, "gerrit-url", "", "base URL of the Gerrit server hosting the language repo, with -forge=gerrit")
+	fs.StringVar(&flagGerritUser, "gerrit-user", "", "username of the Gerrit HTTP credentials, with -forge=gerrit")
+	fs.StringVar(&flagGerritToken, "gerrit-token", "", "Gerrit HTTP password, with -forge=gerrit")
+	fs.StringVar(&flagGitLabURL, "gitlab-url", "https://gitlab.com", "base URL of the GitLab instance hosting the language repo, with -forge=gitlab")
+	fs.StringVar(&flagGitLabToken, "gitlab-token", "", "GitLab access token, with -forge=gitlab")
+}
+
+func addFlagFork(fs *flag.FlagSet) {
+	fs.StringVar(&flagFork, "fork", "", "GitHub user or organization owning a fork of the language repo. When specified, branches are pushed to the fork and pull requests are created from it.")
+}
+
+func addFlagFormat(fs *flag.FlagSet) {
+	fs.StringVar(&flagFormat, "format", "text", "output format: text or json")
+	fs.BoolFunc("json", "output JSON, e.g. for dashboards: shorthand for -format=json", func(value string) error {
+		json, err := strconv.ParseBool(value)
+		if json {
+			flagFormat = "json"
+		}
+		return err
+	})
+}
+
+func addFlagGitHubEndpoint(fs *flag.FlagSet) {
+	fs.StringVar(&flagGitHubHost, "github-host", "github.com", "host name of the GitHub deployment hosting the language repos, e.g. for GitHub Enterprise Server")
+	fs.StringVar(&flagGitHubAPIURL, "github-api-url", "", "base URL of the GitHub REST API. Defaults to https://api.github.com/ for github.com, or https://{host}/api/v3/ for other values of -github-host.")
+	fs.StringVar(&flagGitHubAPIVersion, "github-api-version", "", "value of the X-GitHub-Api-Version header to send instead of the default, for GitHub Enterprise Server versions which don't support it, or none to omit the header")
+}
+
 func addFlagGitHubToken(fs *flag.FlagSet) {
-	fs.StringVar(&flagGitHubToken, "github-token", "", "GitHub access token")
+	fs.StringVar(&flagGitHubToken, "github-token", "", "GitHub access token. If unspecified, a token from a GitHub App (see -github-app-id), the gh CLI or the git credential helper is used.")
+}
+
+func addFlagGitHubApp(fs *flag.FlagSet) {
+	fs.Int64Var(&flagGitHubAppID, "github-app-id", 0, "ID of a GitHub App to authenticate as, when -github-token is not specified")
+	fs.Int64Var(&flagGitHubAppInstallationID, "github-app-installation-id", 0, "installation ID of the GitHub App. Defaults to the installation for the language repo.")
+	fs.StringVar(&flagGitHubAppPrivateKey, "github-app-private-key", "", "path to the PEM-encoded private key of the GitHub App")
+}
+
+func addFlagGoldenDir(fs *flag.FlagSet) {
+	fs.StringVar(&flagGoldenDir, "golden-dir", "testdata/golden", "directory containing golden generated output, in a subdirectory per language")
+}
+
+func addFlagGoogleapisMirror(fs *flag.FlagSet) {
+	fs.StringVar(&flagGoogleapisMirror, "googleapis-mirror", "", "mirror to clone googleapis from instead of github.com: a git repo URL or local path, or a gs://bucket/object snapshot (a .tar.gz of a googleapis clone, including .git, within a single top-level directory). The mirror is checked out at the upstream commit (see -googleapis-sha), after verifying the hashes of its content.")
+	fs.StringVar(&flagGoogleapisSHA, "googleapis-sha", "", "upstream googleapis commit to verify and check out from -googleapis-mirror. Defaults to the latest commit on github.com, which requires a single lightweight request to it; if github.com can't be reached (or with -offline), the latest commit of the mirror is used, with a warning.")
 }
 
 func addFlagImage(fs *flag.FlagSet) {
 	fs.StringVar(&flagImage, "image", "", "language-specific container to run for subcommands. Defaults to google-cloud-{language}-generator")
 }
 
+func addFlagImageChannel(fs *flag.FlagSet) {
+	fs.StringVar(&flagImageChannel, "image-channel", "", "resolve each language's image from the Artifact Registry repository in LIBRARIAN_REPOSITORY, rather than using the image tag in the pipeline state: stable (the highest vX.Y.Z tag) or latest (the most recently pushed tagged image). Ignored if -image is specified, and for languages with an image in their configuration.")
+}
+
+func addFlagInteractive(fs *flag.FlagSet) {
+	fs.BoolVar(&flagInteractive, "interactive", false, "prompt for the language and API path, searching googleapis for API paths, and confirm before configuring")
+}
+
+func addFlagKeepTemp(fs *flag.FlagSet) {
+	fs.BoolVar(&flagKeepTemp, "keep-temp", false, "keep the temporary working directory (including any clones and output) after a successful run, for debugging. It is always kept after a failed run.")
+}
+
 func addFlagLanguage(fs *flag.FlagSet) {
-	fs.StringVar(&flagLanguage, "language", "", "(Required) language to generate code for")
+	fs.Var(repeatableString{&flagLanguage}, "language", "(Required) language to generate code for. The generate and update-apis commands accept several languages, by repeating the flag or as a comma-separated list, or \"all\" for all supported languages.")
+}
+
+func addFlagLibraryID(fs *flag.FlagSet) {
+	fs.StringVar(&flagLibraryID, "library-id", "", "ID of a single library to act on. If unspecified, all libraries in the pipeline state are considered.")
+}
+
+func addFlagListen(fs *flag.FlagSet) {
+	fs.StringVar(&flagListen, "listen", "localhost:8080", "address on which to listen for requests, e.g. :8080 for all interfaces, which requires -webhook-secret")
+}
+
+func addFlagMaxAgeDays(fs *flag.FlagSet) {
+	fs.IntVar(&flagMaxAgeDays, "max-age-days", 30, "age in days after which a generated branch without an open pull request, or a temporary working directory, is considered stale. For a branch whose pull request was closed without being merged, the age is from when it was closed; branches of merged pull requests are deleted immediately")
+}
+
+func addFlagMetricsAddr(fs *flag.FlagSet) {
+	fs.StringVar(&flagMetricsAddr, "metrics-addr", "", "address (e.g. :9090) on which to serve Prometheus metrics at /metrics while running")
+}
+
+func addFlagManifest(fs *flag.FlagSet) {
+	fs.StringVar(&flagManifest, "manifest", "", "YAML or JSON run manifest listing the language, API path and generate options of each entry to run")
+}
+
+func addFlagMinFreeDiskGB(fs *flag.FlagSet) {
+	fs.IntVar(&flagMinFreeDiskGB, "min-free-disk-gb", 10, "minimum free disk space in GB required in temporary, output and cache locations before starting. 0 disables the check.")
+}
+
+func addFlagNoLock(fs *flag.FlagSet) {
+	fs.BoolVar(&flagNoLock, "no-lock", false, "don't lock the language repo and API path against concurrent runs")
+}
+
+func addFlagNotify(fs *flag.FlagSet) {
+	fs.StringVar(&flagNotifyWebhook, "notify-webhook", "", "Google Chat or Slack incoming webhook URL to post run results to, with the error and links to logs")
+	fs.StringVar(&flagNotifyOn, "notify-on", "failure", "runs to post to -notify-webhook: failure or always")
+}
+
+func addFlagOffline(fs *flag.FlagSet) {
+	fs.BoolVar(&flagOffline, "offline", false, "run without network access, for air-gapped environments: googleapis must be supplied with -api-root, the language repo with -repo-root (or a local -repo-url), and images must already be pulled or loaded. Any operation which requires network access fails immediately. Language containers still use -container-network.")
+}
+
+func addFlagOnExistingPR(fs *flag.FlagSet) {
+	fs.StringVar(&flagOnExistingPR, "on-existing-pr", "create", "behavior when an open pull request already exists for the same API path: create (a new pull request), skip, update or fail")
 }
 
 func addFlagOutput(fs *flag.FlagSet) {
 	fs.StringVar(&flagOutput, "output", "", "directory where generated code will be written")
 }
 
+func addFlagOutputCache(fs *flag.FlagSet) {
+	fs.StringVar(&flagOutputCache, "output-cache", "", "directory or Cloud Storage location (gs://bucket/prefix) in which to cache generated output, keyed by the image digest, API path, API files and generation options, so that generation with the same inputs restores the cached output instead")
+}
+
+func addFlagParallelBuild(fs *flag.FlagSet) {
+	fs.IntVar(&flagParallelBuild, "parallel-build", 0, "build each API's commit in its own git worktree of the language repo, running up to this many builds at once while later APIs are committed. Requires the git binary, and cannot be combined with -skip-commit. 0 builds each API in the language repo before moving on.")
+}
+
+func addFlagParallelGenerate(fs *flag.FlagSet) {
+	fs.IntVar(&flagParallelGenerate, "parallel-generate", 0, "overlap the steps of different APIs, generating up to this many APIs at once while the generated code of earlier APIs is cleaned, committed and built in the language repo (one API at a time, in order). 0 processes each API in turn.")
+}
+
+func addFlagParallelism(fs *flag.FlagSet) {
+	fs.IntVar(&flagParallelism, "parallelism", 4, "maximum number of entries to run at once")
+}
+
+func addFlagPRBodyTemplate(fs *flag.FlagSet) {
+	fs.StringVar(&flagPRBodyTemplate, "pr-body-template", "", "path to a Go text/template file used to render pull request bodies, instead of the default template")
+}
+
+func addFlagPullRequestConfig(fs *flag.FlagSet) {
+	fs.StringVar(&flagPRReviewers, "pr-reviewers", "", "comma-separated users, or teams in the form org/team-name, to request pull request reviews from")
+	fs.StringVar(&flagPRLabels, "pr-labels", "", "comma-separated labels to add to pull requests")
+	fs.StringVar(&flagPRAssignees, "pr-assignees", "", "comma-separated users to assign pull requests to")
+}
+
+func addFlagProxy(fs *flag.FlagSet) {
+	fs.StringVar(&flagProxy, "proxy", "", "URL of the HTTP(S) proxy for git, GitHub and other requests, and in language containers, e.g. http://proxy.example.com:3128. Defaults to the HTTPS_PROXY and HTTP_PROXY environment variables. Hosts in NO_PROXY are accessed directly. Images are pulled by the docker daemon, which doesn't use -proxy; configure the daemon's own proxy for pulls.")
+}
+
+func addFlagPublishCredentials(fs *flag.FlagSet) {
+	fs.StringVar(&flagPublishCredentials, "publish-credentials", "", "directory containing package registry credentials, mounted into the language container when publishing. Required by release publish.")
+}
+
 func addFlagPush(fs *flag.FlagSet) {
 	fs.BoolVar(&flagPush, "push", false, "push to GitHub if true")
 }
 
+func addFlagPushRetries(fs *flag.FlagSet) {
+	fs.IntVar(&flagPushRetries, "push-retries", 3, "number of times to retry updating the branch of an existing pull request when another librarian run pushes to it at the same time")
+	fs.DurationVar(&flagPushRetryDelay, "push-retry-delay", 10*time.Second, "delay before each retry of -push-retries, doubling after each retry")
+}
+
+func addFlagContainerRecording(fs *flag.FlagSet) {
+	fs.StringVar(&flagRecordContainers, "record-containers", "", "directory in which to record each container invocation and the files it changes, for later replay")
+	fs.StringVar(&flagReplayContainers, "replay-containers", "", "directory of container invocations recorded with -record-containers to replay instead of running docker, which requires the mounted directories to have the content they had when recorded")
+}
+
+func addFlagRegenerateAll(fs *flag.FlagSet) {
+	fs.BoolVar(&flagRegenerateAll, "regenerate-all", false, "regenerate every API, even those with no new googleapis commits (e.g. after an image update). APIs whose protos, generation options and image digest are unchanged since they were last generated are still skipped.")
+}
+
+func addFlagRepoBranch(fs *flag.FlagSet) {
+	fs.StringVar(&flagRepoBranch, "repo-branch", "", "branch of the language repo to clone, and to target with pull requests. Defaults to the branch configured for the language in the configuration files, or the repository's default branch.")
+}
+
 func addFlagRepoRoot(fs *flag.FlagSet) {
-	fs.StringVar(&flagRepoRoot, "repo-root", "", "Repository root. When this is not specified, the language repo will be cloned.")
+	fs.StringVar(&flagRepoRoot, "repo-root", "", "Repository root. When this is not specified, the language repo will be cloned. For commands which update the repo, an existing clone is fetched and reset to the latest commit, and an empty or missing directory is cloned into.")
+}
+
+func addFlagRepoURL(fs *flag.FlagSet) {
+	fs.StringVar(&flagRepoURL, "repo-url", "", "URL of the language repo to clone, e.g. a fork or mirror. Defaults to the URL configured for the language in the configuration files, or https://github.com/googleapis/google-cloud-{language}.")
+}
+
+func addFlagScheduled(fs *flag.FlagSet) {
+	fs.BoolVar(&flagScheduled, "scheduled", false, "run as a scheduled (e.g. cron) regeneration: only regenerate the libraries with API changes in googleapis since they were last generated, reporting each skipped library and why. Cannot be combined with -api-path.")
+}
+
+func addFlagShell(fs *flag.FlagSet) {
+	fs.StringVar(&flagShell, "shell", "/bin/bash", "shell to run in the container")
+}
+
+func addFlagSignCommits(fs *flag.FlagSet) {
+	fs.StringVar(&flagSignCommits, "sign-commits", "", "sign generated commits using the given program: gpg or gitsign. Commits are unsigned by default.")
+	fs.StringVar(&flagSigningKey, "signing-key", "", "key used to sign commits, when -sign-commits is specified")
+}
+
+func addFlagSkipSteps(fs *flag.FlagSet) {
+	fs.BoolVar(&flagSkipClean, "skip-clean", false, "skip the container clean step, so previously-generated files are not removed before copying generated code")
+	fs.BoolVar(&flagSkipBuild, "skip-build", false, "skip the container build step after each API is generated")
+	fs.BoolVar(&flagSkipCommit, "skip-commit", false, "leave generated changes uncommitted in the language repo. Cannot be combined with -push.")
+}
+
+func addFlagStepTimeout(fs *flag.FlagSet) {
+	fs.DurationVar(&flagStepTimeout, "step-timeout", 0, "maximum duration of each container step (e.g. 30m), after which the container is killed. Unlimited by default.")
+}
+
+func addFlagUpdateCommits(fs *flag.FlagSet) {
+	fs.StringVar(&flagUpdateCommits, "update-commits", "replace", "with -on-existing-pr=update, how the commits of this run replace those on the existing branch: replace (push them as they are), squash (into a single commit) or amend (the last commit on the branch, if made by librarian, keeping any other commits)")
+}
+
+func addFlagWebhookSecret(fs *flag.FlagSet) {
+	fs.StringVar(&flagWebhookSecret, "webhook-secret", "", "secret used to verify the signatures of GitHub webhooks, and required as a bearer token for manual requests. Requests are unauthenticated if unspecified, which is only allowed when -listen is a loopback address.")
 }
 
 func addFlagWorkRoot(fs *flag.FlagSet) {
 	fs.StringVar(&flagWorkRoot, "work-root", "", "Working directory root. When this is not specified, a working directory will be created in /tmp.")
 }
 
+// repeatableString is a flag.Value for a string flag which may be repeated,
+// accumulating the values separated by commas.
+type repeatableString struct {
+	value *string
+}
+
+func (r repeatableString) String() string {
+	if r.value == nil {
+		return ""
+	}
+	return *r.value
+}
+
+func (r repeatableString) Set(s string) error {
+	if *r.value != "" {
+		*r.value += ","
+	}
+	*r.value += s
+	return nil
+}
+
+// stringList is a flag.Value for a string flag which may be repeated,
+// accumulating the values in a slice.
+type stringList struct {
+	values *[]string
+}
+
+func (l stringList) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l stringList) Set(s string) error {
+	*l.values = append(*l.values, s)
+	return nil
+}
+
 var supportedLanguages = map[string]bool{
 	"cpp":    false,
 	"dotnet": true,
 	"go":     false,
 	"java":   false,
 	"node":   false,
-	"php":    false,
+	"php":    true,
 	"python": false,
-	"ruby":   false,
-	"rust":   false,
+	"ruby":   true,
+	"rust":   true,
 	"all":    false,
 }
+
+// splitList splits a comma-separated flag value, ignoring empty elements.
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/googleapis/librarian/internal/bazel"
+	"github.com/googleapis/librarian/internal/container"
+)
+
+// generationConfig returns the generation options for the API at apiPath,
+// derived from the *_gapic_library rule for the current language in the API's
+// BUILD.bazel file. It returns nil if there's no such rule. A BUILD.bazel file
+// which can't be read is logged rather than failing generation.
+func generationConfig(apiRoot, apiPath string) *container.GenerationConfig {
+	library, err := bazel.FindGapicLibrary(apiRoot, apiPath, flagLanguage)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to read BUILD.bazel for '%s': %s", apiPath, err))
+		return nil
+	}
+	if library == nil {
+		return nil
+	}
+	return &container.GenerationConfig{
+		GRPCServiceConfig: library.GRPCServiceConfig,
+		ServiceYAML:       library.ServiceYAML,
+		Transport:         library.Transport,
+		RestNumericEnums:  library.RestNumericEnums,
+	}
+}
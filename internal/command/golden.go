@@ -0,0 +1,245 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// goldenFixtures are the APIs whose generated output is stored as goldens for
+// each language. They're chosen to be small, but to cover a range of features
+// (e.g. LRO, streaming, mixins).
+var goldenFixtures = map[string][]string{
+	"dotnet": {
+		"google/cloud/functions/v2",
+		"google/cloud/secretmanager/v1",
+		"google/pubsub/v1",
+	},
+	"php": {
+		"google/cloud/functions/v2",
+		"google/cloud/secretmanager/v1",
+		"google/pubsub/v1",
+	},
+	"ruby": {
+		"google/cloud/functions/v2",
+		"google/cloud/secretmanager/v1",
+		"google/pubsub/v1",
+	},
+	"rust": {
+		"google/cloud/functions/v2",
+		"google/cloud/secretmanager/v1",
+		"google/pubsub/v1",
+	},
+}
+
+// goldenCommitFile is the name of the file within the golden directory for a
+// language which records the googleapis commit the goldens were generated from.
+const goldenCommitFile = "googleapis-commit"
+
+// CmdGolden generates the golden fixture APIs for a language, and either
+// compares the result with the stored goldens ("check") or replaces them
+// ("update"). Checking a new generator image against the goldens catches
+// generator regressions before it's rolled out to language repos.
+var CmdGolden = &Command{
+	Name:  "golden",
+	Short: "Check or update golden generated output for fixture APIs: golden check|update",
+	// Run is set in init, as it refers to CmdGolden.
+}
+
+func runGolden(ctx context.Context) error {
+	// The action is positional, but flags may appear before or after it.
+	args := CmdGolden.flags.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("golden requires an action: check or update")
+	}
+	action := args[0]
+	if err := CmdGolden.flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if action != "check" && action != "update" {
+		return fmt.Errorf("invalid golden action: %q; must be check or update", action)
+	}
+	if !supportedLanguages[flagLanguage] {
+		return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+	}
+	fixtures := goldenFixtures[flagLanguage]
+	if len(fixtures) == 0 {
+		return fmt.Errorf("no golden fixture APIs defined for %s", flagLanguage)
+	}
+
+	if err := preflight(); err != nil {
+		return err
+	}
+
+	goldenDir, err := filepath.Abs(filepath.Join(flagGoldenDir, flagLanguage))
+	if err != nil {
+		return err
+	}
+	tmpRoot, err := createTmpWorkingRoot(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var apiRepo *gitrepo.Repo
+	if flagAPIRoot == "" {
+		apiRepo, err = cloneGoogleapis(ctx, tmpRoot)
+	} else {
+		var apiRoot string
+		apiRoot, err = filepath.Abs(flagAPIRoot)
+		if err != nil {
+			return err
+		}
+		apiRepo, err = gitrepo.Open(ctx, apiRoot)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Goldens are always generated from a fixed googleapis commit, so that
+	// only generator changes are detected. Updating the goldens moves them
+	// to the current googleapis commit.
+	var commit string
+	if action == "update" {
+		commit, err = gitrepo.HeadHash(ctx, apiRepo)
+	} else {
+		commit, err = readGoldenCommit(goldenDir)
+	}
+	if err != nil {
+		return err
+	}
+	apiRoot := filepath.Join(tmpRoot, "googleapis")
+	slog.Info(fmt.Sprintf("Exporting googleapis at %s", commit))
+	if err := gitrepo.ExportTree(ctx, apiRepo, commit, apiRoot); err != nil {
+		return err
+	}
+
+	containerOpts := containerOptions(nil)
+	outputRoot := filepath.Join(tmpRoot, "output")
+	for _, apiPath := range fixtures {
+		outputDir := filepath.Join(outputRoot, apiPath)
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return err
+		}
+		channel := releaseChannel(apiPath, nil)
+		if err := container.Generate(ctx, containerOpts, apiRoot, outputDir, "", apiPath, channel, generationConfig(apiRoot, apiPath)); err != nil {
+			return err
+		}
+	}
+
+	if action == "update" {
+		if err := os.RemoveAll(goldenDir); err != nil {
+			return err
+		}
+		if err := os.CopyFS(goldenDir, os.DirFS(outputRoot)); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(goldenDir, goldenCommitFile), []byte(commit+"\n"), 0644); err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Updated goldens in %s", goldenDir))
+		return nil
+	}
+
+	differences, err := compareTrees(goldenDir, outputRoot)
+	if err != nil {
+		return err
+	}
+	if len(differences) > 0 {
+		slog.Error(fmt.Sprintf("Generated output differs from goldens:\n%s", strings.Join(differences, "\n")))
+		return fmt.Errorf("%d file(s) differ from goldens in %s", len(differences), goldenDir)
+	}
+	slog.Info("Generated output matches goldens.")
+	return nil
+}
+
+func readGoldenCommit(goldenDir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(goldenDir, goldenCommitFile))
+	if err != nil {
+		return "", fmt.Errorf("unable to read golden googleapis commit; run golden update first: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// compareTrees compares the regular files under the want and got directories,
+// returning a description of each difference in the form "A path", "D path" or
+// "M path" (for files only in got, only in want, or with differing content
+// respectively), sorted by path. The golden commit file is ignored.
+func compareTrees(want, got string) ([]string, error) {
+	wantFiles, err := listFiles(want)
+	if err != nil {
+		return nil, err
+	}
+	gotFiles, err := listFiles(got)
+	if err != nil {
+		return nil, err
+	}
+	delete(wantFiles, goldenCommitFile)
+
+	var differences []string
+	for path := range wantFiles {
+		if !gotFiles[path] {
+			differences = append(differences, "D "+path)
+		}
+	}
+	for path := range gotFiles {
+		if !wantFiles[path] {
+			differences = append(differences, "A "+path)
+			continue
+		}
+		wantContent, err := os.ReadFile(filepath.Join(want, path))
+		if err != nil {
+			return nil, err
+		}
+		gotContent, err := os.ReadFile(filepath.Join(got, path))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(wantContent, gotContent) {
+			differences = append(differences, "M "+path)
+		}
+	}
+	sort.Slice(differences, func(i, j int) bool { return differences[i][2:] < differences[j][2:] })
+	return differences, nil
+}
+
+// listFiles returns the slash-separated paths of all regular files under dir,
+// relative to dir.
+func listFiles(dir string) (map[string]bool, error) {
+	files := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
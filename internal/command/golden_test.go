@@ -0,0 +1,61 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestGoldens runs "golden check" for each language whose goldens are present
+// in the golden directory (testdata/golden by default, or
+// LIBRARIAN_GOLDEN_DIR), so that generator regressions fail the tests. It runs
+// the language images, so it's skipped when docker is unavailable or with
+// -short. googleapis is cloned unless LIBRARIAN_API_ROOT is set.
+func TestGoldens(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping golden tests with -short")
+	}
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		t.Skipf("docker is not available: %v", err)
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	languages := make([]string, 0, len(goldenFixtures))
+	for language := range goldenFixtures {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	for _, language := range languages {
+		t.Run(language, func(t *testing.T) {
+			// -language accumulates values when parsed repeatedly.
+			flagLanguage = ""
+			if err := CmdGolden.Parse([]string{"check", "-language=" + language, "-auto-prune-days=0"}); err != nil {
+				t.Fatal(err)
+			}
+			goldenDir := filepath.Join(flagGoldenDir, language)
+			if _, err := os.Stat(filepath.Join(goldenDir, goldenCommitFile)); err != nil {
+				t.Skipf("no goldens for %s in %s", language, goldenDir)
+			}
+			if err := CmdGolden.Run(context.Background()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
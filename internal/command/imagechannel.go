@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/googleapis/librarian/internal/artifactregistry"
+	"github.com/googleapis/librarian/internal/googleauth"
+)
+
+// channelImages maps each language to the image resolved for it from
+// -image-channel, which takes precedence over the image tag in the pipeline
+// state (but not over an image in the language's configuration).
+var channelImages = map[string]string{}
+
+// resolveImageChannel wraps the Run function of a command so that, when
+// -image-channel is specified (and -image isn't), the image for each language
+// in -language is resolved from the Artifact Registry repository specified by
+// LIBRARIAN_REPOSITORY before the command is run. The images are resolved by
+// digest, so that every container run by the command uses the same image even
+// if the tags are moved during the run. Languages with an image in their
+// configuration (see languageConfig) use that image instead.
+func resolveImageChannel(run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		clear(channelImages)
+		if flagImageChannel == "" || flagImage != "" || flagLanguage == "" {
+			return run(ctx)
+		}
+		parsed, err := parseLanguages(flagLanguage)
+		if err != nil {
+			return err
+		}
+		var languages []string
+		for _, language := range parsed {
+			if image := languageConfigs[language].Image; image != "" {
+				slog.Info(fmt.Sprintf("Not resolving the %s image for %s, as its configured image %s is used", flagImageChannel, language, image))
+				continue
+			}
+			languages = append(languages, language)
+		}
+		if len(languages) == 0 {
+			return run(ctx)
+		}
+		repositoryName := os.Getenv("LIBRARIAN_REPOSITORY")
+		if repositoryName == "" {
+			return fmt.Errorf("-image-channel requires LIBRARIAN_REPOSITORY to be set to an Artifact Registry repository")
+		}
+		repository, err := artifactregistry.ParseRepository(repositoryName)
+		if err != nil {
+			return fmt.Errorf("invalid LIBRARIAN_REPOSITORY for -image-channel: %w", err)
+		}
+		token, err := googleauth.AccessToken(ctx)
+		if err != nil {
+			return err
+		}
+		for _, language := range languages {
+			image, err := repository.Resolve(ctx, token, fmt.Sprintf("google-cloud-%s-generator", language), flagImageChannel)
+			if err != nil {
+				return err
+			}
+			slog.Info(fmt.Sprintf("Resolved %s image for %s: tag %s, digest %s", flagImageChannel, language, image.Tag, image.Digest))
+			channelImages[language] = image.Ref
+		}
+		return run(ctx)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+func TestDeriveLanguageImageConfiguredImageOverridesChannel(t *testing.T) {
+	savedConfigs := languageConfigs
+	t.Cleanup(func() {
+		languageConfigs = savedConfigs
+		clear(channelImages)
+	})
+	languageConfigs = map[string]languageConfig{
+		"kotlin": {Image: "us-docker.pkg.dev/example/images/kotlin-generator"},
+	}
+	channelImages["kotlin"] = "us-docker.pkg.dev/example/repo/google-cloud-kotlin-generator@sha256:1234"
+	channelImages["python"] = "us-docker.pkg.dev/example/repo/google-cloud-python-generator@sha256:5678"
+	flagImage = ""
+	state := &statepb.PipelineState{ImageTag: "v1.2.3"}
+
+	if got, want := deriveLanguageImage("kotlin", state), "us-docker.pkg.dev/example/images/kotlin-generator:v1.2.3"; got != want {
+		t.Errorf("deriveLanguageImage(kotlin) = %q; want %q", got, want)
+	}
+	if got, want := deriveLanguageImage("python", state), channelImages["python"]; got != want {
+		t.Errorf("deriveLanguageImage(python) = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/container"
+)
+
+// generationInputs is everything other than the content of the API's files
+// which determines the code generated for an API.
+type generationInputs struct {
+	Language    string                      `json:"language"`
+	APIPath     string                      `json:"apiPath"`
+	ImageDigest string                      `json:"imageDigest"`
+	Channel     string                      `json:"channel"`
+	Experiments []string                    `json:"experiments"`
+	Config      *container.GenerationConfig `json:"config"`
+}
+
+// generationInputHash returns a hash of the inputs from which an API is
+// generated: the files in its directory of the API root (its protos, service
+// config and BUILD.bazel), the protos they import (directly or indirectly)
+// from elsewhere in the API root, the generator input other than the pipeline state
+// (if generatorInput isn't empty), the generation options derived from
+// BUILD.bazel, the release channel, the active experiments and the image
+// digest. If the hash matches the one recorded when the API was last
+// generated, generating it again would produce the same code, so the container
+// run can be skipped. As the image can't be identified without its digest, an
+// empty hash (which never matches) is returned when imageDigest is empty.
+func generationInputHash(apiRoot, apiPath, generatorInput, imageDigest, channel string, containerOpts *container.Options, config *container.GenerationConfig) (string, error) {
+	if imageDigest == "" {
+		return "", nil
+	}
+	hash := sha256.New()
+	inputs, err := json.Marshal(&generationInputs{
+		Language:    flagLanguage,
+		APIPath:     apiPath,
+		ImageDigest: imageDigest,
+		Channel:     channel,
+		Experiments: containerOpts.Experiments,
+		Config:      config,
+	})
+	if err != nil {
+		return "", err
+	}
+	hash.Write(inputs)
+
+	if err := hashFiles(hash, filepath.Join(apiRoot, filepath.FromSlash(apiPath)), ""); err != nil {
+		return "", fmt.Errorf("unable to hash the inputs of '%s': %w", apiPath, err)
+	}
+	imports, err := protoImports(apiRoot, apiPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to find the imports of '%s': %w", apiPath, err)
+	}
+	for _, imported := range imports {
+		content, err := os.ReadFile(filepath.Join(apiRoot, filepath.FromSlash(imported)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "\x00%s\x00%d\x00", imported, len(content))
+		hash.Write(content)
+	}
+	if generatorInput != "" {
+		// The pipeline state changes whenever any API is generated, and the
+		// parts of it which affect generation are hashed above.
+		if err := hashFiles(hash, generatorInput, "pipeline-state.json"); err != nil {
+			return "", fmt.Errorf("unable to hash the generator input: %w", err)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// protoImport matches an import statement in a proto file.
+var protoImport = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+
+// protoImports returns the slash-separated paths, relative to apiRoot, of the
+// protos outside the directory of the API at apiPath which are imported by its
+// protos, directly or indirectly, in sorted order. Imports which aren't in
+// apiRoot (e.g. the protobuf well-known types) are ignored.
+func protoImports(apiRoot, apiPath string) ([]string, error) {
+	apiDir := filepath.Join(apiRoot, filepath.FromSlash(apiPath))
+	var queue []string
+	err := filepath.WalkDir(apiDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".proto" {
+			return err
+		}
+		queue = append(queue, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	imports := map[string]bool{}
+	for len(queue) > 0 {
+		content, err := os.ReadFile(queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+		for _, match := range protoImport.FindAllSubmatch(content, -1) {
+			imported := path.Clean(string(match[1]))
+			if imports[imported] || !filepath.IsLocal(filepath.FromSlash(imported)) || strings.HasPrefix(imported+"/", apiPath+"/") {
+				continue
+			}
+			importedPath := filepath.Join(apiRoot, filepath.FromSlash(imported))
+			if info, err := os.Stat(importedPath); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			imports[imported] = true
+			queue = append(queue, importedPath)
+		}
+	}
+	return slices.Sorted(maps.Keys(imports)), nil
+}
+
+// hashFiles writes the relative path, size and content of each regular file
+// under dir (other than skip, relative to dir) to w.
+func hashFiles(w io.Writer, dir, skip string) error {
+	fmt.Fprintf(w, "\x00%s\x00", filepath.Base(dir))
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		relative, err := filepath.Rel(dir, path)
+		if err != nil || filepath.ToSlash(relative) == skip {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fmt.Fprintf(w, "%s\x00%d\x00", filepath.ToSlash(relative), info.Size())
+		_, err = io.Copy(w, file)
+		return err
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestGenerationInputHashIncludesImports(t *testing.T) {
+	apiRoot := t.TempDir()
+	writeFiles := func(files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			path := filepath.Join(apiRoot, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeFiles(map[string]string{
+		"google/example/v1/example.proto": "syntax = \"proto3\";\nimport \"google/type/date.proto\";\nimport \"google/protobuf/empty.proto\";\n",
+		"google/type/date.proto":          "syntax = \"proto3\";\nimport public \"google/type/calendar.proto\";\n",
+		"google/type/calendar.proto":      "syntax = \"proto3\";\n",
+		"google/type/unrelated.proto":     "syntax = \"proto3\";\n",
+	})
+	inputHash := func() string {
+		t.Helper()
+		hash, err := generationInputHash(apiRoot, "google/example/v1", "", "sha256:digest", "", &container.Options{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	original := inputHash()
+	writeFiles(map[string]string{"google/type/unrelated.proto": "syntax = \"proto3\";\n// Changed.\n"})
+	if got := inputHash(); got != original {
+		t.Error("changing a proto which isn't imported changed the input hash")
+	}
+	writeFiles(map[string]string{"google/type/calendar.proto": "syntax = \"proto3\";\n// Changed.\n"})
+	if got := inputHash(); got == original {
+		t.Error("changing an indirectly imported proto didn't change the input hash")
+	}
+}
+
+func TestUnchangedInputsAdvanceLastGeneratedCommit(t *testing.T) {
+	ctx := context.Background()
+	useTestlang(t)
+	apiRoot, languageRoot := setUpScratchRepos(t)
+	apiRepo, err := gitrepo.Open(ctx, apiRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	languageRepo, err := gitrepo.Open(ctx, languageRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadState(languageRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &generationResult{
+		repo:          languageRepo,
+		state:         state,
+		containerOpts: &container.Options{Image: "testlang", Runner: testlangRunner{}},
+		imageDigest:   "sha256:digest",
+		apiRoot:       apiRoot,
+	}
+	apiState := state.ApiGenerationStates[0]
+	// Record the current inputs as those the API was last generated from,
+	// although there's a new googleapis commit.
+	apiState.LastGeneratedInputHash, err = generationInputHash(apiRoot, apiState.Id, "", result.imageDigest, releaseChannel(apiState.Id, apiState), result.containerOpts, generationConfig(apiRoot, apiState.Id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashBefore, err := gitrepo.HeadHash(ctx, languageRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update, err := planApiUpdate(ctx, apiRepo, result, "", t.TempDir(), apiState, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update != nil {
+		t.Fatal("API with unchanged inputs was planned for regeneration")
+	}
+	latest, err := gitrepo.HeadHash(ctx, apiRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadState(languageRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.ApiGenerationStates[0].LastGeneratedCommit; got != latest {
+		t.Errorf("last generated commit = %q; want the latest googleapis commit %q", got, latest)
+	}
+	commits, err := gitrepo.CommitsSince(ctx, languageRepo, hashBefore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 {
+		t.Errorf("got %d commits in the language repo; want 1 recording the advanced state", len(commits))
+	}
+}
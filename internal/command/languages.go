@@ -0,0 +1,159 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// languageBuildChecks are the checks run by the build command of each
+// language's container in addition to building and testing, for languages
+// whose toolchains enforce them (see container.Options.BuildChecks). They're
+// only requested from images which declare container.BuildChecksFeature. For
+// Rust, "format" checks that the code is formatted as by cargo fmt, and "lint"
+// that cargo clippy reports no warnings. For PHP, they're the php-cs-fixer and
+// phpstan checks run by google-cloud-php's CI for each component.
+var languageBuildChecks = map[string][]string{
+	"php":  {"format", "lint"},
+	"rust": {"format", "lint"},
+}
+
+// parseLanguages returns the languages specified by a -language value, which
+// is a comma-separated list of languages, or "all" for all supported languages.
+func parseLanguages(value string) ([]string, error) {
+	var languages []string
+	for _, language := range strings.Split(value, ",") {
+		language = strings.TrimSpace(language)
+		if language == "all" {
+			languages = append(languages, completionLanguages()...)
+			continue
+		}
+		if !supportedLanguages[language] {
+			return nil, fmt.Errorf("invalid -language flag specified: %q", language)
+		}
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return slices.Compact(languages), nil
+}
+
+// forEachLanguage wraps the Run function of a command which works on a single
+// language, so that the command can be run for several languages at once. When
+// multiple languages are specified, googleapis is fetched once by fetchAPIRoot
+// (unless -api-root is specified) and shared by each language, which has its own
+// working directory under a common temporary working directory. If -output is
+// specified, each language writes to a subdirectory named after the language;
+// similarly, if -archive is specified, each language writes its own archive.
+// A failure for one language doesn't prevent the others from being run; the
+// result for each language is logged at the end.
+func forEachLanguage(run func(ctx context.Context) error, fetchAPIRoot func(ctx context.Context, tmpRoot string) (string, error)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		languages, err := parseLanguages(flagLanguage)
+		if err != nil {
+			return err
+		}
+		if len(languages) == 1 {
+			flagLanguage = languages[0]
+			return run(ctx)
+		}
+		if flagRepoRoot != "" {
+			return fmt.Errorf("-repo-root cannot be specified with multiple languages")
+		}
+		if flagRepoURL != "" {
+			return fmt.Errorf("-repo-url cannot be specified with multiple languages")
+		}
+
+		tmpRoot, err := createTmpWorkingRoot(time.Now())
+		if err != nil {
+			return err
+		}
+		if flagAPIRoot == "" {
+			apiRoot, err := fetchAPIRoot(ctx, tmpRoot)
+			if err != nil {
+				return err
+			}
+			flagAPIRoot = apiRoot
+		}
+
+		outputRoot := flagOutput
+		archivePath := flagArchive
+		results := map[string]error{}
+		for _, language := range languages {
+			flagLanguage = language
+			flagWorkRoot = filepath.Join(tmpRoot, language)
+			if err := os.Mkdir(flagWorkRoot, 0755); err != nil {
+				return err
+			}
+			if outputRoot != "" {
+				flagOutput = filepath.Join(outputRoot, language)
+				if err := os.MkdirAll(flagOutput, 0755); err != nil {
+					return err
+				}
+			}
+			if archivePath != "" {
+				flagArchive = languageArchivePath(archivePath, language)
+			}
+			slog.Info(fmt.Sprintf("Running for language %s", language))
+			results[language] = run(ctx)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+
+		var failed []string
+		for _, language := range languages {
+			if err := results[language]; err != nil {
+				slog.Error(fmt.Sprintf("%s: failed: %s", language, err))
+				failed = append(failed, language)
+			} else {
+				slog.Info(fmt.Sprintf("%s: succeeded", language))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed for %d of %d languages: %s", len(failed), len(languages), strings.Join(failed, ", "))
+		}
+		return nil
+	}
+}
+
+// fetchGenerateAPIRoot downloads the googleapis tarball shared by each language
+// in a multi-language generate command. Nothing is downloaded when generating
+// from a descriptor set.
+func fetchGenerateAPIRoot(ctx context.Context, tmpRoot string) (string, error) {
+	if flagDescriptorSet != "" {
+		return "", nil
+	}
+	if flagAPITarball == "" {
+		return "", fmt.Errorf("-api-root, -api-tarball or -descriptor-set must be provided")
+	}
+	return downloadGoogleapisTarball(ctx, tmpRoot, flagAPITarball)
+}
+
+// fetchUpdateApisAPIRoot clones the googleapis repository shared by each
+// language in a multi-language update-apis command.
+func fetchUpdateApisAPIRoot(ctx context.Context, tmpRoot string) (string, error) {
+	apiRepo, err := cloneGoogleapis(ctx, tmpRoot)
+	if err != nil {
+		return "", err
+	}
+	return apiRepo.Dir, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/googleapis/librarian/internal/apiindex"
+)
+
+// CmdListApis lists the APIs in googleapis (from -api-root, or the cached
+// clone), optionally only those whose path starts with a given prefix. The API
+// index (api-index-v1.json) is used when googleapis has one, providing the
+// version, host name and title of each API; otherwise the tree is scanned for
+// versioned directories containing protos, and only the version is known.
+var CmdListApis = &Command{
+	Name:  "list-apis",
+	Short: "List the APIs in googleapis: list-apis [path prefix]",
+	// Run is set in init, as it refers to CmdListApis.
+}
+
+// apiListing is an API listed by the list-apis command.
+type apiListing struct {
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	HostName string `json:"hostName,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+func runListApis(ctx context.Context) error {
+	root := flagAPIRoot
+	if root == "" {
+		var err error
+		if root, err = cachedGoogleapisRoot(); err != nil {
+			return err
+		}
+		if root == "" {
+			return fmt.Errorf("-api-root is not provided, and there is no cached googleapis clone (see -clone-cache)")
+		}
+	}
+	apis, err := listAPIs(root)
+	if err != nil {
+		return err
+	}
+	if prefix := strings.TrimPrefix(CmdListApis.flags.Arg(0), "//"); prefix != "" {
+		var filtered []*apiListing
+		for _, api := range apis {
+			if strings.HasPrefix(api.Path, prefix) {
+				filtered = append(filtered, api)
+			}
+		}
+		apis = filtered
+	}
+
+	switch flagFormat {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(apis)
+	case "text":
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "PATH\tVERSION\tHOST\tTITLE")
+		for _, api := range apis {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", api.Path, api.Version, api.HostName, api.Title)
+		}
+		return writer.Flush()
+	default:
+		return fmt.Errorf("invalid -format: %q; must be text or json", flagFormat)
+	}
+}
+
+// listAPIs returns the APIs in the googleapis tree at root, sorted by path,
+// from the API index if there is one, or otherwise by scanning the tree.
+func listAPIs(root string) ([]*apiListing, error) {
+	index, err := apiindex.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	var apis []*apiListing
+	if index != nil {
+		for _, api := range index.APIs {
+			apis = append(apis, &apiListing{
+				Path:     api.Directory,
+				Version:  api.Version,
+				HostName: api.HostName,
+				Title:    api.Title,
+			})
+		}
+		sort.Slice(apis, func(i, j int) bool { return apis[i].Path < apis[j].Path })
+		return apis, nil
+	}
+	paths, err := listAPIPaths(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, apiPath := range paths {
+		apis = append(apis, &apiListing{Path: apiPath, Version: path.Base(apiPath)})
+	}
+	return apis, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// commonProtosMarker is a proto which every googleapis tree contains, and
+// which any API following the AIPs imports. A proto tree without it isn't
+// self-contained.
+const commonProtosMarker = "google/api/annotations.proto"
+
+// defaultGoogleapisRef is the googleapis ref downloaded for the common protos
+// imported by a local proto tree, when -api-tarball isn't specified.
+const defaultGoogleapisRef = "master"
+
+// localProtoTreeFile is the file written to the root of a tree combined from
+// a local proto tree and googleapis, containing the path of the local tree.
+// APIs in such a tree aren't required to have a service config, and the tree
+// is recognized when passed as -api-root to another process (e.g. by batch).
+const localProtoTreeFile = ".librarian-local-proto-tree"
+
+// resolveLocalAPIRoot returns the API root to generate from, given the
+// -api-root directory. Normally that's googleapis (or a copy of it), which is
+// used as-is. Otherwise it's a tree of other protos (e.g. private APIs
+// following the AIPs), with -api-path relative to it. As such a tree doesn't
+// usually contain the common protos it imports (google/api, google/rpc and so
+// on), googleapis is downloaded (at the -api-tarball ref, or master) into
+// tmpRoot and the local protos are copied into it, so that containers see a
+// single tree. Files which exist in both trees are an error, rather than
+// silently replacing googleapis protos, as is a directory without any protos.
+func resolveLocalAPIRoot(ctx context.Context, tmpRoot, apiRoot string) (string, error) {
+	if _, err := os.Stat(filepath.Join(apiRoot, filepath.FromSlash(commonProtosMarker))); err == nil {
+		if isLocalProtoTree(apiRoot) {
+			slog.Info(fmt.Sprintf("Using %s, a local proto tree already combined with googleapis", apiRoot))
+		} else {
+			slog.Info(fmt.Sprintf("Using %s as a googleapis tree, as it contains %s", apiRoot, commonProtosMarker))
+		}
+		return apiRoot, nil
+	}
+	hasProtos, err := containsProtos(apiRoot)
+	if err != nil {
+		return "", err
+	}
+	if !hasProtos {
+		return "", fmt.Errorf("-api-root %s contains no .proto files; it must be googleapis (containing %s) or a local proto tree", apiRoot, commonProtosMarker)
+	}
+	ref := flagAPITarball
+	if ref == "" {
+		ref = defaultGoogleapisRef
+	}
+	slog.Info(fmt.Sprintf("Using %s as a local proto tree, as it doesn't contain %s; using googleapis at %s for its dependencies", apiRoot, commonProtosMarker, ref))
+	combinedRoot, err := downloadGoogleapisTarball(ctx, tmpRoot, ref)
+	if err != nil {
+		return "", err
+	}
+	if err := overlayProtoTree(apiRoot, combinedRoot); err != nil {
+		return "", fmt.Errorf("unable to combine %s with googleapis: %w", apiRoot, err)
+	}
+	if err := os.WriteFile(filepath.Join(combinedRoot, localProtoTreeFile), []byte(apiRoot+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return combinedRoot, nil
+}
+
+// isLocalProtoTree reports whether apiRoot was combined from a local proto
+// tree and googleapis by resolveLocalAPIRoot.
+func isLocalProtoTree(apiRoot string) bool {
+	_, err := os.Stat(filepath.Join(apiRoot, localProtoTreeFile))
+	return err == nil
+}
+
+// containsProtos reports whether there are any .proto files under dir.
+func containsProtos(dir string) (bool, error) {
+	found := false
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.IsDir() && filepath.Ext(path) == ".proto" {
+			found = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
+
+// overlayProtoTree copies the files under src (other than any .git directory)
+// to the same relative paths under dst, none of which may already exist.
+func overlayProtoTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		relative, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relative)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if _, err := os.Stat(target); err == nil {
+			return fmt.Errorf("%s is also in googleapis", filepath.ToSlash(relative))
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return writeFile(target, file, info.Mode().Perm())
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// serveGoogleapisTarball serves a googleapis source archive containing only the
+// common protos in place of GitHub for the duration of the test.
+func serveGoogleapisTarball(t *testing.T) {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	content := []byte("syntax = \"proto3\";\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "googleapis-master/" + commonProtosMarker, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	defaultClient := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: rewriteHost{server.Listener.Addr().String()}}
+	t.Cleanup(func() { http.DefaultClient = defaultClient })
+}
+
+// rewriteHost sends every request to the given host over plain HTTP.
+type rewriteHost struct {
+	host string
+}
+
+func (r rewriteHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = r.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestResolveLocalAPIRoot(t *testing.T) {
+	ctx := context.Background()
+	serveGoogleapisTarball(t)
+	localRoot := t.TempDir()
+	apiPath := "private/example/v1"
+	if err := os.MkdirAll(filepath.Join(localRoot, apiPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(localRoot, apiPath, "example.proto"), []byte("syntax = \"proto3\";\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	apiRoot, err := resolveLocalAPIRoot(ctx, t.TempDir(), localRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{commonProtosMarker, apiPath + "/example.proto"} {
+		if _, err := os.Stat(filepath.Join(apiRoot, filepath.FromSlash(path))); err != nil {
+			t.Errorf("combined API root is missing %s: %v", path, err)
+		}
+	}
+	// APIs in a local proto tree needn't have a service config.
+	if err := validateAPIPath(apiRoot, apiPath); err != nil {
+		t.Errorf("validateAPIPath in a local proto tree: %v", err)
+	}
+	// The combined tree is used as-is when passed to another process.
+	if again, err := resolveLocalAPIRoot(ctx, t.TempDir(), apiRoot); err != nil || again != apiRoot {
+		t.Errorf("resolveLocalAPIRoot of the combined tree = %q, %v; want %q", again, err, apiRoot)
+	}
+
+	if _, err := resolveLocalAPIRoot(ctx, t.TempDir(), t.TempDir()); err == nil {
+		t.Error("resolveLocalAPIRoot of a directory without protos succeeded; want an error")
+	}
+}
+
+func TestValidateAPIPathRequiresServiceConfigInGoogleapis(t *testing.T) {
+	apiRoot, _ := setUpScratchRepos(t)
+	if err := validateAPIPath(apiRoot, selfTestAPIPath); err == nil {
+		t.Error("validateAPIPath of an API without a service config in googleapis succeeded; want an error")
+	}
+}
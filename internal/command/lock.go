@@ -0,0 +1,209 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// lockHolderFormat is the format of the content of a lock file, which
+// identifies the process holding the lock.
+const lockHolderFormat = "pid %d on %s since %s"
+
+// lockTTL is the age after which a lock held by a process on another host,
+// whose liveness can't be checked, is considered stale.
+const lockTTL = 24 * time.Hour
+
+// acquireRepoLock takes the locks for a run which updates the language repo,
+// so that concurrent runs can't race on the same clone or create conflicting
+// pull requests:
+//
+//   - if -repo-root is specified, a lock on that directory alone, as runs for
+//     any APIs share the clone in it.
+//   - a lock on the repository URL and branch and the APIs, so that runs for
+//     the same APIs can't create conflicting pull requests.
+//
+// The returned function releases the locks. A stale lock, left by a run which
+// didn't release it (e.g. because it was killed), is broken (see
+// staleLockReason). No lock is taken if -no-lock is specified.
+func acquireRepoLock() (func(), error) {
+	if flagNoLock {
+		return func() {}, nil
+	}
+	releaseClone := func() {}
+	if flagRepoRoot != "" {
+		repoRoot, err := filepath.Abs(flagRepoRoot)
+		if err != nil {
+			return nil, err
+		}
+		if releaseClone, err = acquireLock(repoRoot); err != nil {
+			return nil, err
+		}
+	}
+	repo := fmt.Sprintf("%s@%s", languageRepoURL(flagLanguage), languageRepoBranch(flagLanguage))
+	releaseAPIs, err := acquireLock(fmt.Sprintf("%s|%s", repo, apiScope()))
+	if err != nil {
+		releaseClone()
+		return nil, err
+	}
+	return func() {
+		releaseAPIs()
+		releaseClone()
+	}, nil
+}
+
+// runLocks are the locks taken by lockForRun, keyed by their key, with the
+// functions which release them.
+var runLocks struct {
+	mu       sync.Mutex
+	releases map[string]func()
+}
+
+// lockForRun takes the lock for key until the end of the current run (see
+// releaseRunLocks), unless it's already held by this run. No lock is taken if
+// -no-lock is specified.
+func lockForRun(key string) error {
+	if flagNoLock {
+		return nil
+	}
+	runLocks.mu.Lock()
+	defer runLocks.mu.Unlock()
+	if _, ok := runLocks.releases[key]; ok {
+		return nil
+	}
+	release, err := acquireLock(key)
+	if err != nil {
+		return err
+	}
+	if runLocks.releases == nil {
+		runLocks.releases = map[string]func(){}
+	}
+	runLocks.releases[key] = release
+	return nil
+}
+
+// releaseRunLocks wraps the Run function of a command so that the locks taken
+// by lockForRun during the run are released when it ends.
+func releaseRunLocks(run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		defer releaseHeldRunLocks()
+		return run(ctx)
+	}
+}
+
+// releaseHeldRunLocks releases the locks taken by lockForRun.
+func releaseHeldRunLocks() {
+	runLocks.mu.Lock()
+	defer runLocks.mu.Unlock()
+	for key, release := range runLocks.releases {
+		release()
+		delete(runLocks.releases, key)
+	}
+}
+
+// acquireLock creates a lock file for key, returning a function which releases
+// the lock, or an error if another process holds it.
+func acquireLock(key string) (func(), error) {
+	hash := sha256.Sum256([]byte(key))
+	dir := filepath.Join(os.TempDir(), "librarian-locks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, hex.EncodeToString(hash[:8])+".lock")
+	hostname, _ := os.Hostname()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrExist) {
+		holder, _ := os.ReadFile(path)
+		if reason := staleLockReason(string(holder), hostname, time.Now()); reason != "" {
+			slog.Warn(fmt.Sprintf("Breaking stale lock %s for %s (%s): %s", path, key, holder, reason))
+			// Only remove the lock if it hasn't been replaced meanwhile.
+			if current, _ := os.ReadFile(path); string(current) == string(holder) {
+				os.Remove(path)
+			}
+			f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		}
+	}
+	if errors.Is(err, os.ErrExist) {
+		holder, _ := os.ReadFile(path)
+		return nil, fmt.Errorf("another run holds the lock for %s (%s): %s; if it's no longer running, delete the lock file or use -no-lock", key, path, holder)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(f, lockHolderFormat, os.Getpid(), hostname, time.Now().Format(time.RFC3339))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	slog.Info(fmt.Sprintf("Acquired lock %s for %s", path, key))
+	return func() {
+		if err := os.Remove(path); err != nil {
+			slog.Warn(fmt.Sprintf("Unable to release lock %s: %s", path, err))
+		}
+	}, nil
+}
+
+// staleLockReason returns why the lock with the given holder (as written with
+// lockHolderFormat) is stale, or an empty string if it isn't: a lock is stale
+// if it's held by a process on this host which no longer exists, or by a
+// process on another host for longer than lockTTL.
+func staleLockReason(holder, hostname string, now time.Time) string {
+	var pid int
+	var host, since string
+	if _, err := fmt.Sscanf(holder, lockHolderFormat, &pid, &host, &since); err != nil {
+		return ""
+	}
+	if host == hostname {
+		if processExists(pid) {
+			return ""
+		}
+		return fmt.Sprintf("process %d no longer exists", pid)
+	}
+	acquired, err := time.Parse(time.RFC3339, since)
+	if err != nil || now.Sub(acquired) < lockTTL {
+		return ""
+	}
+	return fmt.Sprintf("it was acquired on another host more than %s ago", lockTTL)
+}
+
+// processExists reports whether a process with the given pid exists.
+func processExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess fails on Windows if the process doesn't exist, and
+		// signals other than kill aren't supported.
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	// EPERM means that the process exists, but is owned by another user.
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireRepoLockBreaksStaleLock(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	flagNoLock, flagRepoRoot, flagAPIPath = false, t.TempDir(), selfTestAPIPath
+	t.Cleanup(func() { flagRepoRoot, flagAPIPath = "", "" })
+
+	release, err := acquireRepoLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquireRepoLock(); err == nil {
+		t.Fatal("acquireRepoLock() succeeded while the lock is held; want an error")
+	}
+	paths, err := filepath.Glob(filepath.Join(os.TempDir(), "librarian-locks", "*.lock"))
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("lock files = %v, %v; want one for the clone and one for the APIs", paths, err)
+	}
+	release()
+
+	// Leave a lock held by a process which has exited.
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf(lockHolderFormat, exited.Process.Pid, hostname, time.Now().Format(time.RFC3339))
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte(holder), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	release, err = acquireRepoLock()
+	if err != nil {
+		t.Fatalf("acquireRepoLock() with a stale lock: %v", err)
+	}
+	release()
+}
+
+func TestAcquireRepoLockSharesCloneLockAcrossAPIs(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	flagNoLock, flagRepoRoot, flagAPIPath = false, "", "google/example/v1"
+	t.Cleanup(func() { flagRepoRoot, flagAPIPath = "", "" })
+
+	release, err := acquireRepoLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Runs for other APIs in their own clones don't conflict.
+	flagAPIPath = "google/example/v2"
+	other, err := acquireRepoLock()
+	if err != nil {
+		t.Fatalf("acquireRepoLock() for another API: %v", err)
+	}
+	other()
+	release()
+
+	// Runs for any APIs in the same -repo-root do.
+	flagRepoRoot, flagAPIPath = t.TempDir(), "google/example/v1"
+	release, err = acquireRepoLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	flagAPIPath = "google/example/v2"
+	if _, err := acquireRepoLock(); err == nil {
+		t.Error("acquireRepoLock() for another API in the same -repo-root succeeded; want an error")
+	}
+}
+
+func TestStaleLockReason(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		name   string
+		holder string
+		stale  bool
+	}{
+		{"live process on this host", fmt.Sprintf(lockHolderFormat, os.Getpid(), "here", now.Format(time.RFC3339)), false},
+		{"recent lock on another host", fmt.Sprintf(lockHolderFormat, 1, "there", now.Add(-time.Hour).Format(time.RFC3339)), false},
+		{"old lock on another host", fmt.Sprintf(lockHolderFormat, 1, "there", now.Add(-2*lockTTL).Format(time.RFC3339)), true},
+		{"unrecognized content", "locked", false},
+	} {
+		if got := staleLockReason(test.holder, "here", now); (got != "") != test.stale {
+			t.Errorf("%s: staleLockReason(%q) = %q; want stale = %v", test.name, test.holder, got, test.stale)
+		}
+	}
+}
+
+func TestLockForRun(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	flagNoLock = false
+	run := releaseRunLocks(func(ctx context.Context) error {
+		if err := lockForRun("clone-cache|example"); err != nil {
+			return err
+		}
+		// Taking the lock again in the same run succeeds.
+		if err := lockForRun("clone-cache|example"); err != nil {
+			return err
+		}
+		// Another run can't take it.
+		if release, err := acquireLock("clone-cache|example"); err == nil {
+			release()
+			t.Error("acquireLock() succeeded while the lock is held by the run; want an error")
+		}
+		return nil
+	})
+	if err := run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	release, err := acquireLock("clone-cache|example")
+	if err != nil {
+		t.Fatalf("acquireLock() after the run: %v", err)
+	}
+	release()
+}
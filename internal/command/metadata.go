@@ -0,0 +1,72 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/apiindex"
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/serviceconfig"
+)
+
+// apiMetadata returns the metadata for the API at apiPath, read from its
+// service config: the file specified in the API's BUILD.bazel file or in the
+// API index, or otherwise the only service config file in the API's directory.
+// If there's no service config, the title and host name from the API index are
+// used if the API is in the index, and otherwise nil is returned. Errors are
+// logged rather than failing the command, as the metadata is informational.
+func apiMetadata(apiRoot, apiPath string) *container.APIMetadata {
+	index, err := apiindex.Load(apiRoot)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to load API index: %s", err))
+	}
+	var indexed *apiindex.API
+	if index != nil {
+		indexed = index.Find(apiPath)
+	}
+
+	var path string
+	if config := generationConfig(apiRoot, apiPath); config != nil && config.ServiceYAML != "" {
+		path = filepath.Join(apiRoot, filepath.FromSlash(config.ServiceYAML))
+	} else if indexed != nil && indexed.ConfigFile != "" {
+		path = filepath.Join(apiRoot, filepath.FromSlash(apiPath), indexed.ConfigFile)
+	} else {
+		if path, err = serviceconfig.Find(apiRoot, apiPath); err != nil {
+			slog.Warn(fmt.Sprintf("Unable to find service config for '%s': %s", apiPath, err))
+			return nil
+		}
+	}
+	if path == "" {
+		if indexed == nil {
+			return nil
+		}
+		shortName, _, _ := strings.Cut(indexed.HostName, ".")
+		return &container.APIMetadata{Title: indexed.Title, ShortName: shortName}
+	}
+	metadata, err := serviceconfig.Read(path)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to read service config for '%s': %s", apiPath, err))
+		return nil
+	}
+	return &container.APIMetadata{
+		Title:            metadata.Title,
+		ShortName:        metadata.ShortName,
+		DocumentationURI: metadata.DocumentationURI,
+	}
+}
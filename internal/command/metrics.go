@@ -0,0 +1,74 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/metrics"
+)
+
+// instrument wraps the Run function of a command to record run metrics.
+// When -metrics-addr is specified, metrics are served for the duration of the run.
+func instrument(name string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if flagMetricsAddr != "" {
+			serveCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				if err := metrics.Serve(serveCtx, flagMetricsAddr); err != nil {
+					slog.Error(fmt.Sprintf("Unable to serve metrics: %s", err))
+				}
+			}()
+		}
+
+		start := time.Now()
+		err := run(ctx)
+		metrics.RunDuration.ObserveSince(start, name)
+		if err != nil {
+			metrics.Runs.Inc(name, "failure")
+		} else {
+			metrics.Runs.Inc(name, "success")
+		}
+		return err
+	}
+}
+
+// instrumentLanguage wraps the Run function of a command which works on a
+// single language, to record run metrics by language, including the failures
+// of the container steps run.
+func instrumentLanguage(name string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		firstStep := len(container.StepResults())
+		err := run(ctx)
+		metrics.LanguageRunDuration.ObserveSince(start, name, flagLanguage)
+		if err != nil {
+			metrics.LanguageRuns.Inc(name, flagLanguage, "failure")
+		} else {
+			metrics.LanguageRuns.Inc(name, flagLanguage, "success")
+		}
+		for _, step := range container.StepResults()[firstStep:] {
+			if step.Error != "" {
+				metrics.LanguageStepFailures.Inc(step.Step, flagLanguage)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/gcs"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/googleauth"
+)
+
+// commitHashPattern matches a full git commit hash.
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// cloneGoogleapisMirror returns a clone of googleapis from -googleapis-mirror,
+// checked out at the upstream commit after verifying the integrity of its
+// content, so that a stale, corrupt or tampered mirror can't silently change
+// what's generated.
+func cloneGoogleapisMirror(ctx context.Context, tmpRoot string) (*gitrepo.Repo, error) {
+	isSnapshot := strings.HasPrefix(flagGoogleapisMirror, "gs://")
+	if flagOffline && (isSnapshot || !isLocalRepoURL(flagGoogleapisMirror)) {
+		return nil, fmt.Errorf("googleapis mirror %s can't be accessed with -offline; specify a local path, or a local checkout with -api-root", flagGoogleapisMirror)
+	}
+	commit, err := upstreamGoogleapisCommit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var repo *gitrepo.Repo
+	switch {
+	case isSnapshot:
+		repo, err = downloadGoogleapisSnapshot(ctx, tmpRoot, flagGoogleapisMirror)
+	case flagCloneCache:
+		repo, err = cloneOrUpdateCached(ctx, cacheNameForURL(flagGoogleapisMirror), flagGoogleapisMirror, "")
+	default:
+		repo, err = gitrepo.CloneOrOpen(ctx, filepath.Join(tmpRoot, "googleapis"), flagGoogleapisMirror, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if commit == "" {
+		if commit, err = gitrepo.HeadHash(ctx, repo); err != nil {
+			return nil, err
+		}
+		slog.Warn(fmt.Sprintf("Using the latest commit of googleapis mirror %s, %s, which may be behind github.com; specify -googleapis-sha to pin the commit", flagGoogleapisMirror, commit))
+	}
+	slog.Info(fmt.Sprintf("Verifying googleapis commit %s from %s", commit, flagGoogleapisMirror))
+	if err := gitrepo.VerifyCommit(ctx, repo, commit); err != nil {
+		return nil, fmt.Errorf("googleapis mirror %s failed verification against upstream commit %s (it may not have synced that commit yet): %w", flagGoogleapisMirror, commit, err)
+	}
+	if err := gitrepo.ResetHardTo(ctx, repo, commit); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// upstreamGoogleapisCommit returns the commit of googleapis to check out from
+// -googleapis-mirror: the commit specified by -googleapis-sha, or otherwise
+// the latest commit on github.com. If github.com can't be reached (e.g. from
+// a network which only allows access to the mirror, or with -offline), an
+// empty string is returned, and the mirror's latest commit is used instead.
+func upstreamGoogleapisCommit(ctx context.Context) (string, error) {
+	if flagGoogleapisSHA != "" {
+		if !commitHashPattern.MatchString(flagGoogleapisSHA) {
+			return "", fmt.Errorf("invalid -googleapis-sha %q; must be a full commit hash", flagGoogleapisSHA)
+		}
+		return flagGoogleapisSHA, nil
+	}
+	if flagOffline {
+		return "", nil
+	}
+	commit, err := gitrepo.RemoteHeadHash(ctx, googleapisURL)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to find the latest upstream googleapis commit: %s", err))
+		return "", nil
+	}
+	return commit, nil
+}
+
+// downloadGoogleapisSnapshot downloads a snapshot of a googleapis clone from
+// Cloud Storage (a gzipped tarball with a single top-level directory) and
+// extracts it under tmpRoot. The snapshot is only trusted once verified.
+func downloadGoogleapisSnapshot(ctx context.Context, tmpRoot, snapshot string) (*gitrepo.Repo, error) {
+	location, err := gcs.ParseLocation(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if location.Prefix == "" {
+		return nil, fmt.Errorf("invalid googleapis snapshot %q; must be gs://bucket/object", snapshot)
+	}
+	token, err := googleauth.AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(tmpRoot, "googleapis")
+	slog.Info(fmt.Sprintf("Downloading googleapis snapshot %s to %q", snapshot, dir))
+	tarball, err := os.CreateTemp(tmpRoot, "googleapis-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tarball.Name())
+	defer tarball.Close()
+	if err := gcs.Download(ctx, token, location.Bucket, location.Prefix, tarball); err != nil {
+		return nil, fmt.Errorf("unable to download googleapis snapshot %s: %w", snapshot, err)
+	}
+	if _, err := tarball.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	if err := extractTarball(tarball, dir); err != nil {
+		return nil, fmt.Errorf("unable to extract googleapis snapshot %s: %w", snapshot, err)
+	}
+	return gitrepo.Open(ctx, dir)
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestCloneGoogleapisMirrorOfflineUsesMirrorHead(t *testing.T) {
+	ctx := context.Background()
+	apiRoot, _ := setUpScratchRepos(t)
+	mirror, err := gitrepo.Open(ctx, apiRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := gitrepo.HeadHash(ctx, mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flagGoogleapisMirror, flagGoogleapisSHA, flagOffline, flagCloneCache = apiRoot, "", true, false
+	t.Cleanup(func() { flagGoogleapisMirror, flagOffline = "", false })
+
+	repo, err := cloneGoogleapisMirror(ctx, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := gitrepo.HeadHash(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("cloned commit = %s; want mirror HEAD %s", got, want)
+	}
+}
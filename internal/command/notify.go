@@ -0,0 +1,132 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+)
+
+// maxNotificationErrorLength is the maximum length of the error included in a
+// notification; longer errors are truncated.
+const maxNotificationErrorLength = 1000
+
+// notify wraps the Run function of a command so that, when -notify-webhook is
+// specified, the result of each run is posted to the webhook: failures always,
+// and successes too if -notify-on is "always". The message is posted as
+// {"text": "..."}, which is understood by both Google Chat and Slack incoming
+// webhooks. A failure to notify is logged but doesn't affect the result.
+func notify(c *Command, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if flagNotifyWebhook == "" {
+			return run(ctx)
+		}
+		switch flagNotifyOn {
+		case "failure", "always":
+		default:
+			return fmt.Errorf("invalid -notify-on: %q; must be failure or always", flagNotifyOn)
+		}
+		ciResults.mu.Lock()
+		firstPullRequest := len(ciResults.pullRequests)
+		ciResults.mu.Unlock()
+		err := run(ctx)
+		if ctx.Err() != nil || (err == nil && flagNotifyOn != "always") {
+			return err
+		}
+		ciResults.mu.Lock()
+		pullRequests := slices.Clone(ciResults.pullRequests[firstPullRequest:])
+		ciResults.mu.Unlock()
+		if notifyErr := postNotification(ctx, notificationText(c.Name, err, pullRequests)); notifyErr != nil {
+			slog.Warn(fmt.Sprintf("Unable to post notification: %s", notifyErr))
+		}
+		return err
+	}
+}
+
+// notificationText returns the text of the notification of a run, which
+// created the given pull requests.
+func notificationText(command string, runErr error, pullRequests []string) string {
+	var text strings.Builder
+	subject := fmt.Sprintf("librarian %s", command)
+	if flagLanguage != "" {
+		subject += fmt.Sprintf(" (%s)", flagLanguage)
+	}
+	if flagAPIPath != "" {
+		subject += fmt.Sprintf(" for %s", flagAPIPath)
+	}
+	if runErr == nil {
+		fmt.Fprintf(&text, "%s succeeded\n", subject)
+	} else {
+		fmt.Fprintf(&text, "%s failed", subject)
+		var reported *container.Error
+		if errors.As(runErr, &reported) && reported.Code != "" {
+			fmt.Fprintf(&text, " (%s)", reported.Code)
+		}
+		message := runErr.Error()
+		if len(message) > maxNotificationErrorLength {
+			message = message[:maxNotificationErrorLength] + "..."
+		}
+		fmt.Fprintf(&text, "\n```\n%s\n```\n", message)
+	}
+
+	for _, url := range pullRequests {
+		fmt.Fprintf(&text, "Pull request: %s\n", url)
+	}
+	if url := ciRunURL(); url != "" {
+		fmt.Fprintf(&text, "CI run: %s\n", url)
+	}
+	if url := artifactsURL(); url != "" {
+		fmt.Fprintf(&text, "Logs and output: %s\n", url)
+	}
+	return strings.TrimSuffix(text.String(), "\n")
+}
+
+// postNotification posts text to the -notify-webhook URL.
+func postNotification(ctx context.Context, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	// The run's context may be close to its deadline; the notification is
+	// given its own timeout.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, flagNotifyWebhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// configureOffline applies -offline: it rejects flags which can only work
+// with network access, and disables network access by HTTP requests, git
+// operations and image pulls, so that anything which still requires it fails
+// immediately with a clear error rather than timing out.
+func configureOffline() error {
+	if !flagOffline {
+		return nil
+	}
+	networkFlags := []struct {
+		name string
+		set  bool
+	}{
+		{"-push", flagPush},
+		{"-api-tarball", flagAPITarball != ""},
+		{"-image-channel", flagImageChannel != ""},
+		{"-artifact-bucket", flagArtifactBucket != ""},
+		{"-notify-webhook", flagNotifyWebhook != ""},
+		{"-clone-cache", flagCloneCache},
+		{"-output-cache (in Cloud Storage)", strings.HasPrefix(flagOutputCache, "gs://")},
+	}
+	var conflicts []string
+	for _, f := range networkFlags {
+		if f.set {
+			conflicts = append(conflicts, f.name)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%s cannot be used with -offline, as network access is required", strings.Join(conflicts, ", "))
+	}
+	http.DefaultTransport = offlineTransport{}
+	gitrepo.SetOffline(true)
+	container.SetOffline(true)
+	return nil
+}
+
+// offlineTransport is the HTTP transport used by all clients with -offline.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, fmt.Errorf("network access is disabled by -offline: unable to access %s", req.URL.Host)
+}
+
+// isLocalRepoURL reports whether the repo URL is a local path or file:// URL,
+// and so can be cloned with -offline.
+func isLocalRepoURL(repoURL string) bool {
+	return strings.HasPrefix(repoURL, "file://") || filepath.IsAbs(repoURL) || strings.HasPrefix(repoURL, ".")
+}
@@ -0,0 +1,29 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigureOfflineRejectsCloudStorageOutputCache(t *testing.T) {
+	flagOffline, flagOutputCache = true, "gs://bucket/cache"
+	t.Cleanup(func() { flagOffline, flagOutputCache = false, "" })
+	err := configureOffline()
+	if err == nil || !strings.Contains(err.Error(), "-output-cache") {
+		t.Errorf("configureOffline() = %v; want an error for -output-cache", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// languageSourceExtensions are the file extensions of source files in each
+// language. Generated output for an API is expected to include at least one.
+var languageSourceExtensions = map[string][]string{
+	"cpp":    {".cc", ".h"},
+	"dotnet": {".cs"},
+	"go":     {".go"},
+	"java":   {".java"},
+	"node":   {".js", ".ts"},
+	"php":    {".php"},
+	"python": {".py"},
+	"ruby":   {".rb"},
+	"rust":   {".rs"},
+}
+
+// checkGenerateOutput checks that the generate container wrote something
+// useful for apiPath to outputDir: at least one file, including at least one
+// source file for the language. A container which exits successfully without
+// generating anything would otherwise cause the API's generated code to be
+// deleted (by clean) and the deletion committed.
+func checkGenerateOutput(outputDir, language, apiPath string) error {
+	files, sources := 0, 0
+	extensions := languageSourceExtensions[language]
+	if configured := languageConfigs[language].SourceExtensions; configured != nil {
+		extensions = configured
+	}
+	err := filepath.WalkDir(outputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		files++
+		if slices.Contains(extensions, strings.ToLower(filepath.Ext(path))) {
+			sources++
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	switch {
+	case files == 0:
+		return fmt.Errorf("generate for '%s' succeeded but produced no output", apiPath)
+	case len(extensions) > 0 && sources == 0:
+		return fmt.Errorf("generate for '%s' succeeded but produced no %s source files (expected %s)", apiPath, language, strings.Join(extensions, " or "))
+	}
+	return nil
+}
+
+// prepareOutputDir returns the output directory for a command which generates
+// code: the directory specified by -output, or the "output" directory in
+// tmpRoot. The directory is created if necessary. A non-empty directory is
+// only used if -force is specified, in which case its contents are deleted
+// first, so that stale files from an earlier run never mix with the new
+// output. Even with -force, a git repository (e.g. a language repo specified
+// by mistake) is never cleared.
+func prepareOutputDir(tmpRoot string) (string, error) {
+	outputDir := filepath.Join(tmpRoot, "output")
+	if flagOutput != "" {
+		var err error
+		if outputDir, err = filepath.Abs(flagOutput); err != nil {
+			return "", err
+		}
+	} else {
+		slog.Info(fmt.Sprintf("No output directory specified. Defaulting to %s", outputDir))
+	}
+	entries, err := os.ReadDir(outputDir)
+	if os.IsNotExist(err) {
+		return outputDir, os.MkdirAll(outputDir, 0755)
+	}
+	if err != nil || len(entries) == 0 {
+		return outputDir, err
+	}
+	if !flagForce {
+		return "", fmt.Errorf("output directory %s is not empty; specify -force to delete its contents", outputDir)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, ".git")); err == nil {
+		return "", fmt.Errorf("output directory %s is a git repository, so will not be cleared", outputDir)
+	}
+	slog.Warn(fmt.Sprintf("Deleting the contents of output directory %s", outputDir))
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(outputDir, entry.Name())); err != nil {
+			return "", err
+		}
+	}
+	return outputDir, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/gcs"
+	"github.com/googleapis/librarian/internal/googleauth"
+)
+
+// generateWithOutputCache runs generate, which writes the generated code for
+// an API to outputDir, using the output cache specified by -output-cache (a
+// directory, or a Cloud Storage location of the form gs://bucket/prefix).
+// The cache is keyed by the API's input hash (see generationInputHash), which
+// covers the image digest, API path, API files and generation options, so
+// that repeated runs (e.g. on other machines, or retried CI jobs) restore the
+// output instead of regenerating it. The cache isn't used without
+// -output-cache or when the input hash is empty. Failing to read from or write
+// to the cache is logged rather than failing generation.
+func generateWithOutputCache(ctx context.Context, apiPath, inputHash, outputDir string, generate func() error) error {
+	if flagOutputCache == "" || inputHash == "" {
+		return generate()
+	}
+	restored, err := restoreCachedOutput(ctx, inputHash, outputDir)
+	switch {
+	case err != nil:
+		slog.Warn(fmt.Sprintf("Unable to read the output cache for '%s': %s", apiPath, err))
+	case restored:
+		slog.Info(fmt.Sprintf("Restored the output of '%s' from the output cache (%s)", apiPath, inputHash))
+		return nil
+	}
+	if err := generate(); err != nil {
+		return err
+	}
+	if err := storeCachedOutput(ctx, inputHash, outputDir); err != nil {
+		slog.Warn(fmt.Sprintf("Unable to write the output of '%s' to the output cache: %s", apiPath, err))
+	}
+	return nil
+}
+
+// outputCacheEntryName returns the name of the cache entry for an input hash.
+func outputCacheEntryName(inputHash string) string {
+	return inputHash + ".tar.gz"
+}
+
+// restoreCachedOutput extracts the cache entry for inputHash into outputDir,
+// reporting whether there was such an entry. Anything partially extracted from
+// an invalid entry is removed.
+func restoreCachedOutput(ctx context.Context, inputHash, outputDir string) (bool, error) {
+	var archivePath string
+	if strings.HasPrefix(flagOutputCache, "gs://") {
+		tmp, err := os.CreateTemp("", "librarian-output-cache-")
+		if err != nil {
+			return false, err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		location, err := gcs.ParseLocation(flagOutputCache)
+		if err != nil {
+			return false, err
+		}
+		token, err := googleauth.AccessToken(ctx)
+		if err != nil {
+			return false, err
+		}
+		err = gcs.Download(ctx, token, location.Bucket, location.Object(outputCacheEntryName(inputHash)), tmp)
+		if errors.Is(err, gcs.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		archivePath = tmp.Name()
+	} else {
+		archivePath = filepath.Join(flagOutputCache, outputCacheEntryName(inputHash))
+	}
+
+	archive, err := os.Open(archivePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer archive.Close()
+	if err := extractArchive(archive, outputDir); err != nil {
+		if cleanErr := clearDir(outputDir); cleanErr != nil {
+			return false, cleanErr
+		}
+		return false, fmt.Errorf("invalid cache entry %s: %w", outputCacheEntryName(inputHash), err)
+	}
+	return true, nil
+}
+
+// storeCachedOutput writes the content of outputDir as the cache entry for
+// inputHash.
+func storeCachedOutput(ctx context.Context, inputHash, outputDir string) error {
+	if !strings.HasPrefix(flagOutputCache, "gs://") {
+		return writeArchive(outputDir, filepath.Join(flagOutputCache, outputCacheEntryName(inputHash)))
+	}
+	location, err := gcs.ParseLocation(flagOutputCache)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "librarian-output-cache-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	archivePath := filepath.Join(tmpDir, outputCacheEntryName(inputHash))
+	if err := writeArchive(outputDir, archivePath); err != nil {
+		return err
+	}
+	token, err := googleauth.AccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	return gcs.UploadFile(ctx, token, location.Bucket, location.Object(outputCacheEntryName(inputHash)), archivePath)
+}
+
+// clearDir removes the contents of dir, leaving dir itself in place.
+func clearDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"sync"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/metrics"
+)
+
+// pipelinedGeneration is the result of generating an API in updateApisPipelined.
+type pipelinedGeneration struct {
+	err  error
+	done chan struct{}
+}
+
+// updateApisPipelined regenerates the planned APIs (in order) with the steps
+// of different APIs overlapping: up to -parallel-generate APIs are generated
+// at once, into their own output directories, while the generated code of
+// earlier APIs is applied to the language repo (cleaned, copied, committed and
+// built). As those steps share the language repo, only one API is applied at
+// a time, in the original order, so the commits are the same as when each API
+// is processed in turn. Generation never gets more than -parallel-generate
+// APIs ahead of the API being applied. On the first failure, generation is
+// cancelled and the error is returned once all generation has stopped.
+//
+// All planning (which reads the googleapis repo) happens before this is
+// called, so that git is never used concurrently.
+func updateApisPipelined(ctx context.Context, apiRepo *gitrepo.Repo, result *generationResult, generatorInput string, updates []*apiUpdate) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	generations := make([]*pipelinedGeneration, len(updates))
+	for i := range generations {
+		generations[i] = &pipelinedGeneration{done: make(chan struct{})}
+	}
+	// A slot is acquired before each API is generated, and released once it
+	// has been applied.
+	slots := make(chan struct{}, flagParallelGenerate)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, update := range updates {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				for _, generation := range generations[i:] {
+					generation.err = ctx.Err()
+					close(generation.done)
+				}
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(generations[i].done)
+				generations[i].err = update.generate(ctx, result.containerOpts, result.apiRoot, generatorInput)
+			}()
+		}
+	}()
+
+	for i, update := range updates {
+		metrics.QueueDepth.Set(float64(len(updates) - i))
+		<-generations[i].done
+		if err := generations[i].err; err != nil {
+			return err
+		}
+		if err := applyApiUpdate(ctx, apiRepo, result, update); err != nil {
+			return err
+		}
+		<-slots
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// generationResult describes the changes made to a language repo during a
+// single run, for use when pushing them and creating a pull request.
+type generationResult struct {
+	repo          *gitrepo.Repo
+	state         *statepb.PipelineState
+	containerOpts *container.Options
+	startOfRun    time.Time
+	// baseHash is the HEAD commit of the language repo before any changes were made.
+	baseHash string
+	// apiHash is the HEAD commit of the googleapis repo the APIs were
+	// generated from, if known.
+	apiHash string
+	// apiRoot is the API tree generated from by update-apis: the directory of
+	// the API repo, or its combination with googleapis if it's a local proto
+	// tree.
+	apiRoot string
+	// imageDigest is the digest of the image, if known, for comparing the
+	// inputs of each API with those it was last generated from.
+	imageDigest string
+	// title is the title of the pull request, if the default isn't appropriate.
+	title string
+	// summary is the first paragraph of the pull request body, if the default
+	// (describing the regenerated APIs) isn't appropriate.
+	summary string
+	// commitRanges describes the googleapis commits included for each API.
+	commitRanges []apiCommitRange
+	// changelog lists the googleapis commits included for each API.
+	changelog []changelogEntry
+	// apiCommits are the commits made for each regenerated API, in order.
+	apiCommits []apiCommit
+	// commitTemplate renders the commit message for each regenerated API, if
+	// -commit-message-template is specified.
+	commitTemplate *template.Template
+	// builds runs the build of each API's commit in a worktree, with
+	// -parallel-build. When nil, each API is built in the language repo.
+	builds *worktreeBuilds
+}
+
+// apiCommit is a commit made in the language repo for a regenerated API.
+type apiCommit struct {
+	APIPath string
+	Hash    string
+	Message string
+}
+
+// apiCommitRange describes the googleapis commits included when generating an API.
+type apiCommitRange struct {
+	APIPath string
+	// From is the commit the API was previously generated from, if any.
+	From string
+	// To is the commit the API has now been generated from.
+	To string
+}
+
+// dirDiffStat summarizes the changes within a single directory.
+type dirDiffStat struct {
+	Dir       string
+	Files     int
+	Additions int
+	Deletions int
+}
+
+// pullRequestData is the data available to pull request body templates.
+type pullRequestData struct {
+	// APIPath is the API path (or comma-separated paths) the run was restricted
+	// to, or "all".
+	APIPath string
+	// Summary describes the change, if it's not a regeneration of APIPath.
+	Summary     string
+	Image       string
+	ImageDigest string
+	Commits     []apiCommitRange
+	Changelog   []changelogSection
+	DiffStats   []dirDiffStat
+	LogTail     string
+	// Marker identifies pull requests created by librarian for the API path.
+	// It's always included in the body, even if a custom template omits it.
+	Marker string
+}
+
+const defaultPullRequestTemplate = `{{with .Summary}}{{.}}{{else}}Regenerated {{if eq .APIPath "all"}}all changed APIs{{else}}{{.APIPath}}{{end}}. See individual commits for details.{{end}}
+{{with .Commits}}
+## googleapis changes
+{{range .}}
+- {{.APIPath}}: {{if .From}}[{{short .From}}...{{short .To}}](https://github.com/googleapis/googleapis/compare/{{.From}}...{{.To}}){{else}}[{{short .To}}](https://github.com/googleapis/googleapis/commit/{{.To}}){{end}}{{end}}
+{{end}}{{with .Changelog}}
+## Changelog
+{{range .}}
+### {{.Title}}
+{{range .Entries}}
+- {{.APIPath}}: {{.Description}} ([{{short .Commit}}](https://github.com/googleapis/googleapis/commit/{{.Commit}})){{end}}
+{{end}}{{end}}
+## Generator
+
+- Image: ` + "`{{.Image}}`" + `{{with .ImageDigest}}
+- Digest: ` + "`{{.}}`" + `{{end}}
+{{with .DiffStats}}
+## Changes by directory
+
+| Directory | Files | Additions | Deletions |
+|---|---|---|---|
+{{range .}}| {{.Dir}} | {{.Files}} | +{{.Additions}} | -{{.Deletions}} |
+{{end}}{{end}}{{with .LogTail}}
+<details><summary>Container log (tail)</summary>
+
+{{codeBlock .}}
+</details>
+{{end}}
+{{.Marker}}
+`
+
+// createPullRequestBody renders the body of a pull request for a generation
+// result, using the template specified by -pr-body-template or a default
+// template including the googleapis commits, a changelog grouped by
+// conventional commit type, the image and diff statistics.
+func createPullRequestBody(ctx context.Context, result *generationResult) (string, error) {
+	text := defaultPullRequestTemplate
+	if flagPRBodyTemplate != "" {
+		content, err := os.ReadFile(flagPRBodyTemplate)
+		if err != nil {
+			return "", err
+		}
+		text = string(content)
+	}
+	tmpl, err := template.New("body").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid pull request body template: %w", err)
+	}
+
+	data := &pullRequestData{
+		APIPath:   apiScope(),
+		Summary:   result.summary,
+		Image:     result.containerOpts.Image,
+		Commits:   result.commitRanges,
+		Changelog: buildChangelog(result.changelog),
+		LogTail:   result.containerOpts.LogTail.Lines(40),
+		Marker:    pullRequestMarker(),
+	}
+	if data.ImageDigest, err = container.ImageDigest(ctx, result.containerOpts); err != nil {
+		slog.Warn(fmt.Sprintf("Unable to determine image digest: %s", err))
+	}
+	stats, err := gitrepo.DiffStatsSince(ctx, result.repo, result.baseHash)
+	if err != nil {
+		return "", err
+	}
+	data.DiffStats = summarizeDiffStats(stats)
+
+	var body strings.Builder
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", fmt.Errorf("unable to render pull request body: %w", err)
+	}
+	if !strings.Contains(body.String(), data.Marker) {
+		fmt.Fprintf(&body, "\n\n%s\n", data.Marker)
+	}
+	return body.String(), nil
+}
+
+// summarizeDiffStats groups file statistics by directory, using at most the
+// first two path segments, so that each library is typically summarized on a
+// single line.
+func summarizeDiffStats(stats []gitrepo.FileStat) []dirDiffStat {
+	byDir := map[string]*dirDiffStat{}
+	for _, stat := range stats {
+		segments := strings.Split(stat.Name, "/")
+		dir := "/"
+		if len(segments) > 1 {
+			dir = strings.Join(segments[:min(len(segments)-1, 2)], "/")
+		}
+		dirStat, ok := byDir[dir]
+		if !ok {
+			dirStat = &dirDiffStat{Dir: dir}
+			byDir[dir] = dirStat
+		}
+		dirStat.Files++
+		dirStat.Additions += stat.Additions
+		dirStat.Deletions += stat.Deletions
+	}
+	var summary []dirDiffStat
+	for _, dirStat := range byDir {
+		summary = append(summary, *dirStat)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Dir < summary[j].Dir })
+	return summary
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// diffSummaryMarker identifies the diff summary comment on a pull request, so
+// that it's updated rather than duplicated when the pull request is updated.
+const diffSummaryMarker = "<!-- librarian:diff-summary -->"
+
+// postDiffSummaryComment posts (or updates) a comment on a generated pull
+// request summarizing the changes since result.baseHash by directory, any
+// breaking changes in the googleapis commits included, and links to the logs
+// of the run. A failure is logged rather than failing the run, as the pull
+// request itself has been created successfully.
+func postDiffSummaryComment(ctx context.Context, forge gitrepo.Forge, pr *gitrepo.PullRequest, result *generationResult) {
+	body, err := diffSummaryComment(ctx, result)
+	if err == nil {
+		err = forge.UpsertComment(ctx, pr, diffSummaryMarker, body)
+	}
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to post diff summary comment on %s: %s", pr.HTMLURL, err))
+	}
+}
+
+// diffSummaryComment renders the body of the diff summary comment.
+func diffSummaryComment(ctx context.Context, result *generationResult) (string, error) {
+	stats, err := gitrepo.DiffStatsSince(ctx, result.repo, result.baseHash)
+	if err != nil {
+		return "", err
+	}
+	var body strings.Builder
+	body.WriteString("## Generation summary\n\n")
+
+	var files, additions, deletions int
+	for _, stat := range stats {
+		files++
+		additions += stat.Additions
+		deletions += stat.Deletions
+	}
+	fmt.Fprintf(&body, "%d files changed (+%d, -%d).\n\n", files, additions, deletions)
+	if dirStats := summarizeDiffStats(stats); len(dirStats) > 0 {
+		body.WriteString("| Directory | Files | Additions | Deletions |\n|---|---|---|---|\n")
+		for _, dirStat := range dirStats {
+			fmt.Fprintf(&body, "| `%s` | %d | +%d | -%d |\n", dirStat.Dir, dirStat.Files, dirStat.Additions, dirStat.Deletions)
+		}
+		body.WriteString("\n")
+	}
+
+	var breaking []changelogEntry
+	for _, section := range buildChangelog(result.changelog) {
+		for _, entry := range section.Entries {
+			if entry.Breaking {
+				breaking = append(breaking, entry)
+			}
+		}
+	}
+	if len(breaking) > 0 {
+		body.WriteString(":warning: **Breaking changes**\n\n")
+		for _, entry := range breaking {
+			fmt.Fprintf(&body, "- %s: %s ([%s](https://github.com/googleapis/googleapis/commit/%s))\n",
+				entry.APIPath, entry.Description, entry.Commit[:min(len(entry.Commit), 7)], entry.Commit)
+		}
+		body.WriteString("\n")
+	} else {
+		body.WriteString("No breaking changes detected in the googleapis commits.\n\n")
+	}
+
+	var logs []string
+	if url := ciRunURL(); url != "" {
+		logs = append(logs, fmt.Sprintf("[CI run](%s)", url))
+	}
+	if url := artifactsURL(); url != "" {
+		logs = append(logs, fmt.Sprintf("[output and container logs](%s)", url))
+	}
+	if len(logs) > 0 {
+		fmt.Fprintf(&body, "Logs: %s\n\n", strings.Join(logs, ", "))
+	}
+	body.WriteString(diffSummaryMarker + "\n")
+	return body.String(), nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/container"
+)
+
+// preflight checks that the binaries required by the command are available,
+// and that there's sufficient free disk space in the locations the command
+// writes to, so that commands fail early with a clear message rather than
+// part way through a long-running pipeline. (Git operations don't require
+// a git binary.)
+func preflight() error {
+	var problems []string
+	var binaries []string
+	switch flagBackend {
+	case "docker", "":
+		if containerRunner == nil && newContainerRunner() == nil {
+			binaries = append(binaries, "docker")
+		}
+	case "local":
+		if flagReplayContainers == "" {
+			binaries = append(binaries, container.LocalCommand(deriveImage(nil)))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("invalid -backend %q; must be docker or local", flagBackend))
+	}
+	if flagSignCommits != "" {
+		binaries = append(binaries, flagSignCommits)
+	}
+	if flagParallelBuild > 0 || (flagPush && flagOnExistingPR == "update" && flagUpdateCommits == "amend") {
+		binaries = append(binaries, "git")
+	}
+	for _, binary := range binaries {
+		if _, err := exec.LookPath(binary); err != nil {
+			problems = append(problems, fmt.Sprintf("required binary %q not found on PATH", binary))
+		}
+	}
+
+	for _, env := range flagContainerEnv {
+		if key, _, _ := strings.Cut(env, "="); key == "" {
+			problems = append(problems, fmt.Sprintf("invalid -container-env %q; must be KEY=VALUE or KEY", env))
+		}
+	}
+	for _, volume := range flagContainerCache {
+		name, path, _ := strings.Cut(volume, ":")
+		if name == "" || !strings.HasPrefix(path, "/") || strings.ContainsAny(name, `/\`) {
+			problems = append(problems, fmt.Sprintf("invalid -container-cache %q; must be volume-name:container-path", volume))
+		}
+	}
+	if flagContainerSecrets != "" {
+		if info, err := os.Stat(flagContainerSecrets); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("-container-secrets %s is not a directory", flagContainerSecrets))
+		}
+	}
+
+	if flagMinFreeDiskGB > 0 {
+		locations := []string{os.TempDir()}
+		if flagWorkRoot != "" {
+			locations = append(locations, flagWorkRoot)
+		}
+		if flagOutput != "" {
+			locations = append(locations, flagOutput)
+		}
+		if flagCloneCache {
+			if cacheDir, err := os.UserCacheDir(); err == nil {
+				locations = append(locations, cacheDir)
+			}
+		}
+		for _, location := range locations {
+			free, err := freeDiskSpace(nearestExistingDir(location))
+			if errors.Is(err, errors.ErrUnsupported) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if gb := free >> 30; gb < uint64(flagMinFreeDiskGB) {
+				problems = append(problems, fmt.Sprintf("only %dGB of disk space free for %s; at least %dGB required (see -min-free-disk-gb)", gb, location, flagMinFreeDiskGB))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("preflight checks failed:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// nearestExistingDir returns path if it exists, or otherwise its nearest
+// existing ancestor.
+func nearestExistingDir(path string) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
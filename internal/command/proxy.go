@@ -0,0 +1,98 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+)
+
+// proxyEnvVars are the environment variables which configure HTTP(S)
+// proxies. They're honored by Go's HTTP client (and so by go-git and the
+// GitHub, GitLab and Gerrit clients), by git and the gh CLI, and by most
+// language toolchains, so they're the single source of proxy configuration
+// for everything librarian runs. Some tools only read one case of each.
+var proxyEnvVars = []string{
+	"HTTPS_PROXY", "https_proxy",
+	"HTTP_PROXY", "http_proxy",
+	"NO_PROXY", "no_proxy",
+}
+
+// configureProxy applies -proxy by setting the proxy environment variables,
+// so that it's used both by requests made by this process and by the
+// processes it runs. It must be called before any HTTP requests are made, as
+// Go's HTTP client only reads the environment once.
+//
+// Images are pulled by the docker daemon, which has its own proxy
+// configuration, so -proxy doesn't apply to pulls.
+func configureProxy() error {
+	if flagProxy != "" {
+		proxyURL, err := url.Parse(flagProxy)
+		if err != nil || proxyURL.Host == "" || !slices.Contains([]string{"http", "https", "socks5"}, proxyURL.Scheme) {
+			return fmt.Errorf("invalid -proxy %q; must be an http, https or socks5 URL such as http://proxy.example.com:3128", flagProxy)
+		}
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+			if err := os.Setenv(name, flagProxy); err != nil {
+				return err
+			}
+		}
+	}
+	if flagBackend != "local" && flagContainerNetwork != "host" {
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+			if value := os.Getenv(name); isLoopbackProxy(value) {
+				slog.Warn(fmt.Sprintf("Proxy %s (from %s) is on the loopback interface, so language containers can't reach it; use an address reachable from containers (e.g. host.docker.internal), or -container-network=host", value, name))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// isLoopbackProxy returns whether the proxy URL value refers to the loopback
+// interface, which is the container itself rather than the host when used
+// from a container.
+func isLoopbackProxy(value string) bool {
+	proxyURL, err := url.Parse(value)
+	if err != nil || proxyURL.Host == "" {
+		// Proxy environment variables may omit the scheme.
+		if proxyURL, err = url.Parse("http://" + value); err != nil {
+			return false
+		}
+	}
+	host := proxyURL.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// containerProxyEnv returns the names of the proxy environment variables
+// which are set, to pass their values through to language containers (see
+// container.DockerConfig.Env) so that steps with network access, such as
+// build, use the same proxy.
+func containerProxyEnv() []string {
+	var env []string
+	for _, name := range proxyEnvVars {
+		if _, ok := os.LookupEnv(name); ok {
+			env = append(env, name)
+		}
+	}
+	return env
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import "testing"
+
+func TestIsLoopbackProxy(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		want  bool
+	}{
+		{"http://localhost:3128", true},
+		{"http://127.0.0.1:3128", true},
+		{"socks5://[::1]:1080", true},
+		{"127.0.0.1:3128", true},
+		{"http://proxy.example.com:3128", false},
+		{"http://host.docker.internal:3128", false},
+		{"proxy.example.com:3128", false},
+		{"", false},
+	} {
+		if got := isLoopbackProxy(test.value); got != test.want {
+			t.Errorf("isLoopbackProxy(%q) = %v; want %v", test.value, got, test.want)
+		}
+	}
+}
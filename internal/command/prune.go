@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// tmpRootPrefix is the prefix of temporary working directories created
+	// under os.TempDir, which is followed by a timestamp.
+	tmpRootPrefix = "librarian-"
+	// tmpRootTimestampFormat is the format of the timestamp in temporary
+	// working directory names.
+	tmpRootTimestampFormat = "20060102T150405"
+)
+
+var CmdPrune = &Command{
+	Name:  "prune",
+	Short: "Delete old temporary working directories, including their output and clones",
+	Run: func(ctx context.Context) error {
+		if flagMaxAgeDays < 0 {
+			return fmt.Errorf("-max-age-days must not be negative")
+		}
+		return pruneTmpWorkingRoots(time.Duration(flagMaxAgeDays)*24*time.Hour, flagDryRun)
+	},
+}
+
+// pruneTmpWorkingRoots deletes the temporary working directories created by
+// createTmpWorkingRoot which are older than maxAge, based on the timestamp in
+// their names. Other directories are never deleted.
+func pruneTmpWorkingRoots(maxAge time.Duration, dryRun bool) error {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		timestamp, ok := strings.CutPrefix(entry.Name(), tmpRootPrefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		created, err := time.ParseInLocation(tmpRootTimestampFormat, timestamp, time.Local)
+		if err != nil || !created.Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(os.TempDir(), entry.Name())
+		if dryRun {
+			slog.Info(fmt.Sprintf("Would delete %s", path))
+			continue
+		}
+		slog.Info(fmt.Sprintf("Deleting %s", path))
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
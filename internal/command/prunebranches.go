@@ -0,0 +1,96 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+var CmdPruneBranches = &Command{
+	Name:  "prune-branches",
+	Short: "Delete stale generated branches from a language repo",
+	Run: func(ctx context.Context) error {
+		if !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		if err := resolveGitHubToken(ctx); err != nil {
+			return err
+		}
+		if flagGitHubToken == "" {
+			return fmt.Errorf("-github-token must be provided")
+		}
+		if flagMaxAgeDays < 0 {
+			return fmt.Errorf("-max-age-days must not be negative")
+		}
+
+		gitHubRepo, err := gitrepo.ParseGitHubURL(languageRepoURL(flagLanguage))
+		if err != nil {
+			return err
+		}
+		branches, err := gitrepo.ListBranches(ctx, gitHubRepo, flagGitHubToken, generatedBranchPrefix)
+		if err != nil {
+			return err
+		}
+
+		cutoff := time.Now().AddDate(0, 0, -flagMaxAgeDays)
+		for _, branch := range branches {
+			reason := pruneReason(branch, cutoff)
+			if reason == "" {
+				if branch.PullRequestState == "open" {
+					slog.Info(fmt.Sprintf("Keeping branch %s with open pull request", branch.Name))
+				}
+				continue
+			}
+			if flagDryRun {
+				slog.Info(fmt.Sprintf("Would delete branch %s (%s)", branch.Name, reason))
+				continue
+			}
+			slog.Info(fmt.Sprintf("Deleting branch %s (%s)", branch.Name, reason))
+			if err := gitrepo.DeleteBranch(ctx, gitHubRepo, flagGitHubToken, branch.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// pruneReason returns why the branch should be deleted, or an empty string if
+// it should be kept. Branches of merged pull requests are deleted immediately.
+// Branches of pull requests which were closed without being merged are kept
+// until cutoff, so that a pull request closed by mistake can be reopened, as
+// are branches without a pull request. Branches with an open pull request are
+// always kept.
+func pruneReason(branch *gitrepo.BranchInfo, cutoff time.Time) string {
+	switch branch.PullRequestState {
+	case "merged":
+		return "pull request merged"
+	case "open":
+		return ""
+	case "closed":
+		if branch.PullRequestClosed.Before(cutoff) {
+			return fmt.Sprintf("pull request closed at %s", branch.PullRequestClosed.Format(time.RFC3339))
+		}
+		return ""
+	}
+	if branch.LastCommit.Before(cutoff) {
+		return fmt.Sprintf("last commit at %s", branch.LastCommit.Format(time.RFC3339))
+	}
+	return ""
+}
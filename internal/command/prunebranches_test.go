@@ -0,0 +1,44 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestPruneReason(t *testing.T) {
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -30)
+	recent, old := now.AddDate(0, 0, -1), now.AddDate(0, 0, -60)
+	for _, test := range []struct {
+		name   string
+		branch *gitrepo.BranchInfo
+		prune  bool
+	}{
+		{"merged recently", &gitrepo.BranchInfo{PullRequestState: "merged", LastCommit: recent, PullRequestClosed: recent}, true},
+		{"closed recently", &gitrepo.BranchInfo{PullRequestState: "closed", LastCommit: old, PullRequestClosed: recent}, false},
+		{"closed long ago", &gitrepo.BranchInfo{PullRequestState: "closed", LastCommit: old, PullRequestClosed: old}, true},
+		{"open", &gitrepo.BranchInfo{PullRequestState: "open", LastCommit: old}, false},
+		{"no pull request, recent", &gitrepo.BranchInfo{LastCommit: recent}, false},
+		{"no pull request, old", &gitrepo.BranchInfo{LastCommit: old}, true},
+	} {
+		if got := pruneReason(test.branch, cutoff); (got != "") != test.prune {
+			t.Errorf("%s: pruneReason() = %q; want pruned: %v", test.name, got, test.prune)
+		}
+	}
+}
@@ -0,0 +1,528 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// releaseCommitPrefix starts the message of each commit preparing a release.
+// The full prefix for a library is given by releaseCommitMessagePrefix.
+const releaseCommitPrefix = "chore: Release "
+
+// releasePullRequestMarker identifies release pull requests created by librarian.
+const releasePullRequestMarker = "<!-- librarian:release -->"
+
+// releaseTagFormats are the formats of release tags in each language repo, in
+// which "{id}" and "{version}" are replaced by the library ID and version.
+// Languages without an entry (or a configured format; see languageConfig) use
+// defaultReleaseTagFormat.
+var releaseTagFormats = map[string]string{
+	"dotnet": "{id}-{version}",
+	"ruby":   "{id}/v{version}",
+}
+
+const defaultReleaseTagFormat = "{id}-v{version}"
+
+// releaseVersionPattern matches the versions which can be incremented
+// automatically: plain major.minor.patch versions without a suffix.
+var releaseVersionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)$`)
+
+// CmdRelease orchestrates releases of the libraries in a language repo, driven
+// by the library release states in the pipeline state.
+//
+// The "prepare" action determines which libraries have changed since their last
+// release, runs the language container's prepare-release command for each of
+// them, and commits the result (one commit per library), creating a release
+// pull request if -push is specified.
+//
+// The "tag" action is run after a release pull request has been merged. It
+// finds the most recent release commit for each library, and creates a GitHub
+// release (and tag) for it, unless one already exists. Tag names follow the
+// conventions of each language repo (see releaseTagFormats).
+//
+// The "publish" action builds and publishes the packages for each library
+// released by the HEAD commit of the language repo (or for the most recent
+// release of the library specified by -library-id), using the language
+// container's publish command. The repo is built once for each release
+// commit, however many libraries it releases. It requires -publish-credentials.
+var CmdRelease = &Command{
+	Name:  "release",
+	Short: "Prepare, tag or publish releases of changed libraries: release prepare|tag|publish",
+	// Run is set in init, as it refers to CmdRelease.
+}
+
+func runRelease(ctx context.Context) error {
+	// The action is positional, but flags may appear before or after it.
+	args := CmdRelease.flags.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("release requires an action: prepare, tag or publish")
+	}
+	action := args[0]
+	if err := CmdRelease.flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	switch action {
+	case "prepare":
+		return prepareReleases(ctx)
+	case "tag":
+		return tagReleases(ctx)
+	case "publish":
+		return publishReleases(ctx)
+	default:
+		return fmt.Errorf("invalid release action: %q; must be prepare, tag or publish", action)
+	}
+}
+
+// libraryRelease describes a release prepared for a single library.
+type libraryRelease struct {
+	ID      string
+	Version string
+	// Changes are the subject lines of the commits included in the release,
+	// most recent first.
+	Changes []string
+}
+
+func prepareReleases(ctx context.Context) error {
+	if !supportedLanguages[flagLanguage] {
+		return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+	}
+	if err := validatePushFlags(ctx); err != nil {
+		return err
+	}
+	if err := preflight(); err != nil {
+		return err
+	}
+	release, err := acquireRepoLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	startOfRun := time.Now()
+	tmpRoot, err := createTmpWorkingRoot(startOfRun)
+	if err != nil {
+		return err
+	}
+	languageRepo, err := openLanguageRepo(ctx, tmpRoot)
+	if err != nil {
+		return err
+	}
+	state, err := loadState(languageRepo)
+	if err != nil {
+		return err
+	}
+	containerOpts := containerOptions(state)
+	baseHash, err := gitrepo.HeadHash(ctx, languageRepo)
+	if err != nil {
+		return err
+	}
+
+	var releases []libraryRelease
+	for _, library := range state.LibraryReleaseStates {
+		if flagLibraryID != "" && library.Id != flagLibraryID {
+			continue
+		}
+		if library.AutomationLevel == statepb.AutomationLevel_AUTOMATION_LEVEL_BLOCKED {
+			slog.Info(fmt.Sprintf("Ignoring blocked library: '%s'", library.Id))
+			continue
+		}
+		commits, err := libraryCommitsSinceRelease(ctx, languageRepo, library)
+		if err != nil {
+			return err
+		}
+		if len(commits) == 0 {
+			slog.Info(fmt.Sprintf("Library '%s' has no changes since its last release.", library.Id))
+			continue
+		}
+		bump := libraryVersionBump(state, library)
+		slog.Info(fmt.Sprintf("Recommended version bump for '%s' based on proto changes: %s", library.Id, versionBumpName(bump)))
+		version, err := nextReleaseVersion(library, bump)
+		if err != nil {
+			return err
+		}
+		if flagDryRun {
+			slog.Info(fmt.Sprintf("Would prepare release of '%s' version %s with %d change(s)", library.Id, version, len(commits)))
+			continue
+		}
+		slog.Info(fmt.Sprintf("Preparing release of '%s' version %s with %d change(s)", library.Id, version, len(commits)))
+		if err := container.PrepareRelease(ctx, containerOpts, languageRepo.Dir, library.Id, version); err != nil {
+			return err
+		}
+		library.CurrentVersion = version
+		library.NextVersion = ""
+		for _, apiState := range state.ApiGenerationStates {
+			if slices.Contains(library.ApiIds, apiState.Id) {
+				apiState.UnreleasedVersionBump = statepb.VersionBump_VERSION_BUMP_NONE
+			}
+		}
+		if err := saveState(languageRepo, state); err != nil {
+			return err
+		}
+		prepared := libraryRelease{ID: library.Id, Version: version}
+		for _, commit := range commits {
+			subject, _, _ := strings.Cut(commit.Message, "\n")
+			prepared.Changes = append(prepared.Changes, fmt.Sprintf("%s (%s)", subject, commit.Hash.String()[:7]))
+		}
+		msg := fmt.Sprintf("%s\n\nChanges since the last release:\n- %s\n", releaseCommitMessagePrefix(library.Id)+version, strings.Join(prepared.Changes, "\n- "))
+		if err := commitAll(ctx, languageRepo, msg); err != nil {
+			return err
+		}
+		recordChangedLibrary(library.Id)
+		releases = append(releases, prepared)
+	}
+
+	if len(releases) == 0 {
+		slog.Info("No libraries to release.")
+		return nil
+	}
+	if !flagPush {
+		keepTmpRootIfContains(languageRepo.Dir)
+		slog.Info("Pushing not specified; release preparation complete.")
+		return nil
+	}
+	if forgeToken() == "" {
+		return fmt.Errorf("no %s token supplied for push", forgeName())
+	}
+	forge, err := gitrepo.NewForge(flagForge, languageRepo, forgeToken())
+	if err != nil {
+		return err
+	}
+	const yyyyMMddHHmmss = "20060102T150405" // Expected format by time library
+	branch := fmt.Sprintf("%srelease-%s", generatedBranchPrefix, startOfRun.Format(yyyyMMddHHmmss))
+	title := fmt.Sprintf("chore: Release %d libraries", len(releases))
+	if len(releases) == 1 {
+		title = releaseCommitMessagePrefix(releases[0].ID) + releases[0].Version
+	}
+	_, err = createPullRequest(ctx, languageRepo, forge, state, baseHash, branch, title, releasePullRequestBody(releases))
+	return err
+}
+
+func tagReleases(ctx context.Context) error {
+	if !supportedLanguages[flagLanguage] {
+		return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+	}
+	if err := resolveGitHubToken(ctx); err != nil {
+		return err
+	}
+	if flagGitHubToken == "" && !flagDryRun {
+		return fmt.Errorf("-github-token must be provided unless -dry-run is specified")
+	}
+
+	tmpRoot, err := createTmpWorkingRoot(time.Now())
+	if err != nil {
+		return err
+	}
+	languageRepo, err := openLanguageRepo(ctx, tmpRoot)
+	if err != nil {
+		return err
+	}
+	state, err := loadState(languageRepo)
+	if err != nil {
+		return err
+	}
+	// The GitHub repository is only needed to check for and create releases.
+	var upstream *gitrepo.GitHubRepo
+	if flagGitHubToken != "" {
+		if upstream, err = gitrepo.GetGitHubRepo(languageRepo); err != nil {
+			return err
+		}
+	}
+
+	for _, library := range state.LibraryReleaseStates {
+		if flagLibraryID != "" && library.Id != flagLibraryID {
+			continue
+		}
+		commit, err := findReleaseCommit(ctx, languageRepo, library.Id, "")
+		if err != nil {
+			return err
+		}
+		if commit == nil {
+			slog.Info(fmt.Sprintf("No release commit found for '%s'", library.Id))
+			continue
+		}
+		version, notes, _ := parseReleaseCommit(commit.Message, library.Id)
+		tag := releaseTag(library.Id, version)
+		hash := commit.Hash.String()
+		if flagGitHubToken != "" {
+			exists, err := gitrepo.ReleaseExists(ctx, upstream, flagGitHubToken, tag)
+			if err != nil {
+				return err
+			}
+			if exists {
+				slog.Info(fmt.Sprintf("Release %s already exists", tag))
+				continue
+			}
+		}
+		if flagDryRun {
+			slog.Info(fmt.Sprintf("Would create release %s at commit %s:\n%s", tag, hash, notes))
+			continue
+		}
+		name := fmt.Sprintf("%s version %s", library.Id, version)
+		url, err := gitrepo.CreateRelease(ctx, upstream, flagGitHubToken, tag, hash, name, notes, strings.Contains(version, "-"))
+		if err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Created release %s: %s", tag, url))
+	}
+	return nil
+}
+
+func publishReleases(ctx context.Context) error {
+	if !supportedLanguages[flagLanguage] {
+		return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+	}
+	if flagPublishCredentials == "" && !flagDryRun {
+		return fmt.Errorf("-publish-credentials must be provided unless -dry-run is specified")
+	}
+	credentials, err := filepath.Abs(flagPublishCredentials)
+	if err != nil {
+		return err
+	}
+	if err := preflight(); err != nil {
+		return err
+	}
+
+	tmpRoot, err := createTmpWorkingRoot(time.Now())
+	if err != nil {
+		return err
+	}
+	languageRepo, err := openLanguageRepo(ctx, tmpRoot)
+	if err != nil {
+		return err
+	}
+	state, err := loadState(languageRepo)
+	if err != nil {
+		return err
+	}
+	headHash, err := gitrepo.HeadHash(ctx, languageRepo)
+	if err != nil {
+		return err
+	}
+	containerOpts := containerOptions(state)
+
+	// The libraries to publish are grouped by release commit, so that the
+	// repo is exported and built once for all the libraries released by it.
+	type libraryPublication struct {
+		id, version string
+	}
+	var commits []string
+	publications := map[string][]libraryPublication{}
+	for _, library := range state.LibraryReleaseStates {
+		if flagLibraryID != "" && library.Id != flagLibraryID {
+			continue
+		}
+		commit, err := findReleaseCommit(ctx, languageRepo, library.Id, "")
+		if err != nil {
+			return err
+		}
+		if commit == nil {
+			slog.Info(fmt.Sprintf("No release commit found for '%s'", library.Id))
+			continue
+		}
+		hash := commit.Hash.String()
+		// Without -library-id, only the libraries released by HEAD are
+		// published, so that older releases aren't published again.
+		if flagLibraryID == "" && hash != headHash {
+			continue
+		}
+		version, _, _ := parseReleaseCommit(commit.Message, library.Id)
+		if flagDryRun {
+			slog.Info(fmt.Sprintf("Would publish '%s' version %s from commit %s", library.Id, version, hash))
+			continue
+		}
+		if _, ok := publications[hash]; !ok {
+			commits = append(commits, hash)
+		}
+		publications[hash] = append(publications[hash], libraryPublication{library.Id, version})
+	}
+
+	for _, hash := range commits {
+		// Build and publish from a copy of the release commit, so that the
+		// language repo is left unchanged.
+		releaseRoot := filepath.Join(tmpRoot, "publish", hash)
+		if err := gitrepo.ExportTree(ctx, languageRepo, hash, releaseRoot); err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Building release commit %s", hash))
+		if err := container.Build(ctx, containerOpts, "repo-root", releaseRoot, ""); err != nil {
+			return err
+		}
+		for _, publication := range publications[hash] {
+			slog.Info(fmt.Sprintf("Publishing '%s' version %s", publication.id, publication.version))
+			if err := container.Publish(ctx, containerOpts, releaseRoot, publication.id, publication.version, credentials); err != nil {
+				return err
+			}
+		}
+	}
+	if len(commits) == 0 && !flagDryRun {
+		slog.Info("No libraries to publish.")
+	}
+	return nil
+}
+
+// releaseTag returns the name of the tag for a release of the given library,
+// using the format for the language.
+func releaseTag(libraryID, version string) string {
+	format, ok := releaseTagFormats[flagLanguage]
+	if configured := languageConfigs[flagLanguage].ReleaseTagFormat; configured != "" {
+		format, ok = configured, true
+	}
+	if !ok {
+		format = defaultReleaseTagFormat
+	}
+	return strings.NewReplacer("{id}", libraryID, "{version}", version).Replace(format)
+}
+
+// releaseCommitMessagePrefix returns the start of the message of a commit
+// preparing a release of the given library, which is followed by the version.
+func releaseCommitMessagePrefix(libraryID string) string {
+	return fmt.Sprintf("%s%s version ", releaseCommitPrefix, libraryID)
+}
+
+// findReleaseCommit returns the most recent commit preparing a release of the
+// given library, or nil if there is none. The search stops at the given
+// commit, if it's not empty.
+func findReleaseCommit(ctx context.Context, repo *gitrepo.Repo, libraryID, stop string) (*object.Commit, error) {
+	return gitrepo.FindCommit(ctx, repo, stop, func(message string) bool {
+		_, _, found := parseReleaseCommit(message, libraryID)
+		return found
+	})
+}
+
+// parseReleaseCommit returns the version and release notes for the given
+// library from the message of a commit preparing its release. The commit may
+// be the original commit, or a squash-merged pull request containing it, in
+// which case each original commit message starts with "* ".
+func parseReleaseCommit(message, libraryID string) (version, notes string, found bool) {
+	prefix := releaseCommitMessagePrefix(libraryID)
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		line = strings.TrimPrefix(line, "* ")
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		version = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		// Strip any pull request number added by GitHub, e.g. "1.2.0 (#123)".
+		version, _, _ = strings.Cut(version, " ")
+		var noteLines []string
+		for _, line := range lines[i+1:] {
+			if strings.HasPrefix(strings.TrimPrefix(line, "* "), releaseCommitPrefix) {
+				break
+			}
+			noteLines = append(noteLines, line)
+		}
+		return version, strings.TrimSpace(strings.Join(noteLines, "\n")), true
+	}
+	return "", "", false
+}
+
+// libraryCommitsSinceRelease returns the commits which changed the library's
+// source paths since it was last released, most recent first. The last release
+// is the most recent commit preparing a release of the library, or the last
+// release commit recorded in the library's state if that's more recent. Commits
+// preparing releases are never included.
+func libraryCommitsSinceRelease(ctx context.Context, repo *gitrepo.Repo, library *statepb.LibraryReleaseState) ([]object.Commit, error) {
+	releaseCommit, err := findReleaseCommit(ctx, repo, library.Id, library.LastReleaseCommit)
+	if err != nil {
+		return nil, err
+	}
+	since := library.LastReleaseCommit
+	if releaseCommit != nil {
+		since = releaseCommit.Hash.String()
+	}
+	paths := library.SourcePaths
+	if len(paths) == 0 {
+		// The empty path represents the whole repo.
+		paths = []string{""}
+	}
+	seen := map[string]bool{}
+	var commits []object.Commit
+	for _, path := range paths {
+		pathCommits, err := gitrepo.GetApiCommits(ctx, repo, path, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range pathCommits {
+			hash := commit.Hash.String()
+			if seen[hash] || strings.HasPrefix(commit.Message, releaseCommitPrefix) {
+				continue
+			}
+			seen[hash] = true
+			commits = append(commits, commit)
+		}
+	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Committer.When.After(commits[j].Committer.When)
+	})
+	return commits, nil
+}
+
+// nextReleaseVersion returns the version at which to release the library:
+// the next version specified in its state, or otherwise its current version
+// with the given bump applied.
+func nextReleaseVersion(library *statepb.LibraryReleaseState, bump statepb.VersionBump) (string, error) {
+	if library.NextVersion != "" {
+		return library.NextVersion, nil
+	}
+	version, err := applyVersionBump(library.CurrentVersion, bump)
+	if err != nil {
+		return "", fmt.Errorf("unable to determine the next version of '%s': %w; specify next_version in the pipeline state", library.Id, err)
+	}
+	return version, nil
+}
+
+// libraryVersionBump returns the largest version bump recommended for the
+// library's APIs since it was last released.
+func libraryVersionBump(state *statepb.PipelineState, library *statepb.LibraryReleaseState) statepb.VersionBump {
+	bump := statepb.VersionBump_VERSION_BUMP_NONE
+	for _, apiState := range state.ApiGenerationStates {
+		if slices.Contains(library.ApiIds, apiState.Id) {
+			bump = max(bump, apiState.UnreleasedVersionBump)
+		}
+	}
+	return bump
+}
+
+// versionBumpName returns the lower-case name of a version bump, e.g. "minor".
+func versionBumpName(bump statepb.VersionBump) string {
+	return strings.ToLower(strings.TrimPrefix(bump.String(), "VERSION_BUMP_"))
+}
+
+func releasePullRequestBody(releases []libraryRelease) string {
+	var builder strings.Builder
+	builder.WriteString("Release of the following libraries. See individual commits for details.\n")
+	for _, release := range releases {
+		fmt.Fprintf(&builder, "\n## %s %s\n\n", release.ID, release.Version)
+		for _, change := range release.Changes {
+			fmt.Fprintf(&builder, "- %s\n", change)
+		}
+	}
+	builder.WriteString("\n" + releasePullRequestMarker + "\n")
+	return builder.String()
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+func TestParseReleaseCommit(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		message     string
+		wantVersion string
+		wantNotes   string
+		wantFound   bool
+	}{
+		{
+			name:        "original commit",
+			message:     "chore: Release lib-a version 1.2.0\n\n- feat: Add a method\n",
+			wantVersion: "1.2.0",
+			wantNotes:   "- feat: Add a method",
+			wantFound:   true,
+		},
+		{
+			name:        "squash-merged pull request",
+			message:     "chore: Release libraries (#42)\n\n* chore: Release lib-b version 0.3.0\n\nNotes for b\n\n* chore: Release lib-a version 1.2.0 (#41)\n\nNotes for a\n",
+			wantVersion: "1.2.0",
+			wantNotes:   "Notes for a",
+			wantFound:   true,
+		},
+		{
+			name:      "other library",
+			message:   "chore: Release lib-ab version 2.0.0\n",
+			wantFound: false,
+		},
+		{
+			name:      "not a release",
+			message:   "feat: Regenerate lib-a\n",
+			wantFound: false,
+		},
+	} {
+		version, notes, found := parseReleaseCommit(test.message, "lib-a")
+		if version != test.wantVersion || notes != test.wantNotes || found != test.wantFound {
+			t.Errorf("%s: parseReleaseCommit() = (%q, %q, %v); want (%q, %q, %v)", test.name, version, notes, found, test.wantVersion, test.wantNotes, test.wantFound)
+		}
+	}
+}
+
+func TestNextReleaseVersion(t *testing.T) {
+	for _, test := range []struct {
+		current string
+		next    string
+		bump    statepb.VersionBump
+		want    string
+		wantErr bool
+	}{
+		{current: "1.2.3", bump: statepb.VersionBump_VERSION_BUMP_PATCH, want: "1.2.4"},
+		{current: "1.2.3", bump: statepb.VersionBump_VERSION_BUMP_MINOR, want: "1.3.0"},
+		{current: "1.2.3", bump: statepb.VersionBump_VERSION_BUMP_MAJOR, want: "2.0.0"},
+		{current: "1.2.3", bump: statepb.VersionBump_VERSION_BUMP_NONE, want: "1.3.0"},
+		// Before 1.0.0, breaking changes only increment the minor version.
+		{current: "0.4.1", bump: statepb.VersionBump_VERSION_BUMP_MAJOR, want: "0.5.0"},
+		{current: "1.2.3", next: "2.0.0-beta01", bump: statepb.VersionBump_VERSION_BUMP_PATCH, want: "2.0.0-beta01"},
+		{current: "1.2.3-beta01", bump: statepb.VersionBump_VERSION_BUMP_PATCH, wantErr: true},
+	} {
+		library := &statepb.LibraryReleaseState{Id: "lib", CurrentVersion: test.current, NextVersion: test.next}
+		got, err := nextReleaseVersion(library, test.bump)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("nextReleaseVersion(%q, %q, %s) = %q, %v; want %q, error: %v", test.current, test.next, versionBumpName(test.bump), got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestLibraryVersionBump(t *testing.T) {
+	state := &statepb.PipelineState{ApiGenerationStates: []*statepb.ApiGenerationState{
+		{Id: "google/a/v1", UnreleasedVersionBump: statepb.VersionBump_VERSION_BUMP_PATCH},
+		{Id: "google/a/v2", UnreleasedVersionBump: statepb.VersionBump_VERSION_BUMP_MINOR},
+		{Id: "google/b/v1", UnreleasedVersionBump: statepb.VersionBump_VERSION_BUMP_MAJOR},
+	}}
+	library := &statepb.LibraryReleaseState{Id: "a", ApiIds: []string{"google/a/v1", "google/a/v2"}}
+	if got, want := libraryVersionBump(state, library), statepb.VersionBump_VERSION_BUMP_MINOR; got != want {
+		t.Errorf("libraryVersionBump() = %s; want %s", got, want)
+	}
+}
+
+// commitFile writes content to the file at path (relative to the repo) and
+// commits it with the given message.
+func commitFile(t *testing.T, repo *gitrepo.Repo, path, content, message string) string {
+	t.Helper()
+	ctx := context.Background()
+	fullPath := filepath.Join(repo.Dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitAll(ctx, repo, message); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := gitrepo.HeadHash(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestLibraryCommitsSinceRelease(t *testing.T) {
+	ctx := context.Background()
+	repo, err := gitrepo.Init(ctx, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, "a/a.txt", "1", "feat: Initial a")
+	commitFile(t, repo, "a/a.txt", "2", releaseCommitMessagePrefix("a")+"1.0.0")
+	fix := commitFile(t, repo, "a/a.txt", "3", "fix: Fix a")
+	commitFile(t, repo, "b/b.txt", "1", "feat: Add b")
+	feat := commitFile(t, repo, "a/a.txt", "4", "feat: Extend a")
+	commitFile(t, repo, "b/b.txt", "2", releaseCommitMessagePrefix("b")+"0.1.0")
+
+	library := &statepb.LibraryReleaseState{Id: "a", SourcePaths: []string{"a"}}
+	commits, err := libraryCommitsSinceRelease(ctx, repo, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, commit := range commits {
+		got = append(got, commit.Hash.String())
+	}
+	if want := []string{feat, fix}; !slices.Equal(got, want) {
+		t.Errorf("libraryCommitsSinceRelease() = %v; want %v (the commits to a since its release, most recent first)", got, want)
+	}
+}
+
+// countingRunner is a fake language container which records the commands it's
+// run with, and succeeds.
+type countingRunner struct {
+	commands *[]string
+}
+
+func (r countingRunner) Run(ctx context.Context, image string, mounts, args []string) error {
+	*r.commands = append(*r.commands, args[0])
+	return nil
+}
+
+func TestPublishBuildsOncePerReleaseCommit(t *testing.T) {
+	ctx := context.Background()
+	useTestlang(t)
+	var commands []string
+	containerRunner = countingRunner{&commands}
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	flagLanguage = ""
+	t.Cleanup(func() { flagLanguage, flagRepoRoot, flagPublishCredentials = "", "", "" })
+
+	repo, err := gitrepo.Init(ctx, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo.Dir, "generator-input"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	state := &statepb.PipelineState{
+		ImageTag: "selftest",
+		LibraryReleaseStates: []*statepb.LibraryReleaseState{
+			{Id: "a", CurrentVersion: "1.0.0"},
+			{Id: "b", CurrentVersion: "0.1.0"},
+		},
+	}
+	if err := saveState(repo, state); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, "a/a.txt", "1", "chore: Release libraries (#2)\n\n* "+releaseCommitMessagePrefix("a")+"1.0.0\n\n* "+releaseCommitMessagePrefix("b")+"0.1.0\n")
+	credentials := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(credentials, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CmdRelease.Parse([]string{"-language=testlang", "-image=testlang", "-repo-root=" + repo.Dir, "-publish-credentials=" + credentials, "publish"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CmdRelease.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"build", "publish", "publish"}; !slices.Equal(commands, want) {
+		t.Errorf("container commands = %v; want %v", commands, want)
+	}
+}
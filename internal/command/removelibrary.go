@@ -0,0 +1,177 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// CmdRemoveLibrary retires a library whose API has been turned down: the
+// inverse of configure. The library's files are removed by the container's
+// remove command if the image declares it or, for other images, by deleting
+// the library's source paths. The library (and any APIs which no
+// other library includes) is removed from the pipeline state, and the change
+// is committed and, if -push is specified, proposed in a pull request.
+var CmdRemoveLibrary = &Command{
+	Name:  "remove-library",
+	Short: "Remove a library (e.g. for a turned-down API) from a language repo",
+	Run: func(ctx context.Context) error {
+		if !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		if flagLibraryID == "" {
+			return fmt.Errorf("-library-id is not provided")
+		}
+		if err := validatePushFlags(ctx); err != nil {
+			return err
+		}
+		if err := preflight(); err != nil {
+			return err
+		}
+
+		startOfRun := time.Now()
+		tmpRoot, err := createTmpWorkingRoot(startOfRun)
+		if err != nil {
+			return err
+		}
+		languageRepo, err := openLanguageRepo(ctx, tmpRoot)
+		if err != nil {
+			return err
+		}
+		state, err := loadState(languageRepo)
+		if err != nil {
+			return err
+		}
+		index := slices.IndexFunc(state.LibraryReleaseStates, func(library *statepb.LibraryReleaseState) bool {
+			return library.Id == flagLibraryID
+		})
+		if index == -1 {
+			return fmt.Errorf("library %s is not in the pipeline state", flagLibraryID)
+		}
+		library := state.LibraryReleaseStates[index]
+
+		scopeOverride = strings.Join(library.ApiIds, ",")
+		defer func() { scopeOverride = "" }()
+		release, err := acquireRepoLock()
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		hashBefore, err := gitrepo.HeadHash(ctx, languageRepo)
+		if err != nil {
+			return err
+		}
+		containerOpts := containerOptions(state)
+		if container.Declares(ctx, containerOpts, "remove") {
+			if err := container.Remove(ctx, containerOpts, languageRepo.Dir, library.Id); err != nil {
+				return err
+			}
+		} else {
+			slog.Info(fmt.Sprintf("Image %s does not declare the remove command; deleting the source paths of %s", containerOpts.Image, library.Id))
+			if err := removeSourcePaths(languageRepo.Dir, library); err != nil {
+				return err
+			}
+		}
+
+		recordChangedLibrary(library.Id)
+		removeLibraryState(state, index)
+		if err := saveState(languageRepo, state); err != nil {
+			return err
+		}
+		if err := commitAll(ctx, languageRepo, fmt.Sprintf("Removed library %s", library.Id)); err != nil {
+			return err
+		}
+
+		result := &generationResult{
+			repo:          languageRepo,
+			state:         state,
+			containerOpts: containerOpts,
+			startOfRun:    startOfRun,
+			baseHash:      hashBefore,
+			title:         fmt.Sprintf("chore: Remove library %s", library.Id),
+			summary:       fmt.Sprintf("Removed library %s, for APIs which have been turned down: %s.", library.Id, strings.Join(library.ApiIds, ", ")),
+		}
+		return push(ctx, result)
+	},
+}
+
+// removeSourcePaths deletes the source paths of a library from the language
+// repo. Each path must be relative, within the repo and exist, and must be
+// neither the whole repo nor within its .git directory. All the paths are
+// checked before any are deleted.
+func removeSourcePaths(repoRoot string, library *statepb.LibraryReleaseState) error {
+	if len(library.SourcePaths) == 0 {
+		return fmt.Errorf("library %s has no source paths to remove", library.Id)
+	}
+	for _, sourcePath := range library.SourcePaths {
+		if err := checkRemovableSourcePath(repoRoot, sourcePath); err != nil {
+			return fmt.Errorf("invalid source path %q for library %s: %w", sourcePath, library.Id, err)
+		}
+	}
+	for _, sourcePath := range library.SourcePaths {
+		slog.Info(fmt.Sprintf("Deleting %s", sourcePath))
+		if err := os.RemoveAll(filepath.Join(repoRoot, sourcePath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRemovableSourcePath checks that a source path of a library can safely
+// be deleted from the language repo.
+func checkRemovableSourcePath(repoRoot, sourcePath string) error {
+	if !filepath.IsLocal(sourcePath) {
+		return fmt.Errorf("must be a relative path within the repo")
+	}
+	cleaned := filepath.ToSlash(filepath.Clean(sourcePath))
+	if cleaned == "." {
+		return fmt.Errorf("must not be the whole repo")
+	}
+	if first, _, _ := strings.Cut(cleaned, "/"); strings.EqualFold(first, ".git") {
+		return fmt.Errorf("must not be within .git")
+	}
+	if _, err := os.Lstat(filepath.Join(repoRoot, cleaned)); err != nil {
+		return fmt.Errorf("does not exist in the repo: %w", err)
+	}
+	return nil
+}
+
+// removeLibraryState removes the library at the given index from the pipeline
+// state, along with the generation state of its APIs which aren't included in
+// any other library.
+func removeLibraryState(state *statepb.PipelineState, index int) {
+	library := state.LibraryReleaseStates[index]
+	state.LibraryReleaseStates = slices.Delete(state.LibraryReleaseStates, index, index+1)
+	state.ApiGenerationStates = slices.DeleteFunc(state.ApiGenerationStates, func(apiState *statepb.ApiGenerationState) bool {
+		if !slices.Contains(library.ApiIds, apiState.Id) {
+			return false
+		}
+		return !slices.ContainsFunc(state.LibraryReleaseStates, func(other *statepb.LibraryReleaseState) bool {
+			return slices.Contains(other.ApiIds, apiState.Id)
+		})
+	})
+}
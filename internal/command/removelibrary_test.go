@@ -0,0 +1,63 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+func TestRemoveSourcePaths(t *testing.T) {
+	for _, test := range []struct {
+		sourcePath string
+		wantErr    bool
+	}{
+		{"lib", false},
+		{"lib/", false},
+		{"./lib", false},
+		{".", true},
+		{"./", true},
+		{"lib/..", true},
+		{".git", true},
+		{".git/config", true},
+		{"lib/../.git", true},
+		{"../other", true},
+		{"/abs", true},
+		{"missing", true},
+	} {
+		t.Run(test.sourcePath, func(t *testing.T) {
+			repoRoot := t.TempDir()
+			for _, dir := range []string{".git", "lib"} {
+				if err := os.Mkdir(filepath.Join(repoRoot, dir), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			library := &statepb.LibraryReleaseState{Id: "lib", SourcePaths: []string{test.sourcePath}}
+			err := removeSourcePaths(repoRoot, library)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("removeSourcePaths(%q) returned %v; want error: %t", test.sourcePath, err, test.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(repoRoot, ".git")); err != nil {
+				t.Errorf(".git was removed: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(repoRoot, "lib")); test.wantErr == os.IsNotExist(err) {
+				t.Errorf("lib removed: %t; want %t", os.IsNotExist(err), !test.wantErr)
+			}
+		})
+	}
+}
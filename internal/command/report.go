@@ -0,0 +1,169 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+)
+
+// reportFileName is the name of the generation report written to the output
+// directory.
+const reportFileName = "report.json"
+
+// generationReport is the machine-readable report of a run of a command which
+// generates code, written as report.json.
+type generationReport struct {
+	Command         string    `json:"command"`
+	Language        string    `json:"language"`
+	APIPath         string    `json:"apiPath,omitempty"`
+	StartTime       time.Time `json:"startTime"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// Result is "success" or "failure".
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// Steps are the container commands run, in order.
+	Steps            []container.StepResult `json:"steps"`
+	Commits          []reportCommit         `json:"commits"`
+	PullRequests     []string               `json:"pullRequests"`
+	ChangedLibraries []string               `json:"changedLibraries"`
+	// SkippedAPIs are the configured APIs which weren't generated because
+	// they're excluded or blocked.
+	SkippedAPIs []reportSkippedAPI `json:"skippedApis"`
+	// SkippedLibraries are the libraries which a scheduled regeneration
+	// didn't regenerate, and why.
+	SkippedLibraries []reportSkippedLibrary `json:"skippedLibraries,omitempty"`
+}
+
+// reportCommit describes a commit made in the language repo.
+type reportCommit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+	// Files are the paths of the files changed by the commit.
+	Files []string `json:"files"`
+}
+
+// reportSkippedAPI describes an API which wasn't generated, and why.
+type reportSkippedAPI struct {
+	APIPath string `json:"apiPath"`
+	Reason  string `json:"reason"`
+}
+
+// reportSkippedLibrary describes a library which wasn't regenerated, and why.
+type reportSkippedLibrary struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// reports holds the directory the current run's report is written to, and the
+// most recent report (served by the serve command).
+var reports struct {
+	mu   sync.Mutex
+	dir  string
+	last *generationReport
+}
+
+// setReportDir sets the directory the report of the current run is written to.
+// If it's within the temporary working directory, the directory is kept after
+// the run (see cleanupTmpRoot).
+func setReportDir(dir string) {
+	keepTmpRootIfContains(dir)
+	reports.mu.Lock()
+	defer reports.mu.Unlock()
+	reports.dir = dir
+}
+
+// lastReport returns the most recent report, or nil if no report has been
+// produced.
+func lastReport() *generationReport {
+	reports.mu.Lock()
+	defer reports.mu.Unlock()
+	return reports.last
+}
+
+// writeReport wraps the Run function of a command so that a report of each run
+// is written as report.json in the directory set by setReportDir (normally the
+// output directory), whether or not the run succeeds. The report covers the
+// container steps, commits, pull requests and changed libraries recorded
+// during the run, and any APIs skipped. No report is written if the run fails before setting the
+// directory.
+func writeReport(name string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		setReportDir("")
+		start := time.Now()
+		firstStep := len(container.StepResults())
+		ciResults.mu.Lock()
+		firstCommit := len(ciResults.commits)
+		firstPullRequest := len(ciResults.pullRequests)
+		firstLibrary := len(ciResults.changedLibraries)
+		firstSkipped := len(ciResults.skippedAPIs)
+		firstSkippedLibrary := len(ciResults.skippedLibraries)
+		ciResults.mu.Unlock()
+
+		err := run(ctx)
+
+		report := &generationReport{
+			Command:         name,
+			Language:        flagLanguage,
+			APIPath:         flagAPIPath,
+			StartTime:       start,
+			DurationSeconds: time.Since(start).Seconds(),
+			Result:          "success",
+			Steps:           append([]container.StepResult{}, container.StepResults()[firstStep:]...),
+		}
+		if err != nil {
+			report.Result = "failure"
+			report.Error = err.Error()
+		}
+		ciResults.mu.Lock()
+		report.Commits = append([]reportCommit{}, ciResults.commits[firstCommit:]...)
+		report.PullRequests = append([]string{}, ciResults.pullRequests[firstPullRequest:]...)
+		report.ChangedLibraries = append([]string{}, uniqueLibraries(ciResults.changedLibraries[firstLibrary:])...)
+		report.SkippedAPIs = append([]reportSkippedAPI{}, ciResults.skippedAPIs[firstSkipped:]...)
+		report.SkippedLibraries = append([]reportSkippedLibrary{}, ciResults.skippedLibraries[firstSkippedLibrary:]...)
+		ciResults.mu.Unlock()
+
+		reports.mu.Lock()
+		reports.last = report
+		dir := reports.dir
+		reports.mu.Unlock()
+		if dir != "" {
+			if writeErr := writeReportFile(filepath.Join(dir, reportFileName), report); writeErr != nil {
+				slog.Warn(fmt.Sprintf("Unable to write %s: %s", reportFileName, writeErr))
+			}
+		}
+		return err
+	}
+}
+
+func writeReportFile(path string, report *generationReport) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Wrote report to %s", path))
+	return nil
+}
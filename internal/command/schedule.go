@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// scheduledLibrary is a unit of scheduled regeneration: a configured library,
+// or an API which isn't part of any library (identified by its API path, as
+// in the changed libraries of the generation report).
+type scheduledLibrary struct {
+	id      string
+	apiIds  []string
+	blocked bool
+}
+
+// scheduledAPIs decides which libraries a scheduled regeneration (-scheduled)
+// regenerates: those with at least one API which isn't excluded or blocked,
+// and which has commits in googleapis since the last generated commit recorded
+// in the pipeline state. Every other library is logged and recorded as
+// skipped, with the reason. The APIs of the libraries to regenerate are
+// returned; each is still subject to the usual per-API checks in updateApi.
+func scheduledAPIs(ctx context.Context, apiRepo *gitrepo.Repo, state *statepb.PipelineState, exclusions []apiExclusion) (map[string]bool, error) {
+	head, err := gitrepo.HeadHash(ctx, apiRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	var libraries []scheduledLibrary
+	inLibrary := map[string]bool{}
+	for _, library := range state.LibraryReleaseStates {
+		libraries = append(libraries, scheduledLibrary{
+			id:      library.Id,
+			apiIds:  library.ApiIds,
+			blocked: library.AutomationLevel == statepb.AutomationLevel_AUTOMATION_LEVEL_BLOCKED,
+		})
+		for _, apiId := range library.ApiIds {
+			inLibrary[apiId] = true
+		}
+	}
+	for _, apiState := range state.ApiGenerationStates {
+		if !inLibrary[apiState.Id] {
+			libraries = append(libraries, scheduledLibrary{id: apiState.Id, apiIds: []string{apiState.Id}})
+		}
+	}
+
+	apis := map[string]bool{}
+	skipped := 0
+	for _, library := range libraries {
+		reason, err := scheduleLibrary(ctx, apiRepo, state, exclusions, library)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			slog.Info(fmt.Sprintf("Skipping library %s: %s", library.id, reason))
+			recordSkippedLibrary(library.id, reason)
+			skipped++
+			continue
+		}
+		slog.Info(fmt.Sprintf("Regenerating library %s", library.id))
+		for _, apiId := range library.apiIds {
+			apis[apiId] = true
+		}
+	}
+	slog.Info(fmt.Sprintf("Scheduled regeneration at googleapis %s: %d of %d libraries to regenerate, %d skipped", head, len(libraries)-skipped, len(libraries), skipped))
+	return apis, nil
+}
+
+// scheduleLibrary returns the reason a scheduled regeneration skips the
+// library, or the empty string if the library needs regenerating.
+func scheduleLibrary(ctx context.Context, apiRepo *gitrepo.Repo, state *statepb.PipelineState, exclusions []apiExclusion, library scheduledLibrary) (string, error) {
+	if library.blocked {
+		return "library is blocked", nil
+	}
+	eligible := 0
+	for _, apiId := range library.apiIds {
+		apiState := findApiState(state, apiId)
+		if apiState == nil || apiState.AutomationLevel == statepb.AutomationLevel_AUTOMATION_LEVEL_BLOCKED || exclusionReason(exclusions, apiId) != "" {
+			continue
+		}
+		eligible++
+		commits, err := gitrepo.GetApiCommits(ctx, apiRepo, apiId, apiState.LastGeneratedCommit)
+		if err != nil {
+			return "", err
+		}
+		if len(commits) > 0 {
+			return "", nil
+		}
+	}
+	if eligible == 0 {
+		return "all of its APIs are excluded or blocked", nil
+	}
+	return "no changes to its APIs in googleapis since they were last generated", nil
+}
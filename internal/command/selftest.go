@@ -0,0 +1,265 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// selfTestAPIPath is the API configured and generated by the selftest command.
+const selfTestAPIPath = "google/example/selftest/v1"
+
+// CmdSelfTest runs the configure, generate, clean, build and commit steps
+// against scratch googleapis and language repositories, so that operators can
+// validate a deployment end-to-end. By default the language container is
+// faked in-process; if -image is specified, that image is run instead.
+var CmdSelfTest = &Command{
+	Name:  "selftest",
+	Short: "Run an end-to-end test of the pipeline against scratch repositories",
+	Run: func(ctx context.Context) error {
+		startOfRun := time.Now()
+		tmpRoot, err := createTmpWorkingRoot(startOfRun)
+		if err != nil {
+			return err
+		}
+
+		opts := &container.Options{Image: "testlang", Runner: testlangRunner{}}
+		if flagImage != "" {
+			opts = &container.Options{Image: flagImage, Runner: containerRunner}
+		}
+
+		apiRepo, err := gitrepo.Init(ctx, filepath.Join(tmpRoot, "googleapis"))
+		if err != nil {
+			return err
+		}
+		if err := writeSelfTestProto(apiRepo, "Initial version"); err != nil {
+			return err
+		}
+		if err := commitAll(ctx, apiRepo, "feat: Add selftest API"); err != nil {
+			return err
+		}
+		configuredCommit, err := gitrepo.HeadHash(ctx, apiRepo)
+		if err != nil {
+			return err
+		}
+
+		languageRepo, err := gitrepo.Init(ctx, filepath.Join(tmpRoot, "language"))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(languageRepo.Dir, "generator-input"), 0755); err != nil {
+			return err
+		}
+		if err := saveState(languageRepo, &statepb.PipelineState{ImageTag: "selftest"}); err != nil {
+			return err
+		}
+		if err := commitAll(ctx, languageRepo, "chore: Initial pipeline state"); err != nil {
+			return err
+		}
+
+		slog.Info("Self-test: configuring API")
+		generatorInput := filepath.Join(languageRepo.Dir, "generator-input")
+		if err := container.Configure(ctx, opts, apiRepo.Dir, selfTestAPIPath, "", generatorInput, "", nil); err != nil {
+			return err
+		}
+		state, err := loadState(languageRepo)
+		if err != nil {
+			return err
+		}
+		apiState := findApiState(state, selfTestAPIPath)
+		if apiState == nil {
+			return fmt.Errorf("self-test failed: configure did not add %s to the pipeline state", selfTestAPIPath)
+		}
+		// Treat the API as generated at the initial googleapis commit, so that
+		// the next commit is picked up as a change.
+		apiState.LastGeneratedCommit = configuredCommit
+		if err := saveState(languageRepo, state); err != nil {
+			return err
+		}
+		if err := commitAll(ctx, languageRepo, fmt.Sprintf("Configured API %s", selfTestAPIPath)); err != nil {
+			return err
+		}
+
+		if err := writeSelfTestProto(apiRepo, "Updated version"); err != nil {
+			return err
+		}
+		if err := commitAll(ctx, apiRepo, "feat: Update selftest API\n\nPiperOrigin-RevId: 1"); err != nil {
+			return err
+		}
+		updatedCommit, err := gitrepo.HeadHash(ctx, apiRepo)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("Self-test: generating, cleaning, committing and building API")
+		hashBefore, err := gitrepo.HeadHash(ctx, languageRepo)
+		if err != nil {
+			return err
+		}
+		generatorInputCopy := filepath.Join(tmpRoot, "generator-input")
+		if err := os.CopyFS(generatorInputCopy, os.DirFS(generatorInput)); err != nil {
+			return err
+		}
+		outputDir := filepath.Join(tmpRoot, "output")
+		result := &generationResult{
+			repo:          languageRepo,
+			state:         state,
+			containerOpts: opts,
+			startOfRun:    startOfRun,
+			baseHash:      hashBefore,
+			apiRoot:       apiRepo.Dir,
+		}
+		if err := updateApi(ctx, apiRepo, result, generatorInputCopy, outputDir, apiState, nil); err != nil {
+			return err
+		}
+
+		state, err = loadState(languageRepo)
+		if err != nil {
+			return err
+		}
+		if got := state.ApiGenerationStates[0].LastGeneratedCommit; got != updatedCommit {
+			return fmt.Errorf("self-test failed: last generated commit is %q; expected %q", got, updatedCommit)
+		}
+		changed, err := gitrepo.ChangedFilesSince(ctx, languageRepo, hashBefore)
+		if err != nil {
+			return err
+		}
+		generated := false
+		for _, path := range changed {
+			if strings.HasPrefix(path, selfTestAPIPath+"/") {
+				generated = true
+			}
+		}
+		if !generated {
+			return fmt.Errorf("self-test failed: no generated code was committed under %s", selfTestAPIPath)
+		}
+		// The scratch repositories are left for inspection.
+		tmpRootHoldsOutput = true
+		slog.Info(fmt.Sprintf("Self-test passed. Scratch repositories are in %s", tmpRoot))
+		return nil
+	},
+}
+
+func writeSelfTestProto(apiRepo *gitrepo.Repo, comment string) error {
+	dir := filepath.Join(apiRepo.Dir, selfTestAPIPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf(`syntax = "proto3";
+
+package google.example.selftest.v1;
+
+// %s.
+service SelfTest {
+  rpc Echo(EchoRequest) returns (EchoResponse);
+}
+
+message EchoRequest {
+  string message = 1;
+}
+
+message EchoResponse {
+  string message = 1;
+}
+`, comment)
+	return os.WriteFile(filepath.Join(dir, "selftest.proto"), []byte(content), 0644)
+}
+
+// testlangRunner is an in-process fake language container for the "testlang"
+// language. It implements each container command trivially: configure adds the
+// API to the pipeline state, generate copies the API's protos to the output
+// with a header, clean deletes the API's directory, and build checks that the
+// API's directory contains files.
+type testlangRunner struct{}
+
+func (testlangRunner) Run(ctx context.Context, image string, mounts, args []string) error {
+	// Map each option (e.g. --api-root=/apis) to the host path mounted at its value.
+	hostPaths := map[string]string{}
+	for _, mount := range mounts {
+		i := strings.LastIndex(mount, ":")
+		hostPaths[mount[i+1:]] = mount[:i]
+	}
+	options := map[string]string{}
+	for _, arg := range args[1:] {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		options[name] = value
+	}
+	hostPath := func(option string) string { return hostPaths[options[option]] }
+	apiPath := options["api-path"]
+
+	switch args[0] {
+	case "configure":
+		path := filepath.Join(hostPath("generator-input"), "pipeline-state.json")
+		state, err := loadStateFile(path)
+		if err != nil {
+			return err
+		}
+		state.ApiGenerationStates = append(state.ApiGenerationStates, &statepb.ApiGenerationState{
+			Id:              apiPath,
+			AutomationLevel: statepb.AutomationLevel_AUTOMATION_LEVEL_AUTOMATIC,
+		})
+		return saveStateFile(path, state)
+	case "generate":
+		protos, err := filepath.Glob(filepath.Join(hostPath("api-root"), apiPath, "*.proto"))
+		if err != nil {
+			return err
+		}
+		outputDir := filepath.Join(hostPath("output"), apiPath)
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return err
+		}
+		for _, proto := range protos {
+			content, err := os.ReadFile(proto)
+			if err != nil {
+				return err
+			}
+			generated := fmt.Sprintf("// Generated by testlang from %s.\n%s", filepath.Base(proto), content)
+			if err := os.WriteFile(filepath.Join(outputDir, filepath.Base(proto)+".txt"), []byte(generated), 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "clean":
+		if apiPath == "" || apiPath == "none" {
+			return nil
+		}
+		return os.RemoveAll(filepath.Join(hostPath("repo-root"), apiPath))
+	case "build":
+		root := hostPath("repo-root")
+		if root == "" {
+			root = hostPath("generator-output")
+		}
+		entries, err := os.ReadDir(filepath.Join(root, apiPath))
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("testlang build: no files for %s", apiPath)
+		}
+		return nil
+	default:
+		return fmt.Errorf("testlang: unknown command %q", args[0])
+	}
+}
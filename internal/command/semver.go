@@ -0,0 +1,244 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// recommendVersionBump compares the protos of an API between two googleapis
+// commits, and returns the recommended version bump: major if any element
+// (service, method, message, field, enum or enum value) was removed or changed
+// incompatibly, minor if any element was added, patch for any other change to
+// the API's files, or none if nothing changed. A new API (with an empty
+// "from" commit) is a minor change.
+func recommendVersionBump(ctx context.Context, apiRepo *gitrepo.Repo, apiPath, from, to string) (statepb.VersionBump, error) {
+	if from == "" {
+		return statepb.VersionBump_VERSION_BUMP_MINOR, nil
+	}
+	before, err := gitrepo.ReadFiles(ctx, apiRepo, from, apiPath)
+	if err != nil {
+		return statepb.VersionBump_VERSION_BUMP_NONE, err
+	}
+	after, err := gitrepo.ReadFiles(ctx, apiRepo, to, apiPath)
+	if err != nil {
+		return statepb.VersionBump_VERSION_BUMP_NONE, err
+	}
+	if maps.Equal(before, after) {
+		return statepb.VersionBump_VERSION_BUMP_NONE, nil
+	}
+	beforeElements, afterElements := protoElements(before), protoElements(after)
+	bump := statepb.VersionBump_VERSION_BUMP_PATCH
+	for name, signature := range beforeElements {
+		afterSignature, ok := afterElements[name]
+		if !ok || afterSignature != signature {
+			return statepb.VersionBump_VERSION_BUMP_MAJOR, nil
+		}
+	}
+	if len(afterElements) > len(beforeElements) {
+		bump = statepb.VersionBump_VERSION_BUMP_MINOR
+	}
+	return bump, nil
+}
+
+// protoElements returns the elements declared in the .proto files among
+// files, keyed by kind and fully-qualified name (e.g. "field:Book.title"), with
+// a signature which changes if the element changes incompatibly (e.g. the type
+// and number of a field).
+func protoElements(files map[string]string) map[string]string {
+	elements := map[string]string{}
+	for name, content := range files {
+		if path.Ext(name) != ".proto" {
+			continue
+		}
+		parseProtoElements(content, elements)
+	}
+	return elements
+}
+
+// protoScope is a block in a proto file.
+type protoScope struct {
+	// kind is "message", "enum" or "service" for a named scope, "oneof" for a
+	// oneof (whose fields belong to the enclosing message), or empty for any
+	// other block (e.g. an option value or method options).
+	kind string
+	// name is the fully-qualified name of a named scope.
+	name string
+}
+
+// parseProtoElements adds the elements declared in a proto file to elements.
+// This is a lightweight parser, which only understands enough of the proto
+// language to identify declarations.
+func parseProtoElements(content string, elements map[string]string) {
+	var scopes []protoScope
+	enclosing := func() protoScope {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if scopes[i].kind != "oneof" {
+				return scopes[i]
+			}
+		}
+		return protoScope{kind: "file"}
+	}
+	qualify := func(name string) string {
+		if scope := enclosing(); scope.name != "" {
+			return scope.name + "." + name
+		}
+		return name
+	}
+
+	var statement strings.Builder
+	for _, token := range protoTokens(content) {
+		switch token {
+		case "{":
+			words := strings.Fields(statement.String())
+			statement.Reset()
+			scope := protoScope{}
+			if len(words) >= 2 && enclosing().kind != "" {
+				switch words[0] {
+				case "message", "enum", "service":
+					scope = protoScope{kind: words[0], name: qualify(words[1])}
+					elements[words[0]+":"+scope.name] = ""
+				case "oneof":
+					scope = protoScope{kind: "oneof"}
+				default:
+					// A method with options, or a field or enum value with
+					// an option value in braces.
+					addProtoDeclaration(words, enclosing(), elements)
+				}
+			}
+			scopes = append(scopes, scope)
+		case "}":
+			statement.Reset()
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case ";":
+			words := strings.Fields(statement.String())
+			statement.Reset()
+			addProtoDeclaration(words, enclosing(), elements)
+		default:
+			statement.WriteString(" " + token)
+		}
+	}
+}
+
+// addProtoDeclaration adds the field, enum value or method declared by the
+// words of a statement in the given scope, if any.
+func addProtoDeclaration(words []string, scope protoScope, elements map[string]string) {
+	if len(words) == 0 || words[0] == "option" || words[0] == "reserved" {
+		return
+	}
+	switch scope.kind {
+	case "service":
+		// rpc Name ( [stream] Request ) returns ( [stream] Response )
+		if words[0] == "rpc" && len(words) >= 2 {
+			name, signature, _ := strings.Cut(strings.Join(words[1:], " "), " ")
+			elements["method:"+scope.name+"."+name] = signature
+		}
+	case "enum":
+		// NAME = number [options]
+		if len(words) >= 3 && words[1] == "=" {
+			elements["enumvalue:"+scope.name+"."+words[0]] = words[2]
+		}
+	case "message":
+		// [label] type name = number [options]
+		for i := 1; i+2 < len(words); i++ {
+			if words[i+1] == "=" {
+				signature := strings.Join(words[:i], " ") + " " + words[i+2]
+				elements["field:"+scope.name+"."+words[i]] = signature
+				return
+			}
+		}
+	}
+}
+
+// protoTokens splits proto source into tokens, dropping comments. Braces,
+// semicolons and parentheses are separate tokens, and string literals are
+// single tokens.
+func protoTokens(content string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case strings.HasPrefix(content[i:], "//"):
+			flush()
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(content[i:], "/*"):
+			flush()
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+		case c == '"' || c == '\'':
+			flush()
+			start := i
+			for i++; i < len(content) && content[i] != c; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+			tokens = append(tokens, content[start:min(i+1, len(content))])
+		case c == '{' || c == '}' || c == ';' || c == '(' || c == ')' || c == '=':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// applyVersionBump returns the version resulting from applying bump to
+// version, which must be of the form major.minor.patch. Before 1.0.0, breaking
+// changes only increment the minor version. A bump of none is treated as minor.
+func applyVersionBump(version string, bump statepb.VersionBump) (string, error) {
+	match := releaseVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", fmt.Errorf("version %q is not of the form major.minor.patch", version)
+	}
+	// The pattern guarantees that each component is numeric.
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	switch {
+	case bump == statepb.VersionBump_VERSION_BUMP_MAJOR && major > 0:
+		return fmt.Sprintf("%d.0.0", major+1), nil
+	case bump == statepb.VersionBump_VERSION_BUMP_PATCH:
+		return fmt.Sprintf("%d.%d.%d", major, minor, patch+1), nil
+	default:
+		return fmt.Sprintf("%d.%d.0", major, minor+1), nil
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/metrics"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// CmdServe runs an HTTP server which triggers update-apis runs, using the same
+// flags as update-apis. Runs are triggered by GitHub push webhooks from
+// googleapis (at /webhook), when the push changes a file in an API configured
+// in the language repo, or manually (at /run). Runs are queued and executed one
+// at a time; requests received while a run is in progress are coalesced into
+// a single subsequent run. The report of the most recent run is served at
+// /report, and Prometheus metrics (including the queue depth) at /metrics.
+var CmdServe = &Command{
+	Name:  "serve",
+	Short: "Serve webhooks which trigger update-apis runs",
+	Run: func(ctx context.Context) error {
+		if flagWebhookSecret == "" {
+			if !isLoopbackAddress(flagListen) {
+				return fmt.Errorf("-webhook-secret must be specified to listen on %s; requests are only unauthenticated when listening on a loopback address, such as localhost:8080", flagListen)
+			}
+			slog.Warn("No -webhook-secret specified; requests will not be authenticated")
+		}
+		if _, err := parseLanguages(flagLanguage); err != nil {
+			return err
+		}
+		s := &server{requests: make(chan *serveRequest, 100)}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/webhook", s.handleWebhook)
+		mux.HandleFunc("/run", s.handleRun)
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, "ok")
+		})
+		mux.HandleFunc("/report", handleReport)
+		mux.Handle("/metrics", metrics.Handler())
+		httpServer := &http.Server{Addr: flagListen, Handler: mux}
+		go func() {
+			<-ctx.Done()
+			httpServer.Close()
+		}()
+		go s.work(ctx)
+
+		slog.Info(fmt.Sprintf("Serving webhooks on %s", flagListen))
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return ctx.Err()
+	},
+}
+
+// serveRequest is a request to run update-apis.
+type serveRequest struct {
+	// source describes the request, for logging.
+	source string
+	// changed lists the googleapis files changed by a push, or is nil to run
+	// regardless of which APIs are configured.
+	changed []string
+}
+
+type server struct {
+	requests chan *serveRequest
+}
+
+// pushEvent is the subset of a GitHub push webhook payload which is read.
+type pushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+}
+
+func (s *server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 25<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !validWebhookSignature(body, r.Header.Get("X-Hub-Signature-256")) {
+		metrics.ServeRequests.Inc("webhook", "rejected")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "ping":
+		fmt.Fprintln(w, "pong")
+		return
+	case "push":
+	default:
+		http.Error(w, fmt.Sprintf("unsupported event %q", event), http.StatusBadRequest)
+		return
+	}
+	var push pushEvent
+	if err := json.Unmarshal(body, &push); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if push.Ref != "refs/heads/"+push.Repository.DefaultBranch {
+		metrics.ServeRequests.Inc("webhook", "ignored")
+		fmt.Fprintf(w, "ignoring push to %s\n", push.Ref)
+		return
+	}
+	request := &serveRequest{
+		source:  fmt.Sprintf("push of %s to %s", push.After, push.Repository.FullName),
+		changed: push.changedFiles(),
+	}
+	s.enqueue(w, "webhook", request)
+}
+
+// pushEventMaxCommits is the maximum number of commits GitHub includes in a
+// push webhook payload; a push of more commits is truncated.
+const pushEventMaxCommits = 20
+
+// changedFiles returns the files changed by the push, or nil if they aren't
+// all known because the payload's commits may have been truncated, so that
+// update-apis runs for every configured API.
+func (push *pushEvent) changedFiles() []string {
+	if len(push.Commits) >= pushEventMaxCommits {
+		slog.Info(fmt.Sprintf("Push of %s has at least %d commits, which may be truncated; checking all APIs", push.After, pushEventMaxCommits))
+		return nil
+	}
+	changed := []string{}
+	for _, commit := range push.Commits {
+		changed = append(changed, commit.Added...)
+		changed = append(changed, commit.Modified...)
+		changed = append(changed, commit.Removed...)
+	}
+	return changed
+}
+
+// runRequest is the optional body of a request to /run.
+type runRequest struct {
+	// Paths optionally lists changed googleapis files (or API paths), so that
+	// the run only happens if one of them is in a configured API.
+	Paths []string `json:"paths"`
+}
+
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorized(r) {
+		metrics.ServeRequests.Inc("run", "rejected")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var body runRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.enqueue(w, "run", &serveRequest{source: "manual request", changed: body.Paths})
+}
+
+// enqueue queues a request received at the given endpoint (for metrics).
+func (s *server) enqueue(w http.ResponseWriter, endpoint string, request *serveRequest) {
+	select {
+	case s.requests <- request:
+		metrics.ServeRequests.Inc(endpoint, "queued")
+		metrics.ServeQueueDepth.Set(float64(len(s.requests)))
+		slog.Info(fmt.Sprintf("Queued %s", request.source))
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "queued")
+	default:
+		metrics.ServeRequests.Inc(endpoint, "rejected")
+		http.Error(w, "queue full", http.StatusServiceUnavailable)
+	}
+}
+
+// authorized reports whether the request has -webhook-secret as its bearer
+// token. Any request is authorized if no secret is specified.
+func authorized(r *http.Request) bool {
+	if flagWebhookSecret == "" {
+		return true
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(flagWebhookSecret)) == 1
+}
+
+// isLoopbackAddress reports whether the listen address addr (of the form
+// host:port) only accepts connections from this host. An empty host listens
+// on all interfaces.
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleReport serves the report (as in report.json) of the most recent run.
+func handleReport(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	report := lastReport()
+	if report == nil {
+		http.Error(w, "no runs have completed", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
+
+// validWebhookSignature reports whether signature (the X-Hub-Signature-256
+// header) is the HMAC of body using -webhook-secret. Any signature is valid if
+// no secret is specified.
+func validWebhookSignature(body []byte, signature string) bool {
+	if flagWebhookSecret == "" {
+		return true
+	}
+	hexDigest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	digest, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(flagWebhookSecret))
+	mac.Write(body)
+	return hmac.Equal(digest, mac.Sum(nil))
+}
+
+// work runs update-apis for queued requests until ctx is done, coalescing the
+// requests queued while each run is in progress.
+func (s *server) work(ctx context.Context) {
+	for {
+		var request *serveRequest
+		select {
+		case <-ctx.Done():
+			return
+		case request = <-s.requests:
+		}
+		merged := &serveRequest{source: request.source, changed: request.changed}
+		for drained := false; !drained; {
+			select {
+			case next := <-s.requests:
+				merged.source += "; " + next.source
+				if merged.changed == nil || next.changed == nil {
+					merged.changed = nil
+				} else {
+					merged.changed = append(merged.changed, next.changed...)
+				}
+			default:
+				drained = true
+			}
+		}
+		metrics.ServeQueueDepth.Set(0)
+		if err := s.run(ctx, merged); err != nil {
+			slog.Error(fmt.Sprintf("Run for %s failed: %s", merged.source, err))
+		}
+	}
+}
+
+// run runs update-apis for the languages which have an API affected by the
+// request. The flags modified by update-apis are restored afterwards, so that
+// each run starts from the same configuration.
+func (s *server) run(ctx context.Context, request *serveRequest) error {
+	// Locks taken while checking which APIs are affected (e.g. on cached
+	// clones) are only held for this run, not for as long as the server runs.
+	defer releaseHeldRunLocks()
+	languages, err := parseLanguages(flagLanguage)
+	if err != nil {
+		return err
+	}
+	if request.changed != nil {
+		var affected []string
+		for _, language := range languages {
+			apis, err := affectedAPIs(ctx, language, request.changed)
+			if err != nil {
+				return err
+			}
+			if len(apis) > 0 {
+				slog.Info(fmt.Sprintf("%s: affected APIs: %s", language, strings.Join(apis, ", ")))
+				affected = append(affected, language)
+			}
+		}
+		if len(affected) == 0 {
+			slog.Info(fmt.Sprintf("No configured APIs affected by %s", request.source))
+			return nil
+		}
+		languages = affected
+	}
+
+	saved := []*string{&flagLanguage, &flagAPIRoot, &flagWorkRoot, &flagOutput}
+	values := make([]string, len(saved))
+	for i, flag := range saved {
+		values[i] = *flag
+	}
+	defer func() {
+		for i, flag := range saved {
+			*flag = values[i]
+		}
+	}()
+	flagLanguage = strings.Join(languages, ",")
+	resetRunResults()
+	slog.Info(fmt.Sprintf("Running update-apis for %s (%s)", request.source, flagLanguage))
+	return CmdUpdateApis.Run(ctx)
+}
+
+// affectedAPIs returns the APIs configured in the language repo which contain
+// any of the changed googleapis files (or are any of the changed paths),
+// other than those excluded by the language (see loadExclusions).
+func affectedAPIs(ctx context.Context, language string, changed []string) ([]string, error) {
+	state, exclusions, err := languageRepoInputs(ctx, language)
+	if err != nil {
+		return nil, err
+	}
+	var apis []string
+	for _, apiState := range state.ApiGenerationStates {
+		if !slices.ContainsFunc(changed, func(file string) bool {
+			return file == apiState.Id || strings.HasPrefix(file, apiState.Id+"/")
+		}) {
+			continue
+		}
+		if reason := exclusionReason(exclusions, apiState.Id); reason != "" {
+			slog.Info(fmt.Sprintf("%s: ignoring excluded API %s (%s)", language, apiState.Id, reason))
+			continue
+		}
+		apis = append(apis, apiState.Id)
+	}
+	return apis, nil
+}
+
+// languageRepoInputs loads the pipeline state and exclusions of the language
+// repo: from -repo-root if specified, or otherwise from a clone of the
+// language repo.
+func languageRepoInputs(ctx context.Context, language string) (*statepb.PipelineState, []apiExclusion, error) {
+	repoRoot := flagRepoRoot
+	if repoRoot == "" {
+		tmpRoot, err := os.MkdirTemp("", fmt.Sprintf("librarian-serve-%s-", time.Now().Format("20060102T150405")))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer os.RemoveAll(tmpRoot)
+		languageRepo, err := cloneLanguageRepo(ctx, language, tmpRoot)
+		if err != nil {
+			return nil, nil, err
+		}
+		repoRoot = languageRepo.Dir
+	}
+	generatorInput := filepath.Join(repoRoot, "generator-input")
+	state, err := loadStateFile(filepath.Join(generatorInput, "pipeline-state.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	exclusions, err := loadExclusions(generatorInput)
+	if err != nil {
+		return nil, nil, err
+	}
+	return state, exclusions, nil
+}
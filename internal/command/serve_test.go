@@ -0,0 +1,65 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPushEventChangedFiles(t *testing.T) {
+	payload := func(commits int) *pushEvent {
+		var files []string
+		for i := range commits {
+			files = append(files, fmt.Sprintf(`{"modified": ["google/example/v%d/example.proto"]}`, i+1))
+		}
+		var push pushEvent
+		if err := json.Unmarshal([]byte(`{"after": "abc", "commits": [`+strings.Join(files, ",")+`]}`), &push); err != nil {
+			t.Fatal(err)
+		}
+		return &push
+	}
+	if got, want := payload(2).changedFiles(), []string{"google/example/v1/example.proto", "google/example/v2/example.proto"}; !slices.Equal(got, want) {
+		t.Errorf("changedFiles() = %v; want %v", got, want)
+	}
+	if got := payload(0).changedFiles(); got == nil || len(got) != 0 {
+		t.Errorf("changedFiles() with no commits = %#v; want an empty, non-nil slice", got)
+	}
+	if got := payload(pushEventMaxCommits).changedFiles(); got != nil {
+		t.Errorf("changedFiles() with %d commits = %v; want nil, to check all APIs", pushEventMaxCommits, got)
+	}
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	for _, test := range []struct {
+		addr string
+		want bool
+	}{
+		{"localhost:8080", true},
+		{"127.0.0.1:8080", true},
+		{"[::1]:8080", true},
+		{":8080", false},
+		{"0.0.0.0:8080", false},
+		{"example.com:8080", false},
+		{"8080", false},
+	} {
+		if got := isLoopbackAddress(test.addr); got != test.want {
+			t.Errorf("isLoopbackAddress(%q) = %v; want %v", test.addr, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// CmdStatus reports the generation status of each library in a language repo:
+// the googleapis commit each of its APIs was last generated from, how many
+// googleapis commits affecting the API have been made since, and any open
+// pull requests generated for it. The generator image in the pipeline state
+// (and its digest, if the image has been pulled) is reported for the repo as a
+// whole. Open pull requests are only reported if a GitHub token is available.
+var CmdStatus = &Command{
+	Name:  "status",
+	Short: "Report the generation status of each library in a language repo.",
+	Run: func(ctx context.Context) error {
+		if !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		switch flagFormat {
+		case "text", "json":
+		default:
+			return fmt.Errorf("invalid -format: %q; must be text or json", flagFormat)
+		}
+		tmpRoot, err := createTmpWorkingRoot(time.Now())
+		if err != nil {
+			return err
+		}
+		var languageRepo *gitrepo.Repo
+		if flagRepoRoot == "" {
+			languageRepo, err = cloneLanguageRepo(ctx, flagLanguage, tmpRoot)
+		} else {
+			var repoRoot string
+			if repoRoot, err = filepath.Abs(flagRepoRoot); err != nil {
+				return err
+			}
+			languageRepo, err = gitrepo.Open(ctx, repoRoot)
+		}
+		if err != nil {
+			return err
+		}
+		state, err := loadState(languageRepo)
+		if err != nil {
+			return err
+		}
+
+		var apiRepo *gitrepo.Repo
+		if flagAPIRoot == "" {
+			apiRepo, err = cloneGoogleapis(ctx, tmpRoot)
+		} else {
+			var apiRoot string
+			if apiRoot, err = filepath.Abs(flagAPIRoot); err != nil {
+				return err
+			}
+			apiRepo, err = gitrepo.Open(ctx, apiRoot)
+		}
+		if err != nil {
+			return err
+		}
+
+		status, err := repoStatus(ctx, languageRepo, apiRepo, state)
+		if err != nil {
+			return err
+		}
+		if flagFormat == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(status)
+		}
+		return writeStatusText(status)
+	},
+}
+
+// languageRepoStatus is the status reported by the status command.
+type languageRepoStatus struct {
+	Language string `json:"language"`
+	Image    string `json:"image"`
+	// ImageDigest is the digest of Image, if it has been pulled.
+	ImageDigest string           `json:"imageDigest,omitempty"`
+	Libraries   []*libraryStatus `json:"libraries"`
+	// UnreleasedAPIs are APIs which are generated, but not part of any library.
+	UnreleasedAPIs []*apiStatus `json:"unreleasedApis,omitempty"`
+}
+
+// libraryStatus is the status of a library in the language repo.
+type libraryStatus struct {
+	ID             string       `json:"id"`
+	CurrentVersion string       `json:"currentVersion,omitempty"`
+	APIs           []*apiStatus `json:"apis"`
+	// PullRequests are the URLs of open pull requests generated for any of the
+	// library's APIs (including those generated for all APIs).
+	PullRequests []string `json:"pullRequests"`
+}
+
+// apiStatus is the generation status of an API.
+type apiStatus struct {
+	Path                string `json:"path"`
+	LastGeneratedCommit string `json:"lastGeneratedCommit,omitempty"`
+	// PendingCommits is the number of googleapis commits affecting the API
+	// since LastGeneratedCommit, or nil if the API has never been generated.
+	PendingCommits *int `json:"pendingCommits,omitempty"`
+}
+
+// pullRequestMarkerPattern matches the marker embedded in generated pull
+// requests by pullRequestMarker, capturing the API scope.
+var pullRequestMarkerPattern = regexp.MustCompile(`<!-- librarian:api-path=(\S+) -->`)
+
+// repoStatus determines the status of each library in state, using apiRepo
+// for the googleapis history.
+func repoStatus(ctx context.Context, languageRepo, apiRepo *gitrepo.Repo, state *statepb.PipelineState) (*languageRepoStatus, error) {
+	status := &languageRepoStatus{
+		Language:  flagLanguage,
+		Image:     deriveImage(state),
+		Libraries: []*libraryStatus{},
+	}
+	if digest, err := container.ImageDigest(ctx, &container.Options{Image: status.Image}); err == nil {
+		_, status.ImageDigest, _ = strings.Cut(digest, "@")
+	}
+
+	apis := map[string]*apiStatus{}
+	for _, apiState := range state.ApiGenerationStates {
+		api := &apiStatus{Path: apiState.Id, LastGeneratedCommit: apiState.LastGeneratedCommit}
+		if api.LastGeneratedCommit != "" {
+			commits, err := gitrepo.GetApiCommits(ctx, apiRepo, api.Path, api.LastGeneratedCommit)
+			if err != nil {
+				return nil, fmt.Errorf("unable to find googleapis commits for %s: %w", api.Path, err)
+			}
+			pending := len(commits)
+			api.PendingCommits = &pending
+		}
+		apis[api.Path] = api
+	}
+
+	pullRequests, err := generatedPullRequests(ctx, languageRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	released := map[string]bool{}
+	for _, library := range state.LibraryReleaseStates {
+		libStatus := &libraryStatus{
+			ID:             library.Id,
+			CurrentVersion: library.CurrentVersion,
+			APIs:           []*apiStatus{},
+			PullRequests:   append([]string{}, pullRequests["all"]...),
+		}
+		for _, apiID := range library.ApiIds {
+			released[apiID] = true
+			api, ok := apis[apiID]
+			if !ok {
+				api = &apiStatus{Path: apiID}
+			}
+			libStatus.APIs = append(libStatus.APIs, api)
+			libStatus.PullRequests = append(libStatus.PullRequests, pullRequests[apiID]...)
+		}
+		status.Libraries = append(status.Libraries, libStatus)
+	}
+	for _, apiState := range state.ApiGenerationStates {
+		if !released[apiState.Id] {
+			status.UnreleasedAPIs = append(status.UnreleasedAPIs, apis[apiState.Id])
+		}
+	}
+	return status, nil
+}
+
+// generatedPullRequests returns the URLs of the open pull requests generated
+// by librarian in the language repo, keyed by the API path they were generated
+// for (or "all"). If no GitHub token is available, or with -offline, no pull
+// requests are returned.
+func generatedPullRequests(ctx context.Context, languageRepo *gitrepo.Repo) (map[string][]string, error) {
+	if flagOffline {
+		slog.Info("Open pull requests are not reported with -offline.")
+		return nil, nil
+	}
+	if err := resolveGitHubToken(ctx); err != nil {
+		return nil, err
+	}
+	if forgeToken() == "" {
+		slog.Warn(fmt.Sprintf("No %s token is available; open pull requests are not reported.", forgeName()))
+		return nil, nil
+	}
+	forge, err := gitrepo.NewForge(flagForge, languageRepo, forgeToken())
+	if err != nil {
+		return nil, err
+	}
+	prs, err := forge.FindOpenPullRequests(ctx, generatedBranchPrefix, "<!-- librarian:api-path=")
+	if err != nil {
+		return nil, err
+	}
+	byAPI := map[string][]string{}
+	for _, pr := range prs {
+		if match := pullRequestMarkerPattern.FindStringSubmatch(pr.Body); match != nil {
+			// Pull requests configuring a list of APIs have comma-separated paths.
+			for _, apiPath := range strings.Split(match[1], ",") {
+				byAPI[apiPath] = append(byAPI[apiPath], pr.HTMLURL)
+			}
+		}
+	}
+	return byAPI, nil
+}
+
+// writeStatusText writes status as a table, with a row for each API.
+func writeStatusText(status *languageRepoStatus) error {
+	digest := status.ImageDigest
+	if digest == "" {
+		digest = "unknown (image not pulled)"
+	}
+	fmt.Printf("Image: %s\nDigest: %s\n\n", status.Image, digest)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "LIBRARY\tVERSION\tAPI\tLAST GENERATED\tPENDING\tPULL REQUESTS")
+	writeRow := func(library, version string, api *apiStatus, pullRequests []string) {
+		apiPath, lastGenerated, pending := "-", "-", "-"
+		if api != nil {
+			apiPath = api.Path
+			if api.LastGeneratedCommit != "" {
+				lastGenerated = api.LastGeneratedCommit[:min(len(api.LastGeneratedCommit), 12)]
+			}
+			if api.PendingCommits != nil {
+				pending = fmt.Sprint(*api.PendingCommits)
+			}
+		}
+		prs := "-"
+		if len(pullRequests) > 0 {
+			prs = strings.Join(pullRequests, " ")
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", library, version, apiPath, lastGenerated, pending, prs)
+	}
+	for _, library := range status.Libraries {
+		version := library.CurrentVersion
+		if version == "" {
+			version = "-"
+		}
+		if len(library.APIs) == 0 {
+			writeRow(library.ID, version, nil, library.PullRequests)
+		}
+		for i, api := range library.APIs {
+			// Pull requests are listed once per library, on its first row.
+			var pullRequests []string
+			if i == 0 {
+				pullRequests = library.PullRequests
+			}
+			writeRow(library.ID, version, api, pullRequests)
+		}
+	}
+	for _, api := range status.UnreleasedAPIs {
+		writeRow("(none)", "-", api, nil)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/statepb"
+)
+
+// CmdVerify regenerates each configured API from the googleapis commit it was
+// last generated from, and fails if the result differs from the code committed
+// in the language repo. This detects repositories which have drifted from
+// their generators, e.g. due to manual edits or generator changes which
+// haven't been rolled out.
+var CmdVerify = &Command{
+	Name:  "verify",
+	Short: "Verify that generated code in a language repo matches its generator",
+	Run: func(ctx context.Context) error {
+		if !supportedLanguages[flagLanguage] {
+			return fmt.Errorf("invalid -language flag specified: %q", flagLanguage)
+		}
+		if flagAPIPath != "" {
+			apiPath, err := normalizeAPIPath(flagAPIPath)
+			if err != nil {
+				return err
+			}
+			flagAPIPath = apiPath
+		}
+
+		if err := preflight(); err != nil {
+			return err
+		}
+		release, err := acquireRepoLock()
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		tmpRoot, err := createTmpWorkingRoot(time.Now())
+		if err != nil {
+			return err
+		}
+		outputRoot := filepath.Join(tmpRoot, "output")
+		if err := os.MkdirAll(outputRoot, 0755); err != nil {
+			return err
+		}
+		recordArtifactDir("output", outputRoot)
+		setReportDir(outputRoot)
+
+		var apiRepo *gitrepo.Repo
+		if flagAPIRoot == "" {
+			apiRepo, err = cloneGoogleapis(ctx, tmpRoot)
+		} else {
+			var apiRoot string
+			apiRoot, err = filepath.Abs(flagAPIRoot)
+			if err != nil {
+				return err
+			}
+			apiRepo, err = gitrepo.Open(ctx, apiRoot)
+		}
+		if err != nil {
+			return err
+		}
+
+		var languageRepo *gitrepo.Repo
+		if flagRepoRoot == "" {
+			languageRepo, err = cloneLanguageRepo(ctx, flagLanguage, tmpRoot)
+			if err != nil {
+				return err
+			}
+		} else {
+			repoRoot, err := filepath.Abs(flagRepoRoot)
+			if err != nil {
+				return err
+			}
+			languageRepo, err = gitrepo.Open(ctx, repoRoot)
+			if err != nil {
+				return err
+			}
+			clean, err := gitrepo.IsClean(ctx, languageRepo)
+			if err != nil {
+				return err
+			}
+			if !clean {
+				return errors.New("language repo must be clean before verification")
+			}
+		}
+
+		state, err := loadState(languageRepo)
+		if err != nil {
+			return err
+		}
+		containerOpts := containerOptions(state)
+
+		// Take a defensive copy of the generator input directory from the language repo.
+		generatorInput := filepath.Join(tmpRoot, "generator-input")
+		if err := os.CopyFS(generatorInput, os.DirFS(filepath.Join(languageRepo.Dir, "generator-input"))); err != nil {
+			return err
+		}
+
+		// The googleapis tree at each commit is exported separately, so that
+		// the API repo itself is never modified.
+		apiRoots := map[string]string{}
+		var drifted []string
+		for _, apiState := range state.ApiGenerationStates {
+			if flagAPIPath != "" && flagAPIPath != apiState.Id {
+				continue
+			}
+			if apiState.AutomationLevel == statepb.AutomationLevel_AUTOMATION_LEVEL_BLOCKED {
+				slog.Info(fmt.Sprintf("Ignoring blocked API: '%s'", apiState.Id))
+				continue
+			}
+			if apiState.LastGeneratedCommit == "" {
+				slog.Warn(fmt.Sprintf("API '%s' has never been generated; skipping.", apiState.Id))
+				continue
+			}
+			apiRoot, ok := apiRoots[apiState.LastGeneratedCommit]
+			if !ok {
+				apiRoot = filepath.Join(tmpRoot, "googleapis-"+apiState.LastGeneratedCommit)
+				slog.Info(fmt.Sprintf("Exporting googleapis at %s", apiState.LastGeneratedCommit))
+				if err := gitrepo.ExportTree(ctx, apiRepo, apiState.LastGeneratedCommit, apiRoot); err != nil {
+					return err
+				}
+				apiRoots[apiState.LastGeneratedCommit] = apiRoot
+			}
+			matches, err := verifyApi(ctx, languageRepo, containerOpts, apiRoot, generatorInput, tmpRoot, apiState)
+			if err != nil {
+				return err
+			}
+			if !matches {
+				drifted = append(drifted, apiState.Id)
+			}
+		}
+		if len(drifted) > 0 {
+			return fmt.Errorf("generated code differs from the language repo for: %s", strings.Join(drifted, ", "))
+		}
+		slog.Info("Generated code matches the language repo.")
+		return nil
+	},
+}
+
+// verifyApi regenerates a single API into the language repo, reporting whether
+// the language repo is unchanged as a result. The language repo is restored to
+// its original state afterwards, even if verification fails.
+func verifyApi(ctx context.Context, languageRepo *gitrepo.Repo, containerOpts *container.Options, apiRoot, generatorInput, tmpRoot string, apiState *statepb.ApiGenerationState) (clean bool, err error) {
+	slog.Info(fmt.Sprintf("Verifying '%s'", apiState.Id))
+	outputDir := filepath.Join(tmpRoot, "output", apiState.Id)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return false, err
+	}
+
+	channel := releaseChannel(apiState.Id, apiState)
+	if err := container.Generate(ctx, containerOpts, apiRoot, outputDir, generatorInput, apiState.Id, channel, generationConfig(apiRoot, apiState.Id)); err != nil {
+		return false, err
+	}
+	if err := checkGenerateOutput(outputDir, flagLanguage, apiState.Id); err != nil {
+		return false, err
+	}
+	defer func() {
+		if resetErr := gitrepo.ResetHard(ctx, languageRepo); resetErr != nil && err == nil {
+			err = resetErr
+		}
+		if removeErr := gitrepo.RemoveUntracked(ctx, languageRepo); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}()
+	if err := container.Clean(ctx, containerOpts, languageRepo.Dir, apiState.Id); err != nil {
+		return false, err
+	}
+	if err := os.CopyFS(languageRepo.Dir, os.DirFS(outputDir)); err != nil {
+		return false, err
+	}
+
+	if clean, err = gitrepo.IsClean(ctx, languageRepo); err != nil {
+		return false, err
+	}
+	if !clean {
+		slog.Warn(fmt.Sprintf("Regenerating '%s' changed the language repo", apiState.Id))
+		if err := gitrepo.PrintStatus(ctx, languageRepo); err != nil {
+			return false, err
+		}
+	}
+	return clean, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// failingCleanRunner is testlangRunner, except that its clean command changes
+// the repo and then fails.
+type failingCleanRunner struct{ testlangRunner }
+
+func (r failingCleanRunner) Run(ctx context.Context, image string, mounts, args []string) error {
+	if args[0] != "clean" {
+		return r.testlangRunner.Run(ctx, image, mounts, args)
+	}
+	repoRoot := strings.Split(mounts[0], ":")[0]
+	if err := os.WriteFile(filepath.Join(repoRoot, "stray.txt"), []byte("half-cleaned"), 0644); err != nil {
+		return err
+	}
+	return errors.New("clean failed")
+}
+
+func TestVerifyApiRestoresRepoOnFailure(t *testing.T) {
+	ctx := context.Background()
+	flagLanguage = "testlang"
+	t.Cleanup(func() { flagLanguage = "" })
+	useTestlang(t)
+	apiRoot, languageRoot := setUpScratchRepos(t)
+	languageRepo, err := gitrepo.Open(ctx, languageRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadState(languageRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &container.Options{Image: "testlang", Runner: failingCleanRunner{}}
+	if _, err := verifyApi(ctx, languageRepo, opts, apiRoot, filepath.Join(languageRoot, "generator-input"), t.TempDir(), state.ApiGenerationStates[0]); err == nil {
+		t.Fatal("verifyApi() succeeded; want the clean failure")
+	}
+	clean, err := gitrepo.IsClean(ctx, languageRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clean {
+		t.Error("language repo has changes after verifyApi failed; want it restored")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/googleapis/librarian/internal/container"
+)
+
+// Version is the version of librarian. It's normally set at build time, using
+// -ldflags "-X github.com/googleapis/librarian/internal/command.Version=v1.2.3".
+// If unset, the module version from the build information is used.
+var Version string
+
+// versionInfo is the information reported by the version command.
+type versionInfo struct {
+	Version         string         `json:"version"`
+	Commit          string         `json:"commit,omitempty"`
+	CommitTime      string         `json:"commitTime,omitempty"`
+	Modified        bool           `json:"modified,omitempty"`
+	GoVersion       string         `json:"goVersion"`
+	ProtocolVersion int            `json:"protocolVersion"`
+	Images          []imageVersion `json:"images"`
+}
+
+type imageVersion struct {
+	Language string `json:"language"`
+	Image    string `json:"image"`
+	Digest   string `json:"digest,omitempty"`
+}
+
+var CmdVersion = &Command{
+	Name:  "version",
+	Short: "Print the librarian version, container protocol version and language images",
+	Run: func(ctx context.Context) error {
+		info := &versionInfo{
+			Version:         Version,
+			ProtocolVersion: container.ProtocolVersion,
+		}
+		if buildInfo, ok := debug.ReadBuildInfo(); ok {
+			info.GoVersion = buildInfo.GoVersion
+			if info.Version == "" {
+				info.Version = buildInfo.Main.Version
+			}
+			for _, setting := range buildInfo.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Commit = setting.Value
+				case "vcs.time":
+					info.CommitTime = setting.Value
+				case "vcs.modified":
+					info.Modified = setting.Value == "true"
+				}
+			}
+		}
+
+		languages := completionLanguages()
+		if flagLanguage != "" {
+			languages = []string{flagLanguage}
+		}
+		for _, language := range languages {
+			image := imageVersion{Language: language, Image: deriveLanguageImage(language, nil)}
+			// The digest is only available if the image has been pulled.
+			image.Digest, _ = container.ImageDigest(ctx, &container.Options{Image: image.Image})
+			info.Images = append(info.Images, image)
+		}
+
+		switch flagFormat {
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(info)
+		case "text":
+			fmt.Printf("librarian %s\n", info.Version)
+			if info.Commit != "" {
+				modified := ""
+				if info.Modified {
+					modified = " (modified)"
+				}
+				fmt.Printf("commit: %s%s %s\n", info.Commit, modified, info.CommitTime)
+			}
+			fmt.Printf("go: %s\n", info.GoVersion)
+			fmt.Printf("container protocol: %d\n", info.ProtocolVersion)
+			for _, image := range info.Images {
+				digest := image.Digest
+				if digest == "" {
+					digest = "(not pulled)"
+				}
+				fmt.Printf("%s: %s %s\n", image.Language, image.Image, digest)
+			}
+			return nil
+		default:
+			return fmt.Errorf("invalid -format: %q; must be text or json", flagFormat)
+		}
+	},
+}
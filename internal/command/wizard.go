@@ -0,0 +1,281 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxWizardMatches is the maximum number of API paths shown for a search.
+const maxWizardMatches = 10
+
+// errWizardCancelled is returned when the user declines to configure the API.
+var errWizardCancelled = errors.New("configuration cancelled")
+
+// configureWizard prompts for the language and API path to configure (unless
+// already specified with -language and -api-path), previews the result and
+// asks for confirmation. API paths can be searched for if googleapis is
+// available locally, either via -api-root or the clone cache.
+func configureWizard(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	prompt := func(text string) (string, error) {
+		fmt.Fprint(out, text)
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	languages := completionLanguages()
+	for !supportedLanguages[flagLanguage] {
+		answer, err := prompt(fmt.Sprintf("Language (%s): ", strings.Join(languages, ", ")))
+		if err != nil {
+			return err
+		}
+		if len(languages) == 1 && answer == "" {
+			answer = languages[0]
+		}
+		flagLanguage = answer
+	}
+
+	var apiPaths []string
+	var err error
+	if flagAPIRoot != "" {
+		apiPaths, err = listAPIPaths(flagAPIRoot)
+	} else {
+		apiPaths, err = cachedAPIPaths()
+	}
+	if err != nil {
+		return err
+	}
+	for flagAPIPath == "" {
+		if len(apiPaths) == 0 {
+			answer, err := prompt("API path (e.g. google/cloud/functions/v2): ")
+			if err != nil {
+				return err
+			}
+			flagAPIPath = answer
+			continue
+		}
+		query, err := prompt("Search API paths: ")
+		if err != nil {
+			return err
+		}
+		matches := fuzzyMatches(query, apiPaths)
+		if len(matches) == 0 {
+			fmt.Fprintln(out, "No matching API paths.")
+			continue
+		}
+		for i, match := range matches {
+			fmt.Fprintf(out, "  %d) %s\n", i+1, match)
+		}
+		answer, err := prompt("Select a number, or press enter to search again: ")
+		if err != nil {
+			return err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(matches) {
+			flagAPIPath = matches[n-1]
+		}
+	}
+	apiPath, err := normalizeAPIPath(flagAPIPath)
+	if err != nil {
+		return err
+	}
+	flagAPIPath = apiPath
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Language:        %s\n", flagLanguage)
+	fmt.Fprintf(out, "API path:        %s\n", flagAPIPath)
+	apiRoot := flagAPIRoot
+	if apiRoot == "" {
+		apiRoot, _ = cachedGoogleapisRoot()
+	}
+	fmt.Fprintf(out, "Library name:    %s\n", deriveLibraryName(flagLanguage, apiRoot, flagAPIPath))
+	if channel := releaseChannel(flagAPIPath, nil); channel != "" {
+		fmt.Fprintf(out, "Release channel: %s\n", channel)
+	}
+	fmt.Fprintf(out, "Image:           %s\n", deriveImage(nil))
+	answer, err := prompt("Configure this API? [y/N]: ")
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		return errWizardCancelled
+	}
+	return nil
+}
+
+// promptAPIVersion asks the user to choose one of the versions of the API at
+// apiPath.
+func promptAPIVersion(in io.Reader, out io.Writer, apiPath string, versions []string) (string, error) {
+	reader := bufio.NewReader(in)
+	fmt.Fprintf(out, "%s has no stable version. Available versions:\n", apiPath)
+	for i, version := range versions {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, version)
+	}
+	for {
+		fmt.Fprint(out, "Select a number: ")
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && n >= 1 && n <= len(versions) {
+			return versions[n-1], nil
+		}
+	}
+}
+
+// fuzzyMatches returns the candidates which contain the characters of query
+// in order (ignoring case), best matches first. Matches with the query
+// characters closer together, and shorter candidates, rank higher.
+func fuzzyMatches(query string, candidates []string) []string {
+	type match struct {
+		candidate string
+		score     int
+	}
+	query = strings.ToLower(strings.ReplaceAll(query, " ", ""))
+	var matches []match
+	for _, candidate := range candidates {
+		lower := strings.ToLower(candidate)
+		start, pos := -1, 0
+		for _, r := range query {
+			i := strings.IndexRune(lower[pos:], r)
+			if i < 0 {
+				start = -2
+				break
+			}
+			if start == -1 {
+				start = pos + i
+			}
+			pos += i + 1
+		}
+		if start == -2 {
+			continue
+		}
+		if start == -1 {
+			start = 0
+		}
+		matches = append(matches, match{candidate, (pos - start) + len(candidate)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+	var result []string
+	for i := 0; i < len(matches) && i < maxWizardMatches; i++ {
+		result = append(result, matches[i].candidate)
+	}
+	return result
+}
+
+// deriveLibraryName returns the conventional library name for an API in the
+// given language, using the API's service config in apiRoot (if not empty)
+// where the name depends on it. This is only a preview: the language container
+// determines the actual name during configuration.
+func deriveLibraryName(language, apiRoot, apiPath string) string {
+	switch language {
+	case "dotnet":
+		// e.g. google/cloud/functions/v2 => Google.Cloud.Functions.V2
+		var segments []string
+		for _, segment := range strings.Split(apiPath, "/") {
+			runes := []rune(segment)
+			if len(runes) == 0 {
+				continue
+			}
+			runes[0] = unicode.ToUpper(runes[0])
+			segments = append(segments, string(runes))
+		}
+		return strings.Join(segments, ".")
+	case "php":
+		// google-cloud-php has a component directory for each product (with
+		// each API version in it), named after the API's title, e.g. "Secret
+		// Manager API" => SecretManager. Without a service config, the name
+		// can only be guessed from the API path, e.g.
+		// google/cloud/secretmanager/v1 => Secretmanager.
+		if apiRoot != "" {
+			if metadata := apiMetadata(apiRoot, apiPath); metadata != nil && metadata.Title != "" {
+				return phpComponentName(metadata.Title)
+			}
+		}
+		runes := []rune(filepath.Base(filepath.Dir(apiPath)))
+		if len(runes) > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		return string(runes) + " (a guess, as the API's service config is unavailable)"
+	case "ruby":
+		// google-cloud-ruby has a versioned gem for each API version, and a
+		// wrapper gem for the product, e.g. google/cloud/functions/v2 =>
+		// google-cloud-functions-v2 and google-cloud-functions
+		name := googleCloudPackageName(apiPath)
+		return fmt.Sprintf("%s (wrapper: %s)", name, googleCloudPackageName(path.Dir(apiPath)))
+	case "rust":
+		return googleCloudPackageName(apiPath)
+	default:
+		return filepath.Base(filepath.Dir(apiPath))
+	}
+}
+
+// phpComponentName returns the google-cloud-php component name for an API
+// with the given title: the words of the title, without any "Google" or
+// "Cloud" prefix, "API" suffix or parenthesized abbreviation, each starting
+// with an uppercase letter, e.g. "Cloud Pub/Sub API" => PubSub and "BigQuery
+// Data Transfer API" => BigQueryDataTransfer.
+func phpComponentName(title string) string {
+	if i := strings.Index(title, "("); i >= 0 {
+		if j := strings.Index(title[i:], ")"); j >= 0 {
+			title = title[:i] + title[i+j+1:]
+		}
+	}
+	words := strings.FieldsFunc(title, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for len(words) > 1 && (words[0] == "Google" || words[0] == "Cloud") {
+		words = words[1:]
+	}
+	if len(words) > 1 && words[len(words)-1] == "API" {
+		words = words[:len(words)-1]
+	}
+	var name strings.Builder
+	for _, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		name.WriteString(string(runes))
+	}
+	return name.String()
+}
+
+// googleCloudPackageName returns the conventional package name for an API path
+// in languages which name packages after the path with a google-cloud prefix,
+// e.g. google/cloud/functions/v2 => google-cloud-functions-v2, and
+// google/pubsub/v1 => google-cloud-pubsub-v1.
+func googleCloudPackageName(apiPath string) string {
+	name := strings.ReplaceAll(apiPath, "/", "-")
+	if !strings.HasPrefix(name, "google-cloud-") {
+		name = "google-cloud-" + strings.TrimPrefix(name, "google-")
+	}
+	return name
+}
+
+// isInteractive reports whether standard input is a terminal.
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeriveLibraryNameForPHP(t *testing.T) {
+	apiRoot := t.TempDir()
+	for apiPath, title := range map[string]string{
+		"google/cloud/secretmanager/v1":         "Secret Manager API",
+		"google/pubsub/v1":                      "Cloud Pub/Sub API",
+		"google/cloud/bigquery/datatransfer/v1": "BigQuery Data Transfer API",
+		"google/cloud/kms/v1":                   "Cloud Key Management Service (KMS) API",
+	} {
+		dir := filepath.Join(apiRoot, filepath.FromSlash(apiPath))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		config := "type: google.api.Service\ntitle: " + title + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "service_v1.yaml"), []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		apiPath string
+		want    string
+	}{
+		{"google/cloud/secretmanager/v1", "SecretManager"},
+		{"google/pubsub/v1", "PubSub"},
+		{"google/cloud/bigquery/datatransfer/v1", "BigQueryDataTransfer"},
+		{"google/cloud/kms/v1", "KeyManagementService"},
+	} {
+		if got := deriveLibraryName("php", apiRoot, test.apiPath); got != test.want {
+			t.Errorf("deriveLibraryName(php, %s) = %q; want %q", test.apiPath, got, test.want)
+		}
+	}
+
+	// Without a service config, the name is labelled as a guess.
+	if got := deriveLibraryName("php", "", "google/cloud/secretmanager/v1"); !strings.HasPrefix(got, "Secretmanager (") {
+		t.Errorf("deriveLibraryName(php) without a service config = %q; want a guess labelled as such", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+
+	"github.com/googleapis/librarian/internal/container"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// worktreeBuilds runs the build validation of each API's commit in its own
+// linked worktree of the language repo (checked out at that commit), so that
+// up to -parallel-build builds can run at once, and alongside the next APIs
+// being committed, without cloning the language repo again.
+type worktreeBuilds struct {
+	repo  *gitrepo.Repo
+	root  string
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu    sync.Mutex
+	count int
+	errs  []error
+}
+
+func newWorktreeBuilds(repo *gitrepo.Repo, root string) *worktreeBuilds {
+	return &worktreeBuilds{
+		repo:  repo,
+		root:  root,
+		slots: make(chan struct{}, flagParallelBuild),
+	}
+}
+
+// start builds the API at the given commit in the background, waiting for a
+// free slot first. If an earlier build has already failed, no more builds are
+// started and the failure is returned.
+func (b *worktreeBuilds) start(ctx context.Context, containerOpts *container.Options, apiId, commit string) error {
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	if len(b.errs) > 0 {
+		b.mu.Unlock()
+		<-b.slots
+		return errors.Join(b.errs...)
+	}
+	b.count++
+	dir := filepath.Join(b.root, fmt.Sprint(b.count))
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() { <-b.slots }()
+		if err := b.build(ctx, containerOpts, apiId, commit, dir); err != nil {
+			b.mu.Lock()
+			b.errs = append(b.errs, fmt.Errorf("building '%s' at %s: %w", apiId, commit, err))
+			b.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// build runs the build in a new worktree, checking that it doesn't create any
+// changes, and then removes the worktree.
+func (b *worktreeBuilds) build(ctx context.Context, containerOpts *container.Options, apiId, commit, dir string) error {
+	worktree, err := gitrepo.AddWorktree(ctx, b.repo, dir, commit)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := gitrepo.RemoveWorktree(context.Background(), b.repo, dir); err != nil {
+			slog.Warn(fmt.Sprintf("Unable to remove worktree %s: %s", dir, err))
+		}
+	}()
+	slog.Info(fmt.Sprintf("Building '%s' in worktree %s", apiId, dir))
+	if err := container.Build(ctx, containerOpts, "repo-root", worktree.Dir, apiId); err != nil {
+		return err
+	}
+	clean, err := gitrepo.IsClean(ctx, worktree)
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("building '%s' created changes in the repo", apiId)
+	}
+	return nil
+}
+
+// wait waits for all the builds started so far, returning their failures.
+func (b *worktreeBuilds) wait() error {
+	b.wg.Wait()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return errors.Join(b.errs...)
+}
@@ -16,27 +16,247 @@ package container
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/googleapis/librarian/internal/metrics"
 )
 
-func Generate(ctx context.Context, image, apiRoot, output, generatorInput, apiPath string) error {
-	return runGenerate(image, apiRoot, output, generatorInput, apiPath)
+// ProtocolVersion is the version of the protocol (the commands, arguments and
+// mounts) used to communicate with language containers.
+//
+// Version 2 added the prepare-release and publish commands, the GAPIC options
+// (e.g. --grpc-service-config) passed to the generate command, and the API
+// metadata (e.g. --api-title) passed to the configure command.
+//
+// Version 3 mounts an output directory (/output) for every command, and allows
+// containers to report failures in an error.json file in it: a JSON object with
+// "code", "message" and (optionally) "details" (an array of strings).
+const ProtocolVersion = 3
+
+// ProtocolVersionLabel is the image label with which language container images
+// declare the protocol version they implement.
+const ProtocolVersionLabel = "com.google.librarian.protocol-version"
+
+// Options configures how language containers are run.
+type Options struct {
+	// Image is the language-specific container image to run.
+	Image string
+	// Experiments lists the experimental pipeline features which are enabled.
+	// They are passed to the container as a comma-separated --experiments argument.
+	Experiments []string
+	// BuildChecks lists the language-specific checks (e.g. "format" and "lint")
+	// the build command runs in addition to building and testing. They are
+	// passed to the build command as a comma-separated --checks argument, if
+	// the image declares BuildChecksFeature.
+	BuildChecks []string
+	// Commands lists the container commands the image supports, overriding
+	// any it declares in its CommandsLabel label, or is nil to use the label.
+	Commands []string
+	// Runner runs the container. If nil, containers are run using docker.
+	Runner Runner
+	// StepTimeout is the maximum duration of each container run. If it's
+	// exceeded, the container is killed. Zero means no timeout.
+	StepTimeout time.Duration
+	// Docker configures how containers are run when Runner is nil.
+	Docker DockerConfig
+	// LogTail, if not nil, retains the end of the output of the container
+	// commands run with these options, so that it can be reported separately
+	// from other runs in the same process.
+	LogTail *LogTail
+}
+
+// DockerConfig configures how containers are run using docker.
+type DockerConfig struct {
+	// Env lists the environment variables to set in each container, each of
+	// the form KEY=VALUE, or KEY to pass through the value from the host
+	// environment (which keeps the value out of the docker command line).
+	Env []string
+	// SecretsDir is a host directory mounted read-only at /secrets in each
+	// container, or empty for no secrets. Unlike other mounts, it isn't seen
+	// by Runners, so its content is never recorded.
+	SecretsDir string
+	// Network is the docker network mode for each container (e.g. "none" or
+	// "host"), HermeticNetwork to run only the steps which may need network
+	// access with the default network, or empty for the default network.
+	Network string
+	// CacheVolumes lists the named docker volumes to mount in the clean and
+	// build containers, each of the form "volume-name:container-path", to
+	// persist package caches (e.g. NuGet or Maven) between runs.
+	CacheVolumes []string
+	// User is the user (and optionally group) to run each container as, of the
+	// form "uid[:gid]", or empty to run as the image's default user (typically
+	// root). Running as the host user ensures that files written to mounted
+	// directories are owned by that user rather than root.
+	User string
+}
+
+// cacheSteps are the steps in which CacheVolumes are mounted.
+var cacheSteps = map[string]bool{
+	"clean": true,
+	"build": true,
+}
+
+// HermeticNetwork is a DockerConfig.Network value which runs the configure,
+// generate, clean and remove steps without network access, so that their output only
+// depends on their inputs. Other steps (e.g. build, which may need to restore
+// packages) use the default network.
+const HermeticNetwork = "hermetic"
+
+// offlineSteps are the steps which must not need network access.
+var offlineSteps = map[string]bool{
+	"configure": true,
+	"generate":  true,
+	"clean":     true,
+	"remove":    true,
+}
+
+// network returns the docker network mode for the given step, or an empty
+// string for the default network.
+func (config DockerConfig) network(step string) string {
+	if config.Network != HermeticNetwork {
+		return config.Network
+	}
+	if offlineSteps[step] {
+		return "none"
+	}
+	return ""
+}
+
+// Runner runs language containers.
+type Runner interface {
+	// Run runs the given image with the specified volume mounts (each of the
+	// form "host-path:container-path") and container arguments.
+	Run(ctx context.Context, image string, mounts, args []string) error
+}
+
+// GenerationConfig holds API-specific generation options, typically derived
+// from the API's BUILD.bazel file in googleapis. Paths are relative to the API
+// root.
+type GenerationConfig struct {
+	GRPCServiceConfig string
+	ServiceYAML       string
+	Transport         string
+	RestNumericEnums  bool
+}
+
+// args returns the container arguments for the options which are set.
+func (config *GenerationConfig) args() []string {
+	if config == nil {
+		return nil
+	}
+	var args []string
+	if config.GRPCServiceConfig != "" {
+		args = append(args, fmt.Sprintf("--grpc-service-config=%s", config.GRPCServiceConfig))
+	}
+	if config.ServiceYAML != "" {
+		args = append(args, fmt.Sprintf("--service-yaml=%s", config.ServiceYAML))
+	}
+	if config.Transport != "" {
+		args = append(args, fmt.Sprintf("--transport=%s", config.Transport))
+	}
+	if config.RestNumericEnums {
+		args = append(args, "--rest-numeric-enums")
+	}
+	return args
+}
+
+func Generate(ctx context.Context, opts *Options, apiRoot, output, generatorInput, apiPath, releaseChannel string, config *GenerationConfig) error {
+	return runGenerate(ctx, opts, apiRoot, output, generatorInput, apiPath, releaseChannel, config)
+}
+
+// descriptorsMount is the container path at which the directory containing a
+// descriptor set is mounted.
+const descriptorsMount = "/descriptors"
+
+// GenerateFromDescriptorSet generates the API at apiPath from a compiled
+// FileDescriptorSet rather than from proto sources. The directory containing
+// descriptorSet is mounted, so it shouldn't contain anything else. This is
+// only supported by images which declare DescriptorSetFeature.
+func GenerateFromDescriptorSet(ctx context.Context, opts *Options, descriptorSet, output, apiPath, releaseChannel string) error {
+	if opts.Image == "" {
+		return fmt.Errorf("image cannot be empty")
+	}
+	if descriptorSet == "" {
+		return fmt.Errorf("descriptorSet cannot be empty")
+	}
+	if output == "" {
+		return fmt.Errorf("output cannot be empty")
+	}
+	if apiPath == "" {
+		return fmt.Errorf("apiPath cannot be empty")
+	}
+	containerArgs := []string{
+		"generate",
+		fmt.Sprintf("--descriptor-set=%s/%s", descriptorsMount, filepath.Base(descriptorSet)),
+		"--output=/output",
+		fmt.Sprintf("--api-path=%s", apiPath),
+	}
+	mounts := []string{
+		fmt.Sprintf("%s:%s", filepath.Dir(descriptorSet), descriptorsMount),
+		fmt.Sprintf("%s:/output", output),
+	}
+	if releaseChannel != "" {
+		containerArgs = append(containerArgs, fmt.Sprintf("--release-channel=%s", releaseChannel))
+	}
+	return runDocker(ctx, opts, mounts, containerArgs)
+}
+
+func Clean(ctx context.Context, opts *Options, repoRoot, apiPath string) error {
+	return runClean(ctx, opts, repoRoot, apiPath)
 }
 
-func Clean(ctx context.Context, image, repoRoot, apiPath string) error {
-	return runClean(image, repoRoot, apiPath)
+func Build(ctx context.Context, opts *Options, rootOptionName, root, apiPath string) error {
+	return runBuild(ctx, opts, rootOptionName, root, apiPath)
 }
 
-func Build(ctx context.Context, image, rootOptionName, root, apiPath string) error {
-	return runBuild(image, rootOptionName, root, apiPath)
+// APIMetadata describes an API, typically as specified in its service config
+// in googleapis. It's passed to the configure command, e.g. for the container
+// to use in library metadata files.
+type APIMetadata struct {
+	Title            string
+	ShortName        string
+	DocumentationURI string
 }
 
-func Configure(ctx context.Context, image, apiRoot, apiPath, generatorInput string) error {
-	if image == "" {
+// args returns the container arguments for the metadata which is set.
+func (metadata *APIMetadata) args() []string {
+	if metadata == nil {
+		return nil
+	}
+	var args []string
+	if metadata.Title != "" {
+		args = append(args, fmt.Sprintf("--api-title=%s", metadata.Title))
+	}
+	if metadata.ShortName != "" {
+		args = append(args, fmt.Sprintf("--api-short-name=%s", metadata.ShortName))
+	}
+	if metadata.DocumentationURI != "" {
+		args = append(args, fmt.Sprintf("--documentation-uri=%s", metadata.DocumentationURI))
+	}
+	return args
+}
+
+// Configure configures the API at apiPath, updating the generator input
+// directory. If repoRoot is specified (which it should only be for images
+// which declare ConfigureRepoFeature), the language repo is also mounted, so
+// that the configuration can depend on the libraries already in the repo: for
+// example, Ruby configures a wrapper gem as well as the versioned gem for the
+// first version of a product. The container isn't expected to modify the repo.
+func Configure(ctx context.Context, opts *Options, apiRoot, apiPath, releaseChannel, generatorInput, repoRoot string, metadata *APIMetadata) error {
+	if opts.Image == "" {
 		return fmt.Errorf("image cannot be empty")
 	}
 	if apiRoot == "" {
@@ -54,15 +274,147 @@ func Configure(ctx context.Context, image, apiRoot, apiPath, generatorInput stri
 		"--generator-input=/generator-input",
 		fmt.Sprintf("--api-path=%s", apiPath),
 	}
+	if releaseChannel != "" {
+		containerArgs = append(containerArgs, fmt.Sprintf("--release-channel=%s", releaseChannel))
+	}
+	containerArgs = append(containerArgs, metadata.args()...)
 	mounts := []string{
 		fmt.Sprintf("%s:/apis", apiRoot),
 		fmt.Sprintf("%s:/generator-input", generatorInput),
 	}
-	return runDocker(image, mounts, containerArgs)
+	if repoRoot != "" {
+		containerArgs = append(containerArgs, "--repo-root=/repo")
+		mounts = append(mounts, fmt.Sprintf("%s:/repo", repoRoot))
+	}
+	return runDocker(ctx, opts, mounts, containerArgs)
 }
 
-func runGenerate(image, apiRoot, output, generatorInput, apiPath string) error {
-	if image == "" {
+// PrepareRelease prepares a release of the library with the given ID at the
+// given version, by updating files (e.g. version numbers and changelogs) in
+// the language repo.
+func PrepareRelease(ctx context.Context, opts *Options, repoRoot, libraryID, releaseVersion string) error {
+	if opts.Image == "" {
+		return fmt.Errorf("image cannot be empty")
+	}
+	if repoRoot == "" {
+		return fmt.Errorf("repoRoot cannot be empty")
+	}
+	if libraryID == "" {
+		return fmt.Errorf("libraryID cannot be empty")
+	}
+	if releaseVersion == "" {
+		return fmt.Errorf("releaseVersion cannot be empty")
+	}
+	mounts := []string{
+		fmt.Sprintf("%s:/repo", repoRoot),
+	}
+	containerArgs := []string{
+		"prepare-release",
+		"--repo-root=/repo",
+		fmt.Sprintf("--library-id=%s", libraryID),
+		fmt.Sprintf("--release-version=%s", releaseVersion),
+	}
+	return runDocker(ctx, opts, mounts, containerArgs)
+}
+
+// Remove removes the library with the given ID from the language repo, when
+// its API has been turned down: deleting its source and any references to it
+// (e.g. in solution files or package lists). The pipeline state is updated
+// by the caller. This command is optional, and is only run by images which
+// explicitly declare it (see Declares).
+func Remove(ctx context.Context, opts *Options, repoRoot, libraryID string) error {
+	if opts.Image == "" {
+		return fmt.Errorf("image cannot be empty")
+	}
+	if repoRoot == "" {
+		return fmt.Errorf("repoRoot cannot be empty")
+	}
+	if libraryID == "" {
+		return fmt.Errorf("libraryID cannot be empty")
+	}
+	mounts := []string{
+		fmt.Sprintf("%s:/repo", repoRoot),
+	}
+	containerArgs := []string{
+		"remove",
+		"--repo-root=/repo",
+		fmt.Sprintf("--library-id=%s", libraryID),
+	}
+	return runDocker(ctx, opts, mounts, containerArgs)
+}
+
+// Publish publishes the packages for the given release of a library, which
+// must already have been built in repoRoot. The credentials directory contains
+// any credentials required by the package registries, and is mounted at
+// /credentials.
+func Publish(ctx context.Context, opts *Options, repoRoot, libraryID, releaseVersion, credentials string) error {
+	if opts.Image == "" {
+		return fmt.Errorf("image cannot be empty")
+	}
+	if repoRoot == "" {
+		return fmt.Errorf("repoRoot cannot be empty")
+	}
+	if libraryID == "" {
+		return fmt.Errorf("libraryID cannot be empty")
+	}
+	if releaseVersion == "" {
+		return fmt.Errorf("releaseVersion cannot be empty")
+	}
+	if credentials == "" {
+		return fmt.Errorf("credentials cannot be empty")
+	}
+	mounts := []string{
+		fmt.Sprintf("%s:/repo", repoRoot),
+		fmt.Sprintf("%s:/credentials", credentials),
+	}
+	containerArgs := []string{
+		"publish",
+		"--repo-root=/repo",
+		"--credentials=/credentials",
+		fmt.Sprintf("--library-id=%s", libraryID),
+		fmt.Sprintf("--release-version=%s", releaseVersion),
+	}
+	return runDocker(ctx, opts, mounts, containerArgs)
+}
+
+// DebugShell starts an interactive shell in the language container, with the
+// directories used by the pipeline mounted at their usual locations: the API
+// root at /apis, the repo root at /repo, the generator input at
+// /generator-input and the output directory at /output. Empty directories are
+// not mounted. This is only supported with a local docker daemon.
+func DebugShell(ctx context.Context, opts *Options, shell, apiRoot, repoRoot, generatorInput, output string) error {
+	if opts.Image == "" {
+		return fmt.Errorf("image cannot be empty")
+	}
+	if host := remoteDockerHost(); host != "" {
+		return fmt.Errorf("debug shells are not supported with a remote docker host (%s)", host)
+	}
+	var mounts []string
+	for _, mount := range []struct{ hostPath, containerPath string }{
+		{apiRoot, apiRootMount},
+		{repoRoot, "/repo"},
+		{generatorInput, "/generator-input"},
+		{output, outputMount},
+		{opts.Docker.SecretsDir, "/secrets:ro"},
+	} {
+		if mount.hostPath != "" {
+			mounts = append(mounts, fmt.Sprintf("%s:%s", mount.hostPath, mount.containerPath))
+		}
+	}
+	args := []string{"run", "--rm", "--interactive", "--tty", "--entrypoint", shell}
+	for _, mount := range maybeRelocateMounts(mounts) {
+		args = append(args, "-v", mount)
+	}
+	args = append(args, dockerRunner{config: opts.Docker}.runOptions("debug-shell", true)...)
+	args = append(args, opts.Image)
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	slog.Info(cmd.String())
+	return cmd.Run()
+}
+
+func runGenerate(ctx context.Context, opts *Options, apiRoot, output, generatorInput, apiPath, releaseChannel string, config *GenerationConfig) error {
+	if opts.Image == "" {
 		return fmt.Errorf("image cannot be empty")
 	}
 	if apiRoot == "" {
@@ -91,11 +443,15 @@ func runGenerate(image, apiRoot, output, generatorInput, apiPath string) error {
 	if apiPath != "" {
 		containerArgs = append(containerArgs, fmt.Sprintf("--api-path=%s", apiPath))
 	}
-	return runDocker(image, mounts, containerArgs)
+	if releaseChannel != "" {
+		containerArgs = append(containerArgs, fmt.Sprintf("--release-channel=%s", releaseChannel))
+	}
+	containerArgs = append(containerArgs, config.args()...)
+	return runDocker(ctx, opts, mounts, containerArgs)
 }
 
-func runClean(image, repoRoot, apiPath string) error {
-	if image == "" {
+func runClean(ctx context.Context, opts *Options, repoRoot, apiPath string) error {
+	if opts.Image == "" {
 		return fmt.Errorf("image cannot be empty")
 	}
 	if repoRoot == "" {
@@ -111,11 +467,11 @@ func runClean(image, repoRoot, apiPath string) error {
 	if apiPath != "" {
 		containerArgs = append(containerArgs, fmt.Sprintf("--api-path=%s", apiPath))
 	}
-	return runDocker(image, mounts, containerArgs)
+	return runDocker(ctx, opts, mounts, containerArgs)
 }
 
-func runBuild(image, rootName, root, apiPath string) error {
-	if image == "" {
+func runBuild(ctx context.Context, opts *Options, rootName, root, apiPath string) error {
+	if opts.Image == "" {
 		return fmt.Errorf("image cannot be empty")
 	}
 	if rootName == "" {
@@ -134,22 +490,155 @@ func runBuild(image, rootName, root, apiPath string) error {
 	if apiPath != "" {
 		containerArgs = append(containerArgs, fmt.Sprintf("--api-path=%s", apiPath))
 	}
-	return runDocker(image, mounts, containerArgs)
+	if len(opts.BuildChecks) > 0 {
+		if SupportsFeature(ctx, opts, BuildChecksFeature) {
+			containerArgs = append(containerArgs, fmt.Sprintf("--checks=%s", strings.Join(opts.BuildChecks, ",")))
+		} else {
+			slog.Info(fmt.Sprintf("Not running the %s checks, as image %s doesn't declare %q in its %s label", strings.Join(opts.BuildChecks, ","), opts.Image, BuildChecksFeature, FeaturesLabel))
+		}
+	}
+	return runDocker(ctx, opts, mounts, containerArgs)
 }
 
-func runDocker(image string, mounts []string, containerArgs []string) error {
-	mounts = maybeRelocateMounts(mounts)
+func runDocker(ctx context.Context, opts *Options, mounts []string, containerArgs []string) error {
+	if len(opts.Experiments) > 0 {
+		containerArgs = append(containerArgs, fmt.Sprintf("--experiments=%s", strings.Join(opts.Experiments, ",")))
+	}
+	runner := NewDockerRunner(opts.Docker)
+	if opts.Runner != nil {
+		runner = opts.Runner
+	} else if err := checkProtocolVersion(ctx, opts.Image); err != nil {
+		return err
+	}
+	step := containerArgs[0]
+	stepCtx := ctx
+	if opts.StepTimeout > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx, opts.StepTimeout)
+		defer cancel()
+	}
+	runMounts, outputDir, removeOutput, err := addOutputMount(mounts)
+	if err != nil {
+		return err
+	}
+	defer removeOutput()
+	if opts.LogTail != nil {
+		// The output of each step is added to the tail as a whole, so that
+		// steps run in parallel aren't interleaved.
+		stepOutput := &tailBuffer{max: logTailSize}
+		stepCtx = context.WithValue(stepCtx, outputKey{}, io.Writer(stepOutput))
+		defer func() { opts.LogTail.buffer.Write(stepOutput.bytes()) }()
+	}
+	start := time.Now()
+	err = runner.Run(stepCtx, opts.Image, runMounts, containerArgs)
+	if err != nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%s step timed out after %s: %w", step, opts.StepTimeout, err)
+	} else if err != nil {
+		err = containerError(step, outputDir, err)
+	}
+	metrics.StepDuration.ObserveSince(start, step)
+	if err != nil {
+		metrics.StepFailures.Inc(step)
+	}
+	recordStep(ctx, opts, mounts, containerArgs, start, err)
+	return err
+}
+
+// DockerRunner runs containers using the docker CLI, with the default
+// configuration.
+var DockerRunner Runner = dockerRunner{}
+
+// NewDockerRunner returns a Runner which runs containers using the docker CLI,
+// with the given configuration.
+func NewDockerRunner(config DockerConfig) Runner {
+	return dockerRunner{config: config}
+}
+
+type dockerRunner struct {
+	config DockerConfig
+}
+
+// containerCount is used to give each container run by this process a unique name.
+var containerCount atomic.Int64
+
+func (r dockerRunner) Run(ctx context.Context, image string, mounts, containerArgs []string) error {
+	mounts = slices.Clone(mounts)
+	if r.config.SecretsDir != "" {
+		mounts = append(mounts, fmt.Sprintf("%s:/secrets:ro", r.config.SecretsDir))
+	}
+
+	// The container is named so that it can be killed if the context is
+	// cancelled; killing the docker CLI alone leaves the container running.
+	name := fmt.Sprintf("librarian-%s-%d-%d", containerArgs[0], os.Getpid(), containerCount.Add(1))
+	if host := remoteDockerHost(); host != "" {
+		return r.runRemote(ctx, host, name, image, mounts, containerArgs)
+	}
 
+	mounts = maybeRelocateMounts(mounts)
 	args := []string{
 		"run",
 		"--rm", // Automatically delete the container after completion
+		"--name", name,
 	}
 	for _, mount := range mounts {
 		args = append(args, "-v", mount)
 	}
+	args = append(args, r.runOptions(containerArgs[0], true)...)
 	args = append(args, image)
 	args = append(args, containerArgs...)
-	return runCommand("docker", args...)
+
+	delay := initialRetryDelay
+	for attempt := 1; ; attempt++ {
+		stderr := &tailBuffer{max: 16 * 1024}
+		cmd := exec.CommandContext(ctx, "docker", args...)
+		cmd.Cancel = func() error {
+			killContainer(name)
+			return cmd.Process.Kill()
+		}
+		err := runCommand(ctx, cmd, stderr)
+		if err == nil || ctx.Err() != nil || attempt == maxDockerAttempts || !isTransientDockerError(err, stderr.lines(100)) {
+			return err
+		}
+		metrics.ContainerRetries.Inc(containerArgs[0])
+		slog.Warn(fmt.Sprintf("Transient docker failure (attempt %d of %d); retrying in %s: %s", attempt, maxDockerAttempts, delay, err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// runOptions returns the docker options common to every way of running a
+// container for the given step: the cache volumes, environment variables,
+// user (if mapUser is true), network and (when offline) pull policy.
+func (r dockerRunner) runOptions(step string, mapUser bool) []string {
+	var args []string
+	if cacheSteps[step] {
+		// Named volumes are managed by docker, so are never relocated or copied.
+		for _, volume := range r.config.CacheVolumes {
+			args = append(args, "-v", volume)
+		}
+	}
+	for _, env := range r.config.Env {
+		args = append(args, "-e", env)
+	}
+	if r.config.User != "" && mapUser {
+		args = append(args, "--user", r.config.User)
+		// An arbitrary user typically has no home directory in the image,
+		// and many tools require one.
+		if !slices.ContainsFunc(r.config.Env, func(env string) bool { return strings.HasPrefix(env, "HOME=") }) {
+			args = append(args, "-e", "HOME=/tmp")
+		}
+	}
+	if network := r.config.network(step); network != "" {
+		args = append(args, "--network", network)
+	}
+	if offline.Load() {
+		args = append(args, "--pull=never")
+	}
+	return args
 }
 
 func maybeRelocateMounts(mounts []string) []string {
@@ -170,10 +659,328 @@ func maybeRelocateMounts(mounts []string) []string {
 	return relocatedMounts
 }
 
-func runCommand(c string, args ...string) error {
-	cmd := exec.Command(c, args...)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+// ImageDigest returns the repository digest of the image (in the form
+// "repository@sha256:..."), or an empty string if the image has no digest,
+// e.g. because it was built locally.
+func ImageDigest(ctx context.Context, opts *Options) (string, error) {
+	output, err := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{json .RepoDigests}}", opts.Image).Output()
+	if err != nil {
+		return "", err
+	}
+	var digests []string
+	if err := json.Unmarshal(output, &digests); err != nil {
+		return "", err
+	}
+	if len(digests) == 0 {
+		return "", nil
+	}
+	return digests[0], nil
+}
+
+// CommandsLabel is the image label with which language container images
+// declare the container commands they support, as a comma-separated list
+// (e.g. "configure,generate,clean,build").
+const CommandsLabel = "com.google.librarian.commands"
+
+// FeaturesLabel is the image label with which language container images
+// declare the optional features they support, as a comma-separated list.
+const FeaturesLabel = "com.google.librarian.features"
+
+// DescriptorSetFeature is the feature declared by images which can generate
+// from a FileDescriptorSet (see GenerateFromDescriptorSet).
+const DescriptorSetFeature = "descriptor-set"
+
+// ConfigureRepoFeature is the feature declared by images whose configure
+// command needs the language repo (see Configure).
+const ConfigureRepoFeature = "configure-repo"
+
+// BuildChecksFeature is the feature declared by images whose build command
+// accepts the --checks argument (see Options.BuildChecks).
+const BuildChecksFeature = "build-checks"
+
+// imageLabelCache caches the labels of each image inspected by this process.
+var imageLabelCache sync.Map
+
+// offline is whether images must not be pulled, as set by SetOffline.
+var offline atomic.Bool
+
+// SetOffline sets whether images must already be available locally (e.g.
+// pulled or loaded beforehand), for environments without network access.
+// When offline, images are never pulled: running or inspecting an image which
+// isn't available locally fails immediately.
+func SetOffline(value bool) {
+	offline.Store(value)
+}
+
+// imageLabels returns the labels of the image, pulling the image if necessary.
+func imageLabels(ctx context.Context, image string) (map[string]string, error) {
+	if labels, ok := imageLabelCache.Load(image); ok {
+		return labels.(map[string]string), nil
+	}
+	inspect := func() ([]byte, error) {
+		return exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{json .Config.Labels}}", image).Output()
+	}
+	output, err := inspect()
+	if err != nil {
+		// The image may not have been pulled yet.
+		if offline.Load() {
+			return nil, fmt.Errorf("image %s is not available locally, and images aren't pulled in offline mode; pull or load it beforehand", image)
+		}
+		if pullErr := exec.CommandContext(ctx, "docker", "pull", "--quiet", image).Run(); pullErr != nil {
+			return nil, fmt.Errorf("unable to pull image %s: %w", image, pullErr)
+		}
+		if output, err = inspect(); err != nil {
+			return nil, err
+		}
+	}
+	labels := map[string]string{}
+	if err := json.Unmarshal(output, &labels); err != nil {
+		return nil, fmt.Errorf("unable to read labels of image %s: %w", image, err)
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	imageLabelCache.Store(image, labels)
+	return labels, nil
+}
+
+// ImageProtocolVersion returns the protocol version declared by the image's
+// ProtocolVersionLabel label, pulling the image if necessary. It returns zero if
+// the image doesn't declare a protocol version.
+func ImageProtocolVersion(ctx context.Context, image string) (int, error) {
+	labels, err := imageLabels(ctx, image)
+	if err != nil {
+		return 0, err
+	}
+	label := strings.TrimSpace(labels[ProtocolVersionLabel])
+	if label == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(label)
+	if err != nil {
+		return 0, fmt.Errorf("image %s has an invalid %s label: %q", image, ProtocolVersionLabel, label)
+	}
+	return version, nil
+}
+
+// Supports reports whether the image supports the given container command
+// (e.g. "build"), as listed in opts.Commands or declared in its CommandsLabel
+// label. Images which don't declare the commands they support, or can't be
+// inspected, are assumed to support every command, as are all images when a
+// custom Runner is used (e.g. when replaying a recording).
+func Supports(ctx context.Context, opts *Options, command string) bool {
+	if opts.Commands != nil {
+		return slices.Contains(opts.Commands, command)
+	}
+	if opts.Runner != nil {
+		return true
+	}
+	labels, err := imageLabels(ctx, opts.Image)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to determine the commands supported by %s: %s", opts.Image, err))
+		return true
+	}
+	declared, ok := labels[CommandsLabel]
+	if !ok {
+		return true
+	}
+	for _, supported := range strings.Split(declared, ",") {
+		if strings.TrimSpace(supported) == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Declares reports whether the image explicitly declares support for the
+// given optional container command (e.g. "remove"), in opts.Commands or its
+// CommandsLabel label. Unlike Supports, the command is never assumed: images
+// which don't declare their commands, or can't be inspected, don't support it.
+// All images are assumed to support every command when a custom Runner is used
+// (e.g. when replaying a recording).
+func Declares(ctx context.Context, opts *Options, command string) bool {
+	if opts.Commands != nil {
+		return slices.Contains(opts.Commands, command)
+	}
+	if opts.Runner != nil {
+		return true
+	}
+	labels, err := imageLabels(ctx, opts.Image)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to determine the commands supported by %s: %s", opts.Image, err))
+		return false
+	}
+	for _, declared := range strings.Split(labels[CommandsLabel], ",") {
+		if strings.TrimSpace(declared) == command {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsFeature reports whether the image declares support for the given
+// optional feature in its FeaturesLabel label. Unlike commands, features are
+// never assumed: images which don't declare them, or can't be inspected, don't
+// support them. All images are assumed to support every feature when a custom
+// Runner is used (e.g. when replaying a recording).
+func SupportsFeature(ctx context.Context, opts *Options, feature string) bool {
+	if opts.Runner != nil {
+		return true
+	}
+	labels, err := imageLabels(ctx, opts.Image)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Unable to determine the features supported by %s: %s", opts.Image, err))
+		return false
+	}
+	for _, supported := range strings.Split(labels[FeaturesLabel], ",") {
+		if strings.TrimSpace(supported) == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// checkedImages records the images whose protocol version has been checked by
+// this process, mapped to the result of the check.
+var checkedImages sync.Map
+
+// checkProtocolVersion checks (once per image) that the image implements the
+// protocol version used by this version of librarian, returning an error with
+// upgrade guidance if not. Images which don't declare a protocol version are
+// assumed to be compatible, with a warning.
+func checkProtocolVersion(ctx context.Context, image string) error {
+	if result, ok := checkedImages.Load(image); ok {
+		err, _ := result.(error)
+		return err
+	}
+	version, err := ImageProtocolVersion(ctx, image)
+	switch {
+	case err != nil:
+		// Leave failures (e.g. a missing image) to be reported by docker run.
+		slog.Warn(fmt.Sprintf("Unable to determine the protocol version of %s: %s", image, err))
+		return nil
+	case version == 0:
+		slog.Warn(fmt.Sprintf("Image %s does not declare a protocol version (label %s); assuming version %d", image, ProtocolVersionLabel, ProtocolVersion))
+	case version < ProtocolVersion:
+		err = fmt.Errorf("image %s implements container protocol version %d, but this version of librarian requires version %d; use a newer image (see -image)", image, version, ProtocolVersion)
+	case version > ProtocolVersion:
+		err = fmt.Errorf("image %s implements container protocol version %d, but this version of librarian only supports version %d; upgrade librarian (go install github.com/googleapis/librarian/cmd/librarian@latest)", image, version, ProtocolVersion)
+	}
+	if err != nil {
+		checkedImages.Store(image, err)
+	} else {
+		checkedImages.Store(image, true)
+	}
+	return err
+}
+
+// logTailSize is the number of bytes of output retained by RecentOutput and
+// each LogTail.
+const logTailSize = 64 * 1024
+
+// recentOutput retains the end of the output of all container commands.
+var recentOutput = &tailBuffer{max: logTailSize}
+
+// RecentOutput returns (at most) the last maxLines lines of output from
+// container commands run by this process.
+func RecentOutput(maxLines int) string {
+	return recentOutput.lines(maxLines)
+}
+
+// ResetResults discards the output and step results recorded for RecentOutput
+// and StepResults, so that a long-running process (e.g. librarian serve)
+// reports each run separately, without retaining the results of every run.
+func ResetResults() {
+	recentOutput.reset()
+	stepResults.mu.Lock()
+	defer stepResults.mu.Unlock()
+	stepResults.results = nil
+}
+
+// LogTail retains the end of the output of the container commands run with
+// the Options it's set in (see Options.LogTail).
+type LogTail struct {
+	buffer tailBuffer
+}
+
+// NewLogTail returns an empty LogTail.
+func NewLogTail() *LogTail {
+	return &LogTail{buffer: tailBuffer{max: logTailSize}}
+}
+
+// Lines returns (at most) the last maxLines lines of output retained by t,
+// which may be nil.
+func (t *LogTail) Lines(maxLines int) string {
+	if t == nil {
+		return ""
+	}
+	return t.buffer.lines(maxLines)
+}
+
+// outputKey is the context key of an additional io.Writer to which the output
+// of container commands is written by runCommand.
+type outputKey struct{}
+
+// tailBuffer is an io.Writer which retains only the last max bytes written.
+type tailBuffer struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.data)
+}
+
+func (b *tailBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = nil
+}
+
+func (b *tailBuffer) lines(maxLines int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := strings.Split(strings.TrimRight(string(b.data), "\n"), "\n")
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// killContainer kills the named container, logging (but otherwise ignoring)
+// any failure, e.g. because the container has already stopped.
+func killContainer(name string) {
+	slog.Warn(fmt.Sprintf("Killing container %s", name))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, "docker", "kill", name).CombinedOutput(); err != nil {
+		slog.Warn(fmt.Sprintf("Unable to kill container %s: %s %s", name, err, output))
+	}
+}
+
+// runCommand runs a command, writing its output to the process's output and
+// retaining it for RecentOutput, and to any writer added to ctx by runDocker
+// for Options.LogTail. Standard error is also written to stderr.
+func runCommand(ctx context.Context, cmd *exec.Cmd, stderr io.Writer) error {
+	output := io.Discard
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok {
+		output = w
+	}
+	cmd.Stderr = io.MultiWriter(os.Stderr, recentOutput, output, stderr)
+	cmd.Stdout = io.MultiWriter(os.Stdout, recentOutput, output)
 	slog.Info(strings.Repeat("=", 80))
 	slog.Info(cmd.String())
 	slog.Info(strings.Repeat("-", 80))
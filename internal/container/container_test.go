@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLogTailIsPerOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the local generator")
+	}
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho \"cleaning $3\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "fake-generator"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx := context.Background()
+	first := &Options{Image: "fake-generator", Runner: LocalRunner{}, LogTail: NewLogTail()}
+	second := &Options{Image: "fake-generator", Runner: LocalRunner{}, LogTail: NewLogTail()}
+	if err := runClean(ctx, first, t.TempDir(), "google/example/v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runClean(ctx, second, t.TempDir(), "google/example/v2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := first.LogTail.Lines(10); !strings.Contains(got, "google/example/v1") || strings.Contains(got, "google/example/v2") {
+		t.Errorf("first log tail = %q; want only the output of its own run", got)
+	}
+	if got := second.LogTail.Lines(10); !strings.Contains(got, "google/example/v2") || strings.Contains(got, "google/example/v1") {
+		t.Errorf("second log tail = %q; want only the output of its own run", got)
+	}
+}
+
+func TestDeclares(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		commands []string
+		want     bool
+	}{
+		{[]string{"configure", "generate", "clean", "build", "remove"}, true},
+		{[]string{"configure", "generate", "clean", "build"}, false},
+		{[]string{}, false},
+	} {
+		opts := &Options{Image: "example", Commands: test.commands}
+		if got := Declares(ctx, opts, "remove"); got != test.want {
+			t.Errorf("Declares(%v, remove) = %v; want %v", test.commands, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// outputMount is the container path of the output directory. Every container
+// run has an output directory: for commands other than generate, it's a
+// scratch directory which is discarded afterwards.
+const outputMount = "/output"
+
+// errorFile is the name of the file which a container writes to its output
+// directory when it fails, describing the failure.
+const errorFile = "error.json"
+
+// Error is a failure reported by a language container in the error.json file
+// in its output directory.
+type Error struct {
+	// Step is the container command which failed, e.g. "generate".
+	Step string `json:"-"`
+	// Code is a short machine-readable identifier for the failure, e.g.
+	// "ROOT_DIRECTORY_NOT_FOUND".
+	Code string `json:"code"`
+	// Message describes the failure.
+	Message string `json:"message"`
+	// Details optionally provides further information, e.g. the files involved.
+	Details []string `json:"details,omitempty"`
+
+	err error
+}
+
+func (e *Error) Error() string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "%s step failed", e.Step)
+	if e.Code != "" {
+		fmt.Fprintf(&message, " (%s)", e.Code)
+	}
+	fmt.Fprintf(&message, ": %s", e.Message)
+	for _, detail := range e.Details {
+		fmt.Fprintf(&message, "\n  %s", detail)
+	}
+	return message.String()
+}
+
+// Unwrap returns the error from running the container, e.g. its exit status.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// addOutputMount returns mounts with a scratch directory mounted as the output
+// directory if there isn't already one, along with the host path of the output
+// directory and a function to remove the scratch directory, if any.
+func addOutputMount(mounts []string) ([]string, string, func(), error) {
+	for _, mount := range mounts {
+		if hostPath, containerPath := splitMount(mount); containerPath == outputMount {
+			return mounts, hostPath, func() {}, nil
+		}
+	}
+	// When running sibling containers in Kokoro, the directory must be under
+	// the root directory so that it can be relocated (see maybeRelocateMounts).
+	dir, err := os.MkdirTemp(os.Getenv("KOKORO_ROOT_DIR"), "librarian-output-")
+	if err != nil {
+		return nil, "", nil, err
+	}
+	mounts = append(slices.Clone(mounts), fmt.Sprintf("%s:%s", dir, outputMount))
+	return mounts, dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// containerError returns the error reported by a failed container in the
+// error.json file in outputDir, wrapping runErr, or runErr itself if the
+// container didn't report an error. The file is removed, so that it isn't
+// mistaken for generated output.
+func containerError(step, outputDir string, runErr error) error {
+	path := filepath.Join(outputDir, errorFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return runErr
+	}
+	os.Remove(path)
+	reported := &Error{Step: step, err: runErr}
+	if err := json.Unmarshal(content, reported); err != nil || reported.Message == "" {
+		slog.Warn(fmt.Sprintf("Ignoring invalid %s written by the %s step: %s", errorFile, step, strings.TrimSpace(string(content))))
+		return runErr
+	}
+	return reported
+}
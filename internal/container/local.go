@@ -0,0 +1,80 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// LocalRunner runs language generators directly on the host rather than in
+// containers, for languages whose toolchain (e.g. a protoc plugin) can be
+// installed locally. Each image is run as the executable named by
+// LocalCommand, which must implement the same commands and arguments as the
+// image's entrypoint. Container paths in the arguments (e.g. --api-root=/apis)
+// are replaced by the host paths mounted at them, so the executable reads and
+// writes the host directories directly.
+type LocalRunner struct {
+	// Env lists environment variables to set, in addition to the host
+	// environment, as for DockerConfig.Env. Variables which are only named
+	// are already present in the host environment.
+	Env []string
+}
+
+// LocalCommand returns the name of the executable run by a LocalRunner for
+// the image: the last element of its repository, without the tag or digest.
+// For example, the executable for
+// us-central1-docker.pkg.dev/cloud-sdk-production-pipeline/images-dev/google-cloud-go-generator:latest
+// is google-cloud-go-generator.
+func LocalCommand(image string) string {
+	name, _, _ := strings.Cut(path.Base(image), "@")
+	name, _, _ = strings.Cut(name, ":")
+	return name
+}
+
+func (r LocalRunner) Run(ctx context.Context, image string, mounts, containerArgs []string) error {
+	args := make([]string, len(containerArgs))
+	for i, arg := range containerArgs {
+		args[i] = hostPathArg(arg, mounts)
+	}
+	cmd := exec.CommandContext(ctx, LocalCommand(image), args...)
+	cmd.Env = os.Environ()
+	for _, env := range r.Env {
+		if strings.Contains(env, "=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	return runCommand(ctx, cmd, io.Discard)
+}
+
+// hostPathArg returns arg with its value (after "=") replaced by the
+// corresponding host path if it's a path in one of the mounts.
+func hostPathArg(arg string, mounts []string) string {
+	name, value, found := strings.Cut(arg, "=")
+	if !found {
+		return arg
+	}
+	for _, mount := range mounts {
+		hostPath, containerPath := splitMount(mount)
+		if value == containerPath || strings.HasPrefix(value, containerPath+"/") {
+			return name + "=" + hostPath + strings.TrimPrefix(value, containerPath)
+		}
+	}
+	return arg
+}
@@ -0,0 +1,450 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// apiRootMount is the container path at which googleapis is mounted. It's
+// treated as a read-only input: only a manifest of its files is recorded.
+const apiRootMount = "/apis"
+
+// inputMounts are the container paths of the mounts which are read-only
+// inputs, like apiRootMount.
+var inputMounts = map[string]bool{apiRootMount: true, descriptorsMount: true}
+
+// inputHashes returns the hashes of the files in an input mount, keyed as in
+// invocation.Inputs.
+func inputHashes(hostPath, containerPath string) (map[string]string, error) {
+	hashes, err := hashFiles(hostPath)
+	if err != nil || containerPath == apiRootMount {
+		return hashes, err
+	}
+	keyed := map[string]string{}
+	for name, hash := range hashes {
+		keyed[containerPath+"/"+name] = hash
+	}
+	return keyed, nil
+}
+
+// invocation describes a single recorded container invocation.
+type invocation struct {
+	Image string   `json:"image"`
+	Args  []string `json:"args"`
+	// Mounts are the container paths of the mounted directories, in order.
+	Mounts []string `json:"mounts"`
+	// Inputs maps each file in the API root (relative to the root) to the
+	// SHA-256 hash of its content, if the API root was mounted. Files in other
+	// input mounts are keyed by their absolute container path.
+	Inputs map[string]string `json:"inputs,omitempty"`
+	// Changes are the changes made to each other mount, keyed by container
+	// path.
+	Changes map[string]*mountChanges `json:"changes,omitempty"`
+	// Failed records whether the container invocation failed.
+	Failed bool `json:"failed,omitempty"`
+}
+
+// mountChanges are the changes a container made to a mounted directory.
+type mountChanges struct {
+	// Before is a digest of the content of the directory before the container
+	// ran, which must match when replaying the changes.
+	Before string `json:"before"`
+	// Written maps the slash-separated path of each file the container added
+	// or modified to its content and mode. The content is stored in the
+	// objects directory of the recording, named after its SHA-256 hash, so
+	// that content written by several invocations is only stored once.
+	Written map[string]recordedFile `json:"written,omitempty"`
+	// Removed are the paths of the files the container removed.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// recordedFile is the state of a regular file in a mounted directory.
+type recordedFile struct {
+	Hash string      `json:"hash"`
+	Mode fs.FileMode `json:"mode"`
+}
+
+// objectsDir is the directory of a recording in which file content is stored.
+const objectsDir = "objects"
+
+// RecordingRunner wraps another Runner, recording each invocation to a
+// numbered subdirectory of Dir. The recording consists of the image, container
+// arguments and a manifest of the API root (and any other input mounts), along
+// with the changes the container made to every other mounted directory. Only
+// the files which were added, modified or removed are recorded, rather than
+// the whole content of each mount (which may be a large language repo).
+// Recordings can be replayed using a ReplayRunner, to run commands without
+// docker.
+type RecordingRunner struct {
+	Dir    string
+	Runner Runner
+
+	mu    sync.Mutex
+	count int
+}
+
+func (r *RecordingRunner) Run(ctx context.Context, image string, mounts, args []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count++
+	dir := filepath.Join(r.Dir, fmt.Sprintf("%04d", r.count))
+
+	before := map[string]map[string]recordedFile{}
+	for _, mount := range mounts {
+		hostPath, containerPath := splitMount(mount)
+		if inputMounts[containerPath] {
+			continue
+		}
+		files, err := snapshotFiles(hostPath)
+		if err != nil {
+			return err
+		}
+		before[containerPath] = files
+	}
+
+	runErr := r.Runner.Run(ctx, image, mounts, args)
+
+	inv := &invocation{Image: image, Args: args, Failed: runErr != nil}
+	for _, mount := range mounts {
+		hostPath, containerPath := splitMount(mount)
+		inv.Mounts = append(inv.Mounts, containerPath)
+		if inputMounts[containerPath] {
+			inputs, err := inputHashes(hostPath, containerPath)
+			if err != nil {
+				return err
+			}
+			if inv.Inputs == nil {
+				inv.Inputs = map[string]string{}
+			}
+			maps.Copy(inv.Inputs, inputs)
+			continue
+		}
+		after, err := snapshotFiles(hostPath)
+		if err != nil {
+			return err
+		}
+		changes := &mountChanges{Before: digestFiles(before[containerPath]), Written: map[string]recordedFile{}}
+		for name, file := range after {
+			if previous, ok := before[containerPath][name]; ok && previous == file {
+				continue
+			}
+			if err := storeObject(filepath.Join(r.Dir, objectsDir), filepath.Join(hostPath, filepath.FromSlash(name)), file.Hash); err != nil {
+				return err
+			}
+			changes.Written[name] = file
+		}
+		for name := range before[containerPath] {
+			if _, ok := after[name]; !ok {
+				changes.Removed = append(changes.Removed, name)
+			}
+		}
+		slices.Sort(changes.Removed)
+		if inv.Changes == nil {
+			inv.Changes = map[string]*mountChanges{}
+		}
+		inv.Changes[containerPath] = changes
+	}
+	content, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "invocation.json"), content, 0644); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Recorded container invocation %s", dir))
+	return runErr
+}
+
+// ReplayRunner replays the invocations recorded by a RecordingRunner in Dir,
+// in order. Each invocation must match the recorded image, arguments and
+// mounts (and input content, if recorded), and each other mounted directory
+// must have the content it had when the invocation was recorded. The
+// recorded changes to each such directory are then applied to it.
+type ReplayRunner struct {
+	Dir string
+
+	mu    sync.Mutex
+	count int
+}
+
+func (r *ReplayRunner) Run(ctx context.Context, image string, mounts, args []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count++
+	dir := filepath.Join(r.Dir, fmt.Sprintf("%04d", r.count))
+
+	content, err := os.ReadFile(filepath.Join(dir, "invocation.json"))
+	if err != nil {
+		return fmt.Errorf("no recorded container invocation %d: %w", r.count, err)
+	}
+	var inv invocation
+	if err := json.Unmarshal(content, &inv); err != nil {
+		return err
+	}
+	var containerPaths []string
+	for _, mount := range mounts {
+		_, containerPath := splitMount(mount)
+		containerPaths = append(containerPaths, containerPath)
+	}
+	if image != inv.Image || !slices.Equal(args, inv.Args) || !slices.Equal(containerPaths, inv.Mounts) {
+		return fmt.Errorf("container invocation %d does not match recording: got %s %s (mounts %s), want %s %s (mounts %s)",
+			r.count, image, strings.Join(args, " "), strings.Join(containerPaths, ","), inv.Image, strings.Join(inv.Args, " "), strings.Join(inv.Mounts, ","))
+	}
+
+	if inv.Inputs != nil {
+		inputs := map[string]string{}
+		for _, mount := range mounts {
+			hostPath, containerPath := splitMount(mount)
+			if !inputMounts[containerPath] {
+				continue
+			}
+			hashes, err := inputHashes(hostPath, containerPath)
+			if err != nil {
+				return err
+			}
+			maps.Copy(inputs, hashes)
+		}
+		if !maps.Equal(inputs, inv.Inputs) {
+			return fmt.Errorf("container invocation %d: input content does not match recording", r.count)
+		}
+	}
+
+	// All mounts are checked before any is changed, so that a mismatch
+	// leaves them untouched.
+	for _, mount := range mounts {
+		hostPath, containerPath := splitMount(mount)
+		changes := inv.Changes[containerPath]
+		if inputMounts[containerPath] || changes == nil {
+			continue
+		}
+		files, err := snapshotFiles(hostPath)
+		if err != nil {
+			return err
+		}
+		if digestFiles(files) != changes.Before {
+			return fmt.Errorf("container invocation %d: content of %s does not match recording", r.count, containerPath)
+		}
+	}
+	for _, mount := range mounts {
+		hostPath, containerPath := splitMount(mount)
+		changes := inv.Changes[containerPath]
+		if inputMounts[containerPath] || changes == nil {
+			continue
+		}
+		if err := applyChanges(filepath.Join(r.Dir, objectsDir), hostPath, changes); err != nil {
+			return err
+		}
+	}
+	if inv.Failed {
+		return fmt.Errorf("recorded container invocation %d failed", r.count)
+	}
+	return nil
+}
+
+// snapshotFiles returns the hash and mode of each regular file under dir,
+// keyed by its slash-separated path relative to dir. Any .git directory is
+// ignored.
+func snapshotFiles(dir string) (map[string]recordedFile, error) {
+	hashes, err := hashFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]recordedFile{}
+	for name, hash := range hashes {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		files[name] = recordedFile{Hash: hash, Mode: info.Mode().Perm()}
+	}
+	return files, nil
+}
+
+// digestFiles returns a single hash identifying the given files.
+func digestFiles(files map[string]recordedFile) string {
+	hash := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		fmt.Fprintf(hash, "%s\x00%s\x00%o\n", name, files[name].Hash, files[name].Mode)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// storeObject copies the file at path to the objects directory, named after
+// its hash, unless it's already stored.
+func storeObject(objects, path, hash string) error {
+	target := filepath.Join(objects, hash)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(objects, 0755); err != nil {
+		return err
+	}
+	// The object is written under a temporary name and renamed, so that an
+	// interrupted copy never leaves a truncated object.
+	tmp := target + ".tmp"
+	if err := copyFile(path, tmp, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// applyChanges applies recorded changes to the directory dir, reading written
+// content from the objects directory. Directories left empty by removed files
+// are removed.
+func applyChanges(objects, dir string, changes *mountChanges) error {
+	for _, name := range changes.Removed {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for parent := filepath.Dir(path); parent != dir && strings.HasPrefix(parent, dir); parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+	}
+	for name, file := range changes.Written {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := copyFile(filepath.Join(objects, file.Hash), path, file.Mode); err != nil {
+			return err
+		}
+		// The mode given to OpenFile only applies to new files.
+		if err := os.Chmod(path, file.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitMount splits a mount of the form "host-path:container-path".
+func splitMount(mount string) (hostPath, containerPath string) {
+	i := strings.LastIndex(mount, ":")
+	return mount[:i], mount[i+1:]
+}
+
+// hashFiles returns the SHA-256 hash of each regular file under dir, keyed by
+// its slash-separated path relative to dir. Any .git directory is ignored.
+func hashFiles(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		hash := sha256.New()
+		if _, err := io.Copy(hash, f); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	})
+	return hashes, err
+}
+
+// mirrorDir makes the content of dst match src, other than any .git directory
+// in either. Files in dst which aren't in src are removed.
+func mirrorDir(src, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeRunner is a Runner which generates into /output, and changes /repo,
+// from the content of /apis.
+type fakeRunner struct{}
+
+func (fakeRunner) Run(ctx context.Context, image string, mounts, args []string) error {
+	hostPaths := map[string]string{}
+	for _, mount := range mounts {
+		hostPath, containerPath := splitMount(mount)
+		hostPaths[containerPath] = hostPath
+	}
+	proto, err := os.ReadFile(filepath.Join(hostPaths[apiRootMount], "api.proto"))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(hostPaths["/output"], "gen"), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(hostPaths["/output"], "gen", "api.txt"), proto, 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(hostPaths["/output"], "build.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(hostPaths["/repo"], "modified.txt"), []byte("modified"), 0644); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(hostPaths["/repo"], "removed"))
+}
+
+// writeFiles creates the given files, keyed by slash-separated path, under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newMounts creates the directories mounted for fakeRunner, returning the
+// mounts.
+func newMounts(t *testing.T, repoFiles map[string]string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, "apis"), map[string]string{"api.proto": "syntax = \"proto3\";"})
+	writeFiles(t, filepath.Join(dir, "repo"), repoFiles)
+	if err := os.MkdirAll(filepath.Join(dir, "output"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return []string{
+		filepath.Join(dir, "apis") + ":" + apiRootMount,
+		filepath.Join(dir, "repo") + ":/repo",
+		filepath.Join(dir, "output") + ":/output",
+	}
+}
+
+// snapshotMounts returns the files in each mount, keyed by container path.
+func snapshotMounts(t *testing.T, mounts []string) map[string]map[string]recordedFile {
+	t.Helper()
+	snapshots := map[string]map[string]recordedFile{}
+	for _, mount := range mounts {
+		hostPath, containerPath := splitMount(mount)
+		files, err := snapshotFiles(hostPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshots[containerPath] = files
+	}
+	return snapshots
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	repoFiles := map[string]string{
+		"unchanged.txt":       "unchanged",
+		"modified.txt":        "original",
+		"removed/removed.txt": "removed",
+	}
+	args := []string{"generate", "--api-root=/apis", "--output=/output"}
+	recording := t.TempDir()
+
+	recorded := newMounts(t, repoFiles)
+	recorder := &RecordingRunner{Dir: recording, Runner: fakeRunner{}}
+	if err := recorder.Run(ctx, "image", recorded, args); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := os.ReadDir(filepath.Join(recording, objectsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the content of gen/api.txt, build.sh and modified.txt is stored.
+	if len(objects) != 3 {
+		t.Errorf("recorded %d objects; want 3 for the files written", len(objects))
+	}
+
+	replayed := newMounts(t, repoFiles)
+	replayer := &ReplayRunner{Dir: recording}
+	if err := replayer.Run(ctx, "image", replayed, args); err != nil {
+		t.Fatal(err)
+	}
+	want := snapshotMounts(t, recorded)
+	got := snapshotMounts(t, replayed)
+	for containerPath, files := range want {
+		if !maps.Equal(got[containerPath], files) {
+			t.Errorf("replayed %s = %v; want %v", containerPath, got[containerPath], files)
+		}
+	}
+	repo, _ := splitMount(replayed[1])
+	if _, err := os.Stat(filepath.Join(repo, "removed")); !os.IsNotExist(err) {
+		t.Errorf("directory emptied by the recorded invocation was not removed: %v", err)
+	}
+
+	diverged := newMounts(t, map[string]string{"unchanged.txt": "changed since recording"})
+	if err := (&ReplayRunner{Dir: recording}).Run(ctx, "image", diverged, args); err == nil {
+		t.Error("replaying onto a mount with different content succeeded; want an error")
+	}
+}
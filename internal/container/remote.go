@@ -0,0 +1,133 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// remoteDockerHost returns the DOCKER_HOST environment variable if it refers to
+// a docker daemon on another machine (over ssh or tcp), or an empty string if
+// the daemon is local. A remote daemon can't bind-mount local directories.
+func remoteDockerHost() string {
+	host := os.Getenv("DOCKER_HOST")
+	parsed, err := url.Parse(host)
+	if err != nil || (parsed.Scheme != "ssh" && parsed.Scheme != "tcp") {
+		return ""
+	}
+	hostname := parsed.Hostname()
+	if hostname == "localhost" {
+		return ""
+	}
+	if ip := net.ParseIP(hostname); ip != nil && ip.IsLoopback() {
+		return ""
+	}
+	return host
+}
+
+// runRemote runs a container on a remote docker daemon. As local directories
+// can't be mounted, the content of each mount is copied into the container
+// before it's started, and the content of each writable mount is copied back
+// afterwards (even if the container fails, so that any error.json is seen),
+// replacing the previous content of the local directory other than any .git
+// directory. The API root is treated as read-only. Containers aren't run as the
+// local user, as the copied files are owned by the local user regardless.
+func (r dockerRunner) runRemote(ctx context.Context, host, name, image string, mounts, containerArgs []string) error {
+	slog.Info(fmt.Sprintf("Running %s on remote docker host %s, copying mounted directories", containerArgs[0], host))
+	args := []string{"create", "--name", name}
+	args = append(args, r.runOptions(containerArgs[0], false)...)
+	args = append(args, image)
+	args = append(args, containerArgs...)
+	if err := dockerCommand(ctx, args...); err != nil {
+		return err
+	}
+	defer removeContainer(name)
+
+	type copiedMount struct {
+		hostPath, containerPath string
+		readOnly                bool
+	}
+	var copied []copiedMount
+	for _, mount := range mounts {
+		mount, readOnly := strings.CutSuffix(mount, ":ro")
+		hostPath, containerPath := splitMount(mount)
+		copied = append(copied, copiedMount{hostPath, containerPath, readOnly || containerPath == apiRootMount})
+		if err := dockerCommand(ctx, "cp", hostPath+"/.", name+":"+containerPath); err != nil {
+			return fmt.Errorf("unable to copy %s to the container: %w", hostPath, err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "docker", "start", "--attach", name)
+	cmd.Cancel = func() error {
+		killContainer(name)
+		return cmd.Process.Kill()
+	}
+	runErr := runCommand(ctx, cmd, io.Discard)
+	if ctx.Err() != nil {
+		return runErr
+	}
+
+	for _, mount := range copied {
+		if mount.readOnly {
+			continue
+		}
+		if err := copyFromContainer(ctx, name, mount.containerPath, mount.hostPath); err != nil {
+			return fmt.Errorf("unable to copy %s from the container: %w", mount.containerPath, err)
+		}
+	}
+	return runErr
+}
+
+// copyFromContainer replaces the content of hostPath (other than any .git
+// directory) with the content of containerPath in the named container.
+func copyFromContainer(ctx context.Context, name, containerPath, hostPath string) error {
+	tmp, err := os.MkdirTemp("", "librarian-copy-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := dockerCommand(ctx, "cp", name+":"+containerPath+"/.", tmp); err != nil {
+		return err
+	}
+	return mirrorDir(tmp, hostPath)
+}
+
+// dockerCommand runs a docker command which isn't expected to produce useful
+// output, including its output in the error if it fails.
+func dockerCommand(ctx context.Context, args ...string) error {
+	output, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker %s failed: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// removeContainer removes the named container, logging any failure.
+func removeContainer(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := dockerCommand(ctx, "rm", "--force", name); err != nil {
+		slog.Warn(fmt.Sprintf("Unable to remove container %s: %s", name, err))
+	}
+}
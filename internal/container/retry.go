@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// maxDockerAttempts is the maximum number of times a docker command is
+	// attempted when it fails with a transient error.
+	maxDockerAttempts = 4
+	// initialRetryDelay is the delay before the first retry. It doubles for
+	// each subsequent retry.
+	initialRetryDelay = 2 * time.Second
+)
+
+// dockerErrorExitCode is the exit code of "docker run" when the error is
+// in docker itself (including pulling the image), rather than in the container.
+const dockerErrorExitCode = 125
+
+// transientErrors are substrings of docker error output which indicate a
+// failure that's likely to succeed if retried, such as a registry timeout or
+// server error, or a lost connection to the docker daemon.
+var transientErrors = []string{
+	"TLS handshake timeout",
+	"i/o timeout",
+	"connection reset by peer",
+	"connection refused",
+	"Client.Timeout exceeded",
+	"unexpected EOF",
+	"Cannot connect to the Docker daemon",
+	"500 Internal Server Error",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+	"toomanyrequests",
+}
+
+// isTransientDockerError reports whether err, from a docker command which
+// wrote stderr, is a transient docker failure. Failures within the container
+// itself are never considered transient.
+func isTransientDockerError(err error, stderr string) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != dockerErrorExitCode {
+		return false
+	}
+	for _, transient := range transientErrors {
+		if strings.Contains(stderr, transient) {
+			return true
+		}
+	}
+	return false
+}
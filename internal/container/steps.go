@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StepResult describes a container command run by this process.
+type StepResult struct {
+	// Step is the container command, e.g. "generate".
+	Step  string `json:"step"`
+	Image string `json:"image"`
+	// ImageDigest is the digest of the image (e.g. "sha256:..."), if known. It
+	// isn't known for containers run by a custom Runner, or for images built
+	// locally.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Args are the arguments passed to the container, including the command.
+	Args []string `json:"args"`
+	// Mounts are the directories mounted in the container, as
+	// host-path:container-path.
+	Mounts          []string  `json:"mounts"`
+	StartTime       time.Time `json:"startTime"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// ExitCode is the exit code of the container, or -1 if it failed without
+	// exiting (e.g. because it couldn't be started or timed out).
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+}
+
+// stepResults records the result of each container command run by this
+// process.
+var stepResults struct {
+	mu      sync.Mutex
+	results []StepResult
+}
+
+// StepResults returns the results of the container commands run by this
+// process, in the order in which they were run.
+func StepResults() []StepResult {
+	stepResults.mu.Lock()
+	defer stepResults.mu.Unlock()
+	return slices.Clone(stepResults.results)
+}
+
+// recordStep records the result of a container command.
+func recordStep(ctx context.Context, opts *Options, mounts, containerArgs []string, start time.Time, err error) {
+	result := StepResult{
+		Step:            containerArgs[0],
+		Image:           opts.Image,
+		Args:            slices.Clone(containerArgs),
+		Mounts:          slices.Clone(mounts),
+		StartTime:       start,
+		DurationSeconds: time.Since(start).Seconds(),
+	}
+	if opts.Runner == nil {
+		result.ImageDigest = imageDigest(ctx, opts.Image)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		}
+	}
+	stepResults.mu.Lock()
+	defer stepResults.mu.Unlock()
+	stepResults.results = append(stepResults.results, result)
+}
+
+// imageDigests caches the digest of each image, as returned by imageDigest.
+var imageDigests sync.Map
+
+// imageDigest returns the digest of the image, or an empty string if it isn't
+// known.
+func imageDigest(ctx context.Context, image string) string {
+	if _, digest, ok := strings.Cut(image, "@"); ok {
+		return digest
+	}
+	if digest, ok := imageDigests.Load(image); ok {
+		return digest.(string)
+	}
+	var digest string
+	if repoDigest, err := ImageDigest(ctx, &Options{Image: image}); err == nil {
+		_, digest, _ = strings.Cut(repoDigest, "@")
+	}
+	imageDigests.Store(image, digest)
+	return digest
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gcs uploads files to (and downloads them from) Google Cloud Storage
+// using its JSON API.
+package gcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Location is a bucket and object name prefix in Cloud Storage.
+type Location struct {
+	Bucket string
+	Prefix string
+}
+
+// ParseLocation parses a location of the form "gs://bucket/prefix" (or
+// "bucket/prefix"). The prefix is optional.
+func ParseLocation(s string) (*Location, error) {
+	trimmed := strings.TrimPrefix(s, "gs://")
+	bucket, prefix, _ := strings.Cut(trimmed, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid Cloud Storage location %q; must be gs://bucket/prefix", s)
+	}
+	return &Location{Bucket: bucket, Prefix: strings.Trim(prefix, "/")}, nil
+}
+
+// Object returns the name of the object with the given name relative to the
+// location's prefix.
+func (l *Location) Object(name string) string {
+	return path.Join(l.Prefix, name)
+}
+
+func (l *Location) String() string {
+	return fmt.Sprintf("gs://%s/%s", l.Bucket, l.Prefix)
+}
+
+// ErrNotFound is returned by Download when the object doesn't exist.
+var ErrNotFound = errors.New("object not found")
+
+// Download writes the content of the named object in bucket to w. If the
+// object doesn't exist, ErrNotFound is returned.
+func Download(ctx context.Context, token, bucket, object string, w io.Writer) error {
+	downloadURL := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media",
+		url.PathEscape(bucket), url.PathEscape(object))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		_, err := io.Copy(w, resp.Body)
+		return err
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("downloading gs://%s/%s failed: %s: %s", bucket, object, resp.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+// Upload uploads content as the named object in bucket.
+func Upload(ctx context.Context, token, bucket, object string, content io.Reader) error {
+	contentType := mime.TypeByExtension(path.Ext(object))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	uploadURL := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		url.PathEscape(bucket), url.QueryEscape(object))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("uploading gs://%s/%s failed: %s: %s", bucket, object, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// UploadFile uploads the file at filePath as the named object in bucket.
+func UploadFile(ctx context.Context, token, bucket, object, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return Upload(ctx, token, bucket, object, file)
+}
+
+// UploadDir uploads each regular file under dir as an object whose name is
+// the file's slash-separated path relative to dir, prefixed by prefix. Any
+// .git directory is skipped. It returns the number of files uploaded.
+func UploadDir(ctx context.Context, token, bucket, prefix, dir string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		if err := UploadFile(ctx, token, bucket, path.Join(prefix, filepath.ToSlash(rel)), filePath); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
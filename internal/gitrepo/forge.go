@@ -0,0 +1,122 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"context"
+	"fmt"
+)
+
+// Forge is a service hosting a language repo, on which pull requests (merge
+// requests on GitLab, or changes on Gerrit) are created for the changes pushed
+// to it. Each Forge is bound to a single repository and access token.
+type Forge interface {
+	// FindOpenPullRequests returns the open pull requests whose head branch
+	// starts with branchPrefix and whose body contains bodyMarker.
+	FindOpenPullRequests(ctx context.Context, branchPrefix, bodyMarker string) ([]*PullRequest, error)
+	// UploadRef returns the ref to push HEAD to for a new pull request from
+	// branch into baseBranch.
+	UploadRef(branch, baseBranch string) string
+	// ForkURL returns the URL of the given fork of the repository, to push
+	// branches to.
+	ForkURL(fork string) (string, error)
+	// CreatePullRequest creates a pull request to merge branch (in the given
+	// fork, if it's not empty) into baseBranch.
+	CreatePullRequest(ctx context.Context, fork, branch, baseBranch, title, body string) (*PullRequest, error)
+	// RequestReviewers requests reviews of a pull request from the given
+	// users and teams. Team names are specified without the organization.
+	RequestReviewers(ctx context.Context, pr *PullRequest, users, teams []string) error
+	// AddLabels adds labels to a pull request.
+	AddLabels(ctx context.Context, pr *PullRequest, labels []string) error
+	// AddAssignees assigns users to a pull request.
+	AddAssignees(ctx context.Context, pr *PullRequest, assignees []string) error
+	// UpsertComment creates a comment on a pull request, or replaces the body
+	// of an existing comment containing marker.
+	UpsertComment(ctx context.Context, pr *PullRequest, marker, body string) error
+}
+
+// NewForge returns the Forge of the given kind ("github", "gitlab" or "gerrit") for the
+// remote of repo. As with GetGitHubRepo, this requires a single remote to be
+// configured.
+func NewForge(kind string, repo *Repo, accessToken string) (Forge, error) {
+	remoteURL, err := singleRemoteURL(repo)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "github":
+		gitHubRepo, err := ParseGitHubURL(remoteURL)
+		if err != nil {
+			return nil, err
+		}
+		return &gitHubForge{repo: gitHubRepo, accessToken: accessToken}, nil
+	case "gitlab":
+		project, err := ParseGitLabURL(remoteURL)
+		if err != nil {
+			return nil, err
+		}
+		return &gitLabForge{project: project, accessToken: accessToken}, nil
+	case "gerrit":
+		project, err := ParseGerritURL(remoteURL)
+		if err != nil {
+			return nil, err
+		}
+		return &gerritForge{repo: repo, project: project, remoteURL: remoteURL, accessToken: accessToken}, nil
+	default:
+		return nil, fmt.Errorf("unknown forge %q; must be github, gitlab or gerrit", kind)
+	}
+}
+
+// gitHubForge is the Forge for a repository on GitHub.
+type gitHubForge struct {
+	repo        *GitHubRepo
+	accessToken string
+}
+
+func (f *gitHubForge) FindOpenPullRequests(ctx context.Context, branchPrefix, bodyMarker string) ([]*PullRequest, error) {
+	return FindOpenPullRequests(ctx, f.repo, f.accessToken, branchPrefix, bodyMarker)
+}
+
+func (f *gitHubForge) UploadRef(branch, baseBranch string) string {
+	return "refs/heads/" + branch
+}
+
+func (f *gitHubForge) ForkURL(fork string) (string, error) {
+	return fmt.Sprintf("https://%s/%s/%s", gitHubEndpoint.host, fork, f.repo.Name), nil
+}
+
+func (f *gitHubForge) CreatePullRequest(ctx context.Context, fork, branch, baseBranch, title, body string) (*PullRequest, error) {
+	head := branch
+	if fork != "" {
+		head = fmt.Sprintf("%s:%s", fork, branch)
+	}
+	return CreatePullRequest(ctx, f.repo, head, baseBranch, f.accessToken, title, body)
+}
+
+func (f *gitHubForge) RequestReviewers(ctx context.Context, pr *PullRequest, users, teams []string) error {
+	return RequestReviewers(ctx, pr, f.accessToken, users, teams)
+}
+
+func (f *gitHubForge) AddLabels(ctx context.Context, pr *PullRequest, labels []string) error {
+	return AddLabels(ctx, pr, f.accessToken, labels)
+}
+
+func (f *gitHubForge) AddAssignees(ctx context.Context, pr *PullRequest, assignees []string) error {
+	return AddAssignees(ctx, pr, f.accessToken, assignees)
+}
+
+func (f *gitHubForge) UpsertComment(ctx context.Context, pr *PullRequest, marker, body string) error {
+	return UpsertComment(ctx, pr, f.accessToken, marker, body)
+}
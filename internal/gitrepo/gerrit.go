@@ -0,0 +1,274 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// gerritEndpoint is the Gerrit server hosting projects, configured by
+// SetGerritEndpoint.
+var gerritEndpoint struct {
+	url      string
+	username string
+}
+
+// SetGerritEndpoint configures the Gerrit server hosting projects: its base
+// URL (e.g. https://review.example.com) and the username of the HTTP
+// credentials used for its REST API and for pushing.
+func SetGerritEndpoint(baseURL, username string) error {
+	if baseURL != "" {
+		parsed, err := url.Parse(baseURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("invalid Gerrit URL %q; must be an https URL such as https://review.example.com", baseURL)
+		}
+	}
+	gerritEndpoint.url = strings.TrimSuffix(baseURL, "/")
+	gerritEndpoint.username = username
+	return nil
+}
+
+// ParseGerritURL returns the name of the project with the given HTTPS URL on
+// the configured Gerrit server, such as https://review.example.com/a/my/project.
+func ParseGerritURL(remoteURL string) (string, error) {
+	if gerritEndpoint.url == "" {
+		return "", fmt.Errorf("no Gerrit server is configured")
+	}
+	prefix := gerritEndpoint.url + "/"
+	if !strings.HasPrefix(remoteURL, prefix) {
+		return "", fmt.Errorf("remote '%s' is not a Gerrit remote on %s", remoteURL, gerritEndpoint.url)
+	}
+	project := strings.TrimPrefix(strings.TrimSuffix(strings.Trim(remoteURL[len(prefix):], "/"), ".git"), "a/")
+	if project == "" {
+		return "", fmt.Errorf("remote '%s' is not a Gerrit project URL", remoteURL)
+	}
+	return project, nil
+}
+
+// changeIDPattern matches a Change-Id trailer.
+var changeIDPattern = regexp.MustCompile(`(?m)^Change-Id: I[0-9a-f]{40}\s*$`)
+
+// trailerPattern matches a git trailer line, such as "Source-Link: ...".
+var trailerPattern = regexp.MustCompile(`^[A-Za-z0-9-]+: `)
+
+// AddChangeID adds a Change-Id trailer to a commit message which doesn't
+// already have one in its last paragraph, as Gerrit's commit-msg hook does,
+// so that the commit can be uploaded to Gerrit as a change. The trailer is
+// added to the last paragraph if it consists of trailers (such as
+// PiperOrigin-RevId and Source-Link), or as a new paragraph otherwise.
+func AddChangeID(msg string) string {
+	msg = strings.TrimRight(msg, "\n")
+	paragraphs := strings.Split(msg, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) > 1 && changeIDPattern.MatchString(last) {
+		return msg + "\n"
+	}
+	random := make([]byte, 20)
+	rand.Read(random)
+	hash := sha1.Sum(append(random, msg...))
+	trailer := "Change-Id: I" + hex.EncodeToString(hash[:])
+	lines := strings.Split(last, "\n")
+	if len(paragraphs) > 1 && !slices.ContainsFunc(lines, func(line string) bool { return !trailerPattern.MatchString(line) }) {
+		return msg + "\n" + trailer + "\n"
+	}
+	return msg + "\n\n" + trailer + "\n"
+}
+
+// gerritForge is the Forge for a project on Gerrit, using its REST API. Each
+// commit uploaded becomes a change, with all the changes uploaded from a
+// branch sharing the branch name as their topic. The pull request body is
+// posted as a message on the change for HEAD.
+type gerritForge struct {
+	repo        *Repo
+	project     string
+	remoteURL   string
+	accessToken string
+}
+
+// gerritChange is the subset of a change returned by the Gerrit API which is
+// used here.
+type gerritChange struct {
+	Number          int    `json:"_number"`
+	Topic           string `json:"topic"`
+	CurrentRevision string `json:"current_revision"`
+	Messages        []struct {
+		Message string `json:"message"`
+	} `json:"messages"`
+	MoreChanges bool `json:"_more_changes"`
+}
+
+func (f *gerritForge) newPullRequest(change *gerritChange, body string) *PullRequest {
+	return &PullRequest{
+		Repo:         &GitHubRepo{Name: f.project},
+		Number:       change.Number,
+		HTMLURL:      fmt.Sprintf("%s/c/%s/+/%d", gerritEndpoint.url, f.project, change.Number),
+		HeadBranch:   change.Topic,
+		HeadCloneURL: f.remoteURL,
+		Body:         body,
+	}
+}
+
+func (f *gerritForge) FindOpenPullRequests(ctx context.Context, branchPrefix, bodyMarker string) ([]*PullRequest, error) {
+	var found []*PullRequest
+	for start := 0; ; {
+		var changes []*gerritChange
+		query := url.QueryEscape(fmt.Sprintf("status:open project:%s", f.project))
+		if err := f.call(ctx, http.MethodGet, fmt.Sprintf("changes/?q=%s&o=MESSAGES&n=100&S=%d", query, start), nil, &changes); err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			if !strings.HasPrefix(change.Topic, branchPrefix) {
+				continue
+			}
+			for _, message := range change.Messages {
+				if strings.Contains(message.Message, bodyMarker) {
+					found = append(found, f.newPullRequest(change, message.Message))
+					break
+				}
+			}
+		}
+		if len(changes) == 0 || !changes[len(changes)-1].MoreChanges {
+			return found, nil
+		}
+		start += len(changes)
+	}
+}
+
+func (f *gerritForge) UploadRef(branch, baseBranch string) string {
+	return fmt.Sprintf("refs/for/%s%%topic=%s", baseBranch, branch)
+}
+
+func (f *gerritForge) ForkURL(fork string) (string, error) {
+	return "", fmt.Errorf("pushing to a fork is not supported on Gerrit")
+}
+
+// CreatePullRequest finds the change uploaded for HEAD (by pushing to
+// UploadRef), and posts the body on it. The title is ignored, as the subject
+// of a change is that of its commit.
+func (f *gerritForge) CreatePullRequest(ctx context.Context, fork, branch, baseBranch, title, body string) (*PullRequest, error) {
+	if fork != "" {
+		return nil, fmt.Errorf("uploading changes from a fork is not supported on Gerrit")
+	}
+	head, err := HeadHash(ctx, f.repo)
+	if err != nil {
+		return nil, err
+	}
+	var changes []*gerritChange
+	query := url.QueryEscape(fmt.Sprintf("project:%s commit:%s", f.project, head))
+	if err := f.call(ctx, http.MethodGet, "changes/?q="+query, nil, &changes); err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no change found for commit %s after uploading it", head)
+	}
+	pr := f.newPullRequest(changes[0], body)
+	if err := f.call(ctx, http.MethodPost, fmt.Sprintf("changes/%d/revisions/current/review", pr.Number), map[string]any{"message": body}, nil); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Change uploaded: %s\n", pr.HTMLURL)
+	return pr, nil
+}
+
+// RequestReviewers adds the given users and groups as reviewers of the change.
+func (f *gerritForge) RequestReviewers(ctx context.Context, pr *PullRequest, users, teams []string) error {
+	for _, reviewer := range slices.Concat(users, teams) {
+		if err := f.call(ctx, http.MethodPost, fmt.Sprintf("changes/%d/reviewers", pr.Number), map[string]any{"reviewer": reviewer}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddLabels adds the labels to the change as hashtags, as Gerrit labels are
+// votes rather than tags.
+func (f *gerritForge) AddLabels(ctx context.Context, pr *PullRequest, labels []string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	return f.call(ctx, http.MethodPost, fmt.Sprintf("changes/%d/hashtags", pr.Number), map[string]any{"add": labels}, nil)
+}
+
+// AddAssignees adds the users to the attention set of the change, as Gerrit
+// no longer has assignees.
+func (f *gerritForge) AddAssignees(ctx context.Context, pr *PullRequest, assignees []string) error {
+	for _, assignee := range assignees {
+		request := map[string]any{"user": assignee, "reason": "Assigned by librarian"}
+		if err := f.call(ctx, http.MethodPost, fmt.Sprintf("changes/%d/attention", pr.Number), request, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpsertComment posts body as a message on the change. Gerrit change messages
+// can't be edited, so a new message is always posted.
+func (f *gerritForge) UpsertComment(ctx context.Context, pr *PullRequest, marker, body string) error {
+	return f.call(ctx, http.MethodPost, fmt.Sprintf("changes/%d/revisions/current/review", pr.Number), map[string]any{"message": body}, nil)
+}
+
+// call makes an authenticated request to the Gerrit REST API, with request
+// (if not nil) encoded as the JSON body, and decodes the JSON response into
+// response (if not nil).
+func (f *gerritForge) call(ctx context.Context, method, path string, request, response any) error {
+	var body io.Reader
+	if request != nil {
+		encoded, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/a/%s", gerritEndpoint.url, path), body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(gerritEndpoint.username, f.accessToken)
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Gerrit API %s %s failed: %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(content[:min(len(content), 4096)])))
+	}
+	if response == nil {
+		return nil
+	}
+	// Gerrit prefixes JSON responses to prevent cross-site script inclusion.
+	content = bytes.TrimPrefix(content, []byte(")]}'"))
+	if err := json.Unmarshal(content, response); err != nil {
+		return fmt.Errorf("unable to decode Gerrit API response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// gitHubEndpoint is the GitHub deployment used for repositories and API
+// calls, configured by SetGitHubEndpoint.
+var gitHubEndpoint = struct {
+	host       string
+	apiURL     string
+	apiVersion string
+}{host: "github.com"}
+
+// SetGitHubEndpoint configures the GitHub deployment hosting repositories, to
+// support GitHub Enterprise Server. host is the host name of the deployment
+// (e.g. github.example.com), and apiURL is the base URL of its REST API,
+// which defaults to https://{host}/api/v3/ for hosts other than github.com.
+// If apiVersion isn't empty, it's sent as the X-GitHub-Api-Version header
+// instead of the default version (for older servers which don't support it),
+// or the header is omitted if apiVersion is "none".
+func SetGitHubEndpoint(host, apiURL, apiVersion string) error {
+	if host == "" {
+		host = "github.com"
+	}
+	if strings.Contains(host, "/") {
+		return fmt.Errorf("invalid GitHub host %q; must be a host name, not a URL", host)
+	}
+	if apiURL == "" && host != "github.com" {
+		apiURL = fmt.Sprintf("https://%s/api/v3/", host)
+	}
+	if apiURL != "" {
+		if _, err := github.NewClient(nil).WithEnterpriseURLs(apiURL, apiURL); err != nil {
+			return fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+		}
+	}
+	gitHubEndpoint.host = host
+	gitHubEndpoint.apiURL = apiURL
+	gitHubEndpoint.apiVersion = apiVersion
+	return nil
+}
+
+// GitHubHost returns the host name of the configured GitHub deployment.
+func GitHubHost() string {
+	return gitHubEndpoint.host
+}
+
+// newGitHubClient returns a client for the REST API of the configured GitHub
+// deployment, authenticated with the given token.
+func newGitHubClient(accessToken string) *github.Client {
+	var httpClient *http.Client
+	if gitHubEndpoint.apiVersion != "" {
+		httpClient = &http.Client{Transport: &apiVersionTransport{version: gitHubEndpoint.apiVersion}}
+	}
+	client := github.NewClient(httpClient).WithAuthToken(accessToken)
+	if gitHubEndpoint.apiURL != "" {
+		// The URL has already been validated by SetGitHubEndpoint.
+		client, _ = client.WithEnterpriseURLs(gitHubEndpoint.apiURL, gitHubEndpoint.apiURL)
+	}
+	return client
+}
+
+// apiVersionTransport overrides the X-GitHub-Api-Version header of requests.
+type apiVersionTransport struct {
+	version string
+}
+
+func (t *apiVersionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if t.version == "none" {
+		req.Header.Del("X-GitHub-Api-Version")
+	} else {
+		req.Header.Set("X-GitHub-Api-Version", t.version)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// GitHubRepo identifies a repository hosted on GitHub.
+type GitHubRepo struct {
+	Owner string
+	Name  string
+}
+
+// ParseGitHubURL parses a GitHub HTTPS URL such as
+// https://github.com/googleapis/google-cloud-dotnet, on the host configured
+// by SetGitHubEndpoint.
+func ParseGitHubURL(remoteURL string) (*GitHubRepo, error) {
+	prefix := fmt.Sprintf("https://%s/", gitHubEndpoint.host)
+	if !strings.HasPrefix(remoteURL, prefix) {
+		return nil, fmt.Errorf("remote '%s' is not a GitHub remote on %s", remoteURL, gitHubEndpoint.host)
+	}
+	pathParts := strings.Split(strings.TrimSuffix(remoteURL[len(prefix):], ".git"), "/")
+	if len(pathParts) < 2 || pathParts[0] == "" || pathParts[1] == "" {
+		return nil, fmt.Errorf("remote '%s' is not a GitHub repository URL", remoteURL)
+	}
+	return &GitHubRepo{Owner: pathParts[0], Name: pathParts[1]}, nil
+}
+
+// GetGitHubRepo returns the GitHub repository for the remote of repo. At the moment
+// this requires a single remote to be configured, which must have a GitHub HTTPS URL.
+func GetGitHubRepo(repo *Repo) (*GitHubRepo, error) {
+	remoteURL, err := singleRemoteURL(repo)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGitHubURL(remoteURL)
+}
+
+// singleRemoteURL returns the URL of the only remote of repo.
+func singleRemoteURL(repo *Repo) (string, error) {
+	remotes, err := repo.repo.Remotes()
+	if err != nil {
+		return "", err
+	}
+	if len(remotes) != 1 {
+		return "", fmt.Errorf("can only determine the hosted repository with a single remote; number of remotes: %d", len(remotes))
+	}
+	return remotes[0].Config().URLs[0], nil
+}
+
+// PullRequest identifies a pull request in a GitHub repository, or a merge
+// request in a GitLab project (in which case Repo.Owner is the namespace of
+// the project, and Number is the merge request's IID).
+type PullRequest struct {
+	Repo    *GitHubRepo
+	Number  int
+	HTMLURL string
+	// The branch the pull request merges from, and the clone URL of the
+	// repository containing it (which may be a fork).
+	HeadBranch   string
+	HeadCloneURL string
+	Body         string
+}
+
+func newPullRequest(repo *GitHubRepo, pr *github.PullRequest) *PullRequest {
+	return &PullRequest{
+		Repo:         repo,
+		Number:       pr.GetNumber(),
+		HTMLURL:      pr.GetHTMLURL(),
+		HeadBranch:   pr.GetHead().GetRef(),
+		HeadCloneURL: pr.GetHead().GetRepo().GetCloneURL(),
+		Body:         pr.GetBody(),
+	}
+}
+
+// FindOpenPullRequests returns the open pull requests in the GitHub repository
+// whose head branch starts with branchPrefix and whose body contains bodyMarker.
+func FindOpenPullRequests(ctx context.Context, repo *GitHubRepo, accessToken, branchPrefix, bodyMarker string) ([]*PullRequest, error) {
+	gitHubClient := newGitHubClient(accessToken)
+	var found []*PullRequest
+	opts := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		prs, resp, err := gitHubClient.PullRequests.List(ctx, repo.Owner, repo.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			if strings.HasPrefix(pr.GetHead().GetRef(), branchPrefix) && strings.Contains(pr.GetBody(), bodyMarker) {
+				found = append(found, newPullRequest(repo, pr))
+			}
+		}
+		if resp.NextPage == 0 {
+			return found, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// RequestReviewers requests reviews of a pull request from the given users
+// and teams. Team names are specified without the organization.
+func RequestReviewers(ctx context.Context, pr *PullRequest, accessToken string, users, teams []string) error {
+	if len(users) == 0 && len(teams) == 0 {
+		return nil
+	}
+	gitHubClient := newGitHubClient(accessToken)
+	request := github.ReviewersRequest{Reviewers: users, TeamReviewers: teams}
+	_, _, err := gitHubClient.PullRequests.RequestReviewers(ctx, pr.Repo.Owner, pr.Repo.Name, pr.Number, request)
+	return err
+}
+
+// AddLabels adds labels to a pull request.
+func AddLabels(ctx context.Context, pr *PullRequest, accessToken string, labels []string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	gitHubClient := newGitHubClient(accessToken)
+	_, _, err := gitHubClient.Issues.AddLabelsToIssue(ctx, pr.Repo.Owner, pr.Repo.Name, pr.Number, labels)
+	return err
+}
+
+// AddAssignees assigns users to a pull request.
+func AddAssignees(ctx context.Context, pr *PullRequest, accessToken string, assignees []string) error {
+	if len(assignees) == 0 {
+		return nil
+	}
+	gitHubClient := newGitHubClient(accessToken)
+	_, _, err := gitHubClient.Issues.AddAssignees(ctx, pr.Repo.Owner, pr.Repo.Name, pr.Number, assignees)
+	return err
+}
+
+// UpsertComment creates a comment on a pull request, or (if there's already a
+// comment containing marker) replaces the body of the existing comment. The
+// marker should be included in body, so that the comment is found next time.
+func UpsertComment(ctx context.Context, pr *PullRequest, accessToken, marker, body string) error {
+	gitHubClient := newGitHubClient(accessToken)
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := gitHubClient.Issues.ListComments(ctx, pr.Repo.Owner, pr.Repo.Name, pr.Number, opts)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if strings.Contains(comment.GetBody(), marker) {
+				_, _, err := gitHubClient.Issues.EditComment(ctx, pr.Repo.Owner, pr.Repo.Name, comment.GetID(), &github.IssueComment{Body: &body})
+				return err
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	_, _, err := gitHubClient.Issues.CreateComment(ctx, pr.Repo.Owner, pr.Repo.Name, pr.Number, &github.IssueComment{Body: &body})
+	return err
+}
+
+// ReleaseExists reports whether the GitHub repository has a release with the
+// given tag.
+func ReleaseExists(ctx context.Context, repo *GitHubRepo, accessToken, tag string) (bool, error) {
+	gitHubClient := newGitHubClient(accessToken)
+	_, resp, err := gitHubClient.Repositories.GetReleaseByTag(ctx, repo.Owner, repo.Name, tag)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateRelease creates a GitHub release with the given tag, creating the tag
+// at the given commit if it doesn't already exist. It returns the URL of the
+// release.
+func CreateRelease(ctx context.Context, repo *GitHubRepo, accessToken, tag, commit, name, body string, prerelease bool) (string, error) {
+	gitHubClient := newGitHubClient(accessToken)
+	release := &github.RepositoryRelease{
+		TagName:         &tag,
+		TargetCommitish: &commit,
+		Name:            &name,
+		Body:            &body,
+		Prerelease:      &prerelease,
+	}
+	created, _, err := gitHubClient.Repositories.CreateRelease(ctx, repo.Owner, repo.Name, release)
+	if err != nil {
+		return "", err
+	}
+	return created.GetHTMLURL(), nil
+}
+
+// BranchInfo describes a branch in a GitHub repository.
+type BranchInfo struct {
+	Name string
+	// The commit time of the most recent commit on the branch.
+	LastCommit time.Time
+	// The state of the most recent pull request from the branch, if any:
+	// "open", "closed" or "merged".
+	PullRequestState string
+	// The time at which the most recent pull request from the branch was
+	// closed or merged, if it has been.
+	PullRequestClosed time.Time
+}
+
+// ListBranches returns information about all branches in the GitHub repository
+// whose names start with prefix.
+func ListBranches(ctx context.Context, repo *GitHubRepo, accessToken, prefix string) ([]*BranchInfo, error) {
+	gitHubClient := newGitHubClient(accessToken)
+	var branches []*BranchInfo
+	opts := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := gitHubClient.Repositories.ListBranches(ctx, repo.Owner, repo.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range page {
+			if !strings.HasPrefix(branch.GetName(), prefix) {
+				continue
+			}
+			info, err := getBranchInfo(ctx, gitHubClient, repo, branch)
+			if err != nil {
+				return nil, err
+			}
+			branches = append(branches, info)
+		}
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func getBranchInfo(ctx context.Context, gitHubClient *github.Client, repo *GitHubRepo, branch *github.Branch) (*BranchInfo, error) {
+	commit, _, err := gitHubClient.Repositories.GetCommit(ctx, repo.Owner, repo.Name, branch.GetCommit().GetSHA(), nil)
+	if err != nil {
+		return nil, err
+	}
+	info := &BranchInfo{
+		Name:       branch.GetName(),
+		LastCommit: commit.GetCommit().GetCommitter().GetDate().Time,
+	}
+	prs, _, err := gitHubClient.PullRequests.List(ctx, repo.Owner, repo.Name, &github.PullRequestListOptions{
+		Head:  fmt.Sprintf("%s:%s", repo.Owner, branch.GetName()),
+		State: "all",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(prs) > 0 {
+		info.PullRequestClosed = prs[0].GetClosedAt().Time
+		switch pr := prs[0]; {
+		case pr.MergedAt != nil:
+			info.PullRequestState = "merged"
+		default:
+			info.PullRequestState = pr.GetState()
+		}
+	}
+	return info, nil
+}
+
+// DeleteBranch deletes a branch from the GitHub repository.
+func DeleteBranch(ctx context.Context, repo *GitHubRepo, accessToken, branch string) error {
+	gitHubClient := newGitHubClient(accessToken)
+	_, err := gitHubClient.Git.DeleteRef(ctx, repo.Owner, repo.Name, fmt.Sprintf("heads/%s", branch))
+	return err
+}
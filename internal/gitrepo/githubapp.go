@@ -0,0 +1,106 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// CreateInstallationToken authenticates as a GitHub App, and mints a
+// short-lived installation access token which can be used in place of a
+// personal access token. If installationID is zero, the installation of the
+// app for the given repository is used.
+func CreateInstallationToken(ctx context.Context, appID int64, privateKeyPEM []byte, installationID int64, repo *GitHubRepo) (string, error) {
+	jwt, err := createAppJWT(appID, privateKeyPEM, time.Now())
+	if err != nil {
+		return "", err
+	}
+	gitHubClient := newGitHubClient(jwt)
+	if installationID == 0 {
+		installation, _, err := gitHubClient.Apps.FindRepositoryInstallation(ctx, repo.Owner, repo.Name)
+		if err != nil {
+			return "", fmt.Errorf("unable to find GitHub App installation for %s/%s: %w", repo.Owner, repo.Name, err)
+		}
+		installationID = installation.GetID()
+	}
+	token, _, err := gitHubClient.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create GitHub App installation token: %w", err)
+	}
+	return token.GetToken(), nil
+}
+
+// createAppJWT creates the JSON Web Token used to authenticate as a GitHub App,
+// as described at https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func createAppJWT(appID int64, privateKeyPEM []byte, now time.Time) (string, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return "", fmt.Errorf("GitHub App private key is not PEM encoded")
+	}
+	var key *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return "", err
+		}
+		key = parsed
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return "", err
+		}
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("GitHub App private key is not an RSA key")
+		}
+		key = rsaKey
+	default:
+		return "", fmt.Errorf("unexpected GitHub App private key type %q", block.Type)
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	// The issued-at time is backdated to allow for clock drift, and the
+	// expiry is within GitHub's 10 minute maximum.
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,306 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// gitLabURL is the base URL of the GitLab instance hosting projects,
+// configured by SetGitLabURL.
+var gitLabURL = "https://gitlab.com"
+
+// SetGitLabURL configures the GitLab instance hosting projects, e.g.
+// https://gitlab.example.com for a self-managed instance.
+func SetGitLabURL(baseURL string) error {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("invalid GitLab URL %q; must be an https URL such as https://gitlab.com", baseURL)
+	}
+	gitLabURL = strings.TrimSuffix(baseURL, "/")
+	return nil
+}
+
+// ParseGitLabURL parses the HTTPS URL of a project on the configured GitLab
+// instance, such as https://gitlab.com/group/subgroup/project.git. The
+// returned GitHubRepo's Owner is the namespace of the project (which may
+// contain slashes), and its Name is the project name.
+func ParseGitLabURL(remoteURL string) (*GitHubRepo, error) {
+	prefix := gitLabURL + "/"
+	if !strings.HasPrefix(remoteURL, prefix) {
+		return nil, fmt.Errorf("remote '%s' is not a GitLab remote on %s", remoteURL, gitLabURL)
+	}
+	projectPath := strings.TrimSuffix(strings.Trim(remoteURL[len(prefix):], "/"), ".git")
+	slash := strings.LastIndex(projectPath, "/")
+	if slash <= 0 || slash == len(projectPath)-1 {
+		return nil, fmt.Errorf("remote '%s' is not a GitLab project URL", remoteURL)
+	}
+	return &GitHubRepo{Owner: projectPath[:slash], Name: projectPath[slash+1:]}, nil
+}
+
+// gitLabForge is the Forge for a project on GitLab, using its REST API.
+type gitLabForge struct {
+	project     *GitHubRepo
+	accessToken string
+}
+
+// gitLabMergeRequest is the subset of a merge request returned by the GitLab
+// API which is used here.
+type gitLabMergeRequest struct {
+	IID          int    `json:"iid"`
+	WebURL       string `json:"web_url"`
+	SourceBranch string `json:"source_branch"`
+	Description  string `json:"description"`
+	Reviewers    []struct {
+		ID int `json:"id"`
+	} `json:"reviewers"`
+	Assignees []struct {
+		ID int `json:"id"`
+	} `json:"assignees"`
+}
+
+// gitLabNote is a comment on a merge request.
+type gitLabNote struct {
+	ID   int    `json:"id"`
+	Body string `json:"body"`
+}
+
+// projectPath returns the URL-escaped path identifying the project in API
+// calls.
+func (f *gitLabForge) projectPath() string {
+	return url.PathEscape(f.project.Owner + "/" + f.project.Name)
+}
+
+func (f *gitLabForge) newPullRequest(mr *gitLabMergeRequest) *PullRequest {
+	return &PullRequest{
+		Repo:         f.project,
+		Number:       mr.IID,
+		HTMLURL:      mr.WebURL,
+		HeadBranch:   mr.SourceBranch,
+		HeadCloneURL: fmt.Sprintf("%s/%s/%s.git", gitLabURL, f.project.Owner, f.project.Name),
+		Body:         mr.Description,
+	}
+}
+
+func (f *gitLabForge) FindOpenPullRequests(ctx context.Context, branchPrefix, bodyMarker string) ([]*PullRequest, error) {
+	var found []*PullRequest
+	for page := "1"; page != ""; {
+		var mergeRequests []*gitLabMergeRequest
+		next, err := f.call(ctx, http.MethodGet, fmt.Sprintf("projects/%s/merge_requests?state=opened&per_page=100&page=%s", f.projectPath(), page), nil, &mergeRequests)
+		if err != nil {
+			return nil, err
+		}
+		for _, mr := range mergeRequests {
+			if strings.HasPrefix(mr.SourceBranch, branchPrefix) && strings.Contains(mr.Description, bodyMarker) {
+				found = append(found, f.newPullRequest(mr))
+			}
+		}
+		page = next
+	}
+	return found, nil
+}
+
+func (f *gitLabForge) UploadRef(branch, baseBranch string) string {
+	return "refs/heads/" + branch
+}
+
+func (f *gitLabForge) ForkURL(fork string) (string, error) {
+	return "", fmt.Errorf("pushing to a fork is not supported on GitLab")
+}
+
+func (f *gitLabForge) CreatePullRequest(ctx context.Context, fork, branch, baseBranch, title, body string) (*PullRequest, error) {
+	if fork != "" {
+		return nil, fmt.Errorf("creating a merge request from a fork is not supported on GitLab")
+	}
+	request := map[string]any{
+		"source_branch": branch,
+		"target_branch": baseBranch,
+		"title":         title,
+		"description":   body,
+	}
+	var mr gitLabMergeRequest
+	if _, err := f.call(ctx, http.MethodPost, fmt.Sprintf("projects/%s/merge_requests", f.projectPath()), request, &mr); err != nil {
+		return nil, err
+	}
+	fmt.Printf("MR created: %s\n", mr.WebURL)
+	return f.newPullRequest(&mr), nil
+}
+
+// RequestReviewers adds the given users as reviewers of the merge request.
+// GitLab has no equivalent of GitHub teams as reviewers, so teams are logged
+// and otherwise ignored.
+func (f *gitLabForge) RequestReviewers(ctx context.Context, pr *PullRequest, users, teams []string) error {
+	if len(teams) > 0 {
+		slog.Warn(fmt.Sprintf("Ignoring team reviewers for %s, which are not supported on GitLab: %s", pr.HTMLURL, strings.Join(teams, ", ")))
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	ids, err := f.userIDs(ctx, users)
+	if err != nil {
+		return err
+	}
+	mr, err := f.getMergeRequest(ctx, pr)
+	if err != nil {
+		return err
+	}
+	for _, reviewer := range mr.Reviewers {
+		ids = append(ids, reviewer.ID)
+	}
+	return f.updateMergeRequest(ctx, pr, map[string]any{"reviewer_ids": uniqueIDs(ids)})
+}
+
+func (f *gitLabForge) AddLabels(ctx context.Context, pr *PullRequest, labels []string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	return f.updateMergeRequest(ctx, pr, map[string]any{"add_labels": strings.Join(labels, ",")})
+}
+
+func (f *gitLabForge) AddAssignees(ctx context.Context, pr *PullRequest, assignees []string) error {
+	if len(assignees) == 0 {
+		return nil
+	}
+	ids, err := f.userIDs(ctx, assignees)
+	if err != nil {
+		return err
+	}
+	mr, err := f.getMergeRequest(ctx, pr)
+	if err != nil {
+		return err
+	}
+	for _, assignee := range mr.Assignees {
+		ids = append(ids, assignee.ID)
+	}
+	return f.updateMergeRequest(ctx, pr, map[string]any{"assignee_ids": uniqueIDs(ids)})
+}
+
+func (f *gitLabForge) UpsertComment(ctx context.Context, pr *PullRequest, marker, body string) error {
+	notesPath := fmt.Sprintf("projects/%s/merge_requests/%d/notes", f.projectPath(), pr.Number)
+	for page := "1"; page != ""; {
+		var notes []*gitLabNote
+		next, err := f.call(ctx, http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%s", notesPath, page), nil, &notes)
+		if err != nil {
+			return err
+		}
+		for _, note := range notes {
+			if strings.Contains(note.Body, marker) {
+				_, err := f.call(ctx, http.MethodPut, fmt.Sprintf("%s/%d", notesPath, note.ID), map[string]any{"body": body}, nil)
+				return err
+			}
+		}
+		page = next
+	}
+	_, err := f.call(ctx, http.MethodPost, notesPath, map[string]any{"body": body}, nil)
+	return err
+}
+
+// getMergeRequest fetches the current state of the merge request, e.g. so
+// that reviewers and assignees can be added to the existing ones, as GitLab
+// replaces them on update.
+func (f *gitLabForge) getMergeRequest(ctx context.Context, pr *PullRequest) (*gitLabMergeRequest, error) {
+	var mr gitLabMergeRequest
+	if _, err := f.call(ctx, http.MethodGet, fmt.Sprintf("projects/%s/merge_requests/%d", f.projectPath(), pr.Number), nil, &mr); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+func (f *gitLabForge) updateMergeRequest(ctx context.Context, pr *PullRequest, update map[string]any) error {
+	_, err := f.call(ctx, http.MethodPut, fmt.Sprintf("projects/%s/merge_requests/%d", f.projectPath(), pr.Number), update, nil)
+	return err
+}
+
+// userIDs looks up the IDs of the users with the given usernames.
+func (f *gitLabForge) userIDs(ctx context.Context, usernames []string) ([]int, error) {
+	var ids []int
+	for _, username := range usernames {
+		var users []struct {
+			ID int `json:"id"`
+		}
+		if _, err := f.call(ctx, http.MethodGet, "users?username="+url.QueryEscape(username), nil, &users); err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("no GitLab user with username %q", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
+
+// uniqueIDs returns the given user IDs without duplicates, in order of first
+// appearance.
+func uniqueIDs(ids []int) []int {
+	seen := map[int]bool{}
+	var unique []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// call makes a request to the GitLab REST API, with request (if not nil)
+// encoded as the JSON body, and decodes the JSON response into response (if
+// not nil). It returns the next page of results, if any.
+func (f *gitLabForge) call(ctx context.Context, method, path string, request, response any) (string, error) {
+	var body io.Reader
+	if request != nil {
+		encoded, err := json.Marshal(request)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/api/v4/%s", gitLabURL, path), body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+f.accessToken)
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("GitLab API %s %s failed: %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	if response != nil {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return "", fmt.Errorf("unable to decode GitLab API response: %w", err)
+		}
+	}
+	next := resp.Header.Get("X-Next-Page")
+	if _, err := strconv.Atoi(next); err != nil {
+		next = ""
+	}
+	return next, nil
+}
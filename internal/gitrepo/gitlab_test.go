@@ -0,0 +1,73 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestGitLabAddsToExistingReviewersAndAssignees(t *testing.T) {
+	updates := map[string][]int{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/users":
+			ids := map[string]int{"alice": 1, "bob": 2}
+			json.NewEncoder(w).Encode([]map[string]int{{"id": ids[r.URL.Query().Get("username")]}})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/group/project/merge_requests/5":
+			w.Write([]byte(`{"iid": 5, "reviewers": [{"id": 3}, {"id": 1}], "assignees": [{"id": 4}]}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v4/projects/group/project/merge_requests/5":
+			var update map[string][]int
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				t.Error(err)
+			}
+			for field, ids := range update {
+				updates[field] = ids
+			}
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer SetGitLabURL(gitLabURL)
+	if err := SetGitLabURL(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	defaultClient := http.DefaultClient
+	http.DefaultClient = server.Client()
+	defer func() { http.DefaultClient = defaultClient }()
+
+	ctx := context.Background()
+	forge := &gitLabForge{project: &GitHubRepo{Owner: "group", Name: "project"}}
+	pr := &PullRequest{Number: 5}
+	if err := forge.RequestReviewers(ctx, pr, []string{"alice", "bob"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := forge.AddAssignees(ctx, pr, []string{"bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := updates["reviewer_ids"], []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("reviewer_ids = %v; want %v", got, want)
+	}
+	if got, want := updates["assignee_ids"], []int{2, 4}; !slices.Equal(got, want) {
+		t.Errorf("assignee_ids = %v; want %v", got, want)
+	}
+}
@@ -17,20 +17,37 @@ package gitrepo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/google/go-github/v69/github"
 )
 
+// The author (and committer) of the commits made by Commit.
+const (
+	commitAuthorName  = "Google Cloud SDK"
+	commitAuthorEmail = "noreply-cloudsdk@google.com"
+)
+
+// ErrStaleLease is returned by PushBranchWithLease when the remote branch
+// isn't at the expected commit.
+var ErrStaleLease = errors.New("remote branch is not at the expected commit")
+
 // Repo represents a git repository.
 type Repo struct {
 	Dir  string
@@ -43,8 +60,9 @@ type Repo struct {
 // it opens and provides access to that repository.
 //
 // Otherwise, it clones the repository from the given URL (repoURL) and saves it
-// to the specified directory path (dirpath).
-func CloneOrOpen(ctx context.Context, dirpath, repoURL string) (*Repo, error) {
+// to the specified directory path (dirpath), checking out the given branch
+// (or the default branch if branch is empty).
+func CloneOrOpen(ctx context.Context, dirpath, repoURL, branch string) (*Repo, error) {
 	slog.Info(fmt.Sprintf("Cloning %q to %q", repoURL, dirpath))
 
 	_, err := os.Stat(dirpath)
@@ -52,17 +70,22 @@ func CloneOrOpen(ctx context.Context, dirpath, repoURL string) (*Repo, error) {
 		return Open(ctx, dirpath)
 	}
 	if os.IsNotExist(err) {
-		return Clone(ctx, dirpath, repoURL)
+		return Clone(ctx, dirpath, repoURL, branch)
 	}
 	return nil, err
 }
 
 // Clone downloads a copy of a Git repository from repoURL and saves it to the
-// specified directory at dirpath.
-func Clone(ctx context.Context, dirpath, repoURL string) (*Repo, error) {
+// specified directory at dirpath. Only the given branch is cloned; if branch
+// is empty, the remote's default branch is used.
+func Clone(ctx context.Context, dirpath, repoURL, branch string) (*Repo, error) {
+	referenceName := plumbing.HEAD
+	if branch != "" {
+		referenceName = plumbing.NewBranchReferenceName(branch)
+	}
 	options := &git.CloneOptions{
 		URL:           repoURL,
-		ReferenceName: plumbing.HEAD,
+		ReferenceName: referenceName,
 		SingleBranch:  true,
 		Tags:          git.NoTags,
 		// .NET uses submodules for conformance tests.
@@ -73,7 +96,7 @@ func Clone(ctx context.Context, dirpath, repoURL string) (*Repo, error) {
 		options.Progress = os.Stdout // When not a CI build, output progress.
 	}
 
-	repo, err := git.PlainClone(dirpath, false, options)
+	repo, err := git.PlainCloneContext(ctx, dirpath, false, options)
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +106,49 @@ func Clone(ctx context.Context, dirpath, repoURL string) (*Repo, error) {
 	}, nil
 }
 
+// SparseClone performs a shallow clone of the repository at repoURL, checking
+// out only the paths (directories or files) with the given prefixes. This is
+// much faster than a full clone for large repositories such as googleapis, but
+// the resulting repository has no history beyond the most recent commit.
+func SparseClone(ctx context.Context, dirpath, repoURL string, paths []string) (*Repo, error) {
+	slog.Info(fmt.Sprintf("Sparse cloning %q to %q", repoURL, dirpath))
+	options := &git.CloneOptions{
+		URL:           repoURL,
+		ReferenceName: plumbing.HEAD,
+		SingleBranch:  true,
+		Depth:         1,
+		Tags:          git.NoTags,
+		NoCheckout:    true,
+	}
+	if ci := os.Getenv("CI"); ci == "" {
+		options.Progress = os.Stdout // When not a CI build, output progress.
+	}
+
+	repo, err := git.PlainCloneContext(ctx, dirpath, false, options)
+	if err != nil {
+		return nil, err
+	}
+	headRef, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	checkoutOptions := &git.CheckoutOptions{
+		Branch:                    headRef.Name(),
+		SparseCheckoutDirectories: paths,
+	}
+	if err := worktree.Checkout(checkoutOptions); err != nil {
+		return nil, err
+	}
+	return &Repo{
+		Dir:  dirpath,
+		repo: repo,
+	}, nil
+}
+
 // Open provides access to a Git repository that exists at dirpath.
 func Open(ctx context.Context, dirpath string) (*Repo, error) {
 	repo, err := git.PlainOpen(dirpath)
@@ -95,6 +161,102 @@ func Open(ctx context.Context, dirpath string) (*Repo, error) {
 	}, nil
 }
 
+// Init creates a new, empty Git repository at dirpath.
+func Init(ctx context.Context, dirpath string) (*Repo, error) {
+	repo, err := git.PlainInit(dirpath, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{
+		Dir:  dirpath,
+		repo: repo,
+	}, nil
+}
+
+// CurrentBranch returns the name of the branch checked out in the repository,
+// or an empty string if HEAD is detached.
+func CurrentBranch(ctx context.Context, repo *Repo) (string, error) {
+	headRef, err := repo.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !headRef.Name().IsBranch() {
+		return "", nil
+	}
+	return headRef.Name().Short(), nil
+}
+
+// HasRemote reports whether the repository has a remote with the given name.
+func HasRemote(ctx context.Context, repo *Repo, name string) bool {
+	_, err := repo.repo.Remote(name)
+	return err == nil
+}
+
+// FetchAndReset fetches the current branch from the "origin" remote, then
+// hard resets the worktree to the fetched commit and removes any untracked
+// files. This is used to bring a cached clone up to date without recloning.
+func FetchAndReset(ctx context.Context, repo *Repo) error {
+	hash, err := FetchCurrentBranch(ctx, repo)
+	if err != nil {
+		return err
+	}
+	return ResetHardTo(ctx, repo, hash)
+}
+
+// FetchCurrentBranch fetches the current branch from the "origin" remote into
+// its remote-tracking branch (refs/remotes/origin/<branch>), returning the
+// hash of the fetched commit. The local branch and worktree are unchanged.
+func FetchCurrentBranch(ctx context.Context, repo *Repo) (string, error) {
+	headRef, err := repo.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !headRef.Name().IsBranch() {
+		return "", fmt.Errorf("repository at %q is not on a branch", repo.Dir)
+	}
+	branch := headRef.Name().Short()
+	remoteRef := plumbing.NewRemoteReferenceName("origin", branch)
+	refSpec := config.RefSpec(fmt.Sprintf("+%s:%s", headRef.Name(), remoteRef))
+
+	slog.Info(fmt.Sprintf("Fetching %q in %q", branch, repo.Dir))
+	err = repo.repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{refSpec},
+		Tags:       git.NoTags,
+		Force:      true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return "", err
+	}
+
+	ref, err := repo.repo.Reference(remoteRef, true)
+	if err != nil {
+		return "", err
+	}
+	return ref.Hash().String(), nil
+}
+
+// RemoteTrackingHash returns the hash of the remote-tracking branch of the
+// current branch (refs/remotes/origin/<branch>) as last fetched, or an empty
+// string if it has never been fetched.
+func RemoteTrackingHash(ctx context.Context, repo *Repo) (string, error) {
+	headRef, err := repo.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !headRef.Name().IsBranch() {
+		return "", fmt.Errorf("repository at %q is not on a branch", repo.Dir)
+	}
+	ref, err := repo.repo.Reference(plumbing.NewRemoteReferenceName("origin", headRef.Name().Short()), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return ref.Hash().String(), nil
+}
+
 func AddAll(ctx context.Context, repo *Repo) (git.Status, error) {
 	worktree, err := repo.repo.Worktree()
 	if err != nil {
@@ -107,8 +269,9 @@ func AddAll(ctx context.Context, repo *Repo) (git.Status, error) {
 	return worktree.Status()
 }
 
-// returns an error if there is nothing to commit
-func Commit(ctx context.Context, repo *Repo, msg string) error {
+// returns an error if there is nothing to commit. If signer is non-nil,
+// it is used to sign the commit.
+func Commit(ctx context.Context, repo *Repo, msg string, signer git.Signer) error {
 	worktree, err := repo.repo.Worktree()
 	if err != nil {
 		return err
@@ -123,10 +286,11 @@ func Commit(ctx context.Context, repo *Repo, msg string) error {
 	}
 	commit, err := worktree.Commit(msg, &git.CommitOptions{
 		Author: &object.Signature{
-			Name:  "Google Cloud SDK",
-			Email: "noreply-cloudsdk@google.com",
+			Name:  commitAuthorName,
+			Email: commitAuthorEmail,
 			When:  time.Now(),
 		},
+		Signer: signer,
 	})
 	if err != nil {
 		return err
@@ -148,7 +312,75 @@ func HeadHash(ctx context.Context, repo *Repo) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return headRef.String(), nil
+	return headRef.Hash().String(), nil
+}
+
+// ChangedFilesSince returns the paths of all files which differ between the
+// given commit and the HEAD commit.
+func ChangedFilesSince(ctx context.Context, repo *Repo, commit string) ([]string, error) {
+	changes, err := diffSince(ctx, repo, commit)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, change := range changes {
+		if change.From.Name != "" {
+			paths = append(paths, change.From.Name)
+		}
+		if change.To.Name != "" && change.To.Name != change.From.Name {
+			paths = append(paths, change.To.Name)
+		}
+	}
+	return paths, nil
+}
+
+// FileStat describes the number of lines added and deleted in a single file.
+type FileStat struct {
+	Name      string
+	Additions int
+	Deletions int
+}
+
+// DiffStatsSince returns line statistics for each file which differs between
+// the given commit and the HEAD commit.
+func DiffStatsSince(ctx context.Context, repo *Repo, commit string) ([]FileStat, error) {
+	changes, err := diffSince(ctx, repo, commit)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := changes.PatchContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var stats []FileStat
+	for _, stat := range patch.Stats() {
+		stats = append(stats, FileStat{Name: stat.Name, Additions: stat.Addition, Deletions: stat.Deletion})
+	}
+	return stats, nil
+}
+
+func diffSince(ctx context.Context, repo *Repo, commit string) (object.Changes, error) {
+	headRef, err := repo.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	headCommit, err := repo.repo.CommitObject(headRef.Hash())
+	if err != nil {
+		return nil, err
+	}
+	baseCommit, err := repo.repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	baseTree, err := baseCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return object.DiffTreeWithOptions(ctx, baseTree, headTree, object.DefaultDiffTreeOptions)
 }
 
 func IsClean(ctx context.Context, repo *Repo) (bool, error) {
@@ -164,6 +396,17 @@ func IsClean(ctx context.Context, repo *Repo) (bool, error) {
 	return status.IsClean(), nil
 }
 
+// SoftReset moves the current branch to commit, leaving the index and
+// worktree unchanged, so that the changes made since commit can be committed
+// again (e.g. as a single commit).
+func SoftReset(ctx context.Context, repo *Repo, commit string) error {
+	worktree, err := repo.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Reset(&git.ResetOptions{Commit: plumbing.NewHash(commit), Mode: git.SoftReset})
+}
+
 func ResetHard(ctx context.Context, repo *Repo) error {
 	worktree, err := repo.repo.Worktree()
 	if err != nil {
@@ -172,6 +415,100 @@ func ResetHard(ctx context.Context, repo *Repo) error {
 	return worktree.Reset(&git.ResetOptions{Mode: git.HardReset})
 }
 
+// ResetHardTo moves the current branch to commit, resetting the index and
+// worktree to match it and removing any untracked files.
+func ResetHardTo(ctx context.Context, repo *Repo, commit string) error {
+	worktree, err := repo.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: plumbing.NewHash(commit), Mode: git.HardReset}); err != nil {
+		return err
+	}
+	return worktree.Clean(&git.CleanOptions{Dir: true})
+}
+
+// RemoveUntracked removes all untracked files and directories from the worktree.
+func RemoveUntracked(ctx context.Context, repo *Repo) error {
+	worktree, err := repo.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Clean(&git.CleanOptions{Dir: true})
+}
+
+// ExportTree writes the files in the tree of the given commit to dir, without
+// modifying the repository's worktree or HEAD.
+func ExportTree(ctx context.Context, repo *Repo, commit, dir string) error {
+	commitObject, err := repo.repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return err
+	}
+	tree, err := commitObject.Tree()
+	if err != nil {
+		return err
+	}
+	return tree.Files().ForEach(func(file *object.File) error {
+		if file.Mode == filemode.Symlink || file.Mode == filemode.Submodule {
+			return nil
+		}
+		perm := os.FileMode(0644)
+		if file.Mode == filemode.Executable {
+			perm = 0755
+		}
+		path := filepath.Join(dir, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		reader, err := file.Reader()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		output, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(output, reader); err != nil {
+			output.Close()
+			return err
+		}
+		return output.Close()
+	})
+}
+
+// ReadFiles returns the content of each file under dir (a slash-separated
+// path relative to the repository root) in the tree of the given commit, keyed
+// by path relative to dir. If dir doesn't exist in the commit, the result is
+// empty.
+func ReadFiles(ctx context.Context, repo *Repo, commit, dir string) (map[string]string, error) {
+	commitObject, err := repo.repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commitObject.Tree()
+	if err != nil {
+		return nil, err
+	}
+	subtree, err := tree.Tree(dir)
+	if errors.Is(err, object.ErrDirectoryNotFound) || errors.Is(err, object.ErrEntryNotFound) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	err = subtree.Files().ForEach(func(file *object.File) error {
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		files[file.Name] = content
+		return nil
+	})
+	return files, err
+}
+
 func PrintStatus(ctx context.Context, repo *Repo) error {
 	worktree, err := repo.repo.Worktree()
 	if err != nil {
@@ -258,7 +595,7 @@ func GetApiCommits(ctx context.Context, repo *Repo, path string, commit string)
 		if err != nil {
 			return err
 		}
-		currentPathEntry, err := currentTree.FindEntry(path)
+		currentPathHash, err := pathHash(currentTree, path)
 		if err != nil {
 			return err
 		}
@@ -266,13 +603,13 @@ func GetApiCommits(ctx context.Context, repo *Repo, path string, commit string)
 		if err != nil {
 			return err
 		}
-		parentPathEntry, err := parentTree.FindEntry(path)
+		parentPathHash, err := pathHash(parentTree, path)
 		if err != nil {
 			return err
 		}
 
 		// If we've found a change, add it to our list of commits.
-		if currentPathEntry.Hash != parentPathEntry.Hash {
+		if currentPathHash != parentPathHash {
 			commits = append(commits, *commit)
 		}
 
@@ -284,63 +621,393 @@ func GetApiCommits(ctx context.Context, repo *Repo, path string, commit string)
 	return commits, nil
 }
 
-// Creates a branch with the given name in the default remote.
-func PushBranch(ctx context.Context, repo *Repo, remoteBranch string, accessToken string) error {
+// pathHash returns the hash of the entry at the given path in tree, the hash of
+// the tree itself if path is empty, or the zero hash if the path doesn't exist.
+func pathHash(tree *object.Tree, path string) (plumbing.Hash, error) {
+	if path == "" {
+		return tree.Hash, nil
+	}
+	entry, err := tree.FindEntry(path)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return entry.Hash, nil
+}
+
+// FindCommit returns the most recent commit reachable from HEAD whose message
+// satisfies match, or nil if there is no such commit. The search stops at the
+// given commit (which is not included), if it's not empty.
+func FindCommit(ctx context.Context, repo *Repo, stop string, match func(message string) bool) (*object.Commit, error) {
+	logIterator, err := repo.repo.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	var found *object.Commit
+	err = logIterator.ForEach(func(commit *object.Commit) error {
+		if commit.Hash.String() == stop {
+			return storer.ErrStop
+		}
+		if match(commit.Message) {
+			found = commit
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return found, err
+}
+
+// CommitsSince returns the commits made on top of the given commit, most
+// recent first, following the first parent of each commit from HEAD. It's an
+// error if the given commit isn't reached.
+func CommitsSince(ctx context.Context, repo *Repo, commit string) ([]*object.Commit, error) {
 	headRef, err := repo.repo.Head()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var commits []*object.Commit
+	hash := headRef.Hash()
+	for hash.String() != commit {
+		candidate, err := repo.repo.CommitObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		if candidate.NumParents() == 0 {
+			return nil, fmt.Errorf("commit %s is not an ancestor of HEAD", commit)
+		}
+		commits = append(commits, candidate)
+		hash = candidate.ParentHashes[0]
+	}
+	return commits, nil
+}
+
+// CommitsNotIn returns the commits reachable from commit but not from exclude,
+// like git log exclude..commit, following all parents of each commit.
+func CommitsNotIn(ctx context.Context, repo *Repo, commit, exclude string) ([]*object.Commit, error) {
+	start, err := repo.repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := repo.repo.CommitObject(plumbing.NewHash(exclude))
+	if err != nil {
+		return nil, err
+	}
+	// Every commit reachable from both is reachable from a merge base, so
+	// the walk stops at the merge bases rather than checking the whole
+	// shared history.
+	bases, err := start.MergeBase(excluded)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[plumbing.Hash]bool{}
+	for _, base := range bases {
+		seen[base.Hash] = true
+	}
+	var commits []*object.Commit
+	queue := []*object.Commit{start}
+	for len(queue) > 0 {
+		candidate := queue[0]
+		queue = queue[1:]
+		if seen[candidate.Hash] {
+			continue
+		}
+		seen[candidate.Hash] = true
+		shared, err := candidate.IsAncestor(excluded)
+		if err != nil {
+			return nil, err
+		}
+		if shared || candidate.Hash == excluded.Hash {
+			continue
+		}
+		commits = append(commits, candidate)
+		err = candidate.Parents().ForEach(func(parent *object.Commit) error {
+			queue = append(queue, parent)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return commits, nil
+}
+
+// GetCommit returns the commit with the given hash.
+func GetCommit(ctx context.Context, repo *Repo, hash string) (*object.Commit, error) {
+	return repo.repo.CommitObject(plumbing.NewHash(hash))
+}
+
+// IsAncestor reports whether ancestor is reachable from (or the same as) commit.
+func IsAncestor(ctx context.Context, repo *Repo, ancestor, commit string) (bool, error) {
+	ancestorCommit, err := repo.repo.CommitObject(plumbing.NewHash(ancestor))
+	if err != nil {
+		return false, err
+	}
+	commitObject, err := repo.repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return false, err
 	}
+	return ancestorCommit.IsAncestor(commitObject)
+}
+
+// RemoteHeadHash returns the hash of the latest commit on the default branch
+// (HEAD) of the remote with the given URL, like git ls-remote, without
+// fetching any objects.
+func RemoteHeadHash(ctx context.Context, remoteURL string) (string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{remoteURL}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	byName := map[plumbing.ReferenceName]*plumbing.Reference{}
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+	ref := byName[plumbing.HEAD]
+	// HEAD is usually listed as a symbolic reference to the default branch.
+	for ref != nil && ref.Type() == plumbing.SymbolicReference {
+		ref = byName[ref.Target()]
+	}
+	if ref == nil {
+		return "", fmt.Errorf("remote %s has no HEAD", remoteURL)
+	}
+	return ref.Hash().String(), nil
+}
+
+// VerifyCommit checks the integrity of the given commit and of every tree and
+// file in it (but not its history), by recomputing the hash of each object
+// from its content. This detects missing, corrupt or tampered objects in
+// repos obtained from an untrusted source, such as a mirror or snapshot, so
+// that the checked-out content is known to be that of the commit.
+func VerifyCommit(ctx context.Context, repo *Repo, commit string) error {
+	verified := map[plumbing.Hash]bool{}
+	var verify func(objectType plumbing.ObjectType, hash plumbing.Hash) error
+	verify = func(objectType plumbing.ObjectType, hash plumbing.Hash) error {
+		if verified[hash] {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		encoded, err := repo.repo.Storer.EncodedObject(objectType, hash)
+		if err != nil {
+			return fmt.Errorf("unable to read %s %s: %w", objectType, hash, err)
+		}
+		reader, err := encoded.Reader()
+		if err != nil {
+			return err
+		}
+		hasher := plumbing.NewHasher(objectType, encoded.Size())
+		_, err = io.Copy(hasher, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		if actual := hasher.Sum(); actual != hash {
+			return fmt.Errorf("%s %s is corrupt: its content has hash %s", objectType, hash, actual)
+		}
+		verified[hash] = true
+
+		switch objectType {
+		case plumbing.CommitObject:
+			var commitObject object.Commit
+			if err := commitObject.Decode(encoded); err != nil {
+				return err
+			}
+			return verify(plumbing.TreeObject, commitObject.TreeHash)
+		case plumbing.TreeObject:
+			var tree object.Tree
+			if err := tree.Decode(encoded); err != nil {
+				return err
+			}
+			for _, entry := range tree.Entries {
+				switch entry.Mode {
+				case filemode.Dir:
+					err = verify(plumbing.TreeObject, entry.Hash)
+				case filemode.Submodule:
+					// Submodule commits are in other repositories.
+				default:
+					err = verify(plumbing.BlobObject, entry.Hash)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return verify(plumbing.CommitObject, plumbing.NewHash(commit))
+}
+
+// FetchBranch fetches a branch from the remote with the given URL, returning
+// the hash of its latest commit. The branch is stored as
+// refs/librarian/fetched/<branch>, so it doesn't affect the local branches.
+func FetchBranch(ctx context.Context, repo *Repo, remoteURL, branch, accessToken string) (string, error) {
 	auth := http.BasicAuth{
 		Username: "Ignored",
 		Password: accessToken,
 	}
-	refFrom := headRef.Name().String()
-	refTo := fmt.Sprintf("refs/heads/%s", remoteBranch)
-	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", refFrom, refTo))
-	pushOptions := git.PushOptions{
-		RefSpecs: []config.RefSpec{refSpec},
-		Auth:     &auth,
+	localRef := plumbing.ReferenceName("refs/librarian/fetched/" + branch)
+	refSpec := config.RefSpec(fmt.Sprintf("+refs/heads/%s:%s", branch, localRef))
+	slog.Info(fmt.Sprintf("Fetching branch %s", branch))
+	err := repo.repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteURL: remoteURL,
+		RefSpecs:  []config.RefSpec{refSpec},
+		Tags:      git.NoTags,
+		Auth:      &auth,
+		Force:     true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return "", err
+	}
+	ref, err := repo.repo.Reference(localRef, true)
+	if err != nil {
+		return "", err
+	}
+	return ref.Hash().String(), nil
+}
+
+// SanitizeBranchName makes name a valid branch name, following the rules of
+// git check-ref-format: whitespace, control characters and the characters
+// ~^:?*[\ are replaced with "-", as are "..", "@{" and "//"; leading dots and
+// trailing ".lock" are removed from each path component; and leading or
+// trailing "/", "." and "-" are removed from the whole name. The result may be
+// empty if nothing valid remains.
+func SanitizeBranchName(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		if r <= ' ' || r == 0x7f || strings.ContainsRune("~^:?*[\\", r) {
+			r = '-'
+		}
+		builder.WriteRune(r)
+	}
+	name = builder.String()
+	for _, invalid := range []string{"..", "@{", "//"} {
+		for strings.Contains(name, invalid) {
+			name = strings.ReplaceAll(name, invalid, "-")
+		}
+	}
+	components := strings.Split(name, "/")
+	for i, component := range components {
+		component = strings.TrimLeft(component, ".")
+		for strings.HasSuffix(component, ".lock") {
+			component = strings.TrimSuffix(component, ".lock")
+		}
+		components[i] = component
+	}
+	name = strings.Join(slices.DeleteFunc(components, func(component string) bool { return component == "" }), "/")
+	name = strings.Trim(name, "/.-")
+	if name == "@" {
+		return ""
 	}
+	return name
+}
+
+// IsGeneratedCommit reports whether commit was both authored and committed by
+// Commit, rather than by someone else (e.g. amended by a person).
+func IsGeneratedCommit(commit *object.Commit) bool {
+	return commit.Author.Email == commitAuthorEmail && commit.Committer.Email == commitAuthorEmail
+}
 
-	slog.Info(fmt.Sprintf("Pushing to branch %s", remoteBranch))
-	return repo.repo.Push(&pushOptions)
+// Creates a branch with the given name in the remote with the given URL, or in the
+// default remote if remoteURL is empty. If force is true, an existing branch is overwritten.
+func PushBranch(ctx context.Context, repo *Repo, remoteURL, remoteBranch string, accessToken string, force bool) error {
+	return pushRef(ctx, repo, remoteURL, "refs/heads/"+remoteBranch, "", accessToken, force, nil)
 }
 
-// Creates a pull request in the remote repo. At the moment this requires a single remote to be
-// configured, which must have a GitHub HTTPS URL. We assume a base branch of "main".
-func CreatePullRequest(ctx context.Context, repo *Repo, remoteBranch string, accessToken string, title string) error {
-	remotes, err := repo.repo.Remotes()
+// PushToRef pushes HEAD to the given ref (e.g. refs/for/main for a Gerrit
+// change) in the remote with the given URL, or in the default remote if
+// remoteURL is empty. The username used to authenticate defaults to an
+// arbitrary value, which is ignored by GitHub and GitLab when using a token.
+func PushToRef(ctx context.Context, repo *Repo, remoteURL, ref, username, accessToken string) error {
+	return pushRef(ctx, repo, remoteURL, ref, username, accessToken, false, nil)
+}
+
+// PushBranchWithLease overwrites the branch with the given name in the remote
+// with the given URL, like git push --force-with-lease: the push is rejected,
+// with an error wrapping ErrStaleLease, unless the remote branch is at the
+// expected commit.
+func PushBranchWithLease(ctx context.Context, repo *Repo, remoteURL, remoteBranch, accessToken, expected string) error {
+	lease := &git.ForceWithLease{
+		RefName: plumbing.NewBranchReferenceName(remoteBranch),
+		Hash:    plumbing.NewHash(expected),
+	}
+	// go-git resolves the remote-tracking ref of the local branch when
+	// checking a lease, even though the expected commit is given, and fails
+	// with "reference not found" if there isn't one (e.g. on a branch which
+	// was never fetched). Such a ref is created for the duration of the push.
+	headRef, err := repo.repo.Head()
 	if err != nil {
 		return err
 	}
-
-	if len(remotes) != 1 {
-		return fmt.Errorf("can only create a PR with a single remote; number of remotes: %d", len(remotes))
+	trackingRef := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, strings.TrimPrefix(headRef.Name().String(), "refs/heads/"))
+	if _, err := repo.repo.Reference(trackingRef, true); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		if err := repo.repo.Storer.SetReference(plumbing.NewHashReference(trackingRef, lease.Hash)); err != nil {
+			return err
+		}
+		defer repo.repo.Storer.RemoveReference(trackingRef)
+	} else if err != nil {
+		return err
+	}
+	err = pushRef(ctx, repo, remoteURL, "refs/heads/"+remoteBranch, "", accessToken, false, lease)
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
 	}
+	// The lease is checked against the advertised refs before pushing, and
+	// by the server (as the expected commit is sent as the old value of the
+	// ref) in case the branch changes in the meantime.
+	if err != nil && (strings.Contains(err.Error(), "non-fast-forward") || strings.Contains(err.Error(), "stale info") || strings.Contains(err.Error(), "cannot lock ref")) {
+		return fmt.Errorf("%w: %s", ErrStaleLease, err)
+	}
+	return err
+}
 
-	remoteUrl := remotes[0].Config().URLs[0]
-	if !strings.HasPrefix(remoteUrl, "https://github.com/") {
-		return fmt.Errorf("remote '%s' is not a GitHub remote", remoteUrl)
+func pushRef(ctx context.Context, repo *Repo, remoteURL, refTo, username, accessToken string, force bool, lease *git.ForceWithLease) error {
+	headRef, err := repo.repo.Head()
+	if err != nil {
+		return err
+	}
+	if username == "" {
+		username = "Ignored"
+	}
+	auth := http.BasicAuth{
+		Username: username,
+		Password: accessToken,
+	}
+	refFrom := headRef.Name().String()
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", refFrom, refTo))
+	if force {
+		refSpec = "+" + refSpec
+	}
+	pushOptions := git.PushOptions{
+		RemoteURL:      remoteURL,
+		RefSpecs:       []config.RefSpec{refSpec},
+		Auth:           &auth,
+		ForceWithLease: lease,
 	}
-	remotePath := remoteUrl[len("https://github.com/"):]
-	pathParts := strings.Split(remotePath, "/")
-	organization := pathParts[0]
-	repoName := pathParts[1]
 
-	gitHubClient := github.NewClient(nil).WithAuthToken(accessToken)
+	slog.Info(fmt.Sprintf("Pushing to %s", strings.TrimPrefix(refTo, "refs/heads/")))
+	return repo.repo.PushContext(ctx, &pushOptions)
+}
+
+// CreatePullRequest creates a pull request in the GitHub repository, to merge the head branch
+// into baseBranch. To create a pull request from a fork, specify head in the form "owner:branch".
+func CreatePullRequest(ctx context.Context, gitHubRepo *GitHubRepo, head, baseBranch string, accessToken string, title, body string) (*PullRequest, error) {
+	gitHubClient := newGitHubClient(accessToken)
 	newPR := &github.NewPullRequest{
 		Title:               &title,
-		Head:                &remoteBranch,
-		Base:                github.Ptr("main"),
-		Body:                github.Ptr("Regenerated all changed APIs. See individual commits for details."),
+		Head:                &head,
+		Base:                &baseBranch,
+		Body:                &body,
 		MaintainerCanModify: github.Ptr(true),
 	}
 
-	pr, _, err := gitHubClient.PullRequests.Create(ctx, organization, repoName, newPR)
+	pr, _, err := gitHubClient.PullRequests.Create(ctx, gitHubRepo.Owner, gitHubRepo.Name, newPR)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	fmt.Printf("PR created: %s\n", pr.GetHTMLURL())
-	return nil
+	return newPullRequest(gitHubRepo, pr), nil
 }
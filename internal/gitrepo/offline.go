@@ -0,0 +1,61 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+)
+
+// ErrOffline is wrapped by the errors from git operations which require
+// network access when it's disabled by SetOffline.
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+// networkProtocols are the git protocols which require network access, with
+// their default transports.
+var networkProtocols = map[string]transport.Transport{}
+
+func init() {
+	for _, scheme := range []string{"http", "https", "ssh", "git"} {
+		networkProtocols[scheme] = client.Protocols[scheme]
+	}
+}
+
+// SetOffline sets whether git operations may access the network. When
+// offline, cloning, fetching and pushing fail immediately with an error
+// wrapping ErrOffline unless the remote is a local path (or file:// URL).
+func SetOffline(offline bool) {
+	for scheme, defaultTransport := range networkProtocols {
+		if offline {
+			client.InstallProtocol(scheme, offlineTransport{})
+		} else {
+			client.InstallProtocol(scheme, defaultTransport)
+		}
+	}
+}
+
+// offlineTransport is the git transport for network protocols when offline.
+type offlineTransport struct{}
+
+func (offlineTransport) NewUploadPackSession(endpoint *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	return nil, fmt.Errorf("%w: unable to fetch from %s", ErrOffline, endpoint.String())
+}
+
+func (offlineTransport) NewReceivePackSession(endpoint *transport.Endpoint, auth transport.AuthMethod) (transport.ReceivePackSession, error) {
+	return nil, fmt.Errorf("%w: unable to push to %s", ErrOffline, endpoint.String())
+}
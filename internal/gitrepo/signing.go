@@ -0,0 +1,55 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// ProgramSigner signs commits by running an external signing program in the
+// same way as git itself, so it works with both gpg and gitsign (which
+// implements the gpg interface for keyless Sigstore signing).
+type ProgramSigner struct {
+	// Program is the signing program, e.g. "gpg" or "gitsign".
+	Program string
+	// Key identifies the signing key. It may be empty for programs (such
+	// as gitsign) which don't require a key.
+	Key string
+}
+
+// Sign implements git.Signer, returning an armored detached signature.
+func (s *ProgramSigner) Sign(message io.Reader) ([]byte, error) {
+	args := []string{"--status-fd=2", "-bsa"}
+	if s.Key != "" {
+		args = append(args, "-u", s.Key)
+	}
+	cmd := exec.Command(s.Program, args...)
+	cmd.Stdin = message
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Stderr.Write(stderr.Bytes())
+		return nil, fmt.Errorf("signing with %s failed: %w", s.Program, err)
+	}
+	if !bytes.Contains(stderr.Bytes(), []byte("[GNUPG:] SIG_CREATED ")) {
+		return nil, fmt.Errorf("signing with %s did not create a signature", s.Program)
+	}
+	return stdout.Bytes(), nil
+}
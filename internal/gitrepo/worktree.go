@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// AddWorktree checks out the given commit of repo (detached) in a new linked
+// worktree at dir, which shares the repository's objects rather than being a
+// separate clone. As go-git can't create worktrees, this requires the git
+// binary. The worktree must be removed with RemoveWorktree.
+func AddWorktree(ctx context.Context, repo *Repo, dir, commit string) (*Repo, error) {
+	if err := runGit(ctx, repo.Dir, "worktree", "add", "--detach", dir, commit); err != nil {
+		return nil, err
+	}
+	worktree, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{
+		Dir:  dir,
+		repo: worktree,
+	}, nil
+}
+
+// RemoveWorktree removes a linked worktree created by AddWorktree, along with
+// any changes in it.
+func RemoveWorktree(ctx context.Context, repo *Repo, dir string) error {
+	return runGit(ctx, repo.Dir, "worktree", "remove", "--force", dir)
+}
+
+// StageChangesOnto moves the current branch of repo to the commit onto, and
+// stages the changes made between base and the previous HEAD on top of it,
+// without committing them. As go-git can't merge, this requires the git
+// binary. If the changes can't be applied cleanly, the branch is restored to
+// the previous HEAD and an error is returned.
+func StageChangesOnto(ctx context.Context, repo *Repo, base, onto string) error {
+	head, err := HeadHash(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if err := runGit(ctx, repo.Dir, "reset", "--hard", onto); err != nil {
+		return err
+	}
+	if err := runGit(ctx, repo.Dir, "cherry-pick", "--no-commit", fmt.Sprintf("%s..%s", base, head)); err != nil {
+		_ = runGit(ctx, repo.Dir, "cherry-pick", "--abort")
+		if resetErr := runGit(ctx, repo.Dir, "reset", "--hard", head); resetErr != nil {
+			return errors.Join(err, resetErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// runGit runs the git binary with the given arguments in dir.
+func runGit(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
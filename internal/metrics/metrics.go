@@ -0,0 +1,275 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics provides a minimal set of metrics for monitoring long-running
+// librarian processes, exposed in the Prometheus text exposition format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Runs counts completed command runs, by command and result ("success" or "failure").
+	Runs = NewCounter("librarian_runs_total", "Number of completed command runs.", "command", "result")
+	// RunDuration records the duration of command runs, by command.
+	RunDuration = NewHistogram("librarian_run_duration_seconds", "Duration of command runs in seconds.", durationBuckets, "command")
+	// StepFailures counts failures of individual pipeline steps, by step.
+	StepFailures = NewCounter("librarian_step_failures_total", "Number of failed pipeline steps.", "step")
+	// StepDuration records the duration of individual pipeline steps, by step.
+	StepDuration = NewHistogram("librarian_step_duration_seconds", "Duration of pipeline steps in seconds.", durationBuckets, "step")
+	// ContainerRetries counts retries of container runs after transient docker failures, by step.
+	ContainerRetries = NewCounter("librarian_container_retries_total", "Number of container runs retried after transient docker failures.", "step")
+	// QueueDepth reports the number of APIs still waiting to be processed.
+	QueueDepth = NewGauge("librarian_queue_depth", "Number of APIs waiting to be processed.")
+	// CloneCache counts lookups in the clone cache, by result ("hit" or "miss").
+	CloneCache = NewCounter("librarian_clone_cache_requests_total", "Number of clone cache lookups.", "result")
+	// LanguageRuns counts completed runs for a single language, by command, language and result.
+	LanguageRuns = NewCounter("librarian_language_runs_total", "Number of completed runs for a single language.", "command", "language", "result")
+	// LanguageRunDuration records the duration of runs for a single language, by command and language.
+	LanguageRunDuration = NewHistogram("librarian_language_run_duration_seconds", "Duration of runs for a single language in seconds.", durationBuckets, "command", "language")
+	// LanguageStepFailures counts failures of container steps, by step and language.
+	LanguageStepFailures = NewCounter("librarian_language_step_failures_total", "Number of failed container steps, by language.", "step", "language")
+	// ServeRequests counts requests received by the serve command, by endpoint and outcome ("queued", "ignored" or "rejected").
+	ServeRequests = NewCounter("librarian_serve_requests_total", "Number of run requests received by the server.", "endpoint", "outcome")
+	// ServeQueueDepth reports the number of requests waiting to be run by the serve command.
+	ServeQueueDepth = NewGauge("librarian_serve_queue_depth", "Number of run requests waiting to be run by the server.")
+)
+
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+var (
+	registryMu sync.Mutex
+	registry   []metric
+)
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+// desc holds the values common to all metric types, keyed by label values.
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu sync.Mutex
+}
+
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s: got %d label values, want %d", d.name, len(labelValues), len(d.labelNames)))
+	}
+	return strings.Join(labelValues, "\x00")
+}
+
+func (d *desc) labels(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labelNames) > 0 {
+		for i, value := range strings.Split(key, "\x00") {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", d.labelNames[i], value))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (d *desc) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, kind)
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	desc
+	values map[string]float64
+}
+
+// NewCounter creates and registers a counter with the given label names.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labelNames: labelNames}, values: map[string]float64{}}
+	register(c)
+	return c
+}
+
+// Inc increments the counter for the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key]++
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, c.labels(key), c.values[key])
+	}
+}
+
+// Gauge is a value which can go up and down.
+type Gauge struct {
+	desc
+	values map[string]float64
+}
+
+// NewGauge creates and registers a gauge with the given label names.
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help, labelNames: labelNames}, values: map[string]float64{}}
+	register(g)
+	return g
+}
+
+// Set sets the gauge for the given label values.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] = value
+}
+
+// Add adds delta (which may be negative) to the gauge for the given label values.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] += delta
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.header(w, "gauge")
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %g\n", g.name, g.labels(key), g.values[key])
+	}
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	desc
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates and registers a histogram with the given upper bucket
+// bounds (in increasing order) and label names.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{desc: desc{name: name, help: help, labelNames: labelNames}, buckets: buckets, values: map[string]*histogramValue{}}
+	register(h)
+	return h
+}
+
+// Observe records a single observation for the given label values.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+// ObserveSince records the time elapsed since start, in seconds.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(key, "le", fmt.Sprint(bound)), v.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(key, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, h.labels(key), v.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labels(key), v.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Handler returns an HTTP handler which serves all registered metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		for _, m := range registry {
+			m.write(w)
+		}
+	})
+}
+
+// Serve serves metrics on /metrics at the given address until ctx is done.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	slog.Info(fmt.Sprintf("Serving metrics on %s/metrics", addr))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package serviceconfig reads API metadata from the service config YAML files
+// in googleapis.
+package serviceconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata describes an API, as specified in its service config.
+type Metadata struct {
+	// Name is the service name, e.g. "cloudfunctions.googleapis.com".
+	Name string
+	// Title is the human-readable title, e.g. "Cloud Functions API".
+	Title string
+	// ShortName is the short name of the API, e.g. "cloudfunctions".
+	ShortName string
+	// DocumentationURI is the URI of the API's product documentation.
+	DocumentationURI string
+}
+
+// serviceConfig is the subset of a service config file which is read.
+type serviceConfig struct {
+	Name       string `yaml:"name"`
+	Title      string `yaml:"title"`
+	Publishing struct {
+		DocumentationURI string `yaml:"documentation_uri"`
+		APIShortName     string `yaml:"api_short_name"`
+	} `yaml:"publishing"`
+}
+
+// serviceConfigFile matches the names of service config files, e.g.
+// "cloudfunctions_v2.yaml" (but also "cloudfunctions_v2_gapic.yaml", which
+// is excluded separately).
+var serviceConfigFile = regexp.MustCompile(`^\w+_v\d+\w*\.yaml$`)
+
+// Find returns the path of the service config file in the directory of the API
+// at apiPath under apiRoot, or an empty string if there isn't exactly one.
+func Find(apiRoot, apiPath string) (string, error) {
+	found, err := List(apiRoot, apiPath)
+	if err != nil || len(found) != 1 {
+		return "", err
+	}
+	return found[0], nil
+}
+
+// List returns the paths of the service config files in the directory of the
+// API at apiPath under apiRoot.
+func List(apiRoot, apiPath string) ([]string, error) {
+	dir := filepath.Join(apiRoot, filepath.FromSlash(apiPath))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !serviceConfigFile.MatchString(name) || strings.HasSuffix(name, "_gapic.yaml") {
+			continue
+		}
+		found = append(found, filepath.Join(dir, name))
+	}
+	return found, nil
+}
+
+// Read reads the metadata from the service config file at path. If the file
+// doesn't specify a short name, it's derived from the service name.
+func Read(path string) (*Metadata, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config serviceConfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("invalid service config %s: %w", path, err)
+	}
+	metadata := &Metadata{
+		Name:             config.Name,
+		Title:            config.Title,
+		ShortName:        config.Publishing.APIShortName,
+		DocumentationURI: config.Publishing.DocumentationURI,
+	}
+	if metadata.ShortName == "" {
+		metadata.ShortName, _, _ = strings.Cut(config.Name, ".")
+	}
+	return metadata, nil
+}
@@ -94,6 +94,121 @@ func (AutomationLevel) EnumDescriptor() ([]byte, []int) {
 	return file_pipeline_proto_rawDescGZIP(), []int{0}
 }
 
+// The release channel of an API, which determines whether it is
+// published in stable or pre-release packages. The language container
+// decides how each channel maps to package versions and directories.
+type ReleaseChannel int32
+
+const (
+	// Derive the channel from the API version.
+	ReleaseChannel_RELEASE_CHANNEL_UNSPECIFIED ReleaseChannel = 0
+	// Generally available; published in stable packages.
+	ReleaseChannel_RELEASE_CHANNEL_STABLE ReleaseChannel = 1
+	// Beta; published in pre-release packages.
+	ReleaseChannel_RELEASE_CHANNEL_BETA ReleaseChannel = 2
+	// Alpha; published in pre-release packages.
+	ReleaseChannel_RELEASE_CHANNEL_ALPHA ReleaseChannel = 3
+)
+
+// Enum value maps for ReleaseChannel.
+var (
+	ReleaseChannel_name = map[int32]string{
+		0: "RELEASE_CHANNEL_UNSPECIFIED",
+		1: "RELEASE_CHANNEL_STABLE",
+		2: "RELEASE_CHANNEL_BETA",
+		3: "RELEASE_CHANNEL_ALPHA",
+	}
+	ReleaseChannel_value = map[string]int32{
+		"RELEASE_CHANNEL_UNSPECIFIED": 0,
+		"RELEASE_CHANNEL_STABLE":      1,
+		"RELEASE_CHANNEL_BETA":        2,
+		"RELEASE_CHANNEL_ALPHA":       3,
+	}
+)
+
+func (x ReleaseChannel) Enum() *ReleaseChannel {
+	p := new(ReleaseChannel)
+	*p = x
+	return p
+}
+
+func (x ReleaseChannel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReleaseChannel) Descriptor() protoreflect.EnumDescriptor {
+	return file_pipeline_proto_enumTypes[1].Descriptor()
+}
+
+func (ReleaseChannel) Type() protoreflect.EnumType {
+	return &file_pipeline_proto_enumTypes[1]
+}
+
+func (x ReleaseChannel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReleaseChannel.Descriptor instead.
+func (ReleaseChannel) EnumDescriptor() ([]byte, []int) {
+	return file_pipeline_proto_rawDescGZIP(), []int{1}
+}
+
+type VersionBump int32
+
+const (
+	// No changes, or no recommendation.
+	VersionBump_VERSION_BUMP_NONE VersionBump = 0
+	// Changes which don't affect the API surface, e.g. documentation.
+	VersionBump_VERSION_BUMP_PATCH VersionBump = 1
+	// Backward-compatible additions, e.g. new services, methods or fields.
+	VersionBump_VERSION_BUMP_MINOR VersionBump = 2
+	// Breaking changes, e.g. removed methods or fields.
+	VersionBump_VERSION_BUMP_MAJOR VersionBump = 3
+)
+
+// Enum value maps for VersionBump.
+var (
+	VersionBump_name = map[int32]string{
+		0: "VERSION_BUMP_NONE",
+		1: "VERSION_BUMP_PATCH",
+		2: "VERSION_BUMP_MINOR",
+		3: "VERSION_BUMP_MAJOR",
+	}
+	VersionBump_value = map[string]int32{
+		"VERSION_BUMP_NONE":  0,
+		"VERSION_BUMP_PATCH": 1,
+		"VERSION_BUMP_MINOR": 2,
+		"VERSION_BUMP_MAJOR": 3,
+	}
+)
+
+func (x VersionBump) Enum() *VersionBump {
+	p := new(VersionBump)
+	*p = x
+	return p
+}
+
+func (x VersionBump) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (VersionBump) Descriptor() protoreflect.EnumDescriptor {
+	return file_pipeline_proto_enumTypes[2].Descriptor()
+}
+
+func (VersionBump) Type() protoreflect.EnumType {
+	return &file_pipeline_proto_enumTypes[2]
+}
+
+func (x VersionBump) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use VersionBump.Descriptor instead.
+func (VersionBump) EnumDescriptor() ([]byte, []int) {
+	return file_pipeline_proto_rawDescGZIP(), []int{2}
+}
+
 // Overall state of the generation and release pipeline. This is expected
 // to be stored in each language repo as generator-input/pipeline-state.json.
 type PipelineState struct {
@@ -103,8 +218,14 @@ type PipelineState struct {
 	ImageTag             string                 `protobuf:"bytes,1,opt,name=image_tag,json=imageTag,proto3" json:"image_tag,omitempty"`
 	ApiGenerationStates  []*ApiGenerationState  `protobuf:"bytes,2,rep,name=api_generation_states,json=apiGenerationStates,proto3" json:"api_generation_states,omitempty"`
 	LibraryReleaseStates []*LibraryReleaseState `protobuf:"bytes,3,rep,name=library_release_states,json=libraryReleaseStates,proto3" json:"library_release_states,omitempty"`
-	unknownFields        protoimpl.UnknownFields
-	sizeCache            protoimpl.SizeCache
+	// Experimental pipeline features enabled for this repository. This allows
+	// new pipeline stages to be rolled out gradually, one language at a time.
+	// The experiments are passed to each container invocation.
+	Experiments []string `protobuf:"bytes,4,rep,name=experiments,proto3" json:"experiments,omitempty"`
+	// Configuration for pull requests created by the CLI.
+	PullRequestConfig *PullRequestConfig `protobuf:"bytes,5,opt,name=pull_request_config,json=pullRequestConfig,proto3" json:"pull_request_config,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *PipelineState) Reset() {
@@ -158,6 +279,85 @@ func (x *PipelineState) GetLibraryReleaseStates() []*LibraryReleaseState {
 	return nil
 }
 
+func (x *PipelineState) GetExperiments() []string {
+	if x != nil {
+		return x.Experiments
+	}
+	return nil
+}
+
+func (x *PipelineState) GetPullRequestConfig() *PullRequestConfig {
+	if x != nil {
+		return x.PullRequestConfig
+	}
+	return nil
+}
+
+// Configuration for pull requests created by the CLI. These values are
+// combined with any specified on the command line.
+type PullRequestConfig struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Users, or teams in the form "org/team-name", to request reviews from.
+	Reviewers []string `protobuf:"bytes,1,rep,name=reviewers,proto3" json:"reviewers,omitempty"`
+	// Labels to add to each pull request, e.g. "automerge".
+	Labels []string `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty"`
+	// Users to assign each pull request to.
+	Assignees     []string `protobuf:"bytes,3,rep,name=assignees,proto3" json:"assignees,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullRequestConfig) Reset() {
+	*x = PullRequestConfig{}
+	mi := &file_pipeline_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullRequestConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullRequestConfig) ProtoMessage() {}
+
+func (x *PullRequestConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_pipeline_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullRequestConfig.ProtoReflect.Descriptor instead.
+func (*PullRequestConfig) Descriptor() ([]byte, []int) {
+	return file_pipeline_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PullRequestConfig) GetReviewers() []string {
+	if x != nil {
+		return x.Reviewers
+	}
+	return nil
+}
+
+func (x *PullRequestConfig) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *PullRequestConfig) GetAssignees() []string {
+	if x != nil {
+		return x.Assignees
+	}
+	return nil
+}
+
 // Generation state of a single API.
 type ApiGenerationState struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -168,13 +368,23 @@ type ApiGenerationState struct {
 	LastGeneratedCommit string `protobuf:"bytes,2,opt,name=last_generated_commit,json=lastGeneratedCommit,proto3" json:"last_generated_commit,omitempty"`
 	// The automation level for regeneration of this API.
 	AutomationLevel AutomationLevel `protobuf:"varint,3,opt,name=automation_level,json=automationLevel,proto3,enum=google.cloud.sdk.pipeline.AutomationLevel" json:"automation_level,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// The release channel for this API. When unspecified, the channel is
+	// derived from the API version (e.g. v1beta1 is in the beta channel).
+	ReleaseChannel ReleaseChannel `protobuf:"varint,4,opt,name=release_channel,json=releaseChannel,proto3,enum=google.cloud.sdk.pipeline.ReleaseChannel" json:"release_channel,omitempty"`
+	// The version bump recommended for the changes generated since the library
+	// containing this API was last released, based on changes to its protos.
+	UnreleasedVersionBump VersionBump `protobuf:"varint,5,opt,name=unreleased_version_bump,json=unreleasedVersionBump,proto3,enum=google.cloud.sdk.pipeline.VersionBump" json:"unreleased_version_bump,omitempty"`
+	// A hash of the inputs from which the API was last generated: its protos,
+	// service config and BUILD.bazel options, and the generator image digest.
+	// Generation is skipped when the inputs are unchanged.
+	LastGeneratedInputHash string `protobuf:"bytes,6,opt,name=last_generated_input_hash,json=lastGeneratedInputHash,proto3" json:"last_generated_input_hash,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *ApiGenerationState) Reset() {
 	*x = ApiGenerationState{}
-	mi := &file_pipeline_proto_msgTypes[1]
+	mi := &file_pipeline_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -186,7 +396,7 @@ func (x *ApiGenerationState) String() string {
 func (*ApiGenerationState) ProtoMessage() {}
 
 func (x *ApiGenerationState) ProtoReflect() protoreflect.Message {
-	mi := &file_pipeline_proto_msgTypes[1]
+	mi := &file_pipeline_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -199,7 +409,7 @@ func (x *ApiGenerationState) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ApiGenerationState.ProtoReflect.Descriptor instead.
 func (*ApiGenerationState) Descriptor() ([]byte, []int) {
-	return file_pipeline_proto_rawDescGZIP(), []int{1}
+	return file_pipeline_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *ApiGenerationState) GetId() string {
@@ -223,6 +433,27 @@ func (x *ApiGenerationState) GetAutomationLevel() AutomationLevel {
 	return AutomationLevel_AUTOMATION_LEVEL_NONE
 }
 
+func (x *ApiGenerationState) GetReleaseChannel() ReleaseChannel {
+	if x != nil {
+		return x.ReleaseChannel
+	}
+	return ReleaseChannel_RELEASE_CHANNEL_UNSPECIFIED
+}
+
+func (x *ApiGenerationState) GetUnreleasedVersionBump() VersionBump {
+	if x != nil {
+		return x.UnreleasedVersionBump
+	}
+	return VersionBump_VERSION_BUMP_NONE
+}
+
+func (x *ApiGenerationState) GetLastGeneratedInputHash() string {
+	if x != nil {
+		return x.LastGeneratedInputHash
+	}
+	return ""
+}
+
 // Generation state of a single library.
 type LibraryReleaseState struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -235,13 +466,23 @@ type LibraryReleaseState struct {
 	NextVersion string `protobuf:"bytes,3,opt,name=next_version,json=nextVersion,proto3" json:"next_version,omitempty"`
 	// The automation level for releases for this library.
 	AutomationLevel AutomationLevel `protobuf:"varint,4,opt,name=automation_level,json=automationLevel,proto3,enum=google.cloud.sdk.pipeline.AutomationLevel" json:"automation_level,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// The paths (relative to the root of the language repo) containing the
+	// source of this library. Changes to these paths are released. When empty,
+	// any change in the repo is treated as a change to the library.
+	SourcePaths []string `protobuf:"bytes,5,rep,name=source_paths,json=sourcePaths,proto3" json:"source_paths,omitempty"`
+	// The commit (in the language repo) at which the library was last released,
+	// if known. This bounds the search for changes since the last release.
+	LastReleaseCommit string `protobuf:"bytes,6,opt,name=last_release_commit,json=lastReleaseCommit,proto3" json:"last_release_commit,omitempty"`
+	// The IDs of the APIs (see ApiGenerationState.id) included in this library.
+	// These determine the recommended version bump for the next release.
+	ApiIds        []string `protobuf:"bytes,7,rep,name=api_ids,json=apiIds,proto3" json:"api_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *LibraryReleaseState) Reset() {
 	*x = LibraryReleaseState{}
-	mi := &file_pipeline_proto_msgTypes[2]
+	mi := &file_pipeline_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -253,7 +494,7 @@ func (x *LibraryReleaseState) String() string {
 func (*LibraryReleaseState) ProtoMessage() {}
 
 func (x *LibraryReleaseState) ProtoReflect() protoreflect.Message {
-	mi := &file_pipeline_proto_msgTypes[2]
+	mi := &file_pipeline_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -266,7 +507,7 @@ func (x *LibraryReleaseState) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LibraryReleaseState.ProtoReflect.Descriptor instead.
 func (*LibraryReleaseState) Descriptor() ([]byte, []int) {
-	return file_pipeline_proto_rawDescGZIP(), []int{2}
+	return file_pipeline_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *LibraryReleaseState) GetId() string {
@@ -297,12 +538,33 @@ func (x *LibraryReleaseState) GetAutomationLevel() AutomationLevel {
 	return AutomationLevel_AUTOMATION_LEVEL_NONE
 }
 
+func (x *LibraryReleaseState) GetSourcePaths() []string {
+	if x != nil {
+		return x.SourcePaths
+	}
+	return nil
+}
+
+func (x *LibraryReleaseState) GetLastReleaseCommit() string {
+	if x != nil {
+		return x.LastReleaseCommit
+	}
+	return ""
+}
+
+func (x *LibraryReleaseState) GetApiIds() []string {
+	if x != nil {
+		return x.ApiIds
+	}
+	return nil
+}
+
 var File_pipeline_proto protoreflect.FileDescriptor
 
 var file_pipeline_proto_rawDesc = string([]byte{
 	0x0a, 0x0e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 	0x12, 0x19, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x2e, 0x73,
-	0x64, 0x6b, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x22, 0xf5, 0x01, 0x0a, 0x0d,
+	0x64, 0x6b, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x22, 0xf5, 0x02, 0x0a, 0x0d,
 	0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1b, 0x0a,
 	0x09, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
 	0x52, 0x08, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x54, 0x61, 0x67, 0x12, 0x61, 0x0a, 0x15, 0x61, 0x70,
@@ -318,44 +580,95 @@ var file_pipeline_proto_rawDesc = string([]byte{
 	0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x2e, 0x4c, 0x69, 0x62, 0x72, 0x61, 0x72,
 	0x79, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x14, 0x6c,
 	0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x53, 0x74, 0x61,
-	0x74, 0x65, 0x73, 0x22, 0xaf, 0x01, 0x0a, 0x12, 0x41, 0x70, 0x69, 0x47, 0x65, 0x6e, 0x65, 0x72,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x32, 0x0a, 0x15, 0x6c, 0x61,
-	0x73, 0x74, 0x5f, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x63, 0x6f, 0x6d,
-	0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x13, 0x6c, 0x61, 0x73, 0x74, 0x47,
-	0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x55,
-	0x0a, 0x10, 0x61, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6c, 0x65, 0x76,
-	0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
-	0x65, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x2e, 0x73, 0x64, 0x6b, 0x2e, 0x70, 0x69, 0x70, 0x65,
-	0x6c, 0x69, 0x6e, 0x65, 0x2e, 0x41, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4c,
-	0x65, 0x76, 0x65, 0x6c, 0x52, 0x0f, 0x61, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e,
-	0x4c, 0x65, 0x76, 0x65, 0x6c, 0x22, 0xc8, 0x01, 0x0a, 0x13, 0x4c, 0x69, 0x62, 0x72, 0x61, 0x72,
-	0x79, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a,
-	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x27, 0x0a,
-	0x0f, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x56,
-	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x76,
-	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6e, 0x65,
-	0x78, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x55, 0x0a, 0x10, 0x61, 0x75, 0x74,
-	0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x0e, 0x32, 0x2a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6c, 0x6f,
+	0x74, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x65, 0x78, 0x70, 0x65, 0x72, 0x69, 0x6d, 0x65, 0x6e,
+	0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0b, 0x65, 0x78, 0x70, 0x65, 0x72, 0x69,
+	0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x5c, 0x0a, 0x13, 0x70, 0x75, 0x6c, 0x6c, 0x5f, 0x72, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x2c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6c, 0x6f, 0x75,
+	0x64, 0x2e, 0x73, 0x64, 0x6b, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x2e, 0x50,
+	0x75, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x52, 0x11, 0x70, 0x75, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x22, 0x67, 0x0a, 0x11, 0x50, 0x75, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x76, 0x69,
+	0x65, 0x77, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x76,
+	0x69, 0x65, 0x77, 0x65, 0x72, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x12, 0x1c,
+	0x0a, 0x09, 0x61, 0x73, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x09, 0x61, 0x73, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x65, 0x73, 0x22, 0x9e, 0x03, 0x0a,
+	0x12, 0x41, 0x70, 0x69, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x32, 0x0a, 0x15, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x67, 0x65, 0x6e, 0x65,
+	0x72, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x13, 0x6c, 0x61, 0x73, 0x74, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65,
+	0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x55, 0x0a, 0x10, 0x61, 0x75, 0x74, 0x6f, 0x6d,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x2a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x2e, 0x73, 0x64, 0x6b, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x2e, 0x41, 0x75,
+	0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x0f, 0x61,
+	0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x52,
+	0x0a, 0x0f, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65,
+	0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x29, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x2e, 0x73, 0x64, 0x6b, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c,
+	0x69, 0x6e, 0x65, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e,
+	0x65, 0x6c, 0x52, 0x0e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e,
+	0x65, 0x6c, 0x12, 0x5e, 0x0a, 0x17, 0x75, 0x6e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x64,
+	0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x62, 0x75, 0x6d, 0x70, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x26, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6c, 0x6f,
 	0x75, 0x64, 0x2e, 0x73, 0x64, 0x6b, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x2e,
-	0x41, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52,
-	0x0f, 0x61, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x65, 0x76, 0x65, 0x6c,
-	0x2a, 0x8e, 0x01, 0x0a, 0x0f, 0x41, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4c,
-	0x65, 0x76, 0x65, 0x6c, 0x12, 0x19, 0x0a, 0x15, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54, 0x49,
-	0x4f, 0x4e, 0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12,
-	0x1c, 0x0a, 0x18, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x4c, 0x45,
-	0x56, 0x45, 0x4c, 0x5f, 0x42, 0x4c, 0x4f, 0x43, 0x4b, 0x45, 0x44, 0x10, 0x01, 0x12, 0x22, 0x0a,
-	0x1e, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x4c, 0x45, 0x56, 0x45,
-	0x4c, 0x5f, 0x4d, 0x41, 0x4e, 0x55, 0x41, 0x4c, 0x5f, 0x52, 0x45, 0x56, 0x49, 0x45, 0x57, 0x10,
-	0x02, 0x12, 0x1e, 0x0a, 0x1a, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f,
-	0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54, 0x49, 0x43, 0x10,
-	0x03, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
-	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x61, 0x70, 0x69, 0x73, 0x2f, 0x6c, 0x69, 0x62, 0x72, 0x61,
-	0x72, 0x69, 0x61, 0x6e, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x73, 0x74,
-	0x61, 0x74, 0x65, 0x70, 0x62, 0x3b, 0x73, 0x74, 0x61, 0x74, 0x65, 0x70, 0x62, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x75, 0x6d, 0x70, 0x52, 0x15, 0x75, 0x6e, 0x72,
+	0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x75,
+	0x6d, 0x70, 0x12, 0x39, 0x0a, 0x19, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x67, 0x65, 0x6e, 0x65, 0x72,
+	0x61, 0x74, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x16, 0x6c, 0x61, 0x73, 0x74, 0x47, 0x65, 0x6e, 0x65, 0x72,
+	0x61, 0x74, 0x65, 0x64, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x48, 0x61, 0x73, 0x68, 0x22, 0xb4, 0x02,
+	0x0a, 0x13, 0x4c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65,
+	0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x27, 0x0a, 0x0f, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e,
+	0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x21,
+	0x0a, 0x0c, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6e, 0x65, 0x78, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x55, 0x0a, 0x10, 0x61, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f,
+	0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2a, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x2e, 0x73, 0x64, 0x6b, 0x2e, 0x70,
+	0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x2e, 0x41, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x0f, 0x61, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0b,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x50, 0x61, 0x74, 0x68, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x6c,
+	0x61, 0x73, 0x74, 0x5f, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x63, 0x6f, 0x6d, 0x6d,
+	0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x11, 0x6c, 0x61, 0x73, 0x74, 0x52, 0x65,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x61,
+	0x70, 0x69, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x61, 0x70,
+	0x69, 0x49, 0x64, 0x73, 0x2a, 0x8e, 0x01, 0x0a, 0x0f, 0x41, 0x75, 0x74, 0x6f, 0x6d, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x19, 0x0a, 0x15, 0x41, 0x55, 0x54, 0x4f,
+	0x4d, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x4e, 0x4f, 0x4e,
+	0x45, 0x10, 0x00, 0x12, 0x1c, 0x0a, 0x18, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54, 0x49, 0x4f,
+	0x4e, 0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x42, 0x4c, 0x4f, 0x43, 0x4b, 0x45, 0x44, 0x10,
+	0x01, 0x12, 0x22, 0x0a, 0x1e, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f,
+	0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x4d, 0x41, 0x4e, 0x55, 0x41, 0x4c, 0x5f, 0x52, 0x45, 0x56,
+	0x49, 0x45, 0x57, 0x10, 0x02, 0x12, 0x1e, 0x0a, 0x1a, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x41, 0x55, 0x54, 0x4f, 0x4d, 0x41,
+	0x54, 0x49, 0x43, 0x10, 0x03, 0x2a, 0x82, 0x01, 0x0a, 0x0e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x1f, 0x0a, 0x1b, 0x52, 0x45, 0x4c, 0x45,
+	0x41, 0x53, 0x45, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e, 0x45, 0x4c, 0x5f, 0x55, 0x4e, 0x53, 0x50,
+	0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x1a, 0x0a, 0x16, 0x52, 0x45, 0x4c,
+	0x45, 0x41, 0x53, 0x45, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e, 0x45, 0x4c, 0x5f, 0x53, 0x54, 0x41,
+	0x42, 0x4c, 0x45, 0x10, 0x01, 0x12, 0x18, 0x0a, 0x14, 0x52, 0x45, 0x4c, 0x45, 0x41, 0x53, 0x45,
+	0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e, 0x45, 0x4c, 0x5f, 0x42, 0x45, 0x54, 0x41, 0x10, 0x02, 0x12,
+	0x19, 0x0a, 0x15, 0x52, 0x45, 0x4c, 0x45, 0x41, 0x53, 0x45, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e,
+	0x45, 0x4c, 0x5f, 0x41, 0x4c, 0x50, 0x48, 0x41, 0x10, 0x03, 0x2a, 0x6c, 0x0a, 0x0b, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x75, 0x6d, 0x70, 0x12, 0x15, 0x0a, 0x11, 0x56, 0x45, 0x52,
+	0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x42, 0x55, 0x4d, 0x50, 0x5f, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00,
+	0x12, 0x16, 0x0a, 0x12, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x42, 0x55, 0x4d, 0x50,
+	0x5f, 0x50, 0x41, 0x54, 0x43, 0x48, 0x10, 0x01, 0x12, 0x16, 0x0a, 0x12, 0x56, 0x45, 0x52, 0x53,
+	0x49, 0x4f, 0x4e, 0x5f, 0x42, 0x55, 0x4d, 0x50, 0x5f, 0x4d, 0x49, 0x4e, 0x4f, 0x52, 0x10, 0x02,
+	0x12, 0x16, 0x0a, 0x12, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x42, 0x55, 0x4d, 0x50,
+	0x5f, 0x4d, 0x41, 0x4a, 0x4f, 0x52, 0x10, 0x03, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x61, 0x70, 0x69,
+	0x73, 0x2f, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x2f, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x70, 0x62, 0x3b, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 })
 
 var (
@@ -370,24 +683,30 @@ func file_pipeline_proto_rawDescGZIP() []byte {
 	return file_pipeline_proto_rawDescData
 }
 
-var file_pipeline_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_pipeline_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_pipeline_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_pipeline_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_pipeline_proto_goTypes = []any{
 	(AutomationLevel)(0),        // 0: google.cloud.sdk.pipeline.AutomationLevel
-	(*PipelineState)(nil),       // 1: google.cloud.sdk.pipeline.PipelineState
-	(*ApiGenerationState)(nil),  // 2: google.cloud.sdk.pipeline.ApiGenerationState
-	(*LibraryReleaseState)(nil), // 3: google.cloud.sdk.pipeline.LibraryReleaseState
+	(ReleaseChannel)(0),         // 1: google.cloud.sdk.pipeline.ReleaseChannel
+	(VersionBump)(0),            // 2: google.cloud.sdk.pipeline.VersionBump
+	(*PipelineState)(nil),       // 3: google.cloud.sdk.pipeline.PipelineState
+	(*PullRequestConfig)(nil),   // 4: google.cloud.sdk.pipeline.PullRequestConfig
+	(*ApiGenerationState)(nil),  // 5: google.cloud.sdk.pipeline.ApiGenerationState
+	(*LibraryReleaseState)(nil), // 6: google.cloud.sdk.pipeline.LibraryReleaseState
 }
 var file_pipeline_proto_depIdxs = []int32{
-	2, // 0: google.cloud.sdk.pipeline.PipelineState.api_generation_states:type_name -> google.cloud.sdk.pipeline.ApiGenerationState
-	3, // 1: google.cloud.sdk.pipeline.PipelineState.library_release_states:type_name -> google.cloud.sdk.pipeline.LibraryReleaseState
-	0, // 2: google.cloud.sdk.pipeline.ApiGenerationState.automation_level:type_name -> google.cloud.sdk.pipeline.AutomationLevel
-	0, // 3: google.cloud.sdk.pipeline.LibraryReleaseState.automation_level:type_name -> google.cloud.sdk.pipeline.AutomationLevel
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	5, // 0: google.cloud.sdk.pipeline.PipelineState.api_generation_states:type_name -> google.cloud.sdk.pipeline.ApiGenerationState
+	6, // 1: google.cloud.sdk.pipeline.PipelineState.library_release_states:type_name -> google.cloud.sdk.pipeline.LibraryReleaseState
+	4, // 2: google.cloud.sdk.pipeline.PipelineState.pull_request_config:type_name -> google.cloud.sdk.pipeline.PullRequestConfig
+	0, // 3: google.cloud.sdk.pipeline.ApiGenerationState.automation_level:type_name -> google.cloud.sdk.pipeline.AutomationLevel
+	1, // 4: google.cloud.sdk.pipeline.ApiGenerationState.release_channel:type_name -> google.cloud.sdk.pipeline.ReleaseChannel
+	2, // 5: google.cloud.sdk.pipeline.ApiGenerationState.unreleased_version_bump:type_name -> google.cloud.sdk.pipeline.VersionBump
+	0, // 6: google.cloud.sdk.pipeline.LibraryReleaseState.automation_level:type_name -> google.cloud.sdk.pipeline.AutomationLevel
+	7, // [7:7] is the sub-list for method output_type
+	7, // [7:7] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_pipeline_proto_init() }
@@ -400,8 +719,8 @@ func file_pipeline_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pipeline_proto_rawDesc), len(file_pipeline_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   3,
+			NumEnums:      3,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},